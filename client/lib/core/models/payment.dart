@@ -1,4 +1,4 @@
-enum PaymentStatus { pending, success, failed, expired, cancelled }
+enum PaymentStatus { pending, success, failed, expired, cancelled, refunded }
 
 enum SubscriptionPlan { free, vip }
 
@@ -104,13 +104,21 @@ class Payment {
       case 'pending':
         return PaymentStatus.pending;
       case 'success':
+      case 'settlement':
         return PaymentStatus.success;
       case 'failed':
+      case 'deny':
         return PaymentStatus.failed;
       case 'expired':
+      case 'expire':
         return PaymentStatus.expired;
       case 'cancelled':
+      case 'cancel':
         return PaymentStatus.cancelled;
+      case 'refunded':
+      case 'refund':
+      case 'partial_refund':
+        return PaymentStatus.refunded;
       default:
         return PaymentStatus.pending;
     }
@@ -128,6 +136,8 @@ class Payment {
         return 'expired';
       case PaymentStatus.cancelled:
         return 'cancelled';
+      case PaymentStatus.refunded:
+        return 'refunded';
     }
   }
 
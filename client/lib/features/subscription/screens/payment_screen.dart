@@ -473,6 +473,11 @@ class _PaymentScreenState extends State<PaymentScreen> {
         statusIcon = Iconsax.close_square;
         statusText = 'Pembayaran Dibatalkan';
         break;
+      case PaymentStatus.refunded:
+        statusColor = Colors.blue;
+        statusIcon = Iconsax.money_recive;
+        statusText = 'Dana Dikembalikan';
+        break;
     }
 
     return Container(
@@ -110,6 +110,9 @@ class _PaymentWebViewScreenState extends State<PaymentWebViewScreen> {
         case PaymentStatus.expired:
           widget.onPaymentCompleted(PaymentStatus.expired, 'Payment expired');
           break;
+        case PaymentStatus.refunded:
+          widget.onPaymentCompleted(PaymentStatus.refunded, 'Payment refunded');
+          break;
         case PaymentStatus.pending:
           widget.onPaymentCompleted(PaymentStatus.pending, 'Payment is being processed');
           break;
@@ -36,14 +36,21 @@ func main() {
 	if err := database.DB.AutoMigrate(
 		&models.User{},
 		&models.Task{},
+		&models.Device{},           // Push notification devices per user
+		&models.TaskReminder{},     // Extra reminders per task
+		&models.ReminderDelivery{}, // Sent reminders and overdue nudges
 		&models.Category{},
 		&models.Subscription{},
 		&models.PasswordReset{},
 		&models.Transaction{},
 		&models.BotMessage{},
-		&models.Holiday{},     // Holiday model
-		&models.Leave{},       // Leave model
-		&models.ChatMessage{}, // ChatMessage model
+		&models.Holiday{},          // Holiday model
+		&models.Leave{},            // Leave model
+		&models.ChatMessage{},      // ChatMessage model
+		&models.ChatConversation{}, // AI chat threads
+		&models.AIAction{},         // AI assistant tool call log
+		&models.AIUsage{},          // AI token usage per request
+		&models.Job{},              // Background job queue
 		// Security models (Keamanan Basis Data)
 		&models.AuditLog{},
 		&models.SecurityEvent{},
@@ -52,6 +59,25 @@ func main() {
 		&models.PasswordHistory{},
 		// Email Verification model
 		&models.EmailVerification{},
+		// Payment webhook event log
+		&models.WebhookEvent{},
+		// Payment reconciliation run reports
+		&models.PaymentReconciliationReport{},
+		// Notification preferences, held digest notifications and delivery history
+		&models.NotificationPreference{},
+		&models.NotificationDigestItem{},
+		&models.NotificationHistory{},
+		// Real-time events relayed between replicas
+		&models.RealtimeEvent{},
+		// Outgoing email outbox
+		&models.EmailOutbox{},
+		// Server-side login sessions and their rotated refresh tokens
+		&models.ActiveSession{},
+		&models.RefreshToken{},
+		// Shared security state (STATE_STORE=database)
+		&models.StateEntry{},
+		// Hashed single-use MFA backup codes
+		&models.MFABackupCode{},
 	); err != nil {
 		log.Fatal("Failed to run migrations:", err)
 	}
@@ -61,6 +87,12 @@ func main() {
 	userRepo := repository.NewUserRepository(database.DB)
 	categoryRepo := repository.NewCategoryRepository(database.DB)
 	taskRepo := repository.NewTaskRepository(database.DB)
+	taskReminderRepo := repository.NewTaskReminderRepository(database.DB)
+	jobRepo := repository.NewJobRepository(database.DB)
+	deviceRepo := repository.NewDeviceRepository(database.DB)
+	notificationPreferenceRepo := repository.NewNotificationPreferenceRepository(database.DB)
+	realtimeEventRepo := repository.NewRealtimeEventRepository(database.DB)
+	emailOutboxRepo := repository.NewEmailOutboxRepository(database.DB)
 	passwordResetRepo := repository.NewPasswordResetRepository(database.DB)
 	emailVerificationRepo := repository.NewEmailVerificationRepository(database.DB)
 	subscriptionRepo := repository.NewSubscriptionRepository(database.DB)
@@ -69,12 +101,47 @@ func main() {
 	holidayRepo := repository.NewHolidayRepository(database.DB)
 	leaveRepo := repository.NewLeaveRepository(database.DB)
 	chatRepo := repository.NewChatRepository(database.DB)
+	aiActionRepo := repository.NewAIActionRepository(database.DB)
+	chatConversationRepo := repository.NewChatConversationRepository(database.DB)
+	aiUsageRepo := repository.NewAIUsageRepository(database.DB)
 	auditRepo := repository.NewAuditRepository(database.DB) // Security: Audit Repository
+	webhookEventRepo := repository.NewWebhookEventRepository(database.DB)
+	paymentReconciliationRepo := repository.NewPaymentReconciliationRepository(database.DB)
+	stateEntryRepo := repository.NewStateEntryRepository(database.DB)
+	mfaBackupCodeRepo := repository.NewMFABackupCodeRepository(database.DB)
+
+	// Move push tokens stored on users (single device) into the device table
+	if imported, err := deviceRepo.ImportLegacyTokens(); err != nil {
+		log.Printf("⚠️ Failed to import legacy FCM tokens: %v", err)
+	} else if imported > 0 {
+		log.Printf("📱 Imported %d legacy FCM tokens as devices", imported)
+	}
 
 	// Initialize security services first (needed for middleware)
 	auditService := services.NewAuditService(auditRepo)
 	threatConfig := middleware.DefaultThreatDetectionConfig()
 
+	// Initialize shared state store (STATE_STORE=database or redis shares the token
+	// blacklist and rate limits across replicas and restarts)
+	var stateStore services.StateStore
+	switch config.AppConfig.StateStore {
+	case services.StateStoreDatabase:
+		stateStore = services.NewDatabaseStateStore(stateEntryRepo)
+	case services.StateStoreRedis:
+		redisStore, err := services.NewRedisStateStore(config.AppConfig.RedisURL)
+		if err != nil {
+			log.Fatal("Failed to connect to Redis:", err)
+		}
+		defer redisStore.Close()
+		stateStore = redisStore
+	case services.StateStoreMemory:
+		stateStore = services.NewMemoryStateStore()
+	default:
+		log.Fatalf("Unknown STATE_STORE %q", config.AppConfig.StateStore)
+	}
+	services.InitTokenBlacklistService(stateStore)
+	userStateService := services.NewUserStateService(userRepo, stateStore, config.AppConfig.UserStateCacheTTL)
+
 	// Initialize Fiber app
 	app := fiber.New(fiber.Config{
 		AppName: "Workradar API v1.0",
@@ -87,7 +154,7 @@ func main() {
 	// Security middlewares
 	app.Use(middleware.SecurityHeadersMiddleware())
 	app.Use(middleware.RequestIDMiddleware())
-	app.Use(middleware.RateLimitMiddleware())
+	app.Use(middleware.RateLimitMiddleware(stateStore))
 
 	// Threat Detection middleware (Keamanan Basis Data - Minggu 2)
 	app.Use(middleware.ThreatDetectionMiddleware(auditService, threatConfig))
@@ -101,19 +168,104 @@ func main() {
 		AllowCredentials: true,
 	}))
 
+	// Initialize background job queue (notifications and emails, retried and deduplicated)
+	jobQueue := services.NewJobQueue(
+		jobRepo,
+		config.AppConfig.JobWorkers,
+		config.AppConfig.JobPollInterval,
+		config.AppConfig.JobLeaseDuration,
+		config.AppConfig.JobMaxAttempts,
+	)
+
+	// Initialize email (EMAIL_TRANSPORT=file writes emails to a local mailbox)
+	mailTransport, err := services.NewMailTransportFromConfig()
+	if err != nil {
+		log.Fatal("Failed to initialize email transport:", err)
+	}
+	if config.AppConfig.EmailTransport == services.EmailTransportFile {
+		if config.AppConfig.Env == "production" {
+			log.Fatal("EMAIL_TRANSPORT=file is not allowed in production")
+		}
+		log.Printf("⚠️ Using file email transport - emails are saved to %s, not delivered", config.AppConfig.EmailMailboxDir)
+	}
+	emailService := services.NewEmailService(
+		mailTransport,
+		services.NewEmailRenderer(config.AppConfig.EmailTemplateDir),
+		emailOutboxRepo,
+		jobQueue,
+	)
+	jobQueue.Register(services.JobTypeEmail, emailService.HandleJob)
+
+	// Initialize real-time event hub (EVENT_BROKER=database shares events across replicas)
+	var eventBroker services.EventBroker
+	if config.AppConfig.EventBroker == services.EventBrokerDatabase {
+		eventBroker = services.NewDatabaseEventBroker(realtimeEventRepo, config.AppConfig.EventPollInterval)
+	} else {
+		eventBroker = services.NewMemoryEventBroker()
+	}
+	eventHub := services.NewEventHub(eventBroker)
+	defer eventHub.Close()
+
 	// Initialize services
-	authService := services.NewAuthService(userRepo, categoryRepo, passwordResetRepo, emailVerificationRepo)
-	taskService := services.NewTaskService(taskRepo, categoryRepo)
+	sessionService := services.NewSessionManagementService(database.DB, auditService, config.AppConfig.MaxSessionsPerUser)
+	authService := services.NewAuthService(userRepo, categoryRepo, passwordResetRepo, emailVerificationRepo, emailService, sessionService, userStateService)
+	taskService := services.NewTaskService(taskRepo, categoryRepo, taskReminderRepo, userRepo, eventHub)
 	categoryService := services.NewCategoryService(categoryRepo, taskRepo)
 	profileService := services.NewProfileService(userRepo, taskRepo, categoryRepo)
 	calendarService := services.NewCalendarService(taskRepo)
-	subscriptionService := services.NewSubscriptionService(userRepo, subscriptionRepo, database.DB)
+	subscriptionService := services.NewSubscriptionService(userRepo, subscriptionRepo, database.DB, userStateService)
 	workloadService := services.NewWorkloadService(taskRepo)
-	botMessageService := services.NewBotMessageService(botMessageRepo)
-	paymentService := services.NewPaymentService(transactionRepo, userRepo, subscriptionService, botMessageService)
+	botMessageService := services.NewBotMessageService(botMessageRepo, eventHub)
+
+	// Initialize payment gateway (PAYMENT_GATEWAY=fake runs payments fully in-process)
+	var paymentGateway services.PaymentGateway
+	if config.AppConfig.PaymentGateway == services.PaymentGatewayFake {
+		if config.AppConfig.Env == "production" {
+			log.Fatal("PAYMENT_GATEWAY=fake is not allowed in production")
+		}
+		log.Println("⚠️ Using fake payment gateway - payments are simulated via /api/payments/simulate/:order_id")
+		paymentGateway = services.NewFakePaymentGateway()
+	} else {
+		midtransGateway, err := services.NewMidtransGateway(
+			config.AppConfig.MidtransServerKey,
+			config.AppConfig.MidtransIsProduction,
+		)
+		if err != nil {
+			log.Fatal("Failed to initialize Midtrans gateway:", err)
+		}
+		paymentGateway = midtransGateway
+	}
+
+	paymentService := services.NewPaymentService(transactionRepo, userRepo, subscriptionService, botMessageService, paymentGateway, eventHub)
+	paymentReconciler := services.NewPaymentReconciliationService(
+		transactionRepo,
+		paymentReconciliationRepo,
+		paymentService,
+		paymentGateway,
+		config.AppConfig.PaymentReconcileInterval,
+		config.AppConfig.PaymentReconcileStaleAfter,
+		config.AppConfig.PaymentReconcileExpireAfter,
+	)
+	webhookEventService := services.NewWebhookEventService(webhookEventRepo, paymentService)
 	holidayService := services.NewHolidayService(holidayRepo)
+	schedulingService := services.NewSchedulingService(userRepo, taskRepo, holidayRepo, leaveRepo, eventHub)
 	leaveService := services.NewLeaveService(leaveRepo)
-	aiService := services.NewAIService(chatRepo, taskRepo, userRepo, config.AppConfig.GroqAPIKey)
+	aiToolExecutor := services.NewAIToolExecutor(aiActionRepo, userRepo, taskService, leaveService)
+	llmClient := services.NewLLMClientFromConfig(
+		config.AppConfig.LLMProviders,
+		config.AppConfig.LLMMaxRetries,
+		config.AppConfig.LLMRetryBackoff,
+		config.AppConfig.LLMTimeout,
+	)
+	chatConversationService := services.NewChatConversationService(chatConversationRepo, chatRepo, taskRepo, categoryRepo)
+	aiUsageService := services.NewAIUsageService(aiUsageRepo, services.AIUsageLimits{
+		DailyTokensPerUser:   config.AppConfig.AIDailyTokensPerUser,
+		MonthlyTokensPerUser: config.AppConfig.AIMonthlyTokensPerUser,
+		GlobalMonthlyTokens:  config.AppConfig.AIGlobalMonthlyTokens,
+		InputPricePerMTok:    config.AppConfig.AIPriceInputPerMTok,
+		OutputPricePerMTok:   config.AppConfig.AIPriceOutputPerMTok,
+	})
+	aiService := services.NewAIService(chatRepo, taskRepo, userRepo, chatConversationService, aiToolExecutor, aiUsageService, llmClient, config.AppConfig.AIContextTokens)
 	oauthService := services.NewOAuthService(
 		config.AppConfig.GoogleClientID,
 		config.AppConfig.GoogleClientSecret,
@@ -121,27 +273,72 @@ func main() {
 	)
 	weatherService := services.NewWeatherService(config.AppConfig.WeatherAPIKey)
 
-	// Initialize notification service (Firebase FCM)
-	notificationService, err := services.NewNotificationService(
+	// Initialize notification channels (NOTIFICATION_SINK captures push and email locally)
+	notifiers := map[models.NotificationChannel]services.Notifier{
+		models.NotificationChannelEmail: services.NewEmailNotifier(userRepo, emailService),
+		models.NotificationChannelInApp: services.NewInAppNotifier(botMessageService),
+	}
+	switch config.AppConfig.NotificationSink {
+	case services.NotificationSinkMemory, services.NotificationSinkFile:
+		if config.AppConfig.Env == "production" {
+			log.Fatal("NOTIFICATION_SINK is not allowed in production")
+		}
+		sinkPath := ""
+		if config.AppConfig.NotificationSink == services.NotificationSinkFile {
+			sinkPath = config.AppConfig.NotificationSinkFile
+		}
+		log.Printf("⚠️ Using %s notification sink - push and email are captured, not delivered", config.AppConfig.NotificationSink)
+		notifiers[models.NotificationChannelPush] = services.NewSinkNotifier(models.NotificationChannelPush, sinkPath)
+		notifiers[models.NotificationChannelEmail] = services.NewSinkNotifier(models.NotificationChannelEmail, sinkPath)
+	default:
+		if config.AppConfig.FirebaseProjectID != "" && config.AppConfig.FirebaseCredentialsFile != "" {
+			fcmNotifier, err := services.NewFCMNotifier(
+				deviceRepo,
+				config.AppConfig.FirebaseProjectID,
+				config.AppConfig.FirebaseCredentialsFile,
+			)
+			if err != nil {
+				log.Fatalf("Failed to initialize NotificationService: %v", err)
+			}
+			notifiers[models.NotificationChannelPush] = fcmNotifier
+		}
+	}
+	notificationService := services.NewNotificationService(deviceRepo, userRepo, notificationPreferenceRepo, jobQueue, eventHub, notifiers)
+
+	plannerService := services.NewPlannerService(
 		userRepo,
-		config.AppConfig.FirebaseProjectID,
-		config.AppConfig.FirebaseCredentialsFile,
+		taskRepo,
+		holidayRepo,
+		leaveRepo,
+		workloadService,
+		botMessageService,
+		notificationService,
+		aiUsageService,
+		llmClient,
 	)
-	if err != nil {
-		log.Fatalf("Failed to initialize NotificationService: %v", err)
-	}
 
 	// Initialize scheduler service for background notifications
 	schedulerService := services.NewSchedulerService(
 		database.DB,
 		userRepo,
 		taskRepo,
+		taskReminderRepo,
 		notificationService,
 		weatherService,
+		plannerService,
+		jobQueue,
 	)
 	schedulerService.Start()
 	defer schedulerService.Stop()
 
+	// Start job workers after all handlers are registered
+	jobQueue.Start()
+	defer jobQueue.Stop()
+
+	// Start payment reconciliation (recovers transactions whose webhook was lost)
+	paymentReconciler.Start()
+	defer paymentReconciler.Stop()
+
 	// Initialize security scheduler service (Keamanan Basis Data - Phase 4: Monitoring)
 	securitySchedulerService := services.NewSecuritySchedulerService(database.DB)
 	securitySchedulerService.Start()
@@ -149,25 +346,33 @@ func main() {
 
 	// Initialize handlers
 	authHandler := handlers.NewAuthHandler(authService)
+	sessionHandler := handlers.NewSessionHandler(sessionService)
 	taskHandler := handlers.NewTaskHandler(taskService)
+	schedulingHandler := handlers.NewSchedulingHandler(schedulingService)
 	categoryHandler := handlers.NewCategoryHandler(categoryService)
 	profileHandler := handlers.NewProfileHandler(profileService)
 	calendarHandler := handlers.NewCalendarHandler(calendarService)
 	subscriptionHandler := handlers.NewSubscriptionHandler(subscriptionService)
 	workloadHandler := handlers.NewWorkloadHandler(workloadService)
-	paymentHandler := handlers.NewPaymentHandler(paymentService)
+	paymentHandler := handlers.NewPaymentHandler(paymentService, paymentReconciler, webhookEventService)
+	webhookEventHandler := handlers.NewWebhookEventHandler(webhookEventService)
 	botMessageHandler := handlers.NewBotMessageHandler(botMessageService)
 	holidayHandler := handlers.NewHolidayHandler(holidayService)
 	leaveHandler := handlers.NewLeaveHandler(leaveService)
 	chatHandler := handlers.NewChatHandler(aiService)
+	aiUsageHandler := handlers.NewAIUsageHandler(aiUsageService)
+	jobHandler := handlers.NewJobHandler(jobQueue)
+	chatConversationHandler := handlers.NewChatConversationHandler(chatConversationService)
+	plannerHandler := handlers.NewPlannerHandler(plannerService)
 	oauthHandler := handlers.NewOAuthHandler(oauthService, authService)
 	weatherHandler := handlers.NewWeatherHandler(weatherService)
 	notificationHandler := handlers.NewNotificationHandler(notificationService)
+	eventHandler := handlers.NewEventHandler(eventHub, sessionService)
 	securityHandler := handlers.NewSecurityHandler(auditService) // Security: Handler
 
 	// MFA Service & Handler (Minggu 3: Multi-Factor Authentication)
-	mfaService := services.NewMFAService(userRepo)
-	mfaHandler := handlers.NewMFAHandler(mfaService)
+	mfaService := services.NewMFAService(userRepo, mfaBackupCodeRepo, emailService, auditService, stateStore)
+	mfaHandler := handlers.NewMFAHandler(mfaService, authService)
 
 	// Monitoring Handler (Keamanan Basis Data - Phase 4: Monitoring & Maintenance)
 	monitoringHandler := handlers.NewMonitoringHandler()
@@ -184,10 +389,10 @@ func main() {
 	})
 
 	// Enhanced health check endpoints (Keamanan Basis Data - Phase 4)
-	api.Get("/health/detailed", middleware.AuthMiddleware(), monitoringHandler.DetailedHealthCheck)
+	api.Get("/health/detailed", middleware.AuthMiddleware(sessionService, userStateService), monitoringHandler.DetailedHealthCheck)
 	api.Get("/ready", monitoringHandler.ReadinessCheck)
 	api.Get("/live", monitoringHandler.LivenessCheck)
-	api.Get("/metrics", middleware.AuthMiddleware(), monitoringHandler.GetMetrics)
+	api.Get("/metrics", middleware.AuthMiddleware(sessionService, userStateService), monitoringHandler.GetMetrics)
 
 	// Auth routes (public)
 	auth := api.Group("/auth")
@@ -214,66 +419,101 @@ func main() {
 	mfa := auth.Group("/mfa")
 	mfa.Post("/verify-login", mfaHandler.VerifyMFALogin) // Public - verify MFA during login
 	// Protected MFA routes (require authentication)
-	mfaProtected := mfa.Group("", middleware.AuthMiddleware())
+	mfaProtected := mfa.Group("", middleware.AuthMiddleware(sessionService, userStateService))
 	mfaProtected.Get("/status", mfaHandler.GetMFAStatus)
 	mfaProtected.Post("/enable", mfaHandler.EnableMFA)
 	mfaProtected.Post("/verify", mfaHandler.VerifyMFA)
 	mfaProtected.Post("/disable", mfaHandler.DisableMFA)
+	mfaProtected.Post("/backup-codes", mfaHandler.RegenerateBackupCodes)
 
 	// Protected routes - Profile
-	profile := api.Group("/profile", middleware.AuthMiddleware())
+	profile := api.Group("/profile", middleware.AuthMiddleware(sessionService, userStateService))
 	profile.Get("/", profileHandler.GetFullProfile)
 	profile.Get("/stats", profileHandler.GetStats)
 	profile.Put("/", authHandler.UpdateProfile)
 	profile.Post("/change-password", authHandler.ChangePassword)
+	profile.Get("/sessions", sessionHandler.GetSessions)
+	profile.Delete("/sessions", sessionHandler.RevokeOtherSessions)
+	profile.Delete("/sessions/:id", sessionHandler.RevokeSession)
 	profile.Get("/work-hours", profileHandler.GetWorkHours)
 	profile.Put("/work-hours", profileHandler.UpdateWorkHours)
 
 	// Protected routes - Tasks
-	tasks := api.Group("/tasks", middleware.AuthMiddleware())
+	tasks := api.Group("/tasks", middleware.AuthMiddleware(sessionService, userStateService))
 	tasks.Post("/", taskHandler.CreateTask)
+	tasks.Post("/parse", taskHandler.ParseTask)
+	tasks.Get("/schedule/preview", schedulingHandler.PreviewSchedule)
+	tasks.Post("/schedule/apply", schedulingHandler.ApplySchedule)
+	tasks.Delete("/:id/schedule", schedulingHandler.ClearSchedule)
 	tasks.Get("/", taskHandler.GetTasks)
+	tasks.Get("/matrix", taskHandler.GetMatrix)
 	tasks.Get("/:id", taskHandler.GetTaskByID)
 	tasks.Put("/:id", taskHandler.UpdateTask)
 	tasks.Delete("/:id", taskHandler.DeleteTask)
 	tasks.Patch("/:id/toggle", taskHandler.ToggleComplete)
+	tasks.Get("/:id/reminders", taskHandler.GetReminderHistory)
 
 	// Protected routes - Categories
-	categories := api.Group("/categories", middleware.AuthMiddleware())
+	categories := api.Group("/categories", middleware.AuthMiddleware(sessionService, userStateService))
 	categories.Get("/", categoryHandler.GetCategories)
 	categories.Post("/", categoryHandler.CreateCategory)
 	categories.Put("/:id", categoryHandler.UpdateCategory)
 	categories.Delete("/:id", categoryHandler.DeleteCategory)
 
 	// Protected routes - Calendar
-	calendar := api.Group("/calendar", middleware.AuthMiddleware())
+	calendar := api.Group("/calendar", middleware.AuthMiddleware(sessionService, userStateService))
 	calendar.Get("/today", calendarHandler.GetTodayTasks)
 	calendar.Get("/week", calendarHandler.GetWeekTasks)
 	calendar.Get("/month", calendarHandler.GetMonthTasks)
 	calendar.Get("/range", calendarHandler.GetTasksByDateRange)
 
 	// Protected routes - Subscription
-	subscription := api.Group("/subscription", middleware.AuthMiddleware())
+	subscription := api.Group("/subscription", middleware.AuthMiddleware(sessionService, userStateService))
 	subscription.Post("/upgrade", subscriptionHandler.UpgradeToVIP)
 	subscription.Get("/status", subscriptionHandler.GetVIPStatus)
 	subscription.Get("/history", subscriptionHandler.GetHistory)
 
 	// Payment routes
-	payments := api.Group("/payments", middleware.AuthMiddleware())
+	payments := api.Group("/payments", middleware.AuthMiddleware(sessionService, userStateService))
 	payments.Post("/create", paymentHandler.GetSnapToken)            // Create payment and get snap token
 	payments.Get("/history", paymentHandler.GetPaymentHistory)       // Get user payment history
 	payments.Get("/:order_id", paymentHandler.GetPaymentStatus)      // Get payment status
 	payments.Post("/:order_id/cancel", paymentHandler.CancelPayment) // Cancel pending payment
+	if paymentGateway.Name() == services.PaymentGatewayFake {
+		payments.Post("/simulate/:order_id", paymentHandler.SimulatePayment) // Complete payment via fake gateway
+	}
 
 	// Public webhook route for Midtrans
 	api.Post("/webhooks/midtrans", paymentHandler.HandleNotification)
 
+	// Admin routes - Payment reconciliation
+	adminPayments := api.Group("/admin/payments", middleware.AuthMiddleware(sessionService, userStateService), middleware.AdminOnlyMiddleware())
+	adminPayments.Get("/reconciliation", paymentHandler.GetReconciliationReports)
+	adminPayments.Post("/reconciliation/run", paymentHandler.RunReconciliation)
+	adminPayments.Post("/:order_id/refund", paymentHandler.RefundPayment)
+
+	// Admin routes - Payment webhook event log
+	adminWebhooks := api.Group("/admin/webhooks", middleware.AuthMiddleware(sessionService, userStateService), middleware.AdminOnlyMiddleware())
+	adminWebhooks.Get("/", webhookEventHandler.ListEvents)
+	adminWebhooks.Get("/:id", webhookEventHandler.GetEvent)
+	adminWebhooks.Post("/:id/replay", webhookEventHandler.ReplayEvent)
+
+	// Admin routes - Background job queue and dead letters
+	adminJobs := api.Group("/admin/jobs", middleware.AuthMiddleware(sessionService, userStateService), middleware.AdminOnlyMiddleware())
+	adminJobs.Get("/", jobHandler.ListJobs)
+	adminJobs.Get("/stats", jobHandler.GetStats)
+	adminJobs.Post("/:id/retry", jobHandler.RetryJob)
+
+	// Admin routes - AI token usage
+	adminAI := api.Group("/admin/ai", middleware.AuthMiddleware(sessionService, userStateService), middleware.AdminOnlyMiddleware())
+	adminAI.Get("/usage", aiUsageHandler.GetStats)
+
 	// Protected routes - Workload
-	workload := api.Group("/workload", middleware.AuthMiddleware())
+	workload := api.Group("/workload", middleware.AuthMiddleware(sessionService, userStateService))
 	workload.Get("/", workloadHandler.GetWorkload)
 
 	// Protected routes - Bot Messages
-	messages := api.Group("/messages", middleware.AuthMiddleware())
+	messages := api.Group("/messages", middleware.AuthMiddleware(sessionService, userStateService))
 	messages.Get("/", botMessageHandler.GetMessages)
 	messages.Get("/unread", botMessageHandler.GetUnreadMessages)
 	messages.Get("/unread/count", botMessageHandler.GetUnreadCount)
@@ -282,13 +522,13 @@ func main() {
 	messages.Delete("/:id", botMessageHandler.DeleteMessage)
 
 	// Protected routes - Holidays
-	holidays := api.Group("/holidays", middleware.AuthMiddleware())
+	holidays := api.Group("/holidays", middleware.AuthMiddleware(sessionService, userStateService))
 	holidays.Get("/", holidayHandler.GetHolidays)
 	holidays.Post("/personal", holidayHandler.CreatePersonalHoliday)
 	holidays.Delete("/personal/:id", holidayHandler.DeletePersonalHoliday)
 
 	// Protected routes - Leaves
-	leaves := api.Group("/leaves", middleware.AuthMiddleware())
+	leaves := api.Group("/leaves", middleware.AuthMiddleware(sessionService, userStateService))
 	leaves.Get("/", leaveHandler.GetLeaves)
 	leaves.Get("/upcoming/count", leaveHandler.GetUpcomingCount)
 	leaves.Post("/", leaveHandler.CreateLeave)
@@ -296,25 +536,47 @@ func main() {
 	leaves.Delete("/:id", leaveHandler.DeleteLeave)
 
 	// Protected routes - AI Chatbot (VIP ONLY)
-	aiChat := api.Group("/ai", middleware.AuthMiddleware(), middleware.VIPMiddleware())
+	aiChat := api.Group("/ai", middleware.AuthMiddleware(sessionService, userStateService), middleware.VIPMiddleware())
 	aiChat.Post("/chat", chatHandler.Chat)
+	aiChat.Post("/chat/stream", chatHandler.ChatStream)
+	aiChat.Get("/models", chatHandler.GetModels)
+	aiChat.Get("/usage", aiUsageHandler.GetQuota)
 	aiChat.Get("/history", chatHandler.GetHistory)
 	aiChat.Delete("/history", chatHandler.ClearHistory)
+	aiChat.Get("/conversations", chatConversationHandler.ListConversations)
+	aiChat.Post("/conversations", chatConversationHandler.CreateConversation)
+	aiChat.Get("/conversations/:id", chatConversationHandler.GetConversation)
+	aiChat.Patch("/conversations/:id", chatConversationHandler.UpdateConversation)
+	aiChat.Delete("/conversations/:id", chatConversationHandler.DeleteConversation)
+	aiChat.Get("/actions", chatHandler.GetActions)
+	aiChat.Post("/actions/:id/confirm", chatHandler.ConfirmAction)
+	aiChat.Post("/actions/:id/reject", chatHandler.RejectAction)
+	aiChat.Get("/plan", plannerHandler.GetDailyPlan)
+	aiChat.Get("/review", plannerHandler.GetWeeklyReview)
 
 	// Protected routes - Weather (VIP only)
-	weather := api.Group("/weather", middleware.AuthMiddleware(), middleware.VIPMiddleware())
+	weather := api.Group("/weather", middleware.AuthMiddleware(sessionService, userStateService), middleware.VIPMiddleware())
 	weather.Get("/current", weatherHandler.GetCurrentWeather)
 	weather.Get("/forecast", weatherHandler.GetForecast)
 	weather.Get("/hourly", weatherHandler.GetHourlyForecast)
 
+	// Protected routes - Real-time events (Server-Sent Events)
+	api.Get("/events", middleware.AuthMiddleware(sessionService, userStateService), eventHandler.Stream)
+
 	// Protected routes - Notifications
-	notifications := api.Group("/notifications", middleware.AuthMiddleware())
+	notifications := api.Group("/notifications", middleware.AuthMiddleware(sessionService, userStateService))
 	notifications.Post("/register-device", notificationHandler.RegisterDevice)
 	notifications.Delete("/register-device", notificationHandler.UnregisterDevice)
+	notifications.Get("/devices", notificationHandler.ListDevices)
+	notifications.Get("/preferences", notificationHandler.GetPreferences)
+	notifications.Get("/history", notificationHandler.GetHistory)
+	notifications.Put("/preferences", notificationHandler.UpdatePreferences)
+	notifications.Post("/devices", notificationHandler.RegisterDevice)
+	notifications.Delete("/devices/:id", notificationHandler.RemoveDevice)
 	notifications.Post("/test", notificationHandler.SendTestNotification) // For testing
 
 	// Protected routes - Security (Keamanan Basis Data - Minggu 2 & 3)
-	security := api.Group("/security", middleware.AuthMiddleware())
+	security := api.Group("/security", middleware.AuthMiddleware(sessionService, userStateService))
 	security.Get("/audit-logs", securityHandler.GetAuditLogs)
 	security.Get("/events", securityHandler.GetSecurityEvents)
 	security.Post("/events/:id/resolve", securityHandler.ResolveSecurityEvent)
@@ -324,7 +586,7 @@ func main() {
 	security.Get("/dashboard", securityHandler.GetSecurityDashboard)
 
 	// Protected routes - Monitoring (Keamanan Basis Data - Phase 4: Monitoring & Maintenance)
-	monitoring := api.Group("/monitoring", middleware.AuthMiddleware())
+	monitoring := api.Group("/monitoring", middleware.AuthMiddleware(sessionService, userStateService))
 	monitoring.Post("/audit/run", monitoringHandler.RunSecurityAudit)
 	monitoring.Get("/audit/report", monitoringHandler.GetLastAuditReport)
 	monitoring.Get("/audit/history", monitoringHandler.GetAuditHistory)
@@ -5,6 +5,8 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -25,9 +27,16 @@ type Config struct {
 	JWTSecret string
 	JWTExpiry string
 
+	// Logged-in devices per user; the least recently active session is ended beyond this
+	MaxSessionsPerUser int
+
 	// CORS
 	AllowedOrigins string
 
+	// Reverse proxies (IPs or CIDRs, e.g. Cloudflare's ranges) allowed to set the
+	// CF-IPCity/CF-IPCountry location headers; empty trusts none
+	TrustedProxies []string
+
 	// Optional - Google OAuth
 	GoogleClientID     string
 	GoogleClientSecret string
@@ -40,18 +49,64 @@ type Config struct {
 	FirebaseProjectID       string
 	FirebaseCredentialsFile string
 
+	// Notification sink for tests/local development: "" (deliver), "memory" or "file"
+	NotificationSink     string
+	NotificationSinkFile string
+
+	// Payment gateway provider: "midtrans" (default) or "fake" (in-process, local/testing only)
+	PaymentGateway string
+
 	// Optional - Midtrans
 	MidtransServerKey    string
 	MidtransClientKey    string
 	MidtransIsProduction bool
 
-	// Optional - Groq AI (formerly Gemini)
-	GroqAPIKey string
+	// Payment reconciliation (recover from lost Midtrans webhooks)
+	PaymentReconcileInterval    time.Duration
+	PaymentReconcileStaleAfter  time.Duration
+	PaymentReconcileExpireAfter time.Duration
+
+	// Background job queue (notifications, emails)
+	JobWorkers       int
+	JobPollInterval  time.Duration
+	JobLeaseDuration time.Duration
+	JobMaxAttempts   int
+
+	// Real-time events: "memory" (single replica) or "database" (shared by all replicas)
+	EventBroker       string
+	EventPollInterval time.Duration
+
+	// Token blacklist, rate limits and login attempts: "memory" (single replica),
+	// "database" or "redis" (shared by all replicas)
+	StateStore string
+	RedisURL   string
+	// How long AuthMiddleware may use a cached user state (user type, lockout, password change)
+	UserStateCacheTTL time.Duration
+
+	// Optional - AI providers (OpenAI-compatible, e.g. Groq or Ollama), tried in order until one answers
+	LLMProviders    []LLMProviderConfig
+	LLMMaxRetries   int
+	LLMRetryBackoff time.Duration
+	LLMTimeout      time.Duration
+	// AIContextTokens bounds the estimated prompt size sent per chat request
+	AIContextTokens int
+	// AI token quotas (0 = unlimited) and prices in USD per million tokens
+	AIDailyTokensPerUser   int64
+	AIMonthlyTokensPerUser int64
+	AIGlobalMonthlyTokens  int64
+	AIPriceInputPerMTok    float64
+	AIPriceOutputPerMTok   float64
 
 	// Optional - Resend Email
 	ResendAPIKey string
 
-	// Optional - SMTP Email (Legacy - kept for backward compatibility)
+	// Email transport: "resend", "smtp" or "file" (writes .eml files, local/testing only).
+	// Empty picks Resend when RESEND_API_KEY is set.
+	EmailTransport   string
+	EmailMailboxDir  string
+	EmailTemplateDir string // Overrides the embedded templates, e.g. ./internal/templates
+
+	// Optional - SMTP Email (EMAIL_TRANSPORT=smtp); the From name and address are used by every transport
 	SMTPHost      string
 	SMTPPort      string
 	SMTPUsername  string
@@ -60,6 +115,29 @@ type Config struct {
 	SMTPFromEmail string
 }
 
+// LLMProviderConfig describes one OpenAI-compatible chat completion endpoint
+type LLMProviderConfig struct {
+	Name         string
+	BaseURL      string
+	APIKey       string
+	DefaultModel string
+	// Models lists the models a request may select on this provider (DefaultModel is always allowed)
+	Models []string
+}
+
+// knownLLMProviders holds defaults for providers that can be enabled by name only
+var knownLLMProviders = map[string]LLMProviderConfig{
+	"groq": {
+		BaseURL:      "https://api.groq.com/openai/v1",
+		DefaultModel: "llama-3.3-70b-versatile",
+		Models:       []string{"llama-3.3-70b-versatile", "llama-3.1-8b-instant"},
+	},
+	"ollama": {
+		BaseURL:      "http://localhost:11434/v1",
+		DefaultModel: "llama3.1",
+	},
+}
+
 var AppConfig *Config
 
 // Load membaca environment variables dan membuat config
@@ -106,7 +184,10 @@ func Load() error {
 		JWTSecret: getEnv("JWT_SECRET", "default-secret-key"),
 		JWTExpiry: getEnv("JWT_EXPIRY", "24h"),
 
+		MaxSessionsPerUser: getEnvAsInt("MAX_SESSIONS_PER_USER", 5),
+
 		AllowedOrigins: getEnv("ALLOWED_ORIGINS", "*"),
+		TrustedProxies: getEnvAsList("TRUSTED_PROXIES"),
 
 		// Optional
 		GoogleClientID:     getEnv("GOOGLE_CLIENT_ID", ""),
@@ -118,16 +199,51 @@ func Load() error {
 		FirebaseProjectID:       getEnv("FIREBASE_PROJECT_ID", ""),
 		FirebaseCredentialsFile: getEnv("FIREBASE_CREDENTIALS_FILE", ""),
 
+		NotificationSink:     getEnv("NOTIFICATION_SINK", ""),
+		NotificationSinkFile: getEnv("NOTIFICATION_SINK_FILE", "notifications.jsonl"),
+
+		PaymentGateway: getEnv("PAYMENT_GATEWAY", "midtrans"),
+
 		MidtransServerKey:    getEnv("MIDTRANS_SERVER_KEY", ""),
 		MidtransClientKey:    getEnv("MIDTRANS_CLIENT_KEY", ""),
 		MidtransIsProduction: getEnvAsBool("MIDTRANS_IS_PRODUCTION", false),
 
-		GroqAPIKey: getEnv("GROQ_API_KEY", ""),
+		PaymentReconcileInterval:    getEnvAsDuration("PAYMENT_RECONCILE_INTERVAL", 15*time.Minute),
+		PaymentReconcileStaleAfter:  getEnvAsDuration("PAYMENT_RECONCILE_STALE_AFTER", 15*time.Minute),
+		PaymentReconcileExpireAfter: getEnvAsDuration("PAYMENT_RECONCILE_EXPIRE_AFTER", 24*time.Hour),
+
+		JobWorkers:       getEnvAsInt("JOB_WORKERS", 4),
+		JobPollInterval:  getEnvAsDuration("JOB_POLL_INTERVAL", 2*time.Second),
+		JobLeaseDuration: getEnvAsDuration("JOB_LEASE_DURATION", 2*time.Minute),
+		JobMaxAttempts:   getEnvAsInt("JOB_MAX_ATTEMPTS", 5),
+
+		EventBroker:       getEnv("EVENT_BROKER", "memory"),
+		EventPollInterval: getEnvAsDuration("EVENT_POLL_INTERVAL", time.Second),
+
+		StateStore:        getEnv("STATE_STORE", "memory"),
+		RedisURL:          getEnv("REDIS_URL", ""),
+		UserStateCacheTTL: getEnvAsDuration("USER_STATE_CACHE_TTL", 30*time.Second),
+
+		LLMProviders:    loadLLMProviders(),
+		LLMMaxRetries:   getEnvAsInt("LLM_MAX_RETRIES", 2),
+		LLMRetryBackoff: getEnvAsDuration("LLM_RETRY_BACKOFF", 500*time.Millisecond),
+		LLMTimeout:      getEnvAsDuration("LLM_TIMEOUT", 30*time.Second),
+		AIContextTokens: getEnvAsInt("AI_CONTEXT_TOKENS", 6000),
+
+		AIDailyTokensPerUser:   int64(getEnvAsInt("AI_DAILY_TOKENS_PER_USER", 50000)),
+		AIMonthlyTokensPerUser: int64(getEnvAsInt("AI_MONTHLY_TOKENS_PER_USER", 1000000)),
+		AIGlobalMonthlyTokens:  int64(getEnvAsInt("AI_GLOBAL_MONTHLY_TOKENS", 0)),
+		AIPriceInputPerMTok:    getEnvAsFloat("AI_PRICE_INPUT_PER_MTOK", 0.59),
+		AIPriceOutputPerMTok:   getEnvAsFloat("AI_PRICE_OUTPUT_PER_MTOK", 0.79),
 
 		// Resend Email Configuration
 		ResendAPIKey: getEnv("RESEND_API_KEY", ""),
 
-		// SMTP Email Configuration (Legacy - kept for backward compatibility)
+		EmailTransport:   getEnv("EMAIL_TRANSPORT", ""),
+		EmailMailboxDir:  getEnv("EMAIL_MAILBOX_DIR", "mailbox"),
+		EmailTemplateDir: getEnv("EMAIL_TEMPLATE_DIR", ""),
+
+		// SMTP Email Configuration
 		SMTPHost:      getEnv("SMTP_HOST", "smtp.gmail.com"),
 		SMTPPort:      getEnv("SMTP_PORT", "587"),
 		SMTPUsername:  getEnv("SMTP_USERNAME", ""),
@@ -175,6 +291,17 @@ func getFirstEnv(keys []string, defaultValue string) string {
 	return defaultValue
 }
 
+// getEnvAsList splits a comma-separated variable, skipping empty entries
+func getEnvAsList(key string) []string {
+	var list []string
+	for _, item := range strings.Split(getEnv(key, ""), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
 func getEnvAsBool(key string, defaultValue bool) bool {
 	valStr := getEnv(key, "")
 	if val, err := strconv.ParseBool(valStr); err == nil {
@@ -182,3 +309,76 @@ func getEnvAsBool(key string, defaultValue bool) bool {
 	}
 	return defaultValue
 }
+
+func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
+	valStr := getEnv(key, "")
+	if val, err := time.ParseDuration(valStr); err == nil {
+		return val
+	}
+	return defaultValue
+}
+
+func getEnvAsInt(key string, defaultValue int) int {
+	valStr := getEnv(key, "")
+	if val, err := strconv.Atoi(valStr); err == nil {
+		return val
+	}
+	return defaultValue
+}
+
+func getEnvAsFloat(key string, defaultValue float64) float64 {
+	valStr := getEnv(key, "")
+	if val, err := strconv.ParseFloat(valStr, 64); err == nil {
+		return val
+	}
+	return defaultValue
+}
+
+// loadLLMProviders reads the ordered provider chain from LLM_PROVIDERS (default "groq").
+// Each provider NAME is configured with LLM_<NAME>_BASE_URL, LLM_<NAME>_API_KEY,
+// LLM_<NAME>_MODEL and LLM_<NAME>_MODELS; known providers only need overrides.
+func loadLLMProviders() []LLMProviderConfig {
+	var providers []LLMProviderConfig
+
+	for _, name := range strings.Split(getEnv("LLM_PROVIDERS", "groq"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		prefix := "LLM_" + strings.ToUpper(name) + "_"
+		defaults := knownLLMProviders[name]
+
+		provider := LLMProviderConfig{
+			Name:         name,
+			BaseURL:      strings.TrimRight(getEnv(prefix+"BASE_URL", defaults.BaseURL), "/"),
+			APIKey:       getEnv(prefix+"API_KEY", ""),
+			DefaultModel: getEnv(prefix+"MODEL", defaults.DefaultModel),
+			Models:       defaults.Models,
+		}
+		if name == "groq" && provider.APIKey == "" {
+			provider.APIKey = getEnv("GROQ_API_KEY", "")
+		}
+		if models := getEnv(prefix+"MODELS", ""); models != "" {
+			provider.Models = nil
+			for _, model := range strings.Split(models, ",") {
+				if model = strings.TrimSpace(model); model != "" {
+					provider.Models = append(provider.Models, model)
+				}
+			}
+		}
+
+		if provider.BaseURL == "" || provider.DefaultModel == "" {
+			log.Printf("⚠️ LLM provider %q skipped: base URL and model are required", name)
+			continue
+		}
+		// Hosted providers are useless without a key; local servers (Ollama, llama.cpp) need none
+		if name == "groq" && provider.APIKey == "" {
+			continue
+		}
+
+		providers = append(providers, provider)
+	}
+
+	return providers
+}
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/workradar/server/internal/services"
+)
+
+type AIUsageHandler struct {
+	usageService *services.AIUsageService
+}
+
+func NewAIUsageHandler(usageService *services.AIUsageService) *AIUsageHandler {
+	return &AIUsageHandler{usageService: usageService}
+}
+
+// GetQuota returns the user's AI token usage and remaining quota
+// GET /api/ai/usage
+func (h *AIUsageHandler) GetQuota(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	quota, err := h.usageService.GetQuota(userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"quota": quota,
+	})
+}
+
+// GetStats returns AI token usage and cost across all users
+// GET /api/admin/ai/usage?days=30
+func (h *AIUsageHandler) GetStats(c *fiber.Ctx) error {
+	stats, err := h.usageService.GetStats(c.QueryInt("days", 30))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(stats)
+}
@@ -105,11 +105,12 @@ type LoginRequest struct {
 }
 
 type LoginResponse struct {
-	Message     string      `json:"message"`
-	User        interface{} `json:"user,omitempty"`
-	Token       string      `json:"token,omitempty"`
-	RequiresMFA bool        `json:"requires_mfa,omitempty"`
-	MFAToken    string      `json:"mfa_token,omitempty"`
+	Message      string      `json:"message"`
+	User         interface{} `json:"user,omitempty"`
+	Token        string      `json:"token,omitempty"`
+	RefreshToken string      `json:"refresh_token,omitempty"`
+	RequiresMFA  bool        `json:"requires_mfa,omitempty"`
+	MFAToken     string      `json:"mfa_token,omitempty"`
 }
 
 func (h *AuthHandler) Login(c *fiber.Ctx) error {
@@ -137,7 +138,7 @@ func (h *AuthHandler) Login(c *fiber.Ctx) error {
 	}
 
 	// Use MFA-aware login
-	result, err := h.authService.LoginWithMFA(req.Email, req.Password)
+	result, err := h.authService.LoginWithMFA(req.Email, req.Password, clientInfo(c))
 	if err != nil {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 			"error": err.Error(),
@@ -154,10 +155,11 @@ func (h *AuthHandler) Login(c *fiber.Ctx) error {
 	}
 
 	return c.Status(fiber.StatusOK).JSON(LoginResponse{
-		Message:     "Login successful",
-		User:        result.User.ToResponse(),
-		Token:       result.Token,
-		RequiresMFA: false,
+		Message:      "Login successful",
+		User:         result.User.ToResponse(),
+		Token:        result.Token,
+		RefreshToken: result.RefreshToken,
+		RequiresMFA:  false,
 	})
 }
 
@@ -255,6 +257,8 @@ func (h *AuthHandler) GetProfile(c *fiber.Ctx) error {
 type UpdateProfileRequest struct {
 	Username       string  `json:"username"`
 	ProfilePicture *string `json:"profile_picture"`
+	Language       *string `json:"language"` // id or en, used for emails
+	Timezone       *string `json:"timezone"` // IANA name, e.g. Asia/Jakarta
 }
 
 func (h *AuthHandler) UpdateProfile(c *fiber.Ctx) error {
@@ -267,7 +271,7 @@ func (h *AuthHandler) UpdateProfile(c *fiber.Ctx) error {
 		})
 	}
 
-	user, err := h.authService.UpdateProfile(userID, req.Username, req.ProfilePicture)
+	user, err := h.authService.UpdateProfile(userID, req.Username, req.ProfilePicture, req.Language, req.Timezone)
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": err.Error(),
@@ -302,14 +306,18 @@ func (h *AuthHandler) ChangePassword(c *fiber.Ctx) error {
 		})
 	}
 
-	if err := h.authService.ChangePassword(userID, req.OldPassword, req.NewPassword); err != nil {
+	// Other devices are logged out; this device continues with the new tokens
+	token, refreshToken, err := h.authService.ChangePassword(userID, req.OldPassword, req.NewPassword, clientInfo(c))
+	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": err.Error(),
 		})
 	}
 
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
-		"message": "Password changed successfully",
+		"message":       "Password changed successfully",
+		"token":         token,
+		"refresh_token": refreshToken,
 	})
 }
 
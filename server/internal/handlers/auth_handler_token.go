@@ -6,7 +6,7 @@ import (
 	"github.com/workradar/server/pkg/utils"
 )
 
-// RefreshToken endpoint untuk get new access token
+// RefreshToken endpoint untuk get new access token dan refresh token baru
 // POST /api/auth/refresh
 func (h *AuthHandler) RefreshToken(c *fiber.Ctx) error {
 	type RefreshRequest struct {
@@ -26,39 +26,17 @@ func (h *AuthHandler) RefreshToken(c *fiber.Ctx) error {
 		})
 	}
 
-	// Validate refresh token
-	claims, err := utils.ValidateToken(req.RefreshToken)
+	// The refresh token is rotated: clients must store the new one
+	accessToken, refreshToken, err := h.authService.RefreshTokens(req.RefreshToken, clientInfo(c))
 	if err != nil {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Invalid or expired refresh token",
-		})
-	}
-
-	// Check token type
-	if claims.Type != "refresh" {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Invalid token type",
-		})
-	}
-
-	// Check blacklist
-	blacklistService := services.GetTokenBlacklistService()
-	if blacklistService.IsBlacklisted(claims.ID) {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Refresh token has been revoked",
-		})
-	}
-
-	// Generate new access token
-	newAccessToken, err := utils.GenerateAccessToken(claims.UserID, claims.Email, claims.UserType)
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to generate access token",
+			"error": err.Error(),
 		})
 	}
 
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
-		"access_token": newAccessToken,
+		"access_token":  accessToken,
+		"refresh_token": refreshToken,
 	})
 }
 
@@ -79,25 +57,24 @@ func (h *AuthHandler) Logout(c *fiber.Ctx) error {
 
 	blacklistService := services.GetTokenBlacklistService()
 
-	// Blacklist access token
-	if req.AccessToken != "" {
-		jti, err := utils.GetJTI(req.AccessToken)
-		if err == nil {
-			claims, _ := utils.ValidateToken(req.AccessToken)
-			if claims != nil {
-				blacklistService.AddToken(jti, claims.ExpiresAt.Time)
-			}
+	// Blacklist both tokens and end their session
+	for _, token := range []string{req.AccessToken, req.RefreshToken} {
+		if token == "" {
+			continue
 		}
-	}
-
-	// Blacklist refresh token
-	if req.RefreshToken != "" {
-		jti, err := utils.GetJTI(req.RefreshToken)
-		if err == nil {
-			claims, _ := utils.ValidateToken(req.RefreshToken)
-			if claims != nil {
-				blacklistService.AddToken(jti, claims.ExpiresAt.Time)
-			}
+		claims, err := utils.ValidateToken(token)
+		if err != nil {
+			continue
+		}
+		if err := blacklistService.AddToken(claims.ID, claims.ExpiresAt.Time); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to revoke token",
+			})
+		}
+		if err := h.authService.EndSession(claims); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to end session",
+			})
 		}
 	}
 
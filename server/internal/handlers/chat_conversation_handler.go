@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/workradar/server/internal/services"
+)
+
+type ChatConversationHandler struct {
+	conversationService *services.ChatConversationService
+}
+
+func NewChatConversationHandler(conversationService *services.ChatConversationService) *ChatConversationHandler {
+	return &ChatConversationHandler{conversationService: conversationService}
+}
+
+// ListConversations returns the user's chat threads
+// GET /api/ai/conversations?archived=true
+func (h *ChatConversationHandler) ListConversations(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	conversations, err := h.conversationService.ListConversations(userID, c.QueryBool("archived", false))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"conversations": conversations,
+	})
+}
+
+// CreateConversation starts a new chat thread
+// POST /api/ai/conversations
+func (h *ChatConversationHandler) CreateConversation(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	var req services.CreateConversationDTO
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Format request tidak valid",
+		})
+	}
+
+	conversation, err := h.conversationService.CreateConversation(userID, req)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"conversation": conversation,
+	})
+}
+
+// GetConversation returns a thread with its messages
+// GET /api/ai/conversations/:id
+func (h *ChatConversationHandler) GetConversation(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	conversation, err := h.conversationService.GetConversation(userID, c.Params("id"))
+	if err != nil {
+		return conversationError(c, err)
+	}
+
+	messages, err := h.conversationService.GetMessages(userID, conversation.ID, c.QueryInt("limit", 50))
+	if err != nil {
+		return conversationError(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"conversation": conversation,
+		"messages":     messages,
+	})
+}
+
+// UpdateConversation renames, archives or re-pins a thread
+// PATCH /api/ai/conversations/:id
+func (h *ChatConversationHandler) UpdateConversation(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	var req services.UpdateConversationDTO
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Format request tidak valid",
+		})
+	}
+
+	conversation, err := h.conversationService.UpdateConversation(userID, c.Params("id"), req)
+	if err != nil {
+		return conversationError(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"conversation": conversation,
+	})
+}
+
+// DeleteConversation deletes a thread and its messages
+// DELETE /api/ai/conversations/:id
+func (h *ChatConversationHandler) DeleteConversation(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	if err := h.conversationService.DeleteConversation(userID, c.Params("id")); err != nil {
+		return conversationError(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Conversation deleted successfully",
+	})
+}
+
+func conversationError(c *fiber.Ctx, err error) error {
+	status := fiber.StatusBadRequest
+	if errors.Is(err, services.ErrConversationNotFound) {
+		status = fiber.StatusNotFound
+	}
+	return c.Status(status).JSON(fiber.Map{
+		"error": err.Error(),
+	})
+}
@@ -1,6 +1,13 @@
 package handlers
 
 import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+
 	"github.com/gofiber/fiber/v2"
 	"github.com/workradar/server/internal/services"
 )
@@ -19,7 +26,11 @@ func (h *ChatHandler) Chat(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(string)
 
 	var req struct {
-		Message string `json:"message"`
+		Message        string `json:"message"`
+		ConversationID string `json:"conversation_id"` // Optional, defaults to the latest thread
+		Model          string `json:"model"`           // Optional, see GET /api/ai/models
+		// ConfirmActions queues task/leave changes for confirmation instead of running them
+		ConfirmActions bool `json:"confirm_actions"`
 	}
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
@@ -33,7 +44,27 @@ func (h *ChatHandler) Chat(c *fiber.Ctx) error {
 		})
 	}
 
-	response, err := h.aiService.GenerateResponse(userID, req.Message)
+	result, err := h.aiService.GenerateResponse(userID, req.Message, services.ChatOptions{
+		ConversationID: req.ConversationID,
+		Model:          req.Model,
+		ConfirmActions: req.ConfirmActions,
+	})
+	if errors.Is(err, services.ErrAIModelNotSupported) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if errors.Is(err, services.ErrConversationNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if errors.Is(err, services.ErrAIQuotaExceeded) {
+		return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+			"error": err.Error(),
+			"quota": result.Quota,
+		})
+	}
 	if err != nil {
 		// Return proper error message from service
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -42,16 +73,126 @@ func (h *ChatHandler) Chat(c *fiber.Ctx) error {
 	}
 
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
-		"response": response,
+		"conversation_id": result.ConversationID,
+		"response":        result.Response,
+		"actions":         result.Actions,
+		"quota":           result.Quota,
+	})
+}
+
+// ChatStream streams the AI response as Server-Sent Events
+// POST /api/ai/chat/stream
+//
+// Events:
+//
+//	event: delta  data: {"content": "..."}   - next fragment of the reply
+//	event: done   data: {"response": "...", "conversation_id": "...", "quota": {...}, "text_only": true} - full reply, already saved to history
+//	event: error  data: {"error": "..."}     - provider failed mid-stream
+//
+// Streaming is text-only: the assistant cannot run task or leave actions here, so the done
+// event carries no "actions" and sets text_only. Use POST /api/ai/chat for actions.
+//
+// A client that disconnects mid-stream aborts the provider request; the part streamed so far
+// is saved to history.
+func (h *ChatHandler) ChatStream(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	var req struct {
+		Message        string `json:"message"`
+		ConversationID string `json:"conversation_id"`
+		Model          string `json:"model"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Format request tidak valid",
+		})
+	}
+
+	if req.Message == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Pesan tidak boleh kosong",
+		})
+	}
+
+	if !h.aiService.SupportsModel(req.Model) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": services.ErrAIModelNotSupported.Error(),
+		})
+	}
+
+	// Resolve the thread and quota up front so errors are still plain JSON responses
+	if quota, err := h.aiService.CheckQuota(userID); err != nil {
+		return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+			"error": err.Error(),
+			"quota": quota,
+		})
+	}
+
+	conversation, err := h.aiService.ResolveConversation(userID, req.ConversationID)
+	if err != nil {
+		return conversationError(c, err)
+	}
+
+	c.Set("Content-Type", "text/event-stream")
+	c.Set("Cache-Control", "no-cache")
+	c.Set("Connection", "keep-alive")
+	c.Set("X-Accel-Buffering", "no") // Disable proxy buffering (nginx/Railway)
+
+	message := req.Message
+	opts := services.ChatOptions{ConversationID: conversation.ID, Model: req.Model}
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		response, err := h.aiService.StreamResponse(ctx, userID, message, opts, func(delta string) error {
+			// Flush fails once the client has disconnected, which aborts the upstream stream
+			return writeSSE(w, "delta", fiber.Map{"content": delta})
+		})
+		if err != nil {
+			if errors.Is(err, services.ErrAIStreamAborted) {
+				return
+			}
+			log.Printf("❌ AI Stream: %v", err)
+			_ = writeSSE(w, "error", fiber.Map{"error": err.Error()})
+			return
+		}
+
+		quota, _ := h.aiService.GetQuota(userID)
+		_ = writeSSE(w, "done", fiber.Map{"response": response, "conversation_id": opts.ConversationID, "quota": quota, "text_only": true})
+	})
+
+	return nil
+}
+
+// writeSSE writes a single Server-Sent Event and flushes it to the client
+func writeSSE(w *bufio.Writer, event string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+// GetModels lists the AI models a chat request may select, per provider in fallback order
+// GET /api/ai/models
+func (h *ChatHandler) GetModels(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"providers": h.aiService.GetModels(),
 	})
 }
 
 // GetHistory returns chat history for a user
-// GET /api/ai/history
+// GET /api/ai/history?conversation_id=...
 func (h *ChatHandler) GetHistory(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(string)
 
-	history, err := h.aiService.GetChatHistory(userID)
+	history, err := h.aiService.GetChatHistory(userID, c.Query("conversation_id"))
+	if errors.Is(err, services.ErrConversationNotFound) {
+		return conversationError(c, err)
+	}
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": err.Error(),
@@ -78,3 +219,63 @@ func (h *ChatHandler) ClearHistory(c *fiber.Ctx) error {
 		"message": "Chat history cleared successfully",
 	})
 }
+
+// GetActions returns the action log of the assistant
+// GET /api/ai/actions?status=pending
+func (h *ChatHandler) GetActions(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	actions, err := h.aiService.GetActions(userID, c.Query("status"))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"actions": actions,
+	})
+}
+
+// ConfirmAction executes a pending action
+// POST /api/ai/actions/:id/confirm
+func (h *ChatHandler) ConfirmAction(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	action, err := h.aiService.ConfirmAction(userID, c.Params("id"))
+	if err != nil {
+		return actionError(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"action": action,
+	})
+}
+
+// RejectAction discards a pending action
+// POST /api/ai/actions/:id/reject
+func (h *ChatHandler) RejectAction(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	action, err := h.aiService.RejectAction(userID, c.Params("id"))
+	if err != nil {
+		return actionError(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"action": action,
+	})
+}
+
+func actionError(c *fiber.Ctx, err error) error {
+	status := fiber.StatusInternalServerError
+	switch {
+	case errors.Is(err, services.ErrAIActionNotFound):
+		status = fiber.StatusNotFound
+	case errors.Is(err, services.ErrAIActionNotPending):
+		status = fiber.StatusConflict
+	}
+	return c.Status(status).JSON(fiber.Map{
+		"error": err.Error(),
+	})
+}
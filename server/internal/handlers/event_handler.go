@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/workradar/server/internal/services"
+)
+
+// eventKeepAlive keeps proxies from closing idle streams
+const eventKeepAlive = 25 * time.Second
+
+// EventHandler streams real-time events to the user as Server-Sent Events
+type EventHandler struct {
+	hub            *services.EventHub
+	sessionService *services.SessionManagementService
+}
+
+func NewEventHandler(hub *services.EventHub, sessionService *services.SessionManagementService) *EventHandler {
+	return &EventHandler{hub: hub, sessionService: sessionService}
+}
+
+// Stream keeps the connection open and pushes task, message, payment and notification events
+// GET /api/events
+func (h *EventHandler) Stream(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	sessionID, _ := c.Locals("session_id").(string)
+
+	sub, err := h.hub.Subscribe(userID)
+	if err != nil {
+		if errors.Is(err, services.ErrTooManyStreams) {
+			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	c.Set("Content-Type", "text/event-stream")
+	c.Set("Cache-Control", "no-cache")
+	c.Set("Connection", "keep-alive")
+	c.Set("X-Accel-Buffering", "no") // Disable nginx buffering
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer h.hub.Unsubscribe(sub)
+
+		// Clients reconnect after 5s and should re-fetch to catch up on missed events
+		fmt.Fprint(w, "retry: 5000\n\n")
+		if err := w.Flush(); err != nil {
+			return
+		}
+
+		keepAlive := time.NewTicker(eventKeepAlive)
+		defer keepAlive.Stop()
+
+		for {
+			select {
+			case event, ok := <-sub.C:
+				if !ok {
+					return // Server shutting down
+				}
+				data, err := json.Marshal(event)
+				if err != nil {
+					continue
+				}
+				fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+			case <-keepAlive.C:
+				// The session is checked on connect only; end the stream once it is
+				// revoked (logout, remote logout, token reuse) on any replica
+				if _, err := h.sessionService.ValidateSession(sessionID); errors.Is(err, services.ErrSessionNotFound) {
+					fmt.Fprint(w, "event: session_revoked\ndata: {}\n\n")
+					w.Flush()
+					return
+				}
+				fmt.Fprint(w, ": ping\n\n")
+			}
+
+			// Flush fails once the client has gone away
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	})
+
+	return nil
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/workradar/server/internal/services"
+)
+
+// JobHandler exposes the background job queue and its dead letters to admins
+type JobHandler struct {
+	jobQueue *services.JobQueue
+}
+
+func NewJobHandler(jobQueue *services.JobQueue) *JobHandler {
+	return &JobHandler{jobQueue: jobQueue}
+}
+
+// ListJobs lists queued jobs (admin only)
+// GET /api/admin/jobs?status=dead&type=email&limit=50&offset=0
+func (h *JobHandler) ListJobs(c *fiber.Ctx) error {
+	limit, _ := strconv.Atoi(c.Query("limit", "50"))
+	offset, _ := strconv.Atoi(c.Query("offset", "0"))
+
+	if limit <= 0 || limit > 100 {
+		limit = 100
+	}
+
+	jobs, total, err := h.jobQueue.List(c.Query("status"), c.Query("type"), limit, offset)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Failed to retrieve jobs",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status": "success",
+		"data":   jobs,
+		"total":  total,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+// GetStats returns job counts per status (admin only)
+// GET /api/admin/jobs/stats
+func (h *JobHandler) GetStats(c *fiber.Ctx) error {
+	stats, err := h.jobQueue.Stats()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Failed to retrieve job stats",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status": "success",
+		"data":   stats,
+	})
+}
+
+// RetryJob puts a dead-lettered job back in the queue (admin only)
+// POST /api/admin/jobs/:id/retry
+func (h *JobHandler) RetryJob(c *fiber.Ctx) error {
+	job, err := h.jobQueue.Retry(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"status":  "error",
+			"message": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status": "success",
+		"data":   job,
+	})
+}
@@ -1,19 +1,23 @@
 package handlers
 
 import (
+	"errors"
+
 	"github.com/gofiber/fiber/v2"
 	"github.com/workradar/server/internal/services"
 )
 
 // MFAHandler handles MFA-related endpoints
 type MFAHandler struct {
-	mfaService *services.MFAService
+	mfaService  *services.MFAService
+	authService *services.AuthService
 }
 
 // NewMFAHandler creates a new MFA handler
-func NewMFAHandler(mfaService *services.MFAService) *MFAHandler {
+func NewMFAHandler(mfaService *services.MFAService, authService *services.AuthService) *MFAHandler {
 	return &MFAHandler{
-		mfaService: mfaService,
+		mfaService:  mfaService,
+		authService: authService,
 	}
 }
 
@@ -22,17 +26,14 @@ func NewMFAHandler(mfaService *services.MFAService) *MFAHandler {
 func (h *MFAHandler) GetMFAStatus(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(string)
 
-	user, err := h.mfaService.GetMFAStatus(userID)
+	status, err := h.mfaService.GetMFAStatus(userID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to get MFA status",
 		})
 	}
 
-	return c.JSON(fiber.Map{
-		"mfa_enabled": user.MFAEnabled,
-		"has_secret":  user.MFASecret != nil && *user.MFASecret != "",
-	})
+	return c.JSON(status)
 }
 
 // EnableMFA generates a new MFA secret and returns QR code URL
@@ -96,15 +97,53 @@ func (h *MFAHandler) VerifyMFA(c *fiber.Ctx) error {
 		})
 	}
 
-	if err := h.mfaService.VerifyAndEnable(userID, req.Code); err != nil {
+	backupCodes, err := h.mfaService.VerifyAndEnable(userID, req.Code)
+	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": err.Error(),
 		})
 	}
 
 	return c.JSON(fiber.Map{
-		"message":     "MFA has been successfully enabled for your account",
-		"mfa_enabled": true,
+		"message":      "MFA has been successfully enabled for your account",
+		"mfa_enabled":  true,
+		"backup_codes": backupCodes,
+		"backup_codes_info": "Store these backup codes somewhere safe. Each code can be used once to log in " +
+			"when your authenticator app is not available, and they will not be shown again.",
+	})
+}
+
+// RegenerateBackupCodes replaces the backup codes after verifying a TOTP code
+// POST /api/auth/mfa/backup-codes
+func (h *MFAHandler) RegenerateBackupCodes(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	var req struct {
+		Code string `json:"code"`
+	}
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if !services.IsTOTPCode(req.Code) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Please provide a valid 6-digit code from your authenticator app",
+		})
+	}
+
+	backupCodes, err := h.mfaService.RegenerateBackupCodes(userID, req.Code)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message":      "New backup codes generated. Previous backup codes no longer work.",
+		"backup_codes": backupCodes,
 	})
 }
 
@@ -141,12 +180,14 @@ func (h *MFAHandler) DisableMFA(c *fiber.Ctx) error {
 	})
 }
 
-// VerifyMFALogin verifies MFA code during login (called after password verification)
-// POST /api/auth/mfa/login
+// VerifyMFALogin completes a login that requires MFA: it takes the mfa_token from the
+// login response and a code from the authenticator app or a backup code, and returns the
+// same tokens as a login without MFA
+// POST /api/auth/mfa/verify-login
 func (h *MFAHandler) VerifyMFALogin(c *fiber.Ctx) error {
 	var req struct {
-		UserID string `json:"user_id"`
-		Code   string `json:"code"`
+		MFAToken string `json:"mfa_token"`
+		Code     string `json:"code"`
 	}
 
 	if err := c.BodyParser(&req); err != nil {
@@ -155,26 +196,39 @@ func (h *MFAHandler) VerifyMFALogin(c *fiber.Ctx) error {
 		})
 	}
 
-	if req.UserID == "" || req.Code == "" {
+	if req.MFAToken == "" || req.Code == "" {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "User ID and code are required",
+			"error": "MFA token and code are required",
 		})
 	}
 
-	if len(req.Code) != 6 {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Please provide a valid 6-digit code",
+	client := clientInfo(c)
+	userID, result, err := h.mfaService.VerifyPendingLogin(req.MFAToken, req.Code, client)
+	if err != nil {
+		status := fiber.StatusUnauthorized
+		if errors.Is(err, services.ErrTooManyMFAAttempts) {
+			status = fiber.StatusTooManyRequests
+		}
+		return c.Status(status).JSON(fiber.Map{
+			"error": err.Error(),
 		})
 	}
 
-	if err := h.mfaService.VerifyLogin(req.UserID, req.Code); err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": err.Error(),
+	user, token, refreshToken, err := h.authService.CompleteMFALogin(userID, client)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to complete login",
 		})
 	}
 
-	return c.JSON(fiber.Map{
-		"message":  "MFA verification successful",
-		"verified": true,
-	})
+	response := fiber.Map{
+		"message":       "Login successful",
+		"user":          user.ToResponse(),
+		"token":         token,
+		"refresh_token": refreshToken,
+	}
+	if result.UsedBackupCode {
+		response["backup_codes_remaining"] = result.BackupCodesRemaining
+	}
+	return c.JSON(response)
 }
@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"strconv"
+
 	"github.com/gofiber/fiber/v2"
 	"github.com/workradar/server/internal/services"
 )
@@ -15,15 +17,12 @@ func NewNotificationHandler(notificationService *services.NotificationService) *
 	}
 }
 
-// RegisterDevice registers a device FCM token
-// POST /api/notifications/register-device
+// RegisterDevice registers or refreshes a device for push notifications
+// POST /api/notifications/devices (also POST /api/notifications/register-device)
 func (h *NotificationHandler) RegisterDevice(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(string)
 
-	var req struct {
-		FCMToken string `json:"fcm_token"`
-	}
-
+	var req services.DeviceRegistration
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": "Invalid request body",
@@ -36,24 +35,71 @@ func (h *NotificationHandler) RegisterDevice(c *fiber.Ctx) error {
 		})
 	}
 
-	if err := h.notificationService.RegisterDevice(userID, req.FCMToken); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+	device, err := h.notificationService.RegisterDevice(userID, req)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": err.Error(),
 		})
 	}
 
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
 		"message": "Device registered successfully",
+		"device":  device,
+	})
+}
+
+// ListDevices lists the user's registered devices
+// GET /api/notifications/devices
+func (h *NotificationHandler) ListDevices(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	devices, err := h.notificationService.ListDevices(userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"devices": devices,
+		"count":   len(devices),
+	})
+}
+
+// RemoveDevice removes one device by ID
+// DELETE /api/notifications/devices/:id
+func (h *NotificationHandler) RemoveDevice(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	if err := h.notificationService.RemoveDevice(userID, c.Params("id")); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Device unregistered successfully",
 	})
 }
 
-// UnregisterDevice removes FCM token from user
+// UnregisterDevice removes the device with the given FCM token, or all devices without one
 // DELETE /api/notifications/register-device
 func (h *NotificationHandler) UnregisterDevice(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(string)
 
-	if err := h.notificationService.UnregisterDevice(userID); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+	var req struct {
+		FCMToken string `json:"fcm_token"`
+	}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+	}
+
+	if err := h.notificationService.UnregisterDevice(userID, req.FCMToken); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": err.Error(),
 		})
 	}
@@ -63,6 +109,77 @@ func (h *NotificationHandler) UnregisterDevice(c *fiber.Ctx) error {
 	})
 }
 
+// GetPreferences returns the user's notification preferences
+// GET /api/notifications/preferences
+func (h *NotificationHandler) GetPreferences(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	pref, err := h.notificationService.GetPreferences(userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"preferences": pref,
+	})
+}
+
+// UpdatePreferences changes channels per type, quiet hours and the daily digest
+// PUT /api/notifications/preferences
+func (h *NotificationHandler) UpdatePreferences(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	var req services.UpdateNotificationPreferencesDTO
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	pref, err := h.notificationService.UpdatePreferences(userID, req)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message":     "Notification preferences updated",
+		"preferences": pref,
+	})
+}
+
+// GetHistory lists the notifications sent to the user with their delivery status
+// GET /api/notifications/history?limit=50&offset=0
+func (h *NotificationHandler) GetHistory(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	limit, _ := strconv.Atoi(c.Query("limit", "50"))
+	offset, _ := strconv.Atoi(c.Query("offset", "0"))
+	if limit <= 0 || limit > 100 {
+		limit = 100
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	history, total, err := h.notificationService.GetHistory(userID, limit, offset)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"history": history,
+		"total":   total,
+		"limit":   limit,
+		"offset":  offset,
+	})
+}
+
 // SendTestNotification sends a test notification (for testing purposes)
 // POST /api/notifications/test
 func (h *NotificationHandler) SendTestNotification(c *fiber.Ctx) error {
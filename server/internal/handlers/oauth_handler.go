@@ -102,6 +102,7 @@ func (h *OAuthHandler) GoogleCallback(c *fiber.Ctx) error {
 		userInfo.Email,
 		userInfo.Name,
 		userInfo.Picture,
+		clientInfo(c),
 	)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -81,6 +81,7 @@ func (h *OAuthHandler) GoogleMobileAuth(c *fiber.Ctx) error {
 		email,
 		name,
 		picture,
+		clientInfo(c),
 	)
 	if err != nil {
 		log.Printf("[GoogleMobileAuth] ❌ Failed to process Google login: %v", err)
@@ -1,7 +1,9 @@
 package handlers
 
 import (
+	"errors"
 	"log"
+	"strconv"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/workradar/server/internal/models"
@@ -10,10 +12,20 @@ import (
 
 type PaymentHandler struct {
 	paymentService *services.PaymentService
+	reconciler     *services.PaymentReconciliationService
+	webhookService *services.WebhookEventService
 }
 
-func NewPaymentHandler(paymentService *services.PaymentService) *PaymentHandler {
-	return &PaymentHandler{paymentService: paymentService}
+func NewPaymentHandler(
+	paymentService *services.PaymentService,
+	reconciler *services.PaymentReconciliationService,
+	webhookService *services.WebhookEventService,
+) *PaymentHandler {
+	return &PaymentHandler{
+		paymentService: paymentService,
+		reconciler:     reconciler,
+		webhookService: webhookService,
+	}
 }
 
 // GetSnapToken request token pembayaran
@@ -49,51 +61,56 @@ func (h *PaymentHandler) GetSnapToken(c *fiber.Ctx) error {
 // HandleNotification webhook midtrans
 // POST /api/webhooks/midtrans
 func (h *PaymentHandler) HandleNotification(c *fiber.Ctx) error {
-	var notificationPayload map[string]interface{}
+	// Verify and persist the notification before doing anything else
+	event, duplicate, err := h.webhookService.Record(h.paymentService.Gateway().Name(), c.Body(), c.GetReqHeaders())
+	if err != nil {
+		return webhookErrorResponse(c, err, "")
+	}
 
-	if err := c.BodyParser(&notificationPayload); err != nil {
-		log.Printf("❌ Error parsing notification: %v", err)
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid notification payload",
+	// Already processed successfully - acknowledge so the gateway stops retrying
+	if duplicate {
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"status":    "OK",
+			"duplicate": true,
 		})
 	}
 
-	// Extract required fields for signature verification
-	orderID, orderIDOK := notificationPayload["order_id"].(string)
-	statusCode, statusCodeOK := notificationPayload["status_code"].(string)
-	grossAmount, grossAmountOK := notificationPayload["gross_amount"].(string)
-	signatureKey, signatureKeyOK := notificationPayload["signature_key"].(string)
+	// Process notification
+	if err := h.webhookService.Process(event); err != nil {
+		return webhookErrorResponse(c, err, event.ID)
+	}
+
+	log.Printf("✅ Webhook processed successfully for order: %s", event.OrderID)
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status": "OK",
+	})
+}
 
-	if !orderIDOK || !statusCodeOK || !grossAmountOK || !signatureKeyOK {
-		log.Printf("❌ Missing required fields in notification payload")
+// webhookErrorResponse maps a webhook recording or processing error to a response
+func webhookErrorResponse(c *fiber.Ctx, err error, eventID string) error {
+	switch {
+	case errors.Is(err, services.ErrWebhookInvalidPayload):
+		log.Printf("❌ Invalid notification payload")
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Missing required fields",
+			"error": "Invalid notification payload",
 		})
-	}
-
-	// SECURITY: Verify signature from Midtrans
-	if !h.paymentService.VerifyNotificationSignature(orderID, statusCode, grossAmount, signatureKey) {
-		log.Printf("❌ Invalid signature for order: %s", orderID)
+	case errors.Is(err, services.ErrWebhookInvalidSignature):
+		log.Printf("❌ Invalid notification signature")
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 			"error": "Invalid signature",
 		})
-	}
-
-	log.Printf("✅ Signature verified for order: %s", orderID)
-
-	// Process notification
-	if err := h.paymentService.HandleNotification(notificationPayload); err != nil {
+	case eventID == "":
+		log.Printf("❌ Error storing webhook event: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to store notification",
+		})
+	default:
 		log.Printf("❌ Error handling notification: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to process notification",
 		})
 	}
-
-	log.Printf("✅ Webhook processed successfully for order: %s", orderID)
-
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{
-		"status": "OK",
-	})
 }
 
 // GetPaymentStatus gets the status of a specific transaction
@@ -153,3 +170,106 @@ func (h *PaymentHandler) CancelPayment(c *fiber.Ctx) error {
 		"message": "Payment cancelled successfully",
 	})
 }
+
+// SimulatePayment completes a payment through the fake gateway (local/testing only)
+// POST /api/payments/simulate/:order_id
+func (h *PaymentHandler) SimulatePayment(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	orderID := c.Params("order_id")
+
+	var req struct {
+		TransactionStatus string `json:"transaction_status"` // settlement, capture, deny, cancel, expire, pending
+		FraudStatus       string `json:"fraud_status"`
+		PaymentType       string `json:"payment_type"`
+	}
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Invalid request body",
+		})
+	}
+
+	if req.TransactionStatus == "" {
+		req.TransactionStatus = "settlement"
+	}
+	if req.PaymentType == "" {
+		req.PaymentType = "bank_transfer"
+	}
+
+	transaction, err := h.paymentService.SimulatePayment(userID, orderID, req.TransactionStatus, req.FraudStatus, req.PaymentType)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"message": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status": "success",
+		"data":   transaction,
+	})
+}
+
+// RefundPayment refunds a settled payment and revokes the VIP subscription (admin only)
+// POST /api/admin/payments/:order_id/refund
+func (h *PaymentHandler) RefundPayment(c *fiber.Ctx) error {
+	orderID := c.Params("order_id")
+
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	_ = c.BodyParser(&req)
+
+	result, err := h.paymentService.RefundTransaction(orderID, req.Reason)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"message": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status": "success",
+		"data":   result,
+	})
+}
+
+// GetReconciliationReports returns recent payment reconciliation reports (admin only)
+// GET /api/admin/payments/reconciliation
+func (h *PaymentHandler) GetReconciliationReports(c *fiber.Ctx) error {
+	limit, _ := strconv.Atoi(c.Query("limit", "10"))
+
+	reports, err := h.reconciler.GetReports(limit)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Failed to get payment reconciliation reports",
+			"error":   err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status": "success",
+		"data":   reports,
+		"count":  len(reports),
+	})
+}
+
+// RunReconciliation triggers a payment reconciliation immediately (admin only)
+// POST /api/admin/payments/reconciliation/run
+func (h *PaymentHandler) RunReconciliation(c *fiber.Ctx) error {
+	report, err := h.reconciler.Reconcile()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Failed to run payment reconciliation",
+			"error":   err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status": "success",
+		"data":   report,
+	})
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/workradar/server/internal/services"
+)
+
+type PlannerHandler struct {
+	plannerService *services.PlannerService
+}
+
+func NewPlannerHandler(plannerService *services.PlannerService) *PlannerHandler {
+	return &PlannerHandler{plannerService: plannerService}
+}
+
+// GetDailyPlan returns the plan of a work day (default today)
+// GET /api/ai/plan?date=2026-01-31
+func (h *PlannerHandler) GetDailyPlan(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	date := time.Now()
+	if dateStr := c.Query("date"); dateStr != "" {
+		loc := h.plannerService.UserLocation(userID)
+		parsed, err := time.ParseInLocation("2006-01-02", dateStr, loc)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid date format. Use YYYY-MM-DD",
+			})
+		}
+		now := time.Now().In(loc)
+		if parsed.Before(time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Tidak bisa membuat rencana untuk tanggal yang sudah lewat",
+			})
+		}
+		date = parsed
+	}
+
+	plan, err := h.plannerService.GenerateDailyPlan(userID, date)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"plan": plan,
+	})
+}
+
+// GetWeeklyReview returns the review of a week (default the current week)
+// GET /api/ai/review?week_start=2026-01-26
+func (h *PlannerHandler) GetWeeklyReview(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	weekStart := time.Now()
+	if weekStr := c.Query("week_start"); weekStr != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", weekStr, h.plannerService.UserLocation(userID))
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid week_start format. Use YYYY-MM-DD",
+			})
+		}
+		weekStart = parsed
+	}
+
+	review, err := h.plannerService.GenerateWeeklyReview(userID, weekStart)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"review": review,
+	})
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/workradar/server/internal/services"
+)
+
+type SchedulingHandler struct {
+	schedulingService *services.SchedulingService
+}
+
+func NewSchedulingHandler(schedulingService *services.SchedulingService) *SchedulingHandler {
+	return &SchedulingHandler{schedulingService: schedulingService}
+}
+
+// PreviewSchedule proposes time blocks for unscheduled tasks without saving them
+// GET /api/tasks/schedule/preview?days=14
+func (h *SchedulingHandler) PreviewSchedule(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	preview, err := h.schedulingService.PreviewSchedule(userID, c.QueryInt("days", 0))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"schedule": preview,
+	})
+}
+
+// ApplySchedule saves the proposed time blocks (all, or only task_ids)
+// POST /api/tasks/schedule/apply
+func (h *SchedulingHandler) ApplySchedule(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	var req struct {
+		Days    int      `json:"days"`
+		TaskIDs []string `json:"task_ids"`
+	}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+	}
+
+	preview, err := h.schedulingService.ApplySchedule(userID, req.Days, req.TaskIDs)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message":  "Schedule applied successfully",
+		"schedule": preview,
+	})
+}
+
+// ClearSchedule removes the time block of a task
+// DELETE /api/tasks/:id/schedule
+func (h *SchedulingHandler) ClearSchedule(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	task, err := h.schedulingService.ClearSchedule(userID, c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"task": task,
+	})
+}
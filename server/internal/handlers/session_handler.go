@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/workradar/server/internal/config"
+	"github.com/workradar/server/internal/services"
+	"github.com/workradar/server/pkg/utils"
+)
+
+// SessionHandler lists the user's logged-in devices and logs them out remotely
+type SessionHandler struct {
+	sessionService *services.SessionManagementService
+}
+
+func NewSessionHandler(sessionService *services.SessionManagementService) *SessionHandler {
+	return &SessionHandler{sessionService: sessionService}
+}
+
+// GetSessions returns active sessions with device, IP, location and last activity
+// GET /api/profile/sessions
+func (h *SessionHandler) GetSessions(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	currentSessionID, _ := c.Locals("session_id").(string)
+
+	sessions, err := h.sessionService.GetUserSessions(userID, currentSessionID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get sessions",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"sessions": sessions,
+	})
+}
+
+// RevokeSession logs out one device
+// DELETE /api/profile/sessions/:id
+func (h *SessionHandler) RevokeSession(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	if err := h.sessionService.InvalidateSession(userID, c.Params("id")); err != nil {
+		if errors.Is(err, services.ErrSessionNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Session not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to revoke session",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Session revoked",
+	})
+}
+
+// RevokeOtherSessions logs out every device except the current one
+// DELETE /api/profile/sessions
+func (h *SessionHandler) RevokeOtherSessions(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	currentSessionID, _ := c.Locals("session_id").(string)
+
+	revoked, err := h.sessionService.InvalidateAllSessions(userID, currentSessionID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to revoke sessions",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Other sessions revoked",
+		"revoked": revoked,
+	})
+}
+
+// clientInfo describes the requesting device for a new session. Location comes from
+// the visitor location headers of the CDN (Cloudflare), only when the request came
+// through one of TRUSTED_PROXIES; anyone else could send them.
+func clientInfo(c *fiber.Ctx) services.ClientInfo {
+	var location []string
+	if config.AppConfig != nil && utils.IsTrustedProxy(c.Context().RemoteIP().String(), config.AppConfig.TrustedProxies) {
+		for _, header := range []string{"CF-IPCity", "CF-IPCountry"} {
+			if v := strings.TrimSpace(c.Get(header)); v != "" && v != "XX" {
+				location = append(location, v)
+			}
+		}
+	}
+
+	return services.ClientInfo{
+		IPAddress: c.IP(),
+		UserAgent: c.Get("User-Agent"),
+		Location:  strings.Join(location, ", "),
+	}
+}
@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"strings"
+
 	"github.com/gofiber/fiber/v2"
 	"github.com/workradar/server/internal/services"
 )
@@ -38,6 +40,41 @@ func (h *TaskHandler) CreateTask(c *fiber.Ctx) error {
 	})
 }
 
+// ParseTask parses a quick-add sentence into a task preview, optionally creating it
+// POST /api/tasks/parse
+func (h *TaskHandler) ParseTask(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	var req struct {
+		Text   string `json:"text"`
+		Commit bool   `json:"commit"` // Create the task instead of only previewing it
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if strings.TrimSpace(req.Text) == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "text is required",
+		})
+	}
+
+	result, err := h.taskService.ParseTask(userID, req.Text, req.Commit)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	status := fiber.StatusOK
+	if result.Task != nil {
+		status = fiber.StatusCreated
+	}
+	return c.Status(status).JSON(result)
+}
+
 // GetTasks mendapatkan semua tasks user
 // GET /api/tasks?category_id=xxx
 func (h *TaskHandler) GetTasks(c *fiber.Ctx) error {
@@ -63,6 +100,23 @@ func (h *TaskHandler) GetTasks(c *fiber.Ctx) error {
 	})
 }
 
+// GetMatrix mengelompokkan pending tasks ke Eisenhower matrix (penting x mendesak)
+// GET /api/tasks/matrix
+func (h *TaskHandler) GetMatrix(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	matrix, err := h.taskService.GetMatrix(userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"matrix": matrix,
+	})
+}
+
 // GetTaskByID mendapatkan detail task
 // GET /api/tasks/:id
 func (h *TaskHandler) GetTaskByID(c *fiber.Ctx) error {
@@ -107,6 +161,25 @@ func (h *TaskHandler) UpdateTask(c *fiber.Ctx) error {
 	})
 }
 
+// GetReminderHistory mendapatkan reminder dan nudge yang sudah dikirim untuk task
+// GET /api/tasks/:id/reminders
+func (h *TaskHandler) GetReminderHistory(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	taskID := c.Params("id")
+
+	deliveries, err := h.taskService.GetReminderHistory(userID, taskID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"deliveries": deliveries,
+		"count":      len(deliveries),
+	})
+}
+
 // DeleteTask menghapus task
 // DELETE /api/tasks/:id
 func (h *TaskHandler) DeleteTask(c *fiber.Ctx) error {
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/workradar/server/internal/services"
+)
+
+// WebhookEventHandler exposes the payment webhook event log to admins
+type WebhookEventHandler struct {
+	webhookService *services.WebhookEventService
+}
+
+func NewWebhookEventHandler(webhookService *services.WebhookEventService) *WebhookEventHandler {
+	return &WebhookEventHandler{webhookService: webhookService}
+}
+
+// ListEvents lists stored webhook events (admin only)
+// GET /api/admin/webhooks?status=failed&order_id=...&limit=50&offset=0
+func (h *WebhookEventHandler) ListEvents(c *fiber.Ctx) error {
+	limit, _ := strconv.Atoi(c.Query("limit", "50"))
+	offset, _ := strconv.Atoi(c.Query("offset", "0"))
+
+	if limit <= 0 || limit > 100 {
+		limit = 100
+	}
+
+	events, total, err := h.webhookService.ListEvents(c.Query("status"), c.Query("order_id"), limit, offset)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Failed to retrieve webhook events",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status": "success",
+		"data":   events,
+		"total":  total,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+// GetEvent returns a single webhook event including raw body and headers (admin only)
+// GET /api/admin/webhooks/:id
+func (h *WebhookEventHandler) GetEvent(c *fiber.Ctx) error {
+	event, err := h.webhookService.GetEvent(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Webhook event not found",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status": "success",
+		"data":   event,
+	})
+}
+
+// ReplayEvent re-processes a stored webhook event through PaymentService (admin only)
+// POST /api/admin/webhooks/:id/replay
+func (h *WebhookEventHandler) ReplayEvent(c *fiber.Ctx) error {
+	adminID := c.Locals("user_id").(string)
+
+	event, err := h.webhookService.Replay(c.Params("id"), adminID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"status":  "error",
+			"message": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status": "success",
+		"data":   event,
+	})
+}
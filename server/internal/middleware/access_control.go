@@ -11,7 +11,7 @@ func AccessControlMiddleware(permission services.Permission) fiber.Handler {
 
 	return func(c *fiber.Ctx) error {
 		// Get user ID from context (set by AuthMiddleware)
-		userID := c.Locals("userID")
+		userID := c.Locals("user_id")
 		if userID == nil {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 				"success": false,
@@ -56,7 +56,7 @@ func ResourceOwnerMiddleware(resourceIDParam string, permission services.Permiss
 	acService := services.GetAccessControlService()
 
 	return func(c *fiber.Ctx) error {
-		userID := c.Locals("userID")
+		userID := c.Locals("user_id")
 		if userID == nil {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 				"success": false,
@@ -128,7 +128,7 @@ func RequirePermissions(permissions ...services.Permission) fiber.Handler {
 	acService := services.GetAccessControlService()
 
 	return func(c *fiber.Ctx) error {
-		userID := c.Locals("userID")
+		userID := c.Locals("user_id")
 		if userID == nil {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 				"success": false,
@@ -176,7 +176,7 @@ func RequireAnyPermission(permissions ...services.Permission) fiber.Handler {
 	acService := services.GetAccessControlService()
 
 	return func(c *fiber.Ctx) error {
-		userID := c.Locals("userID")
+		userID := c.Locals("user_id")
 		if userID == nil {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 				"success": false,
@@ -1,15 +1,19 @@
 package middleware
 
 import (
+	"errors"
 	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/workradar/server/internal/services"
 	"github.com/workradar/server/pkg/utils"
 )
 
-// AuthMiddleware memvalidasi JWT token
-func AuthMiddleware() fiber.Handler {
+// AuthMiddleware memvalidasi JWT token, session-nya dan state user terbaru.
+// user_type comes from the user state, not from the token, so upgrades and expired
+// VIP periods apply immediately.
+func AuthMiddleware(sessionService *services.SessionManagementService, userStateService *services.UserStateService) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		authHeader := c.Get("Authorization")
 		if authHeader == "" {
@@ -51,10 +55,58 @@ func AuthMiddleware() fiber.Handler {
 			})
 		}
 
+		// Check the session is still active (not logged out from another device)
+		if claims.SessionID == "" {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Session expired, please login again",
+			})
+		}
+		session, err := sessionService.ValidateSession(claims.SessionID)
+		if err != nil {
+			if errors.Is(err, services.ErrSessionNotFound) {
+				return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+					"error": "Session has been revoked",
+				})
+			}
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to validate session",
+			})
+		}
+		if session.UserID != claims.UserID {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Session has been revoked",
+			})
+		}
+
+		// Check the current user state (password changes, lockouts, VIP status)
+		state, err := userStateService.Get(claims.UserID)
+		if err != nil {
+			if errors.Is(err, services.ErrUserNotFound) {
+				return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+					"error": "User not found",
+				})
+			}
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to load user",
+			})
+		}
+		now := time.Now()
+		if claims.IssuedAt == nil || state.IssuedBeforePasswordChange(claims.IssuedAt.Time) {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Password has been changed, please login again",
+			})
+		}
+		if state.IsLocked(now) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "Account is temporarily locked",
+			})
+		}
+
 		// Store user info in context
 		c.Locals("user_id", claims.UserID)
+		c.Locals("session_id", claims.SessionID)
 		c.Locals("user_email", claims.Email)
-		c.Locals("user_type", claims.UserType)
+		c.Locals("user_type", string(state.EffectiveUserType(now)))
 
 		return c.Next()
 	}
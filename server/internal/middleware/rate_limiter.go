@@ -1,43 +1,29 @@
 package middleware
 
 import (
-	"sync"
+	"fmt"
+	"log"
+	"strconv"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/workradar/server/internal/services"
 )
 
-// RateLimiter struct untuk track requests
+// RateLimiter counts requests per IP/User in a sliding window kept in the state store,
+// so the limits hold across replicas and restarts
 type RateLimiter struct {
-	visitors map[string]*Visitor
-	mu       sync.RWMutex
-	cleanup  *time.Ticker
+	store services.StateStore
 }
 
-// Visitor struct untuk track request per IP/User
-type Visitor struct {
-	lastSeen time.Time
-	requests []time.Time
-}
-
-var rateLimiter *RateLimiter
-
-func init() {
-	rateLimiter = &RateLimiter{
-		visitors: make(map[string]*Visitor),
-		cleanup:  time.NewTicker(5 * time.Minute),
-	}
-
-	// Cleanup goroutine
-	go func() {
-		for range rateLimiter.cleanup.C {
-			rateLimiter.cleanupOldVisitors()
-		}
-	}()
+func NewRateLimiter(store services.StateStore) *RateLimiter {
+	return &RateLimiter{store: store}
 }
 
 // RateLimitMiddleware membatasi request per menit
-func RateLimitMiddleware() fiber.Handler {
+func RateLimitMiddleware(store services.StateStore) fiber.Handler {
+	rateLimiter := NewRateLimiter(store)
+
 	return func(c *fiber.Ctx) error {
 		// Get identifier (IP atau User ID jika authenticated)
 		identifier := c.IP()
@@ -48,6 +34,7 @@ func RateLimitMiddleware() fiber.Handler {
 
 		// Special limits untuk login endpoint (prevent brute force)
 		if c.Path() == "/api/auth/login" || c.Path() == "/api/auth/register" {
+			identifier = "auth:" + identifier
 			limit = 5
 			window = 1 * time.Minute
 		} else if userID != nil {
@@ -69,7 +56,7 @@ func RateLimitMiddleware() fiber.Handler {
 		}
 
 		// Check rate limit
-		if !rateLimiter.allow(identifier, limit, window) {
+		if !rateLimiter.Allow(identifier, limit, window) {
 			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
 				"error": "Too many requests. Please try again later.",
 			})
@@ -79,57 +66,33 @@ func RateLimitMiddleware() fiber.Handler {
 	}
 }
 
-// allow checks if request is allowed
-func (rl *RateLimiter) allow(identifier string, limit int, window time.Duration) bool {
-	rl.mu.Lock()
-	defer rl.mu.Unlock()
-
+// Allow checks if request is allowed. It approximates a sliding window from the counts of
+// the current and the previous fixed window, so a client cannot burst twice the limit
+// around a window boundary. Store errors are logged and the request is let through so an
+// outage of the store does not take the API down.
+func (rl *RateLimiter) Allow(identifier string, limit int, window time.Duration) bool {
 	now := time.Now()
-	visitor, exists := rl.visitors[identifier]
+	current := now.UnixNano() / int64(window)
 
-	if !exists {
-		// New visitor
-		rl.visitors[identifier] = &Visitor{
-			lastSeen: now,
-			requests: []time.Time{now},
-		}
+	// Keep the counter for one more window: it is the previous window of the next one
+	count, err := rl.store.Incr(fmt.Sprintf("ratelimit:%s:%d", identifier, current), 2*window)
+	if err != nil {
+		log.Printf("⚠️ Rate limiter unavailable: %v", err)
 		return true
 	}
 
-	// Update last seen
-	visitor.lastSeen = now
-
-	// Remove old requests outside window
-	var validRequests []time.Time
-	for _, req := range visitor.requests {
-		if now.Sub(req) < window {
-			validRequests = append(validRequests, req)
-		}
+	previous := 0
+	value, found, err := rl.store.Get(fmt.Sprintf("ratelimit:%s:%d", identifier, current-1))
+	if err != nil {
+		log.Printf("⚠️ Rate limiter unavailable: %v", err)
+		return true
 	}
-
-	// Check if limit exceeded
-	if len(validRequests) >= limit {
-		visitor.requests = validRequests
-		return false
+	if found {
+		previous, _ = strconv.Atoi(value)
 	}
 
-	// Add current request
-	validRequests = append(validRequests, now)
-	visitor.requests = validRequests
-
-	return true
-}
-
-// cleanupOldVisitors removes visitors yang sudah tidak aktif
-func (rl *RateLimiter) cleanupOldVisitors() {
-	rl.mu.Lock()
-	defer rl.mu.Unlock()
-
-	now := time.Now()
-	for identifier, visitor := range rl.visitors {
-		// Remove jika tidak ada activity dalam 10 menit
-		if now.Sub(visitor.lastSeen) > 10*time.Minute {
-			delete(rl.visitors, identifier)
-		}
-	}
+	// Weight the previous window by how much of it still overlaps the sliding window
+	elapsed := float64(now.UnixNano()%int64(window)) / float64(window)
+	estimate := float64(previous)*(1-elapsed) + float64(count)
+	return estimate <= float64(limit)
 }
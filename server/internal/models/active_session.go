@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// ActiveSession is a server-side login session. Its ID is embedded in the access and
+// refresh tokens (sid claim) so revoking the row logs the device out.
+type ActiveSession struct {
+	ID           string    `gorm:"type:varchar(36);primaryKey" json:"id"`
+	UserID       string    `gorm:"type:varchar(36);index" json:"user_id"`
+	TokenHash    string    `gorm:"type:varchar(64);uniqueIndex" json:"-"` // SHA-256 of the refresh token
+	DeviceInfo   string    `gorm:"type:varchar(255)" json:"device_info"`
+	IPAddress    string    `gorm:"type:varchar(45)" json:"ip_address"`
+	UserAgent    string    `gorm:"type:text" json:"user_agent"`
+	Location     string    `gorm:"type:varchar(255)" json:"location,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+	LastActivity time.Time `json:"last_activity"`
+	ExpiresAt    time.Time `gorm:"index" json:"expires_at"`
+}
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type AIActionStatus string
+
+const (
+	AIActionStatusPending  AIActionStatus = "pending"  // Waiting for user confirmation
+	AIActionStatusRunning  AIActionStatus = "running"  // Confirmed, being executed
+	AIActionStatusExecuted AIActionStatus = "executed" // Executed successfully
+	AIActionStatusFailed   AIActionStatus = "failed"   // Execution returned an error
+	AIActionStatusRejected AIActionStatus = "rejected" // User declined the action
+)
+
+// AIAction records a tool call requested by the AI assistant and its outcome
+type AIAction struct {
+	ID         string                 `gorm:"type:varchar(36);primaryKey" json:"id"`
+	UserID     string                 `gorm:"type:varchar(36);not null;index" json:"user_id"`
+	Tool       string                 `gorm:"type:varchar(50);not null" json:"tool"`
+	Arguments  map[string]interface{} `gorm:"serializer:json;type:text" json:"arguments"`
+	Status     AIActionStatus         `gorm:"type:varchar(20);not null;index" json:"status"`
+	Result     map[string]interface{} `gorm:"serializer:json;type:text" json:"result,omitempty"`
+	Error      *string                `gorm:"type:text" json:"error,omitempty"`
+	ClaimedAt  *time.Time             `json:"claimed_at,omitempty"`
+	ExecutedAt *time.Time             `json:"executed_at,omitempty"`
+	CreatedAt  time.Time              `json:"created_at"`
+	UpdatedAt  time.Time              `json:"updated_at"`
+	User       User                   `gorm:"foreignKey:UserID" json:"-"`
+}
+
+// BeforeCreate hook to generate UUID
+func (a *AIAction) BeforeCreate(tx *gorm.DB) error {
+	if a.ID == "" {
+		a.ID = uuid.New().String()
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type AIUsageKind string
+
+const (
+	AIUsageKindChat    AIUsageKind = "chat"    // Non-streamed chat completion (one row per tool round)
+	AIUsageKindStream  AIUsageKind = "stream"  // Streamed chat completion
+	AIUsageKindSummary AIUsageKind = "summary" // Conversation summarization
+	AIUsageKindPlanner AIUsageKind = "planner" // Daily plan and weekly review
+)
+
+// AIUsage records the tokens consumed by one LLM request. Before the request is sent a
+// reserved row holds its estimated tokens, so requests in flight count against the budget.
+type AIUsage struct {
+	ID               string      `gorm:"type:varchar(36);primaryKey" json:"id"`
+	UserID           string      `gorm:"type:varchar(36);not null;index:idx_ai_usage_user_created" json:"user_id"`
+	Kind             AIUsageKind `gorm:"type:varchar(20);not null" json:"kind"`
+	Model            string      `gorm:"type:varchar(100)" json:"model"`
+	PromptTokens     int         `gorm:"not null" json:"prompt_tokens"`
+	CompletionTokens int         `gorm:"not null" json:"completion_tokens"`
+	TotalTokens      int         `gorm:"not null" json:"total_tokens"`
+	Estimated        bool        `gorm:"default:false" json:"estimated"` // Provider did not report usage
+	Reserved         bool        `gorm:"default:false" json:"reserved"`  // Tokens held for a request still in flight
+	CostUSD          float64     `gorm:"type:decimal(12,6);default:0" json:"cost_usd"`
+	CreatedAt        time.Time   `gorm:"index:idx_ai_usage_user_created" json:"created_at"`
+	User             User        `gorm:"foreignKey:UserID" json:"-"`
+}
+
+// BeforeCreate hook to generate UUID
+func (u *AIUsage) BeforeCreate(tx *gorm.DB) error {
+	if u.ID == "" {
+		u.ID = uuid.New().String()
+	}
+	return nil
+}
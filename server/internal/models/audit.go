@@ -71,7 +71,11 @@ const (
 	EventPasswordChanged     SecurityEventType = "PASSWORD_CHANGED"
 	EventMFAEnabled          SecurityEventType = "MFA_ENABLED"
 	EventMFADisabled         SecurityEventType = "MFA_DISABLED"
+	EventMFABackupCodeUsed   SecurityEventType = "MFA_BACKUP_CODE_USED"
 	EventSessionTimeout      SecurityEventType = "SESSION_TIMEOUT"
+	EventSessionCreated      SecurityEventType = "SESSION_CREATED"
+	EventSessionRevoked      SecurityEventType = "SESSION_REVOKED"
+	EventRefreshTokenReuse   SecurityEventType = "REFRESH_TOKEN_REUSE"
 	EventDataExport          SecurityEventType = "DATA_EXPORT"
 	EventBulkAccess          SecurityEventType = "BULK_ACCESS"
 )
@@ -15,6 +15,8 @@ const (
 	MessageTypeTip     MessageType = "tip"
 	MessageTypeAlert   MessageType = "alert"
 	MessageTypeUpdate  MessageType = "update"
+	MessageTypePlan    MessageType = "plan"   // Daily plan
+	MessageTypeReview  MessageType = "review" // Weekly review
 )
 
 type BotMessage struct {
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ChatConversation is a named AI chat thread. A pinned task or category is added to the
+// assistant's context for every message in the thread.
+type ChatConversation struct {
+	ID               string     `gorm:"type:varchar(36);primaryKey" json:"id"`
+	UserID           string     `gorm:"type:varchar(36);not null;index" json:"user_id"`
+	Title            string     `gorm:"type:varchar(100);not null" json:"title"`
+	IsArchived       bool       `gorm:"default:false;index" json:"is_archived"`
+	PinnedTaskID     *string    `gorm:"type:varchar(36)" json:"pinned_task_id,omitempty"`
+	PinnedCategoryID *string    `gorm:"type:varchar(36)" json:"pinned_category_id,omitempty"`
+	LastMessageAt    *time.Time `gorm:"index" json:"last_message_at,omitempty"`
+	// Summary condenses messages up to SummarizedUntil so long threads fit the model's context
+	Summary         string     `gorm:"type:text" json:"summary,omitempty"`
+	SummarizedUntil *time.Time `json:"summarized_until,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+	User            User       `gorm:"foreignKey:UserID" json:"-"`
+}
+
+func (c *ChatConversation) BeforeCreate(tx *gorm.DB) error {
+	if c.ID == "" {
+		c.ID = uuid.New().String()
+	}
+	return nil
+}
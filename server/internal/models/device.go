@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type DevicePlatform string
+
+const (
+	DevicePlatformAndroid DevicePlatform = "android"
+	DevicePlatformIOS     DevicePlatform = "ios"
+	DevicePlatformWeb     DevicePlatform = "web"
+)
+
+// Device is a push notification target of a user. A token belongs to one device, so
+// registering it again (e.g. after switching accounts) moves it to the new user.
+type Device struct {
+	ID         string         `gorm:"type:varchar(36);primaryKey" json:"id"`
+	UserID     string         `gorm:"type:varchar(36);not null;index" json:"user_id"`
+	Token      string         `gorm:"type:varchar(255);not null;uniqueIndex" json:"-"` // FCM registration token
+	Platform   DevicePlatform `gorm:"type:varchar(20)" json:"platform"`
+	AppVersion string         `gorm:"type:varchar(50)" json:"app_version,omitempty"`
+	Locale     string         `gorm:"type:varchar(20)" json:"locale,omitempty"`
+	LastSeenAt time.Time      `gorm:"index" json:"last_seen_at"`
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+}
+
+// BeforeCreate hook untuk generate UUID
+func (d *Device) BeforeCreate(tx *gorm.DB) error {
+	if d.ID == "" {
+		d.ID = uuid.New().String()
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type EmailOutboxStatus string
+
+const (
+	EmailOutboxPending EmailOutboxStatus = "pending" // Rendered, waiting for the email job
+	EmailOutboxSent    EmailOutboxStatus = "sent"    // Accepted by the mail transport
+	EmailOutboxFailed  EmailOutboxStatus = "failed"  // Last attempt failed; the job queue retries it unless it expired
+)
+
+// EmailOutbox is a rendered outgoing email. It is stored before sending so the exact
+// message survives restarts and is retried by the job queue. Once sent or expired the
+// bodies are cleared; the metadata is kept for auditing.
+type EmailOutbox struct {
+	ID        string            `gorm:"type:varchar(36);primaryKey" json:"id"`
+	UniqueKey *string           `gorm:"type:varchar(191);uniqueIndex" json:"unique_key,omitempty"`
+	Kind      string            `gorm:"type:varchar(50);not null" json:"kind"`
+	To        string            `gorm:"column:to_address;type:varchar(255);not null;index" json:"to"`
+	Lang      string            `gorm:"type:varchar(5);not null" json:"lang"`
+	Subject   string            `gorm:"type:varchar(255);not null" json:"subject"`
+	HTML      string            `gorm:"column:html_body;type:mediumtext" json:"-"`
+	Text      string            `gorm:"column:text_body;type:text" json:"-"`
+	Status    EmailOutboxStatus `gorm:"type:varchar(20);not null;index" json:"status"`
+	Attempts  int               `gorm:"default:0" json:"attempts"`
+	LastError *string           `gorm:"type:text" json:"last_error,omitempty"`
+	ExpiresAt *time.Time        `json:"expires_at,omitempty"` // Emails carrying a code are useless after the code expires
+	SentAt    *time.Time        `json:"sent_at,omitempty"`
+	CreatedAt time.Time         `gorm:"index" json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+// BeforeCreate hook untuk generate UUID
+func (e *EmailOutbox) BeforeCreate(tx *gorm.DB) error {
+	if e.ID == "" {
+		e.ID = uuid.New().String()
+	}
+	if e.Status == "" {
+		e.Status = EmailOutboxPending
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type JobStatus string
+
+const (
+	JobStatusPending   JobStatus = "pending"   // Waiting for RunAt
+	JobStatusRunning   JobStatus = "running"   // Leased by a worker until LockedUntil
+	JobStatusSucceeded JobStatus = "succeeded" // Handler returned nil
+	JobStatusDead      JobStatus = "dead"      // Out of attempts or permanent failure (dead letter)
+)
+
+// Job is a unit of background work stored in the database so it survives restarts.
+// UniqueKey makes enqueueing idempotent across retries and replicas; dead jobs release it.
+type Job struct {
+	ID          string     `gorm:"type:varchar(36);primaryKey" json:"id"`
+	Type        string     `gorm:"type:varchar(50);not null;index" json:"type"`
+	Payload     string     `gorm:"type:text" json:"payload"`
+	UniqueKey   *string    `gorm:"type:varchar(191);uniqueIndex" json:"unique_key,omitempty"`
+	Status      JobStatus  `gorm:"type:varchar(20);not null;index:idx_job_status_run_at" json:"status"`
+	RunAt       time.Time  `gorm:"not null;index:idx_job_status_run_at" json:"run_at"`
+	Attempts    int        `gorm:"default:0" json:"attempts"`
+	MaxAttempts int        `gorm:"default:5" json:"max_attempts"`
+	LockedBy    *string    `gorm:"type:varchar(100)" json:"locked_by,omitempty"`
+	LockedUntil *time.Time `json:"locked_until,omitempty"`
+	LastError   *string    `gorm:"type:text" json:"last_error,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// BeforeCreate hook to generate UUID
+func (j *Job) BeforeCreate(tx *gorm.DB) error {
+	if j.ID == "" {
+		j.ID = uuid.New().String()
+	}
+	if j.Status == "" {
+		j.Status = JobStatusPending
+	}
+	return nil
+}
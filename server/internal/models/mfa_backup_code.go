@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// MFABackupCode is a single-use recovery code for logging in without the authenticator
+// app. Only a hash of the code is stored; regenerating replaces all codes of the user.
+type MFABackupCode struct {
+	ID        string     `gorm:"type:varchar(36);primaryKey" json:"id"`
+	UserID    string     `gorm:"type:varchar(36);not null;index" json:"user_id"`
+	CodeHash  string     `gorm:"type:varchar(100);not null" json:"-"` // bcrypt hash of the code
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// BeforeCreate hook untuk generate UUID
+func (c *MFABackupCode) BeforeCreate(tx *gorm.DB) error {
+	if c.ID == "" {
+		c.ID = uuid.New().String()
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type NotificationStatus string
+
+const (
+	NotificationStatusSent     NotificationStatus = "sent"
+	NotificationStatusFailed   NotificationStatus = "failed"
+	NotificationStatusDropped  NotificationStatus = "dropped"  // Type turned off by the user
+	NotificationStatusDeferred NotificationStatus = "deferred" // Held until quiet hours end
+	NotificationStatusDigested NotificationStatus = "digested" // Held for the daily digest
+)
+
+// NotificationHistory records every notification attempt so users can see what was sent
+type NotificationHistory struct {
+	ID        string              `gorm:"type:varchar(36);primaryKey" json:"id"`
+	UserID    string              `gorm:"type:varchar(36);not null;index:idx_notification_history_user_created" json:"user_id"`
+	Type      NotificationType    `gorm:"type:varchar(30);not null" json:"type"`
+	Channel   NotificationChannel `gorm:"type:varchar(20)" json:"channel,omitempty"`
+	Status    NotificationStatus  `gorm:"type:varchar(20);not null" json:"status"`
+	Title     string              `gorm:"type:varchar(255);not null" json:"title"`
+	Body      string              `gorm:"type:text" json:"body"`
+	Error     *string             `gorm:"type:text" json:"error,omitempty"`
+	CreatedAt time.Time           `gorm:"index:idx_notification_history_user_created" json:"created_at"`
+}
+
+// BeforeCreate hook untuk generate UUID
+func (h *NotificationHistory) BeforeCreate(tx *gorm.DB) error {
+	if h.ID == "" {
+		h.ID = uuid.New().String()
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type NotificationType string
+
+const (
+	NotificationTypeTaskReminder NotificationType = "task_reminder"
+	NotificationTypeTaskOverdue  NotificationType = "task_overdue"
+	NotificationTypeWeather      NotificationType = "weather_alert"
+	NotificationTypeHealth       NotificationType = "health_recommendation"
+	NotificationTypeDailyPlan    NotificationType = "daily_plan"
+	NotificationTypeWeeklyReview NotificationType = "weekly_review"
+	NotificationTypeDigest       NotificationType = "digest" // The daily digest itself
+)
+
+// NotificationTypes lists every type a user can configure, in display order
+var NotificationTypes = []NotificationType{
+	NotificationTypeTaskReminder,
+	NotificationTypeTaskOverdue,
+	NotificationTypeWeather,
+	NotificationTypeHealth,
+	NotificationTypeDailyPlan,
+	NotificationTypeWeeklyReview,
+	NotificationTypeDigest,
+}
+
+// IsValid reports whether t is a known notification type
+func (t NotificationType) IsValid() bool {
+	for _, known := range NotificationTypes {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+type NotificationChannel string
+
+const (
+	NotificationChannelPush  NotificationChannel = "push"
+	NotificationChannelEmail NotificationChannel = "email"
+	NotificationChannelInApp NotificationChannel = "in_app" // Bot message
+	NotificationChannelOff   NotificationChannel = "off"
+)
+
+// IsValid reports whether c is a known channel
+func (c NotificationChannel) IsValid() bool {
+	switch c {
+	case NotificationChannelPush, NotificationChannelEmail, NotificationChannelInApp, NotificationChannelOff:
+		return true
+	}
+	return false
+}
+
+// NotificationPreference holds how a user wants to be notified. Channels maps a
+// NotificationType to a NotificationChannel; missing types use the default (push).
+// Quiet hours and the digest time are "HH:MM" in server local time; a quiet window
+// whose end is before its start spans midnight.
+type NotificationPreference struct {
+	ID                    string                                   `gorm:"type:varchar(36);primaryKey" json:"-"`
+	UserID                string                                   `gorm:"type:varchar(36);not null;uniqueIndex" json:"user_id"`
+	Channels              map[NotificationType]NotificationChannel `gorm:"serializer:json;type:text" json:"channels"`
+	QuietHoursEnabled     bool                                     `gorm:"default:false" json:"quiet_hours_enabled"`
+	QuietHoursStart       string                                   `gorm:"type:varchar(5);default:'22:00'" json:"quiet_hours_start"`
+	QuietHoursEnd         string                                   `gorm:"type:varchar(5);default:'07:00'" json:"quiet_hours_end"`
+	QuietHoursAllowUrgent bool                                     `gorm:"default:false" json:"quiet_hours_allow_urgent"` // High priority still comes through
+	DigestEnabled         bool                                     `gorm:"default:false" json:"digest_enabled"`           // Batch low priority notifications
+	DigestTime            string                                   `gorm:"type:varchar(5);default:'18:00'" json:"digest_time"`
+	CreatedAt             time.Time                                `json:"created_at"`
+	UpdatedAt             time.Time                                `json:"updated_at"`
+}
+
+// BeforeCreate hook untuk generate UUID
+func (p *NotificationPreference) BeforeCreate(tx *gorm.DB) error {
+	if p.ID == "" {
+		p.ID = uuid.New().String()
+	}
+	return nil
+}
+
+// NotificationDigestItem is a low priority notification held back for the user's daily digest
+type NotificationDigestItem struct {
+	ID        string           `gorm:"type:varchar(36);primaryKey" json:"id"`
+	UserID    string           `gorm:"type:varchar(36);not null;index" json:"user_id"`
+	Type      NotificationType `gorm:"type:varchar(30);not null" json:"type"`
+	Title     string           `gorm:"type:varchar(255);not null" json:"title"`
+	Body      string           `gorm:"type:text" json:"body"`
+	CreatedAt time.Time        `json:"created_at"`
+}
+
+// BeforeCreate hook untuk generate UUID
+func (i *NotificationDigestItem) BeforeCreate(tx *gorm.DB) error {
+	if i.ID == "" {
+		i.ID = uuid.New().String()
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ReconciliationAction describes what the reconciler did with a pending transaction
+type ReconciliationAction string
+
+const (
+	ReconciliationActionNone    ReconciliationAction = "none"    // Still pending at the gateway
+	ReconciliationActionUpdated ReconciliationAction = "updated" // Local status synced to gateway status
+	ReconciliationActionExpired ReconciliationAction = "expired" // Abandoned order expired locally
+	ReconciliationActionSkipped ReconciliationAction = "skipped" // Already resolved by a webhook or another replica
+	ReconciliationActionError   ReconciliationAction = "error"   // Gateway check or update failed
+)
+
+// ReconciliationMismatch is a transaction whose local status differed from the gateway
+type ReconciliationMismatch struct {
+	OrderID        string               `json:"order_id"`
+	UserID         string               `json:"user_id"`
+	LocalStatus    TransactionStatus    `json:"local_status"`
+	GatewayStatus  string               `json:"gateway_status"`
+	ResolvedStatus TransactionStatus    `json:"resolved_status"`
+	Action         ReconciliationAction `json:"action"`
+	Error          string               `json:"error,omitempty"`
+}
+
+// PaymentReconciliationReport summarizes a single reconciliation run. Reports are stored
+// so every replica (and a restarted server) shows admins the same history.
+type PaymentReconciliationReport struct {
+	ID           string                   `gorm:"type:varchar(36);primaryKey" json:"id"`
+	StartedAt    time.Time                `gorm:"index" json:"started_at"`
+	CompletedAt  time.Time                `json:"completed_at"`
+	Checked      int                      `gorm:"default:0" json:"checked"`
+	Updated      int                      `gorm:"default:0" json:"updated"`
+	Expired      int                      `gorm:"default:0" json:"expired"`
+	StillPending int                      `gorm:"default:0" json:"still_pending"`
+	Skipped      int                      `gorm:"default:0" json:"skipped"`
+	Failed       int                      `gorm:"default:0" json:"failed"`
+	Mismatches   []ReconciliationMismatch `gorm:"serializer:json;type:text" json:"mismatches"`
+	CreatedAt    time.Time                `json:"created_at"`
+}
+
+// BeforeCreate hook to generate UUID
+func (r *PaymentReconciliationReport) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == "" {
+		r.ID = uuid.New().String()
+	}
+	return nil
+}
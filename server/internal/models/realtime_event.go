@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// RealtimeEvent is an event relayed between server replicas by the database event broker.
+// ID is auto-increment so every replica can read new events in order from where it stopped.
+type RealtimeEvent struct {
+	ID        uint64    `gorm:"primaryKey;autoIncrement" json:"id"`
+	EventID   string    `gorm:"type:varchar(36);not null" json:"event_id"`
+	UserID    string    `gorm:"type:varchar(36);not null" json:"user_id"`
+	Type      string    `gorm:"type:varchar(50);not null" json:"type"`
+	Data      string    `gorm:"type:mediumtext" json:"data"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+}
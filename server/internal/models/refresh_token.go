@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// RefreshToken is one refresh token of a session, stored as a hash. Every refresh rotates
+// the token; the session ID is the token family, so presenting a token that was already
+// rotated (UsedAt set) means it was stolen and the whole family is revoked, unless it
+// comes from a concurrent refresh within a short grace window.
+type RefreshToken struct {
+	ID        string     `gorm:"type:varchar(36);primaryKey" json:"id"`
+	FamilyID  string     `gorm:"type:varchar(36);not null;index" json:"family_id"` // ActiveSession.ID
+	UserID    string     `gorm:"type:varchar(36);not null;index" json:"user_id"`
+	TokenHash string     `gorm:"type:varchar(64);not null;uniqueIndex" json:"-"` // SHA-256 of the token
+	ExpiresAt time.Time  `gorm:"index" json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	// The token this one was rotated to: its hash, and the token itself encrypted with a
+	// key derived from this token, returned again to a concurrent refresh
+	ReplacedByHash   string    `gorm:"type:varchar(64)" json:"-"`
+	ReplacedBySealed string    `gorm:"type:text" json:"-"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// BeforeCreate hook untuk generate UUID
+func (t *RefreshToken) BeforeCreate(tx *gorm.DB) error {
+	if t.ID == "" {
+		t.ID = uuid.New().String()
+	}
+	return nil
+}
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// StateEntry is a key of the database state store (token blacklist, rate limit counters,
+// login attempt records) shared by every server replica. Expired entries are ignored
+// and removed periodically.
+type StateEntry struct {
+	Key       string    `gorm:"type:varchar(191);primaryKey" json:"key"`
+	Value     string    `gorm:"type:text" json:"value"`
+	ExpiresAt time.Time `gorm:"not null;index" json:"expires_at"`
+}
@@ -17,27 +17,45 @@ const (
 	RepeatMonthly RepeatType = "monthly"
 )
 
+type TaskPriority string
+
+// Priority is the importance of a task; urgency is derived from the deadline
+const (
+	PriorityLow    TaskPriority = "low"
+	PriorityMedium TaskPriority = "medium"
+	PriorityHigh   TaskPriority = "high"
+)
+
+// IsValid reports whether p is a known priority
+func (p TaskPriority) IsValid() bool {
+	return p == PriorityLow || p == PriorityMedium || p == PriorityHigh
+}
+
 type Task struct {
-	ID              string     `gorm:"type:varchar(36);primaryKey" json:"id"`
-	UserID          string     `gorm:"type:varchar(36);not null;index:idx_user_id" json:"user_id"`
-	CategoryID      *string    `gorm:"type:varchar(36);index:idx_category_id" json:"category_id"`
-	Title           string     `gorm:"type:varchar(255);not null" json:"title"`
-	Description     *string    `gorm:"type:text" json:"description,omitempty"`
-	Deadline        *time.Time `json:"deadline,omitempty"`
-	ReminderMinutes *int       `json:"reminder_minutes,omitempty"`
-	DurationMinutes *int       `json:"duration_minutes,omitempty"`
-	Difficulty      *string    `gorm:"type:varchar(20)" json:"difficulty,omitempty"` // relaxed, normal, focus
-	RepeatType      RepeatType `gorm:"type:enum('none','hourly','daily','weekly','monthly');default:'none'" json:"repeat_type"`
-	RepeatInterval  int        `gorm:"default:1" json:"repeat_interval"`
-	RepeatEndDate   *time.Time `gorm:"type:date" json:"repeat_end_date,omitempty"`
-	IsCompleted     bool       `gorm:"default:false;index:idx_is_completed" json:"is_completed"`
-	CompletedAt     *time.Time `json:"completed_at,omitempty"`
-	CreatedAt       time.Time  `json:"created_at"`
-	UpdatedAt       time.Time  `json:"updated_at"`
+	ID              string       `gorm:"type:varchar(36);primaryKey" json:"id"`
+	UserID          string       `gorm:"type:varchar(36);not null;index:idx_user_id" json:"user_id"`
+	CategoryID      *string      `gorm:"type:varchar(36);index:idx_category_id" json:"category_id"`
+	Title           string       `gorm:"type:varchar(255);not null" json:"title"`
+	Description     *string      `gorm:"type:text" json:"description,omitempty"`
+	Deadline        *time.Time   `json:"deadline,omitempty"`
+	ReminderMinutes *int         `json:"reminder_minutes,omitempty"`
+	DurationMinutes *int         `json:"duration_minutes,omitempty"`
+	Difficulty      *string      `gorm:"type:varchar(20)" json:"difficulty,omitempty"` // relaxed, normal, focus
+	Priority        TaskPriority `gorm:"type:varchar(10);default:'medium'" json:"priority"`
+	ScheduledStart  *time.Time   `json:"scheduled_start,omitempty"`           // Time block for doing the work
+	AutoScheduled   bool         `gorm:"default:false" json:"auto_scheduled"` // Time block set by the auto-scheduler
+	RepeatType      RepeatType   `gorm:"type:enum('none','hourly','daily','weekly','monthly');default:'none'" json:"repeat_type"`
+	RepeatInterval  int          `gorm:"default:1" json:"repeat_interval"`
+	RepeatEndDate   *time.Time   `gorm:"type:date" json:"repeat_end_date,omitempty"`
+	IsCompleted     bool         `gorm:"default:false;index:idx_is_completed" json:"is_completed"`
+	CompletedAt     *time.Time   `json:"completed_at,omitempty"`
+	CreatedAt       time.Time    `json:"created_at"`
+	UpdatedAt       time.Time    `json:"updated_at"`
 
 	// Relations
-	User     User      `gorm:"foreignKey:UserID" json:"-"`
-	Category *Category `gorm:"foreignKey:CategoryID" json:"category,omitempty"`
+	User      User           `gorm:"foreignKey:UserID" json:"-"`
+	Category  *Category      `gorm:"foreignKey:CategoryID" json:"category,omitempty"`
+	Reminders []TaskReminder `gorm:"foreignKey:TaskID" json:"reminders,omitempty"`
 }
 
 // BeforeCreate hook untuk generate UUID
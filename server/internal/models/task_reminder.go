@@ -0,0 +1,61 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// TaskReminder is one extra reminder of a task: either OffsetMinutes before the
+// deadline (relative) or at RemindAt (absolute). Task.ReminderMinutes stays the
+// primary relative reminder.
+type TaskReminder struct {
+	ID            string     `gorm:"type:varchar(36);primaryKey" json:"id"`
+	TaskID        string     `gorm:"type:varchar(36);not null;index" json:"task_id"`
+	OffsetMinutes *int       `json:"offset_minutes,omitempty"`
+	RemindAt      *time.Time `gorm:"index" json:"remind_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// BeforeCreate hook untuk generate UUID
+func (r *TaskReminder) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == "" {
+		r.ID = uuid.New().String()
+	}
+	return nil
+}
+
+type ReminderDeliveryStatus string
+
+const (
+	ReminderDeliveryQueued  ReminderDeliveryStatus = "queued" // Claimed and handed to the job queue
+	ReminderDeliverySent    ReminderDeliveryStatus = "sent"
+	ReminderDeliverySkipped ReminderDeliveryStatus = "skipped" // Superseded by a later reminder, or task completed before sending
+	ReminderDeliveryFailed  ReminderDeliveryStatus = "failed"
+)
+
+// ReminderDelivery records every reminder or overdue nudge that fired. Key is unique,
+// so a reminder is claimed once and never sent twice, also across restarts.
+type ReminderDelivery struct {
+	ID        string                 `gorm:"type:varchar(36);primaryKey" json:"id"`
+	Key       string                 `gorm:"type:varchar(191);not null;uniqueIndex" json:"key"`
+	TaskID    string                 `gorm:"type:varchar(36);not null;index" json:"task_id"`
+	UserID    string                 `gorm:"type:varchar(36);not null;index" json:"user_id"`
+	Kind      string                 `gorm:"type:varchar(20);not null" json:"kind"` // reminder, overdue
+	FireAt    time.Time              `json:"fire_at"`
+	Status    ReminderDeliveryStatus `gorm:"type:varchar(20);not null" json:"status"`
+	Error     *string                `gorm:"type:text" json:"error,omitempty"`
+	CreatedAt time.Time              `gorm:"index" json:"created_at"`
+}
+
+// BeforeCreate hook untuk generate UUID
+func (d *ReminderDelivery) BeforeCreate(tx *gorm.DB) error {
+	if d.ID == "" {
+		d.ID = uuid.New().String()
+	}
+	if d.Status == "" {
+		d.Status = ReminderDeliveryQueued
+	}
+	return nil
+}
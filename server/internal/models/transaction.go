@@ -14,6 +14,9 @@ const (
 	TransactionStatusExpire     TransactionStatus = "expire"
 	TransactionStatusCancel     TransactionStatus = "cancel"
 	TransactionStatusDeny       TransactionStatus = "deny"
+	TransactionStatusRefund     TransactionStatus = "refund"
+	// Part of the amount was returned; the subscription stays active
+	TransactionStatusPartialRefund TransactionStatus = "partial_refund"
 )
 
 type Transaction struct {
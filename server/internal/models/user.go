@@ -30,6 +30,8 @@ type User struct {
 	UserType       UserType     `gorm:"type:enum('regular','vip');default:'regular'" json:"user_type"`
 	VIPExpiresAt   *time.Time   `gorm:"column:vip_expires_at" json:"vip_expires_at,omitempty"`
 	WorkDays       *string      `gorm:"type:json" json:"work_days,omitempty"`
+	Language       string       `gorm:"type:varchar(5);default:'id'" json:"language"` // Email language: id or en
+	Timezone       string       `gorm:"type:varchar(64)" json:"timezone,omitempty"`   // IANA name, e.g. Asia/Jakarta
 
 	// Field-Level Encryption Fields (Minggu 4: Enkripsi & Perlindungan Data)
 	Phone          *string `gorm:"type:varchar(20)" json:"phone,omitempty"`
@@ -66,6 +68,16 @@ func (u *User) BeforeCreate(tx *gorm.DB) error {
 	return nil
 }
 
+// Location returns the user's timezone, or the server timezone when none is set
+func (u *User) Location() *time.Location {
+	if u.Timezone != "" {
+		if loc, err := time.LoadLocation(u.Timezone); err == nil {
+			return loc
+		}
+	}
+	return time.Local
+}
+
 // UserResponse untuk response tanpa sensitive data
 type UserResponse struct {
 	ID             string       `json:"id"`
@@ -77,6 +89,8 @@ type UserResponse struct {
 	EmailVerified  bool         `json:"email_verified"`
 	VIPExpiresAt   *time.Time   `json:"vip_expires_at,omitempty"`
 	WorkDays       *string      `json:"work_days,omitempty"`
+	Language       string       `json:"language"`
+	Timezone       string       `json:"timezone,omitempty"`
 	CreatedAt      time.Time    `json:"created_at"`
 	UpdatedAt      time.Time    `json:"updated_at"`
 }
@@ -92,6 +106,8 @@ func (u *User) ToResponse() UserResponse {
 		EmailVerified:  u.EmailVerified,
 		VIPExpiresAt:   u.VIPExpiresAt,
 		WorkDays:       u.WorkDays,
+		Language:       u.Language,
+		Timezone:       u.Timezone,
 		CreatedAt:      u.CreatedAt,
 		UpdatedAt:      u.UpdatedAt,
 	}
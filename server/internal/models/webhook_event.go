@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type WebhookEventStatus string
+
+const (
+	WebhookEventStatusReceived  WebhookEventStatus = "received"  // Stored, not processed yet
+	WebhookEventStatusProcessed WebhookEventStatus = "processed" // HandleNotification succeeded
+	WebhookEventStatusFailed    WebhookEventStatus = "failed"    // HandleNotification returned an error
+	WebhookEventStatusRejected  WebhookEventStatus = "rejected"  // Failed verification on replay
+)
+
+// WebhookEvent stores every verified payment gateway notification for inspection and replay
+type WebhookEvent struct {
+	ID             string             `gorm:"type:varchar(36);primaryKey" json:"id"`
+	Provider       string             `gorm:"type:varchar(20);not null;index" json:"provider"`
+	NotificationID string             `gorm:"type:varchar(191);not null;uniqueIndex" json:"notification_id"` // Dedupe key derived from the signed fields
+	OrderID        string             `gorm:"type:varchar(50);index" json:"order_id"`
+	RawBody        string             `gorm:"type:text" json:"raw_body"`
+	Headers        map[string]string  `gorm:"serializer:json;type:text" json:"headers"`
+	SignatureValid bool               `gorm:"default:false" json:"signature_valid"`
+	Status         WebhookEventStatus `gorm:"type:varchar(20);not null;index" json:"status"`
+	Error          *string            `gorm:"type:text" json:"error,omitempty"`
+	Attempts       int                `gorm:"default:0" json:"attempts"`
+	LastReplayedBy *string            `gorm:"type:varchar(36)" json:"last_replayed_by,omitempty"`
+	ProcessedAt    *time.Time         `json:"processed_at,omitempty"`
+	CreatedAt      time.Time          `gorm:"index" json:"created_at"`
+	UpdatedAt      time.Time          `json:"updated_at"`
+}
+
+// BeforeCreate hook to generate UUID
+func (e *WebhookEvent) BeforeCreate(tx *gorm.DB) error {
+	if e.ID == "" {
+		e.ID = uuid.New().String()
+	}
+	if e.Status == "" {
+		e.Status = WebhookEventStatusReceived
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/workradar/server/internal/models"
+	"gorm.io/gorm"
+)
+
+type AIActionRepository struct {
+	db *gorm.DB
+}
+
+func NewAIActionRepository(db *gorm.DB) *AIActionRepository {
+	return &AIActionRepository{db: db}
+}
+
+// Create stores a new AI action
+func (r *AIActionRepository) Create(action *models.AIAction) error {
+	return r.db.Create(action).Error
+}
+
+// Update saves all fields of an AI action
+func (r *AIActionRepository) Update(action *models.AIAction) error {
+	return r.db.Save(action).Error
+}
+
+// ClaimPending moves a pending action of the user to status; false means the action
+// does not exist, belongs to another user or is no longer pending
+func (r *AIActionRepository) ClaimPending(id, userID string, status models.AIActionStatus) (bool, error) {
+	result := r.db.Model(&models.AIAction{}).
+		Where("id = ? AND user_id = ? AND status = ?", id, userID, models.AIActionStatusPending).
+		Updates(map[string]interface{}{"status": status, "claimed_at": time.Now()})
+	return result.RowsAffected == 1, result.Error
+}
+
+// FailStaleRunning marks the user's actions claimed before before and still running as failed
+func (r *AIActionRepository) FailStaleRunning(userID string, before time.Time, errMsg string) (int64, error) {
+	result := r.db.Model(&models.AIAction{}).
+		Where("user_id = ? AND status = ? AND claimed_at < ?", userID, models.AIActionStatusRunning, before).
+		Updates(map[string]interface{}{"status": models.AIActionStatusFailed, "error": errMsg})
+	return result.RowsAffected, result.Error
+}
+
+// FindByID retrieves an AI action by ID
+func (r *AIActionRepository) FindByID(id string) (*models.AIAction, error) {
+	var action models.AIAction
+	if err := r.db.Where("id = ?", id).First(&action).Error; err != nil {
+		return nil, err
+	}
+	return &action, nil
+}
+
+// FindByUserID retrieves a user's AI actions, newest first, optionally filtered by status
+func (r *AIActionRepository) FindByUserID(userID string, status string, limit int) ([]models.AIAction, error) {
+	var actions []models.AIAction
+	query := r.db.Where("user_id = ?", userID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	err := query.Order("created_at desc").Limit(limit).Find(&actions).Error
+	return actions, err
+}
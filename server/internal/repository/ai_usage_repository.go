@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/workradar/server/internal/models"
+	"gorm.io/gorm"
+)
+
+type AIUsageRepository struct {
+	db *gorm.DB
+}
+
+func NewAIUsageRepository(db *gorm.DB) *AIUsageRepository {
+	return &AIUsageRepository{db: db}
+}
+
+// AIUsageTotals aggregates usage rows
+type AIUsageTotals struct {
+	Requests         int64   `json:"requests"`
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	TotalTokens      int64   `json:"total_tokens"`
+	CostUSD          float64 `json:"cost_usd"`
+}
+
+// AIUsageByUser is the usage of one user
+type AIUsageByUser struct {
+	UserID   string `json:"user_id"`
+	Username string `json:"username"`
+	AIUsageTotals
+}
+
+// AIUsageByKey is the usage grouped by a single column (model or day)
+type AIUsageByKey struct {
+	Key string `json:"key"`
+	AIUsageTotals
+}
+
+const aiUsageTotalsSelect = "COUNT(*) AS requests, " +
+	"COALESCE(SUM(prompt_tokens), 0) AS prompt_tokens, " +
+	"COALESCE(SUM(completion_tokens), 0) AS completion_tokens, " +
+	"COALESCE(SUM(total_tokens), 0) AS total_tokens, " +
+	"COALESCE(SUM(cost_usd), 0) AS cost_usd"
+
+func (r *AIUsageRepository) Create(usage *models.AIUsage) error {
+	return r.db.Create(usage).Error
+}
+
+// Update saves all fields of a usage row, e.g. when a reservation is settled
+func (r *AIUsageRepository) Update(usage *models.AIUsage) error {
+	return r.db.Save(usage).Error
+}
+
+// Delete removes a usage row, e.g. a reservation whose request was never sent
+func (r *AIUsageRepository) Delete(id string) error {
+	return r.db.Where("id = ?", id).Delete(&models.AIUsage{}).Error
+}
+
+// SumTokensByUser returns the tokens a user consumed since the given time
+func (r *AIUsageRepository) SumTokensByUser(userID string, since time.Time) (int64, error) {
+	var total int64
+	err := r.db.Model(&models.AIUsage{}).
+		Where("user_id = ? AND created_at >= ?", userID, since).
+		Select("COALESCE(SUM(total_tokens), 0)").
+		Scan(&total).Error
+	return total, err
+}
+
+// SumTokens returns the tokens consumed by all users since the given time
+func (r *AIUsageRepository) SumTokens(since time.Time) (int64, error) {
+	var total int64
+	err := r.db.Model(&models.AIUsage{}).
+		Where("created_at >= ?", since).
+		Select("COALESCE(SUM(total_tokens), 0)").
+		Scan(&total).Error
+	return total, err
+}
+
+// Totals aggregates all usage since the given time
+func (r *AIUsageRepository) Totals(since time.Time) (*AIUsageTotals, error) {
+	var totals AIUsageTotals
+	err := r.db.Model(&models.AIUsage{}).
+		Where("created_at >= ?", since).
+		Select(aiUsageTotalsSelect).
+		Scan(&totals).Error
+	return &totals, err
+}
+
+// TopUsers returns the heaviest users since the given time
+func (r *AIUsageRepository) TopUsers(since time.Time, limit int) ([]AIUsageByUser, error) {
+	var rows []AIUsageByUser
+	err := r.db.Table("ai_usages").
+		Select("ai_usages.user_id, users.username, "+aiUsageTotalsSelect).
+		Joins("LEFT JOIN users ON users.id = ai_usages.user_id").
+		Where("ai_usages.created_at >= ?", since).
+		Group("ai_usages.user_id, users.username").
+		Order("total_tokens desc").
+		Limit(limit).
+		Scan(&rows).Error
+	return rows, err
+}
+
+// ByModel groups usage since the given time by model
+func (r *AIUsageRepository) ByModel(since time.Time) ([]AIUsageByKey, error) {
+	var rows []AIUsageByKey
+	err := r.db.Model(&models.AIUsage{}).
+		Select("model AS `key`, "+aiUsageTotalsSelect).
+		Where("created_at >= ?", since).
+		Group("model").
+		Order("total_tokens desc").
+		Scan(&rows).Error
+	return rows, err
+}
+
+// ByDay groups usage since the given time by calendar day
+func (r *AIUsageRepository) ByDay(since time.Time) ([]AIUsageByKey, error) {
+	var rows []AIUsageByKey
+	err := r.db.Model(&models.AIUsage{}).
+		Select("CAST(DATE(created_at) AS CHAR) AS `key`, "+aiUsageTotalsSelect).
+		Where("created_at >= ?", since).
+		Group("`key`").
+		Order("`key` asc").
+		Scan(&rows).Error
+	return rows, err
+}
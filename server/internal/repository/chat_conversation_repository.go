@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/workradar/server/internal/models"
+	"gorm.io/gorm"
+)
+
+type ChatConversationRepository struct {
+	db *gorm.DB
+}
+
+func NewChatConversationRepository(db *gorm.DB) *ChatConversationRepository {
+	return &ChatConversationRepository{db: db}
+}
+
+func (r *ChatConversationRepository) Create(conversation *models.ChatConversation) error {
+	return r.db.Create(conversation).Error
+}
+
+// UpdateSettings saves the user-editable fields (title, archive flag, pins)
+func (r *ChatConversationRepository) UpdateSettings(conversation *models.ChatConversation) error {
+	return r.db.Model(conversation).
+		Select("title", "is_archived", "pinned_task_id", "pinned_category_id").
+		Updates(conversation).Error
+}
+
+func (r *ChatConversationRepository) FindByID(id string) (*models.ChatConversation, error) {
+	var conversation models.ChatConversation
+	if err := r.db.Where("id = ?", id).First(&conversation).Error; err != nil {
+		return nil, err
+	}
+	return &conversation, nil
+}
+
+// FindByUserID returns the user's conversations, most recently active first
+func (r *ChatConversationRepository) FindByUserID(userID string, archived bool) ([]models.ChatConversation, error) {
+	var conversations []models.ChatConversation
+	err := r.db.Where("user_id = ? AND is_archived = ?", userID, archived).
+		Order("COALESCE(last_message_at, created_at) desc").
+		Find(&conversations).Error
+	return conversations, err
+}
+
+// FindLatestActive returns the most recently active non-archived conversation
+func (r *ChatConversationRepository) FindLatestActive(userID string) (*models.ChatConversation, error) {
+	var conversation models.ChatConversation
+	err := r.db.Where("user_id = ? AND is_archived = ?", userID, false).
+		Order("COALESCE(last_message_at, created_at) desc").
+		First(&conversation).Error
+	if err != nil {
+		return nil, err
+	}
+	return &conversation, nil
+}
+
+// UpdateActivity records the latest message time and title without touching other fields
+func (r *ChatConversationRepository) UpdateActivity(id, title string, at time.Time) error {
+	return r.db.Model(&models.ChatConversation{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"title":           title,
+		"last_message_at": at,
+	}).Error
+}
+
+// UpdateSummary stores the rolling summary of messages up to until
+func (r *ChatConversationRepository) UpdateSummary(id, summary string, until time.Time) error {
+	return r.db.Model(&models.ChatConversation{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"summary":          summary,
+		"summarized_until": until,
+	}).Error
+}
+
+// Delete removes a conversation together with its messages
+func (r *ChatConversationRepository) Delete(id string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("conversation_id = ?", id).Delete(&models.ChatMessage{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("id = ?", id).Delete(&models.ChatConversation{}).Error
+	})
+}
+
+// DeleteByUserID removes all of a user's conversations (messages are cleared separately)
+func (r *ChatConversationRepository) DeleteByUserID(userID string) error {
+	return r.db.Where("user_id = ?", userID).Delete(&models.ChatConversation{}).Error
+}
@@ -1,6 +1,8 @@
 package repository
 
 import (
+	"time"
+
 	"github.com/workradar/server/internal/models"
 	"gorm.io/gorm"
 )
@@ -29,6 +31,51 @@ func (r *ChatRepository) FindByUserID(userID string, limit int) ([]models.ChatMe
 	return messages, err
 }
 
+// FindByConversationID returns the latest messages of a conversation in chronological order
+func (r *ChatRepository) FindByConversationID(conversationID string, limit int) ([]models.ChatMessage, error) {
+	var messages []models.ChatMessage
+	err := r.db.Where("conversation_id = ?", conversationID).Order("created_at desc").Limit(limit).Find(&messages).Error
+
+	// Reverse to get chronological order for chat history
+	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+		messages[i], messages[j] = messages[j], messages[i]
+	}
+
+	return messages, err
+}
+
+// FindByConversationAfter returns the latest messages of a conversation created after
+// the given time (all when nil), in chronological order
+func (r *ChatRepository) FindByConversationAfter(conversationID string, after *time.Time, limit int) ([]models.ChatMessage, error) {
+	var messages []models.ChatMessage
+	query := r.db.Where("conversation_id = ?", conversationID)
+	if after != nil {
+		query = query.Where("created_at > ?", *after)
+	}
+	err := query.Order("created_at desc").Limit(limit).Find(&messages).Error
+
+	// Reverse to get chronological order for chat history
+	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+		messages[i], messages[j] = messages[j], messages[i]
+	}
+
+	return messages, err
+}
+
+// CountWithoutConversation counts a user's messages sent before conversations existed
+func (r *ChatRepository) CountWithoutConversation(userID string) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.ChatMessage{}).Where("user_id = ? AND conversation_id IS NULL", userID).Count(&count).Error
+	return count, err
+}
+
+// AssignWithoutConversation moves a user's legacy messages into a conversation
+func (r *ChatRepository) AssignWithoutConversation(userID, conversationID string) error {
+	return r.db.Model(&models.ChatMessage{}).
+		Where("user_id = ? AND conversation_id IS NULL", userID).
+		Update("conversation_id", conversationID).Error
+}
+
 func (r *ChatRepository) DeleteByUserID(userID string) error {
 	return r.db.Where("user_id = ?", userID).Delete(&models.ChatMessage{}).Error
 }
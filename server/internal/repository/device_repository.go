@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/workradar/server/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type DeviceRepository struct {
+	db *gorm.DB
+}
+
+func NewDeviceRepository(db *gorm.DB) *DeviceRepository {
+	return &DeviceRepository{db: db}
+}
+
+// Upsert menyimpan device; token yang sudah ada dipindah ke user ini dan diperbarui
+func (r *DeviceRepository) Upsert(device *models.Device) error {
+	if err := r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "token"}},
+		DoUpdates: clause.AssignmentColumns([]string{"user_id", "platform", "app_version", "locale", "last_seen_at", "updated_at"}),
+	}).Create(device).Error; err != nil {
+		return err
+	}
+	// On conflict the generated ID was not stored; load the actual row. Into a fresh value:
+	// GORM would add the generated primary key to the conditions.
+	var stored models.Device
+	if err := r.db.Where("token = ?", device.Token).First(&stored).Error; err != nil {
+		return err
+	}
+	*device = stored
+	return nil
+}
+
+// FindByUserID mencari devices user, terbaru dulu
+func (r *DeviceRepository) FindByUserID(userID string) ([]models.Device, error) {
+	var devices []models.Device
+	err := r.db.Where("user_id = ?", userID).Order("last_seen_at DESC").Find(&devices).Error
+	return devices, err
+}
+
+// FindActiveByUserID mencari devices user yang terlihat sejak waktu tertentu
+func (r *DeviceRepository) FindActiveByUserID(userID string, since time.Time) ([]models.Device, error) {
+	var devices []models.Device
+	err := r.db.Where("user_id = ? AND last_seen_at >= ?", userID, since).
+		Order("last_seen_at DESC").
+		Find(&devices).Error
+	return devices, err
+}
+
+// DeleteByID menghapus satu device milik user
+func (r *DeviceRepository) DeleteByID(userID, id string) (int64, error) {
+	result := r.db.Where("id = ? AND user_id = ?", id, userID).Delete(&models.Device{})
+	return result.RowsAffected, result.Error
+}
+
+// DeleteByToken menghapus device milik user berdasarkan token
+func (r *DeviceRepository) DeleteByToken(userID, token string) (int64, error) {
+	result := r.db.Where("token = ? AND user_id = ?", token, userID).Delete(&models.Device{})
+	return result.RowsAffected, result.Error
+}
+
+// DeleteByUserID menghapus semua devices user
+func (r *DeviceRepository) DeleteByUserID(userID string) error {
+	return r.db.Where("user_id = ?", userID).Delete(&models.Device{}).Error
+}
+
+// DeleteTokens menghapus token yang ditolak FCM
+func (r *DeviceRepository) DeleteTokens(tokens []string) error {
+	if len(tokens) == 0 {
+		return nil
+	}
+	return r.db.Where("token IN ?", tokens).Delete(&models.Device{}).Error
+}
+
+// DeleteOldest keeps the keep most recently seen devices of a user
+func (r *DeviceRepository) DeleteOldest(userID string, keep int) error {
+	var stale []string
+	if err := r.db.Model(&models.Device{}).
+		Where("user_id = ?", userID).
+		Order("last_seen_at DESC").
+		Offset(keep).
+		Limit(1000).
+		Pluck("id", &stale).Error; err != nil {
+		return err
+	}
+	if len(stale) == 0 {
+		return nil
+	}
+	return r.db.Where("id IN ?", stale).Delete(&models.Device{}).Error
+}
+
+// ImportLegacyTokens moves tokens from users.fcm_token into the device table
+func (r *DeviceRepository) ImportLegacyTokens() (int64, error) {
+	var users []models.User
+	if err := r.db.Select("id", "fcm_token").
+		Where("fcm_token IS NOT NULL AND fcm_token != ''").
+		Find(&users).Error; err != nil {
+		return 0, err
+	}
+
+	var imported int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		for _, user := range users {
+			result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.Device{
+				UserID:     user.ID,
+				Token:      *user.FCMToken,
+				LastSeenAt: now,
+			})
+			if result.Error != nil {
+				return result.Error
+			}
+			imported += result.RowsAffected
+			if err := tx.Model(&models.User{}).Where("id = ?", user.ID).Update("fcm_token", nil).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return imported, err
+}
@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/workradar/server/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type EmailOutboxRepository struct {
+	db *gorm.DB
+}
+
+func NewEmailOutboxRepository(db *gorm.DB) *EmailOutboxRepository {
+	return &EmailOutboxRepository{db: db}
+}
+
+// Create menyimpan email baru; false jika email dengan unique key yang sama sudah ada
+func (r *EmailOutboxRepository) Create(email *models.EmailOutbox) (bool, error) {
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(email)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// FindByID mencari email berdasarkan ID
+func (r *EmailOutboxRepository) FindByID(id string) (*models.EmailOutbox, error) {
+	var email models.EmailOutbox
+	if err := r.db.Where("id = ?", id).First(&email).Error; err != nil {
+		return nil, err
+	}
+	return &email, nil
+}
+
+// MarkSent menandai email sudah terkirim dan menghapus isinya (bisa berisi kode)
+func (r *EmailOutboxRepository) MarkSent(id string, sentAt time.Time) error {
+	return r.db.Model(&models.EmailOutbox{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":     models.EmailOutboxSent,
+		"attempts":   gorm.Expr("attempts + 1"),
+		"last_error": nil,
+		"sent_at":    sentAt,
+		"html_body":  "",
+		"text_body":  "",
+	}).Error
+}
+
+// MarkExpired menandai email gagal karena kodenya kedaluwarsa sebelum terkirim, dan menghapus isinya
+func (r *EmailOutboxRepository) MarkExpired(id string) error {
+	return r.db.Model(&models.EmailOutbox{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":     models.EmailOutboxFailed,
+		"last_error": "expired before delivery",
+		"html_body":  "",
+		"text_body":  "",
+	}).Error
+}
+
+// MarkFailed mencatat percobaan kirim yang gagal
+func (r *EmailOutboxRepository) MarkFailed(id, errMsg string) error {
+	return r.db.Model(&models.EmailOutbox{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":     models.EmailOutboxFailed,
+		"attempts":   gorm.Expr("attempts + 1"),
+		"last_error": errMsg,
+	}).Error
+}
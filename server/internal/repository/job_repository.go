@@ -0,0 +1,163 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/workradar/server/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type JobRepository struct {
+	db *gorm.DB
+}
+
+func NewJobRepository(db *gorm.DB) *JobRepository {
+	return &JobRepository{db: db}
+}
+
+// Enqueue stores a new job; false means a job with the same unique key already exists
+func (r *JobRepository) Enqueue(job *models.Job) (bool, error) {
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(job)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// Lease locks the next runnable job for a worker: a pending job whose RunAt has passed, or a
+// running job whose lease expired (its worker died). Rows locked by other workers are skipped.
+func (r *JobRepository) Lease(workerID string, types []string, lease time.Duration, now time.Time) (*models.Job, error) {
+	var leased *models.Job
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var job models.Job
+		query := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("(status = ? AND run_at <= ?) OR (status = ? AND locked_until < ?)",
+				models.JobStatusPending, now, models.JobStatusRunning, now)
+		if len(types) > 0 {
+			query = query.Where("type IN ?", types)
+		}
+		if err := query.Order("run_at ASC").Limit(1).Find(&job).Error; err != nil {
+			return err
+		}
+		if job.ID == "" {
+			return nil
+		}
+
+		lockedUntil := now.Add(lease)
+		job.Status = models.JobStatusRunning
+		job.LockedBy = &workerID
+		job.LockedUntil = &lockedUntil
+		job.Attempts++
+		if err := tx.Model(&models.Job{}).Where("id = ?", job.ID).Updates(map[string]interface{}{
+			"status":       job.Status,
+			"locked_by":    workerID,
+			"locked_until": lockedUntil,
+			"attempts":     job.Attempts,
+		}).Error; err != nil {
+			return err
+		}
+		leased = &job
+		return nil
+	})
+	return leased, err
+}
+
+// Complete marks a job as succeeded if the worker still holds its lease
+func (r *JobRepository) Complete(id, workerID string, now time.Time) error {
+	return r.db.Model(&models.Job{}).
+		Where("id = ? AND locked_by = ?", id, workerID).
+		Updates(map[string]interface{}{
+			"status":       models.JobStatusSucceeded,
+			"locked_by":    nil,
+			"locked_until": nil,
+			"last_error":   nil,
+			"completed_at": now,
+		}).Error
+}
+
+// Fail records an error and either schedules a retry at runAt or dead-letters the job.
+// A dead job releases its unique key, so the same work can be enqueued again.
+func (r *JobRepository) Fail(id, workerID, errMsg string, runAt time.Time, dead bool) error {
+	updates := map[string]interface{}{
+		"status":       models.JobStatusPending,
+		"locked_by":    nil,
+		"locked_until": nil,
+		"last_error":   errMsg,
+		"run_at":       runAt,
+	}
+	if dead {
+		updates["status"] = models.JobStatusDead
+		updates["unique_key"] = nil
+	}
+	return r.db.Model(&models.Job{}).
+		Where("id = ? AND locked_by = ?", id, workerID).
+		Updates(updates).Error
+}
+
+// Retry moves a dead job back to pending with a fresh set of attempts
+func (r *JobRepository) Retry(id string, now time.Time) (int64, error) {
+	result := r.db.Model(&models.Job{}).
+		Where("id = ? AND status = ?", id, models.JobStatusDead).
+		Updates(map[string]interface{}{
+			"status":   models.JobStatusPending,
+			"attempts": 0,
+			"run_at":   now,
+		})
+	return result.RowsAffected, result.Error
+}
+
+// FindByID retrieves a job by ID
+func (r *JobRepository) FindByID(id string) (*models.Job, error) {
+	var job models.Job
+	if err := r.db.Where("id = ?", id).First(&job).Error; err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// List retrieves jobs, newest first, optionally filtered by status and type
+func (r *JobRepository) List(status, jobType string, limit, offset int) ([]models.Job, int64, error) {
+	var jobs []models.Job
+	var total int64
+
+	query := r.db.Model(&models.Job{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if jobType != "" {
+		query = query.Where("type = ?", jobType)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err := query.Order("created_at DESC").Limit(limit).Offset(offset).Find(&jobs).Error
+	return jobs, total, err
+}
+
+// CountByStatus returns the number of jobs per status
+func (r *JobRepository) CountByStatus() (map[models.JobStatus]int64, error) {
+	var rows []struct {
+		Status models.JobStatus
+		Count  int64
+	}
+	if err := r.db.Model(&models.Job{}).
+		Select("status, COUNT(*) AS count").
+		Group("status").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	counts := make(map[models.JobStatus]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Status] = row.Count
+	}
+	return counts, nil
+}
+
+// DeleteSucceededBefore removes finished jobs; their unique keys become usable again
+func (r *JobRepository) DeleteSucceededBefore(before time.Time) (int64, error) {
+	result := r.db.Where("status = ? AND completed_at < ?", models.JobStatusSucceeded, before).Delete(&models.Job{})
+	return result.RowsAffected, result.Error
+}
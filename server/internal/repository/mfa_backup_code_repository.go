@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/workradar/server/internal/models"
+	"gorm.io/gorm"
+)
+
+type MFABackupCodeRepository struct {
+	db *gorm.DB
+}
+
+func NewMFABackupCodeRepository(db *gorm.DB) *MFABackupCodeRepository {
+	return &MFABackupCodeRepository{db: db}
+}
+
+// ReplaceForUser menghapus backup code lama user dan menyimpan yang baru
+func (r *MFABackupCodeRepository) ReplaceForUser(userID string, codeHashes []string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.MFABackupCode{}).Error; err != nil {
+			return err
+		}
+
+		codes := make([]models.MFABackupCode, len(codeHashes))
+		for i, hash := range codeHashes {
+			codes[i] = models.MFABackupCode{UserID: userID, CodeHash: hash}
+		}
+		return tx.Create(&codes).Error
+	})
+}
+
+// FindUnused mengambil backup code user yang belum dipakai
+func (r *MFABackupCodeRepository) FindUnused(userID string) ([]models.MFABackupCode, error) {
+	var codes []models.MFABackupCode
+	err := r.db.Where("user_id = ? AND used_at IS NULL", userID).Find(&codes).Error
+	return codes, err
+}
+
+// Use menandai backup code sebagai terpakai; false jika code sudah dipakai.
+// The conditional update makes a code usable once even with concurrent requests.
+func (r *MFABackupCodeRepository) Use(id string, usedAt time.Time) (bool, error) {
+	result := r.db.Model(&models.MFABackupCode{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", usedAt)
+	return result.RowsAffected > 0, result.Error
+}
+
+// CountRemaining menghitung backup code yang belum dipakai
+func (r *MFABackupCodeRepository) CountRemaining(userID string) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.MFABackupCode{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Count(&count).Error
+	return count, err
+}
+
+// DeleteByUser menghapus semua backup code user
+func (r *MFABackupCodeRepository) DeleteByUser(userID string) error {
+	return r.db.Where("user_id = ?", userID).Delete(&models.MFABackupCode{}).Error
+}
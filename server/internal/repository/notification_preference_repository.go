@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/workradar/server/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type NotificationPreferenceRepository struct {
+	db *gorm.DB
+}
+
+func NewNotificationPreferenceRepository(db *gorm.DB) *NotificationPreferenceRepository {
+	return &NotificationPreferenceRepository{db: db}
+}
+
+// FindByUserID mencari preferensi notifikasi user; nil jika user belum pernah mengatur
+func (r *NotificationPreferenceRepository) FindByUserID(userID string) (*models.NotificationPreference, error) {
+	var pref models.NotificationPreference
+	err := r.db.Where("user_id = ?", userID).First(&pref).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &pref, nil
+}
+
+// Upsert menyimpan preferensi notifikasi user
+func (r *NotificationPreferenceRepository) Upsert(pref *models.NotificationPreference) error {
+	if err := r.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"channels", "quiet_hours_enabled", "quiet_hours_start", "quiet_hours_end",
+			"quiet_hours_allow_urgent", "digest_enabled", "digest_time", "updated_at",
+		}),
+	}).Create(pref).Error; err != nil {
+		return err
+	}
+	return r.db.Where("user_id = ?", pref.UserID).First(pref).Error
+}
+
+// AddDigestItem menyimpan notifikasi untuk digest harian
+func (r *NotificationPreferenceRepository) AddDigestItem(item *models.NotificationDigestItem) error {
+	return r.db.Create(item).Error
+}
+
+// FindDigestItems mencari notifikasi yang menunggu digest user, terlama dulu
+func (r *NotificationPreferenceRepository) FindDigestItems(userID string) ([]models.NotificationDigestItem, error) {
+	var items []models.NotificationDigestItem
+	err := r.db.Where("user_id = ?", userID).Order("created_at ASC").Find(&items).Error
+	return items, err
+}
+
+// DeleteDigestItems menghapus notifikasi yang sudah masuk digest
+func (r *NotificationPreferenceRepository) DeleteDigestItems(ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.db.Where("id IN ?", ids).Delete(&models.NotificationDigestItem{}).Error
+}
+
+// AddHistory mencatat satu percobaan pengiriman notifikasi
+func (r *NotificationPreferenceRepository) AddHistory(entry *models.NotificationHistory) error {
+	return r.db.Create(entry).Error
+}
+
+// FindHistory mencari riwayat notifikasi user, terbaru dulu
+func (r *NotificationPreferenceRepository) FindHistory(userID string, limit, offset int) ([]models.NotificationHistory, int64, error) {
+	var entries []models.NotificationHistory
+	var total int64
+
+	query := r.db.Model(&models.NotificationHistory{}).Where("user_id = ?", userID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err := query.Order("created_at DESC").Limit(limit).Offset(offset).Find(&entries).Error
+	return entries, total, err
+}
+
+// DeleteHistoryBefore menghapus riwayat notifikasi lama
+func (r *NotificationPreferenceRepository) DeleteHistoryBefore(before time.Time) (int64, error) {
+	result := r.db.Where("created_at < ?", before).Delete(&models.NotificationHistory{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/workradar/server/internal/models"
+	"gorm.io/gorm"
+)
+
+type PaymentReconciliationRepository struct {
+	db *gorm.DB
+}
+
+func NewPaymentReconciliationRepository(db *gorm.DB) *PaymentReconciliationRepository {
+	return &PaymentReconciliationRepository{db: db}
+}
+
+// Create menyimpan laporan rekonsiliasi baru
+func (r *PaymentReconciliationRepository) Create(report *models.PaymentReconciliationReport) error {
+	return r.db.Create(report).Error
+}
+
+// FindRecent mengambil laporan terbaru, paling baru lebih dulu
+func (r *PaymentReconciliationRepository) FindRecent(limit int) ([]models.PaymentReconciliationReport, error) {
+	var reports []models.PaymentReconciliationReport
+	err := r.db.Order("started_at desc").Limit(limit).Find(&reports).Error
+	return reports, err
+}
+
+// DeleteOlderThan menghapus laporan yang dimulai sebelum waktu tertentu
+func (r *PaymentReconciliationRepository) DeleteOlderThan(cutoff time.Time) (int64, error) {
+	result := r.db.Where("started_at < ?", cutoff).Delete(&models.PaymentReconciliationReport{})
+	return result.RowsAffected, result.Error
+}
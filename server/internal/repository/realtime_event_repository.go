@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/workradar/server/internal/models"
+	"gorm.io/gorm"
+)
+
+type RealtimeEventRepository struct {
+	db *gorm.DB
+}
+
+func NewRealtimeEventRepository(db *gorm.DB) *RealtimeEventRepository {
+	return &RealtimeEventRepository{db: db}
+}
+
+// Create menyimpan event untuk dibaca semua replica
+func (r *RealtimeEventRepository) Create(event *models.RealtimeEvent) error {
+	return r.db.Create(event).Error
+}
+
+// LatestID mengembalikan ID event terakhir; 0 jika belum ada
+func (r *RealtimeEventRepository) LatestID() (uint64, error) {
+	var id uint64
+	err := r.db.Model(&models.RealtimeEvent{}).Select("COALESCE(MAX(id), 0)").Scan(&id).Error
+	return id, err
+}
+
+// FindAfter mencari event setelah ID tertentu, urut dari yang terlama
+func (r *RealtimeEventRepository) FindAfter(id uint64, limit int) ([]models.RealtimeEvent, error) {
+	var events []models.RealtimeEvent
+	err := r.db.Where("id > ?", id).Order("id ASC").Limit(limit).Find(&events).Error
+	return events, err
+}
+
+// FindLate mencari event sampai ID tertentu yang dibuat sejak waktu tertentu. Auto-increment
+// ID dibagikan saat insert, bukan saat commit, jadi event dengan ID lebih kecil bisa muncul
+// setelah ID yang lebih besar sudah dibaca.
+func (r *RealtimeEventRepository) FindLate(id uint64, since time.Time) ([]models.RealtimeEvent, error) {
+	var events []models.RealtimeEvent
+	err := r.db.Where("id <= ? AND created_at >= ?", id, since).Order("id ASC").Find(&events).Error
+	return events, err
+}
+
+// DeleteBefore menghapus event lama yang sudah dibaca semua replica
+func (r *RealtimeEventRepository) DeleteBefore(before time.Time) (int64, error) {
+	result := r.db.Where("created_at < ?", before).Delete(&models.RealtimeEvent{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/workradar/server/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type StateEntryRepository struct {
+	db *gorm.DB
+}
+
+func NewStateEntryRepository(db *gorm.DB) *StateEntryRepository {
+	return &StateEntryRepository{db: db}
+}
+
+// Find mencari entry yang belum kedaluwarsa
+func (r *StateEntryRepository) Find(key string, now time.Time) (*models.StateEntry, error) {
+	var entry models.StateEntry
+	err := r.db.Where("`key` = ? AND expires_at > ?", key, now).First(&entry).Error
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// Upsert menyimpan entry, menimpa nilai dan waktu kedaluwarsa yang lama
+func (r *StateEntryRepository) Upsert(entry *models.StateEntry) error {
+	return r.db.Clauses(clause.OnConflict{UpdateAll: true}).Create(entry).Error
+}
+
+// Delete menghapus entry
+func (r *StateEntryRepository) Delete(key string) error {
+	return r.db.Where("`key` = ?", key).Delete(&models.StateEntry{}).Error
+}
+
+// Increment menambah counter secara atomik. Counter yang belum ada atau sudah
+// kedaluwarsa dimulai lagi dari 1 dengan waktu kedaluwarsa expiresAt.
+func (r *StateEntryRepository) Increment(key string, expiresAt, now time.Time) (int64, error) {
+	var value int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		// Both assignments see the old row (SQLite evaluates them together, MySQL in
+		// order with value first). CASE and CAST AS SIGNED work on MySQL and SQLite.
+		entry := models.StateEntry{Key: key, Value: "1", ExpiresAt: expiresAt}
+		err := tx.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "key"}},
+			DoUpdates: []clause.Assignment{
+				{Column: clause.Column{Name: "value"}, Value: gorm.Expr("CASE WHEN expires_at <= ? THEN '1' ELSE CAST(value AS SIGNED) + 1 END", now)},
+				{Column: clause.Column{Name: "expires_at"}, Value: gorm.Expr("CASE WHEN expires_at <= ? THEN ? ELSE expires_at END", now, expiresAt)},
+			},
+		}).Create(&entry).Error
+		if err != nil {
+			return err
+		}
+
+		var current models.StateEntry
+		if err := tx.Where("`key` = ?", key).First(&current).Error; err != nil {
+			return err
+		}
+		value, err = strconv.ParseInt(current.Value, 10, 64)
+		return err
+	})
+	return value, err
+}
+
+// DeleteExpired menghapus entry yang sudah kedaluwarsa
+func (r *StateEntryRepository) DeleteExpired(now time.Time) (int64, error) {
+	result := r.db.Where("expires_at <= ?", now).Delete(&models.StateEntry{})
+	return result.RowsAffected, result.Error
+}
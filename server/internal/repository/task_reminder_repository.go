@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/workradar/server/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type TaskReminderRepository struct {
+	db *gorm.DB
+}
+
+func NewTaskReminderRepository(db *gorm.DB) *TaskReminderRepository {
+	return &TaskReminderRepository{db: db}
+}
+
+// ReplaceForTask mengganti semua reminders task
+func (r *TaskReminderRepository) ReplaceForTask(taskID string, reminders []models.TaskReminder) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("task_id = ?", taskID).Delete(&models.TaskReminder{}).Error; err != nil {
+			return err
+		}
+		for i := range reminders {
+			reminders[i].ID = ""
+			reminders[i].TaskID = taskID
+			if err := tx.Create(&reminders[i]).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// DeleteByTaskID menghapus reminders dan riwayat pengiriman task
+func (r *TaskReminderRepository) DeleteByTaskID(taskID string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("task_id = ?", taskID).Delete(&models.TaskReminder{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("task_id = ?", taskID).Delete(&models.ReminderDelivery{}).Error
+	})
+}
+
+// MaxReminderOffset returns the largest relative reminder (in minutes) of pending tasks
+// whose deadline is after now; 0 when there is none
+func (r *TaskReminderRepository) MaxReminderOffset(now time.Time) (int, error) {
+	var primary, extra int
+	if err := r.db.Model(&models.Task{}).
+		Where("is_completed = ? AND deadline > ?", false, now).
+		Select("COALESCE(MAX(reminder_minutes), 0)").
+		Scan(&primary).Error; err != nil {
+		return 0, err
+	}
+	if err := r.db.Model(&models.TaskReminder{}).
+		Joins("JOIN tasks ON tasks.id = task_reminders.task_id").
+		Where("tasks.is_completed = ? AND tasks.deadline > ?", false, now).
+		Select("COALESCE(MAX(task_reminders.offset_minutes), 0)").
+		Scan(&extra).Error; err != nil {
+		return 0, err
+	}
+	if extra > primary {
+		return extra, nil
+	}
+	return primary, nil
+}
+
+// FindReminderCandidates returns pending tasks that may have a reminder or nudge due:
+// deadline in [now-pastWindow, now+leadWindow], or an absolute reminder in [now-pastWindow, now]
+func (r *TaskReminderRepository) FindReminderCandidates(now time.Time, leadWindow, pastWindow time.Duration) ([]models.Task, error) {
+	since := now.Add(-pastWindow)
+	absolute := r.db.Model(&models.TaskReminder{}).
+		Select("task_id").
+		Where("remind_at BETWEEN ? AND ?", since, now)
+
+	var tasks []models.Task
+	err := r.db.Preload("Reminders").
+		Where("is_completed = ?", false).
+		Where("(deadline BETWEEN ? AND ?) OR id IN (?)", since, now.Add(leadWindow), absolute).
+		Find(&tasks).Error
+	return tasks, err
+}
+
+// ClaimDelivery inserts a delivery record; false means the key was already claimed
+func (r *TaskReminderRepository) ClaimDelivery(delivery *models.ReminderDelivery) (bool, error) {
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(delivery)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// UpdateDeliveryStatus menyimpan hasil pengiriman
+func (r *TaskReminderRepository) UpdateDeliveryStatus(id string, status models.ReminderDeliveryStatus, errMsg *string) error {
+	return r.db.Model(&models.ReminderDelivery{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{"status": status, "error": errMsg}).Error
+}
+
+// FindDeliveriesByTaskID mencari riwayat reminder task, terbaru dulu
+func (r *TaskReminderRepository) FindDeliveriesByTaskID(taskID string) ([]models.ReminderDelivery, error) {
+	var deliveries []models.ReminderDelivery
+	err := r.db.Where("task_id = ?", taskID).
+		Order("fire_at DESC").
+		Find(&deliveries).Error
+	return deliveries, err
+}
+
+// DeleteDeliveriesBefore menghapus riwayat pengiriman lama
+func (r *TaskReminderRepository) DeleteDeliveriesBefore(before time.Time) (int64, error) {
+	result := r.db.Where("created_at < ?", before).Delete(&models.ReminderDelivery{})
+	return result.RowsAffected, result.Error
+}
@@ -23,7 +23,7 @@ func (r *TaskRepository) Create(task *models.Task) error {
 // FindByID mencari task by ID dengan category
 func (r *TaskRepository) FindByID(id string) (*models.Task, error) {
 	var task models.Task
-	err := r.db.Preload("Category").First(&task, "id = ?", id).Error
+	err := r.db.Preload("Category").Preload("Reminders").First(&task, "id = ?", id).Error
 	if err != nil {
 		return nil, err
 	}
@@ -33,7 +33,7 @@ func (r *TaskRepository) FindByID(id string) (*models.Task, error) {
 // FindByUserID mencari semua tasks milik user
 func (r *TaskRepository) FindByUserID(userID string) ([]models.Task, error) {
 	var tasks []models.Task
-	err := r.db.Preload("Category").
+	err := r.db.Preload("Category").Preload("Reminders").
 		Where("user_id = ?", userID).
 		Order("created_at DESC").
 		Find(&tasks).Error
@@ -43,7 +43,7 @@ func (r *TaskRepository) FindByUserID(userID string) ([]models.Task, error) {
 // FindByUserIDAndComplete mencari tasks by completed status
 func (r *TaskRepository) FindByUserIDAndComplete(userID string, isCompleted bool) ([]models.Task, error) {
 	var tasks []models.Task
-	err := r.db.Preload("Category").
+	err := r.db.Preload("Category").Preload("Reminders").
 		Where("user_id = ? AND is_completed = ?", userID, isCompleted).
 		Order("created_at DESC").
 		Find(&tasks).Error
@@ -53,7 +53,7 @@ func (r *TaskRepository) FindByUserIDAndComplete(userID string, isCompleted bool
 // FindByUserIDAndCategory mencari tasks by category
 func (r *TaskRepository) FindByUserIDAndCategory(userID, categoryID string) ([]models.Task, error) {
 	var tasks []models.Task
-	err := r.db.Preload("Category").
+	err := r.db.Preload("Category").Preload("Reminders").
 		Where("user_id = ? AND category_id = ?", userID, categoryID).
 		Order("created_at DESC").
 		Find(&tasks).Error
@@ -63,16 +63,23 @@ func (r *TaskRepository) FindByUserIDAndCategory(userID, categoryID string) ([]m
 // FindByUserIDAndDateRange mencari tasks dalam range tanggal
 func (r *TaskRepository) FindByUserIDAndDateRange(userID string, start, end time.Time) ([]models.Task, error) {
 	var tasks []models.Task
-	err := r.db.Preload("Category").
+	err := r.db.Preload("Category").Preload("Reminders").
 		Where("user_id = ? AND deadline BETWEEN ? AND ?", userID, start, end).
 		Order("deadline ASC").
 		Find(&tasks).Error
 	return tasks, err
 }
 
+// UpdateSchedule menyimpan time block task
+func (r *TaskRepository) UpdateSchedule(id string, start *time.Time, auto bool) error {
+	return r.db.Model(&models.Task{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{"scheduled_start": start, "auto_scheduled": auto}).Error
+}
+
 // Update memperbarui task
 func (r *TaskRepository) Update(task *models.Task) error {
-	return r.db.Save(task).Error
+	return r.db.Omit("Reminders").Save(task).Error
 }
 
 // Delete menghapus task
@@ -1,6 +1,8 @@
 package repository
 
 import (
+	"time"
+
 	"github.com/workradar/server/internal/models"
 	"gorm.io/gorm"
 )
@@ -27,9 +29,16 @@ func (r *TransactionRepository) FindByOrderID(orderID string) (*models.Transacti
 	return &transaction, nil
 }
 
-// UpdateStatus memperbarui status transaksi
-func (r *TransactionRepository) UpdateStatus(orderID string, status models.TransactionStatus) error {
-	return r.db.Model(&models.Transaction{}).Where("order_id = ?", orderID).Update("status", status).Error
+// UpdateStatus memperbarui status transaksi hanya jika status saat ini masih from (compare-and-set).
+// Mengembalikan false jika proses lain (webhook / replica lain) sudah mengubahnya lebih dulu.
+func (r *TransactionRepository) UpdateStatus(orderID string, from, to models.TransactionStatus) (bool, error) {
+	result := r.db.Model(&models.Transaction{}).
+		Where("order_id = ? AND status = ?", orderID, from).
+		Update("status", to)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
 }
 
 // UpdateSnapToken menyimpan snap token
@@ -55,3 +64,13 @@ func (r *TransactionRepository) FindPendingByUserID(userID string) ([]models.Tra
 	err := r.db.Where("user_id = ? AND status = ?", userID, models.TransactionStatusPending).Order("created_at desc").Find(&transactions).Error
 	return transactions, err
 }
+
+// FindStalePending mencari transaksi pending yang dibuat sebelum waktu tertentu
+func (r *TransactionRepository) FindStalePending(createdBefore time.Time, limit int) ([]models.Transaction, error) {
+	var transactions []models.Transaction
+	err := r.db.Where("status = ? AND created_at < ?", models.TransactionStatusPending, createdBefore).
+		Order("created_at asc").
+		Limit(limit).
+		Find(&transactions).Error
+	return transactions, err
+}
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"github.com/workradar/server/internal/models"
+	"gorm.io/gorm"
+)
+
+type WebhookEventRepository struct {
+	db *gorm.DB
+}
+
+func NewWebhookEventRepository(db *gorm.DB) *WebhookEventRepository {
+	return &WebhookEventRepository{db: db}
+}
+
+// Create stores a new webhook event
+func (r *WebhookEventRepository) Create(event *models.WebhookEvent) error {
+	return r.db.Create(event).Error
+}
+
+// Update saves all fields of a webhook event
+func (r *WebhookEventRepository) Update(event *models.WebhookEvent) error {
+	return r.db.Save(event).Error
+}
+
+// FindByID retrieves a webhook event by ID
+func (r *WebhookEventRepository) FindByID(id string) (*models.WebhookEvent, error) {
+	var event models.WebhookEvent
+	if err := r.db.Where("id = ?", id).First(&event).Error; err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// FindByNotificationID retrieves a webhook event by its dedupe key
+func (r *WebhookEventRepository) FindByNotificationID(provider, notificationID string) (*models.WebhookEvent, error) {
+	var event models.WebhookEvent
+	if err := r.db.Where("provider = ? AND notification_id = ?", provider, notificationID).First(&event).Error; err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// List retrieves webhook events, newest first, optionally filtered by status and order ID
+func (r *WebhookEventRepository) List(status, orderID string, limit, offset int) ([]models.WebhookEvent, int64, error) {
+	var events []models.WebhookEvent
+	var total int64
+
+	query := r.db.Model(&models.WebhookEvent{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if orderID != "" {
+		query = query.Where("order_id = ?", orderID)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// Raw bodies are only returned by FindByID
+	err := query.Omit("raw_body").Order("created_at desc").Limit(limit).Offset(offset).Find(&events).Error
+	return events, total, err
+}
@@ -0,0 +1,207 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/workradar/server/internal/models"
+)
+
+const (
+	// messageTokenOverhead approximates the role/formatting tokens added per chat message
+	messageTokenOverhead = 4
+	// taskPromptShare is the part of the prompt budget reserved for the task list
+	taskPromptShare = 30
+	// summaryKeepRecent messages always stay verbatim; older ones get summarized
+	summaryKeepRecent = 6
+	// summaryTriggerMessages unsummarized messages start a new summary even under budget
+	summaryTriggerMessages = 24
+	maxSummaryTokens       = 400
+)
+
+// EstimateTokens approximates the token count of text. Llama-family tokenizers average
+// roughly four characters per token for Indonesian and English prose; this errs on the
+// high side so prompts stay under the real limit.
+func EstimateTokens(text string) int {
+	if text == "" {
+		return 0
+	}
+	return (utf8.RuneCountInString(text) + 3) / 4
+}
+
+// estimateMessageTokens approximates the tokens a chat message costs in a prompt
+func estimateMessageTokens(msg ChatMessage) int {
+	tokens := EstimateTokens(msg.Content) + messageTokenOverhead
+	for _, call := range msg.ToolCalls {
+		tokens += EstimateTokens(call.Function.Name) + EstimateTokens(call.Function.Arguments)
+	}
+	return tokens
+}
+
+// RankTasksForPrompt orders pending tasks by how useful they are as chat context: tasks
+// mentioned in the user's message first, then by urgency (overdue, due soon, priority,
+// harder work).
+// Completed tasks are dropped.
+func RankTasksForPrompt(tasks []models.Task, userMessage string, now time.Time) []models.Task {
+	keywords := promptKeywords(userMessage)
+
+	type scored struct {
+		task  models.Task
+		score float64
+	}
+	var ranked []scored
+	for _, t := range tasks {
+		if t.IsCompleted {
+			continue
+		}
+		ranked = append(ranked, scored{task: t, score: taskRelevance(t, keywords) + taskUrgency(t, now)})
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		if ranked[i].score != ranked[j].score {
+			return ranked[i].score > ranked[j].score
+		}
+		// Earlier deadline first among equals; tasks without deadline last
+		di, dj := ranked[i].task.Deadline, ranked[j].task.Deadline
+		if di == nil || dj == nil {
+			return di != nil
+		}
+		return di.Before(*dj)
+	})
+
+	result := make([]models.Task, len(ranked))
+	for i, r := range ranked {
+		result[i] = r.task
+	}
+	return result
+}
+
+// taskRelevance scores how strongly a task matches the keywords of the user's message
+func taskRelevance(t models.Task, keywords map[string]bool) float64 {
+	if len(keywords) == 0 {
+		return 0
+	}
+
+	text := t.Title
+	if t.Description != nil {
+		text += " " + *t.Description
+	}
+	if t.Category != nil {
+		text += " " + t.Category.Name
+	}
+
+	matches := 0
+	for word := range promptKeywords(text) {
+		if keywords[word] {
+			matches++
+		}
+	}
+	return float64(matches) * 10
+}
+
+// taskUrgency scores deadline pressure, priority and effort
+func taskUrgency(t models.Task, now time.Time) float64 {
+	score := priorityWeight(t.Priority)
+
+	if t.Deadline != nil {
+		until := t.Deadline.Sub(now)
+		switch {
+		case until < 0:
+			score += 8 // Overdue
+		case until <= 24*time.Hour:
+			score += 7
+		case until <= 3*24*time.Hour:
+			score += 5
+		case until <= 7*24*time.Hour:
+			score += 3
+		default:
+			score += 1
+		}
+	}
+
+	if t.Difficulty != nil && *t.Difficulty == "focus" {
+		score += 1
+	}
+	return score
+}
+
+// promptKeywords extracts lowercase words worth matching (length >= 4 skips most stop words)
+func promptKeywords(text string) map[string]bool {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	keywords := make(map[string]bool, len(words))
+	for _, w := range words {
+		if utf8.RuneCountInString(w) >= 4 {
+			keywords[w] = true
+		}
+	}
+	return keywords
+}
+
+// formatPromptTask renders one task line for the system prompt; times are shown in now's timezone
+func formatPromptTask(t models.Task, now time.Time) string {
+	line := fmt.Sprintf("- %s [id: %s", t.Title, t.ID)
+	if t.Deadline != nil {
+		line += ", deadline: " + t.Deadline.In(now.Location()).Format("02 Jan 15:04")
+		if t.Deadline.Before(now) {
+			line += " (TERLAMBAT)"
+		}
+	}
+	if t.DurationMinutes != nil {
+		line += fmt.Sprintf(", %d menit", *t.DurationMinutes)
+	}
+	switch t.Priority {
+	case models.PriorityHigh:
+		line += ", prioritas: tinggi"
+	case models.PriorityLow:
+		line += ", prioritas: rendah"
+	}
+	return line + "]\n"
+}
+
+// fitHistory keeps the newest messages that fit in budget, in chronological order
+func fitHistory(history []ChatMessage, budget int) []ChatMessage {
+	used := 0
+	start := len(history)
+	for i := len(history) - 1; i >= 0; i-- {
+		cost := estimateMessageTokens(history[i])
+		if used+cost > budget {
+			break
+		}
+		used += cost
+		start = i
+	}
+	return history[start:]
+}
+
+// fallbackSummary condenses messages without an LLM: the previous summary plus the
+// topics the user raised, trimmed to maxSummaryTokens from the oldest side
+func fallbackSummary(previous string, messages []models.ChatMessage) string {
+	var lines []string
+	if previous != "" {
+		lines = append(lines, previous)
+	}
+	for _, m := range messages {
+		if m.Role != models.ChatRoleUser {
+			continue
+		}
+		topic := strings.Join(strings.Fields(m.Content), " ")
+		if utf8.RuneCountInString(topic) > 100 {
+			topic = string([]rune(topic)[:100]) + "…"
+		}
+		lines = append(lines, "- User bertanya: "+topic)
+	}
+
+	summary := strings.Join(lines, "\n")
+	for EstimateTokens(summary) > maxSummaryTokens && len(lines) > 1 {
+		lines = lines[1:]
+		summary = strings.Join(lines, "\n")
+	}
+	return summary
+}
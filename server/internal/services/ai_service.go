@@ -1,13 +1,12 @@
 package services
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
-	"io"
 	"log"
-	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/workradar/server/internal/models"
@@ -15,42 +14,83 @@ import (
 )
 
 type AIService struct {
-	chatRepo *repository.ChatRepository
-	taskRepo *repository.TaskRepository
-	userRepo *repository.UserRepository
-	apiKey   string
-	model    string
-	baseURL  string
+	chatRepo      *repository.ChatRepository
+	taskRepo      *repository.TaskRepository
+	userRepo      *repository.UserRepository
+	conversations *ChatConversationService
+	toolExecutor  *AIToolExecutor
+	usage         *AIUsageService
+	llm           *LLMClient
+	// contextTokens is the prompt budget (system prompt + summary + history + message)
+	contextTokens int
+	// summarizing holds the IDs of conversations with a summary in progress
+	summarizing sync.Map
 }
 
-func NewAIService(chatRepo *repository.ChatRepository, taskRepo *repository.TaskRepository, userRepo *repository.UserRepository, apiKey string) *AIService {
+func NewAIService(chatRepo *repository.ChatRepository, taskRepo *repository.TaskRepository, userRepo *repository.UserRepository, conversations *ChatConversationService, toolExecutor *AIToolExecutor, usage *AIUsageService, llm *LLMClient, contextTokens int) *AIService {
 	return &AIService{
-		chatRepo: chatRepo,
-		taskRepo: taskRepo,
-		userRepo: userRepo,
-		apiKey:   apiKey,
-		model:    "llama-3.3-70b-versatile", // Groq's fastest model
-		baseURL:  "https://api.groq.com/openai/v1",
+		chatRepo:      chatRepo,
+		taskRepo:      taskRepo,
+		userRepo:      userRepo,
+		conversations: conversations,
+		toolExecutor:  toolExecutor,
+		usage:         usage,
+		llm:           llm,
+		contextTokens: contextTokens,
 	}
 }
 
-// OpenAI-compatible API structures (for Groq)
+// ChatOptions are per-request chat settings
+type ChatOptions struct {
+	// ConversationID selects the thread; empty continues the most recently active one
+	ConversationID string
+	// Model selects a specific model; empty uses each provider's default
+	Model string
+	// ConfirmActions queues mutating tool calls for user confirmation instead of running them
+	ConfirmActions bool
+}
+
+// ErrAIModelNotSupported is returned when no configured provider offers the requested model
+var ErrAIModelNotSupported = errors.New("model tidak tersedia")
+
+// OpenAI-compatible API structures (Groq, Ollama, ...)
 type ChatRequest struct {
-	Model       string        `json:"model"`
-	Messages    []ChatMessage `json:"messages"`
-	Temperature float64       `json:"temperature"`
-	MaxTokens   int           `json:"max_tokens,omitempty"`
+	Model         string             `json:"model"`
+	Messages      []ChatMessage      `json:"messages"`
+	Temperature   float64            `json:"temperature"`
+	MaxTokens     int                `json:"max_tokens,omitempty"`
+	Stream        bool               `json:"stream,omitempty"`
+	StreamOptions *ChatStreamOptions `json:"stream_options,omitempty"`
+	Tools         []ChatTool         `json:"tools,omitempty"`
+	ToolChoice    string             `json:"tool_choice,omitempty"`
+}
+
+// ChatStreamOptions asks OpenAI-compatible providers to report usage in the final chunk
+type ChatStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
 }
 
 type ChatMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role       string     `json:"role"`
+	Content    string     `json:"content"`
+	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string     `json:"tool_call_id,omitempty"`
+}
+
+// ChatUsage is the token usage reported by the provider
+type ChatUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
 }
 
 type ChatResponse struct {
+	Model   string     `json:"model"`
+	Usage   *ChatUsage `json:"usage,omitempty"`
 	Choices []struct {
 		Message struct {
-			Content string `json:"content"`
+			Content   string     `json:"content"`
+			ToolCalls []ToolCall `json:"tool_calls,omitempty"`
 		} `json:"message"`
 	} `json:"choices"`
 	Error *struct {
@@ -59,40 +99,190 @@ type ChatResponse struct {
 	} `json:"error,omitempty"`
 }
 
-func (s *AIService) GenerateResponse(userID, userMessage string) (string, error) {
+// chatStreamChunk is a single server-sent event of an OpenAI-compatible streamed completion
+type chatStreamChunk struct {
+	Model string     `json:"model"`
+	Usage *ChatUsage `json:"usage,omitempty"` // Final chunk (OpenAI stream_options)
+	XGroq *struct {
+		Usage *ChatUsage `json:"usage,omitempty"` // Final chunk (Groq)
+	} `json:"x_groq,omitempty"`
+	Choices []struct {
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+		FinishReason *string `json:"finish_reason"`
+	} `json:"choices"`
+	Error *struct {
+		Message string `json:"message"`
+		Type    string `json:"type"`
+	} `json:"error,omitempty"`
+}
+
+// ErrAIStreamAborted is returned when the client went away while a response was streaming
+var ErrAIStreamAborted = errors.New("AI stream aborted by client")
+
+// maxToolRounds bounds how many times the model may call tools before it must answer
+const maxToolRounds = 4
+
+// AIChatResult is the assistant reply together with the actions it took (or queued)
+type AIChatResult struct {
+	ConversationID string            `json:"conversation_id"`
+	Response       string            `json:"response"`
+	Actions        []models.AIAction `json:"actions"`
+	Quota          *AIQuota          `json:"quota,omitempty"`
+}
+
+// GenerateResponse answers a chat message. The model may call tools to manage the user's
+// tasks and leaves; with opts.ConfirmActions set, mutating tool calls are queued as pending
+// actions that the user confirms or rejects via the actions endpoints.
+func (s *AIService) GenerateResponse(userID, userMessage string, opts ChatOptions) (*AIChatResult, error) {
 	log.Printf("🤖 AI Chat: Starting response generation for user %s", userID)
 
-	if s.apiKey == "" {
-		log.Println("⚠️ AI Chat: API key is empty!")
-		return "Maaf, AI assistant belum dikonfigurasi. Silakan hubungi admin.", nil
+	if !s.llm.SupportsModel(opts.Model) {
+		return nil, ErrAIModelNotSupported
 	}
 
-	// 1. Build system prompt
+	conversation, err := s.conversations.ResolveForChat(userID, opts.ConversationID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &AIChatResult{ConversationID: conversation.ID, Actions: []models.AIAction{}}
+
+	if quota, err := s.CheckQuota(userID); err != nil {
+		log.Printf("⚠️ AI Chat: Quota exceeded for user %s", userID)
+		result.Quota = quota
+		return result, err
+	}
+
+	if !s.llm.Enabled() {
+		log.Println("⚠️ AI Chat: No AI provider configured!")
+		result.Response = "Maaf, AI assistant belum dikonfigurasi. Silakan hubungi admin."
+		s.saveConversation(userID, conversation, userMessage, result.Response)
+		return result, nil
+	}
+
+	// 1-3. Build messages array (OpenAI format)
+	messages := s.buildMessages(userID, conversation, userMessage, s.toolExecutor != nil)
+
+	var tools []ChatTool
+	if s.toolExecutor != nil {
+		tools = s.toolExecutor.Definitions()
+	}
+
+	for round := 0; ; round++ {
+		// 4. Create request; on the last round tools are withheld so the model has to answer
+		reqBody := ChatRequest{
+			Model:       opts.Model,
+			Messages:    messages,
+			Temperature: 0.7,
+			MaxTokens:   1024,
+		}
+		if len(tools) > 0 && round < maxToolRounds {
+			reqBody.Tools = tools
+			reqBody.ToolChoice = "auto"
+		}
+
+		// 5. Hold the tokens of this round, then call the provider chain
+		reservation, quota, err := s.reserve(userID, models.AIUsageKindChat, reqBody)
+		if err != nil {
+			log.Printf("⚠️ AI Chat: Quota exceeded for user %s in round %d", userID, round)
+			result.Quota = quota
+			if round == 0 {
+				return result, err
+			}
+			// Tools already ran: keep their actions and end the turn
+			result.Response = "Maaf, kuota AI kamu habis sebelum jawaban selesai."
+			s.saveConversation(userID, conversation, userMessage, result.Response)
+			return result, nil
+		}
+		chatResp, err := s.llm.Complete(context.Background(), reqBody)
+		if err != nil {
+			s.releaseReservation(reservation)
+			// Every provider failed: answer with canned tips instead of an error
+			log.Printf("⚠️ AI Chat: %v, returning fallback response", err)
+			result.Response = s.getFallbackResponse(userMessage)
+			s.saveConversation(userID, conversation, userMessage, result.Response)
+			return result, nil
+		}
+		s.settleCompletion(reservation, userID, models.AIUsageKindChat, reqBody, chatResp)
+
+		// Extract response text
+		if len(chatResp.Choices) == 0 {
+			log.Println("⚠️ AI Chat: Empty response from AI provider")
+			result.Response = "Maaf, saya tidak bisa memberikan jawaban saat ini."
+			s.saveConversation(userID, conversation, userMessage, result.Response)
+			return result, nil
+		}
+
+		reply := chatResp.Choices[0].Message
+		if len(reply.ToolCalls) == 0 {
+			result.Response = reply.Content
+			break
+		}
+
+		// Run the requested tools and feed their results back to the model
+		log.Printf("🤖 AI Chat: Model requested %d tool call(s)", len(reply.ToolCalls))
+		messages = append(messages, ChatMessage{
+			Role:      "assistant",
+			Content:   reply.Content,
+			ToolCalls: reply.ToolCalls,
+		})
+		for _, call := range reply.ToolCalls {
+			action, content := s.toolExecutor.Handle(userID, call, opts.ConfirmActions)
+			if action != nil {
+				result.Actions = append(result.Actions, *action)
+			}
+			messages = append(messages, ChatMessage{
+				Role:       "tool",
+				Content:    content,
+				ToolCallID: call.ID,
+			})
+		}
+	}
+
+	log.Printf("✅ AI Chat: Got response (%d chars, %d actions)", len(result.Response), len(result.Actions))
+
+	// 6. Save conversation to DB
+	s.saveConversation(userID, conversation, userMessage, result.Response)
+	result.Quota, _ = s.GetQuota(userID)
+
+	log.Println("✅ AI Chat: Response generation completed successfully")
+	return result, nil
+}
+
+// buildMessages assembles the system prompt, the thread summary, as much recent history as
+// fits the token budget and the new user message. withTools tells the model whether tool
+// calls are offered in this request.
+func (s *AIService) buildMessages(userID string, conversation *models.ChatConversation, userMessage string, withTools bool) []ChatMessage {
+	userMsg := ChatMessage{Role: "user", Content: userMessage}
+	remaining := s.contextTokens - estimateMessageTokens(userMsg)
+
+	// 1. Build system prompt; the task list may use a fixed share of the budget
 	log.Printf("🤖 AI Chat: Building system prompt for user %s", userID)
-	systemPrompt, err := s.buildSystemPrompt(userID)
+	systemPrompt, err := s.buildSystemPrompt(userID, userMessage, remaining*taskPromptShare/100, withTools)
 	if err != nil {
 		log.Printf("⚠️ AI Chat: Error building system prompt: %v", err)
 		systemPrompt = "Anda adalah asisten produktivitas Workradar."
 	}
+	if pinned := s.conversations.PinnedContext(conversation); pinned != "" {
+		systemPrompt += "\n" + pinned
+	}
+	if conversation.Summary != "" {
+		systemPrompt += "\nRingkasan percakapan sebelumnya:\n" + conversation.Summary + "\n"
+	}
+
+	systemMsg := ChatMessage{Role: "system", Content: systemPrompt}
+	remaining -= estimateMessageTokens(systemMsg)
 
-	// 2. Load chat history
+	// 2. Load the messages not covered by the summary
 	log.Println("🤖 AI Chat: Loading chat history from DB")
-	history, err := s.chatRepo.FindByUserID(userID, 10)
+	history, err := s.chatRepo.FindByConversationAfter(conversation.ID, conversation.SummarizedUntil, 50)
 	if err != nil {
 		log.Printf("⚠️ AI Chat: Error loading chat history: %v", err)
 	}
-	log.Printf("🤖 AI Chat: Loaded %d messages from history", len(history))
-
-	// 3. Build messages array (OpenAI format)
-	var messages []ChatMessage
-
-	// Add system message
-	messages = append(messages, ChatMessage{
-		Role:    "system",
-		Content: systemPrompt,
-	})
 
-	// Add chat history
+	var historyMsgs []ChatMessage
 	for _, msg := range history {
 		role := string(msg.Role)
 		if role == "assistant" || role == "model" {
@@ -100,138 +290,269 @@ func (s *AIService) GenerateResponse(userID, userMessage string) (string, error)
 		} else {
 			role = "user"
 		}
-		messages = append(messages, ChatMessage{
+		historyMsgs = append(historyMsgs, ChatMessage{
 			Role:    role,
 			Content: msg.Content,
 		})
 	}
+	historyMsgs = fitHistory(historyMsgs, remaining)
+	log.Printf("🤖 AI Chat: Using %d of %d history messages", len(historyMsgs), len(history))
+
+	// 3. Build messages array (OpenAI format)
+	messages := make([]ChatMessage, 0, len(historyMsgs)+2)
+	messages = append(messages, systemMsg)
+	messages = append(messages, historyMsgs...)
+	messages = append(messages, userMsg)
+
+	return messages
+}
 
-	// Add current user message
-	messages = append(messages, ChatMessage{
-		Role:    "user",
-		Content: userMessage,
+// saveConversation persists the user message and the assistant reply to the thread
+func (s *AIService) saveConversation(userID string, conversation *models.ChatConversation, userMessage, aiResponse string) {
+	log.Println("🤖 AI Chat: Saving conversation to DB")
+	s.chatRepo.Create(&models.ChatMessage{
+		UserID:         userID,
+		ConversationID: &conversation.ID,
+		Role:           models.ChatRoleUser,
+		Content:        userMessage,
 	})
 
-	// 4. Create request
-	reqBody := ChatRequest{
-		Model:       s.model,
-		Messages:    messages,
-		Temperature: 0.7,
-		MaxTokens:   1024,
+	s.chatRepo.Create(&models.ChatMessage{
+		UserID:         userID,
+		ConversationID: &conversation.ID,
+		Role:           models.ChatRoleModel,
+		Content:        aiResponse,
+	})
+
+	s.conversations.RecordMessage(conversation, userMessage)
+
+	go s.summarizeIfNeeded(conversation.ID)
+}
+
+// summarizeIfNeeded folds older messages of a thread into its rolling summary once the
+// unsummarized part outgrows the history budget. The newest messages always stay verbatim.
+func (s *AIService) summarizeIfNeeded(conversationID string) {
+	if _, running := s.summarizing.LoadOrStore(conversationID, true); running {
+		return
 	}
+	defer s.summarizing.Delete(conversationID)
 
-	jsonData, err := json.Marshal(reqBody)
+	conversation, err := s.conversations.conversationRepo.FindByID(conversationID)
 	if err != nil {
-		log.Printf("❌ AI Chat: Failed to marshal request: %v", err)
-		return "", fmt.Errorf("gagal memproses request: %v", err)
+		return
+	}
+
+	messages, err := s.chatRepo.FindByConversationAfter(conversationID, conversation.SummarizedUntil, 200)
+	if err != nil || len(messages) <= summaryKeepRecent {
+		return
 	}
 
-	// 5. Call Groq API
-	url := fmt.Sprintf("%s/chat/completions", s.baseURL)
-	log.Printf("🤖 AI Chat: Calling Groq API with model %s", s.model)
+	tokens := 0
+	for _, m := range messages {
+		tokens += EstimateTokens(m.Content) + messageTokenOverhead
+	}
+	if tokens <= s.contextTokens/2 && len(messages) < summaryTriggerMessages {
+		return
+	}
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	older := messages[:len(messages)-summaryKeepRecent]
+	summary, err := s.summarizeMessages(conversation.UserID, conversation.Summary, older)
 	if err != nil {
-		log.Printf("❌ AI Chat: Failed to create request: %v", err)
-		return "", fmt.Errorf("gagal membuat request: %v", err)
+		log.Printf("⚠️ AI Summary: %v, using fallback summary for %s", err, conversationID)
+		summary = fallbackSummary(conversation.Summary, older)
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", s.apiKey))
+	until := older[len(older)-1].CreatedAt
+	if err := s.conversations.SaveSummary(conversationID, summary, until); err != nil {
+		log.Printf("⚠️ AI Summary: Failed to save summary for %s: %v", conversationID, err)
+		return
+	}
+	log.Printf("✅ AI Summary: Summarized %d messages of conversation %s", len(older), conversationID)
+}
+
+// summarizeMessages asks the model to merge messages into the previous summary
+func (s *AIService) summarizeMessages(userID, previous string, messages []models.ChatMessage) (string, error) {
+	if !s.llm.Enabled() {
+		return "", ErrLLMUnavailable
+	}
+
+	var transcript strings.Builder
+	if previous != "" {
+		transcript.WriteString("Ringkasan sebelumnya:\n" + previous + "\n\n")
+	}
+	transcript.WriteString("Percakapan lanjutan:\n")
+	for _, m := range messages {
+		speaker := "User"
+		if m.Role == models.ChatRoleModel {
+			speaker = "Asisten"
+		}
+		transcript.WriteString(speaker + ": " + m.Content + "\n")
+	}
 
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(req)
+	req := ChatRequest{
+		Messages: []ChatMessage{
+			{
+				Role: "system",
+				Content: "Ringkas percakapan antara user dan asisten produktivitas berikut dalam Bahasa Indonesia, " +
+					"maksimal 150 kata. Pertahankan fakta penting: tugas, tanggal, keputusan, dan preferensi user.",
+			},
+			{Role: "user", Content: transcript.String()},
+		},
+		Temperature: 0.2,
+		MaxTokens:   maxSummaryTokens,
+	}
+	resp, err := s.llm.Complete(context.Background(), req)
 	if err != nil {
-		log.Printf("❌ AI Chat: HTTP request failed: %v", err)
-		return "", fmt.Errorf("gagal menghubungi AI: %v", err)
+		return "", err
 	}
-	defer resp.Body.Close()
+	// Summaries keep the thread usable and are not held back by the quota
+	s.settleCompletion(nil, userID, models.AIUsageKindSummary, req, resp)
+	if len(resp.Choices) == 0 || strings.TrimSpace(resp.Choices[0].Message.Content) == "" {
+		return "", errors.New("empty summary")
+	}
+	return strings.TrimSpace(resp.Choices[0].Message.Content), nil
+}
 
-	body, err := io.ReadAll(resp.Body)
+// StreamResponse streams the assistant reply as it is generated. onDelta is called for every
+// content fragment; if it returns an error (e.g. the client disconnected) the upstream request
+// is aborted and ErrAIStreamAborted is returned. Like GenerateResponse, setup and fallback
+// replies are persisted too; after a disconnect the part streamed so far is kept so the
+// user's message does not vanish from the history. Provider errors persist nothing.
+//
+// Streaming is text-only: no tools are offered, so the model cannot create, reschedule or
+// complete tasks here and the system prompt tells it so. Clients that need task actions use
+// GenerateResponse.
+func (s *AIService) StreamResponse(ctx context.Context, userID, userMessage string, opts ChatOptions, onDelta func(string) error) (string, error) {
+	log.Printf("🤖 AI Stream: Starting streamed response for user %s", userID)
+
+	if !s.llm.SupportsModel(opts.Model) {
+		return "", ErrAIModelNotSupported
+	}
+
+	conversation, err := s.conversations.ResolveForChat(userID, opts.ConversationID)
 	if err != nil {
-		log.Printf("❌ AI Chat: Failed to read response: %v", err)
-		return "", fmt.Errorf("gagal membaca respons AI: %v", err)
+		return "", err
 	}
 
-	var chatResp ChatResponse
-	if err := json.Unmarshal(body, &chatResp); err != nil {
-		log.Printf("❌ AI Chat: Failed to parse response: %v", err)
-		return "", fmt.Errorf("gagal memproses respons AI: %v", err)
+	if _, err := s.CheckQuota(userID); err != nil {
+		log.Printf("⚠️ AI Stream: Quota exceeded for user %s", userID)
+		return "", err
+	}
+
+	if !s.llm.Enabled() {
+		log.Println("⚠️ AI Stream: No AI provider configured!")
+		return s.streamReply(userID, conversation, userMessage, "Maaf, AI assistant belum dikonfigurasi. Silakan hubungi admin.", onDelta)
 	}
 
-	// Check for API error
-	if chatResp.Error != nil {
-		log.Printf("❌ AI Chat: Groq API error: %s (type: %s)", chatResp.Error.Message, chatResp.Error.Type)
-		
-		// Handle rate limit error with friendly message
-		if strings.Contains(strings.ToLower(chatResp.Error.Type), "rate_limit") || strings.Contains(strings.ToLower(chatResp.Error.Message), "rate limit") {
-			log.Println("⚠️ AI Chat: Rate limit exceeded, returning fallback response")
-			return s.getFallbackResponse(userMessage), nil
+	req := ChatRequest{
+		Model:       opts.Model,
+		Messages:    s.buildMessages(userID, conversation, userMessage, false),
+		Temperature: 0.7,
+		MaxTokens:   1024,
+	}
+	reservation, _, err := s.reserve(userID, models.AIUsageKindStream, req)
+	if err != nil {
+		log.Printf("⚠️ AI Stream: Quota exceeded for user %s", userID)
+		return "", err
+	}
+	stream, err := s.llm.Stream(ctx, req, onDelta)
+	aiResponse := stream.Content
+	if errors.Is(err, ErrLLMUnavailable) {
+		s.releaseReservation(reservation)
+	} else {
+		// Tokens are consumed even when the client disconnects mid-stream
+		s.settleStream(reservation, userID, req, stream)
+	}
+	if err != nil {
+		if errors.Is(err, ErrAIStreamAborted) {
+			log.Printf("⚠️ AI Stream: Client disconnected for user %s after %d chars", userID, len(aiResponse))
+			if aiResponse != "" {
+				s.saveConversation(userID, conversation, userMessage, aiResponse)
+			}
+			return aiResponse, err
+		}
+		if errors.Is(err, ErrLLMUnavailable) {
+			// Every provider failed before sending anything: stream the canned tips instead
+			log.Printf("⚠️ AI Stream: %v, returning fallback response", err)
+			return s.streamReply(userID, conversation, userMessage, s.getFallbackResponse(userMessage), onDelta)
 		}
-		
-		return "", fmt.Errorf("AI error: %s", chatResp.Error.Message)
+		log.Printf("❌ AI Stream: %v", err)
+		return aiResponse, fmt.Errorf("koneksi ke AI terputus: %v", err)
 	}
 
-	// Extract response text
-	if len(chatResp.Choices) == 0 {
-		log.Println("⚠️ AI Chat: Empty response from Groq")
-		return "Maaf, saya tidak bisa memberikan jawaban saat ini.", nil
+	if aiResponse == "" {
+		return s.streamReply(userID, conversation, userMessage, "Maaf, saya tidak bisa memberikan jawaban saat ini.", onDelta)
 	}
 
-	aiResponse := chatResp.Choices[0].Message.Content
-	log.Printf("✅ AI Chat: Got response from Groq (%d chars)", len(aiResponse))
+	s.saveConversation(userID, conversation, userMessage, aiResponse)
 
-	// 6. Save conversation to DB
-	log.Println("🤖 AI Chat: Saving conversation to DB")
-	s.chatRepo.Create(&models.ChatMessage{
-		UserID:  userID,
-		Role:    models.ChatRoleUser,
-		Content: userMessage,
-	})
+	log.Printf("✅ AI Stream: Completed for user %s (%d chars)", userID, len(aiResponse))
+	return aiResponse, nil
+}
 
-	s.chatRepo.Create(&models.ChatMessage{
-		UserID:  userID,
-		Role:    models.ChatRoleModel,
-		Content: aiResponse,
-	})
+// streamReply sends a reply that did not come from the model as a single delta. It is saved
+// before sending, so it is kept even if the client has gone away.
+func (s *AIService) streamReply(userID string, conversation *models.ChatConversation, userMessage, reply string, onDelta func(string) error) (string, error) {
+	s.saveConversation(userID, conversation, userMessage, reply)
+	if err := onDelta(reply); err != nil {
+		return reply, ErrAIStreamAborted
+	}
+	return reply, nil
+}
 
-	log.Println("✅ AI Chat: Response generation completed successfully")
-	return aiResponse, nil
+// GetModels lists the models a chat request may select
+func (s *AIService) GetModels() []LLMProviderModels {
+	return s.llm.Models()
 }
 
-func (s *AIService) buildSystemPrompt(userID string) (string, error) {
+// SupportsModel reports whether a chat request may select model
+func (s *AIService) SupportsModel(model string) bool {
+	return s.llm.SupportsModel(model)
+}
+
+// buildSystemPrompt describes the user and their most relevant pending tasks; the task
+// list is cut off at taskBudget tokens
+func (s *AIService) buildSystemPrompt(userID, userMessage string, taskBudget int, withTools bool) (string, error) {
 	user, err := s.userRepo.FindByID(userID)
 	if err != nil {
 		return "", err
 	}
 
 	tasks, _ := s.taskRepo.FindByUserID(userID)
+	now := time.Now().In(user.Location())
 
-	pendingTasks := 0
 	completedTasks := 0
-	var upcomingDeadlines []string
-
+	overdueTasks := 0
 	for _, t := range tasks {
 		if t.IsCompleted {
 			completedTasks++
-		} else {
-			pendingTasks++
-			if t.Deadline != nil && t.Deadline.After(time.Now()) {
-				deadlineStr := t.Deadline.Format("02 Jan 15:04")
-				upcomingDeadlines = append(upcomingDeadlines, fmt.Sprintf("- %s (%s)", t.Title, deadlineStr))
-			}
+		} else if t.Deadline != nil && t.Deadline.Before(now) {
+			overdueTasks++
 		}
 	}
+	ranked := RankTasksForPrompt(tasks, userMessage, now)
 
 	var sb strings.Builder
 	sb.WriteString("Anda adalah asisten produktivitas cerdas untuk aplikasi Workradar.\n")
 	sb.WriteString(fmt.Sprintf("Nama User: %s\n", user.Username))
-	sb.WriteString(fmt.Sprintf("Statistik Tugas:\n- Pending: %d\n- Selesai: %d\n", pendingTasks, completedTasks))
-
-	if len(upcomingDeadlines) > 0 {
-		sb.WriteString("Tugas Mendatang:\n")
-		for _, d := range upcomingDeadlines {
-			sb.WriteString(d + "\n")
+	sb.WriteString(fmt.Sprintf("Waktu Sekarang: %s\n", now.Format("Monday, 2006-01-02 15:04")))
+	sb.WriteString(fmt.Sprintf("Statistik Tugas:\n- Pending: %d\n- Terlambat: %d\n- Selesai: %d\n", len(ranked), overdueTasks, completedTasks))
+
+	if len(ranked) > 0 {
+		sb.WriteString("Tugas Paling Relevan/Mendesak:\n")
+		used := 0
+		listed := 0
+		for _, t := range ranked {
+			line := formatPromptTask(t, now)
+			if used+EstimateTokens(line) > taskBudget {
+				break
+			}
+			sb.WriteString(line)
+			used += EstimateTokens(line)
+			listed++
+		}
+		if listed < len(ranked) {
+			sb.WriteString(fmt.Sprintf("(dan %d tugas pending lainnya)\n", len(ranked)-listed))
 		}
 	}
 
@@ -240,16 +561,59 @@ func (s *AIService) buildSystemPrompt(userID string) (string, error) {
 	sb.WriteString("2. Usahakan jawaban singkat dan padat.\n")
 	sb.WriteString("3. Fokus pada produktivitas dan psikologi kerja.\n")
 	sb.WriteString("4. Jika user bertanya tentang tugas mereka, gunakan data statistik di atas.\n")
+	if withTools {
+		sb.WriteString("5. Gunakan tools yang tersedia jika user meminta membuat, mengubah jadwal, menyelesaikan, atau melihat tugas, maupun mengajukan cuti. Jangan mengarang ID tugas; ambil dari daftar di atas atau gunakan list_tasks.\n")
+		sb.WriteString("6. Jika hasil tool berstatus pending_confirmation, beri tahu user bahwa aksi menunggu konfirmasi.\n")
+	} else {
+		sb.WriteString("5. Kamu tidak bisa membuat, mengubah, atau menyelesaikan tugas di percakapan ini. Jangan mengaku sudah melakukannya; sarankan user mengubahnya sendiri di aplikasi.\n")
+	}
 
 	return sb.String(), nil
 }
 
-func (s *AIService) GetChatHistory(userID string) ([]models.ChatMessage, error) {
+// ResolveConversation returns the thread a chat message will be saved to
+func (s *AIService) ResolveConversation(userID, conversationID string) (*models.ChatConversation, error) {
+	return s.conversations.ResolveForChat(userID, conversationID)
+}
+
+// GetChatHistory returns the latest messages of a thread, or across all threads when conversationID is empty
+func (s *AIService) GetChatHistory(userID, conversationID string) ([]models.ChatMessage, error) {
+	if conversationID != "" {
+		return s.conversations.GetMessages(userID, conversationID, 50)
+	}
 	return s.chatRepo.FindByUserID(userID, 20)
 }
 
+// ClearChatHistory deletes all threads and messages of a user
 func (s *AIService) ClearChatHistory(userID string) error {
-	return s.chatRepo.DeleteByUserID(userID)
+	if err := s.chatRepo.DeleteByUserID(userID); err != nil {
+		return err
+	}
+	return s.conversations.ClearAll(userID)
+}
+
+// GetActions returns the actions the assistant took or queued for the user
+func (s *AIService) GetActions(userID, status string) ([]models.AIAction, error) {
+	if s.toolExecutor == nil {
+		return []models.AIAction{}, nil
+	}
+	return s.toolExecutor.ListActions(userID, status, 50)
+}
+
+// ConfirmAction executes an action queued in confirm mode
+func (s *AIService) ConfirmAction(userID, actionID string) (*models.AIAction, error) {
+	if s.toolExecutor == nil {
+		return nil, ErrAIActionNotFound
+	}
+	return s.toolExecutor.Confirm(userID, actionID)
+}
+
+// RejectAction discards an action queued in confirm mode
+func (s *AIService) RejectAction(userID, actionID string) (*models.AIAction, error) {
+	if s.toolExecutor == nil {
+		return nil, ErrAIActionNotFound
+	}
+	return s.toolExecutor.Reject(userID, actionID)
 }
 
 // getFallbackResponse returns a helpful response when API quota is exceeded
@@ -348,3 +712,48 @@ func (s *AIService) getFallbackResponse(userMessage string) string {
 		"Atau tunggu 1-2 menit untuk mendapat respons AI yang lebih detail! 🤖\n\n" +
 		"� *Sementara itu, cek dashboard Workradar untuk melihat statistik tugas kamu.*"
 }
+
+// GetQuota returns the user's remaining AI budget, or nil when usage is not tracked
+func (s *AIService) GetQuota(userID string) (*AIQuota, error) {
+	if s.usage == nil {
+		return nil, nil
+	}
+	return s.usage.GetQuota(userID)
+}
+
+// CheckQuota returns ErrAIQuotaExceeded (with the quota) when the user may not chat
+func (s *AIService) CheckQuota(userID string) (*AIQuota, error) {
+	if s.usage == nil {
+		return nil, nil
+	}
+	return s.usage.CheckQuota(userID)
+}
+
+// reserve holds the tokens of req against the user's budget
+func (s *AIService) reserve(userID string, kind models.AIUsageKind, req ChatRequest) (*AIReservation, *AIQuota, error) {
+	if s.usage == nil {
+		return nil, nil, nil
+	}
+	return s.usage.Reserve(userID, kind, req)
+}
+
+// releaseReservation drops a reservation whose request consumed nothing
+func (s *AIService) releaseReservation(reservation *AIReservation) {
+	if s.usage != nil {
+		s.usage.Release(reservation)
+	}
+}
+
+// settleCompletion stores the tokens of one completion
+func (s *AIService) settleCompletion(reservation *AIReservation, userID string, kind models.AIUsageKind, req ChatRequest, resp *ChatResponse) {
+	if s.usage != nil {
+		s.usage.SettleCompletion(reservation, userID, kind, req, resp)
+	}
+}
+
+// settleStream stores the tokens of a streamed reply
+func (s *AIService) settleStream(reservation *AIReservation, userID string, req ChatRequest, result StreamResult) {
+	if s.usage != nil {
+		s.usage.SettleStream(reservation, userID, req, result)
+	}
+}
@@ -0,0 +1,420 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/workradar/server/internal/models"
+	"github.com/workradar/server/internal/repository"
+)
+
+// Tools the AI assistant can call on behalf of the user
+const (
+	AIToolCreateTask     = "create_task"
+	AIToolRescheduleTask = "reschedule_task"
+	AIToolCompleteTask   = "complete_task"
+	AIToolListTasks      = "list_tasks"
+	AIToolRequestLeave   = "request_leave"
+)
+
+var (
+	ErrAIActionNotFound   = errors.New("AI action not found")
+	ErrAIActionNotPending = errors.New("AI action is not pending confirmation")
+)
+
+// readOnlyAITools never change user data, so they run even in confirm mode
+var readOnlyAITools = map[string]bool{
+	AIToolListTasks: true,
+}
+
+// ChatTool is an OpenAI-compatible function tool definition
+type ChatTool struct {
+	Type     string           `json:"type"`
+	Function ChatToolFunction `json:"function"`
+}
+
+type ChatToolFunction struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	Parameters  map[string]interface{} `json:"parameters"`
+}
+
+// ToolCall is a function call requested by the model
+type ToolCall struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+// AIToolExecutor runs assistant tool calls through the regular services, so ownership
+// checks and validation are the same as for the REST API. Every call is logged as an AIAction.
+type AIToolExecutor struct {
+	actionRepo   *repository.AIActionRepository
+	userRepo     *repository.UserRepository
+	taskService  *TaskService
+	leaveService *LeaveService
+}
+
+func NewAIToolExecutor(actionRepo *repository.AIActionRepository, userRepo *repository.UserRepository, taskService *TaskService, leaveService *LeaveService) *AIToolExecutor {
+	return &AIToolExecutor{
+		actionRepo:   actionRepo,
+		userRepo:     userRepo,
+		taskService:  taskService,
+		leaveService: leaveService,
+	}
+}
+
+// Definitions returns the tool schemas sent to the model
+func (e *AIToolExecutor) Definitions() []ChatTool {
+	dateTimeDesc := "Tanggal/waktu lokal, format YYYY-MM-DD atau YYYY-MM-DDTHH:MM"
+
+	return []ChatTool{
+		aiTool(AIToolCreateTask, "Buat tugas baru untuk user", map[string]interface{}{
+			"title":            map[string]interface{}{"type": "string", "description": "Judul tugas"},
+			"description":      map[string]interface{}{"type": "string", "description": "Deskripsi tugas"},
+			"deadline":         map[string]interface{}{"type": "string", "description": dateTimeDesc},
+			"duration_minutes": map[string]interface{}{"type": "integer", "description": "Perkiraan durasi dalam menit"},
+			"reminder_minutes": map[string]interface{}{"type": "integer", "description": "Pengingat berapa menit sebelum deadline"},
+			"difficulty":       map[string]interface{}{"type": "string", "enum": []string{"relaxed", "normal", "focus"}},
+			"priority":         map[string]interface{}{"type": "string", "enum": []string{"low", "medium", "high"}, "description": "Seberapa penting tugas ini"},
+		}, []string{"title"}),
+		aiTool(AIToolRescheduleTask, "Ubah deadline tugas yang sudah ada", map[string]interface{}{
+			"task_id":  map[string]interface{}{"type": "string", "description": "ID tugas"},
+			"deadline": map[string]interface{}{"type": "string", "description": dateTimeDesc},
+		}, []string{"task_id", "deadline"}),
+		aiTool(AIToolCompleteTask, "Tandai tugas sebagai selesai", map[string]interface{}{
+			"task_id": map[string]interface{}{"type": "string", "description": "ID tugas"},
+		}, []string{"task_id"}),
+		aiTool(AIToolListTasks, "Tampilkan tugas user dengan deadline pada tanggal tertentu", map[string]interface{}{
+			"date": map[string]interface{}{"type": "string", "description": "Tanggal, format YYYY-MM-DD"},
+		}, []string{"date"}),
+		aiTool(AIToolRequestLeave, "Ajukan cuti/izin untuk tanggal tertentu", map[string]interface{}{
+			"date":   map[string]interface{}{"type": "string", "description": "Tanggal cuti, format YYYY-MM-DD"},
+			"reason": map[string]interface{}{"type": "string", "description": "Alasan cuti"},
+		}, []string{"date", "reason"}),
+	}
+}
+
+func aiTool(name, description string, properties map[string]interface{}, required []string) ChatTool {
+	return ChatTool{
+		Type: "function",
+		Function: ChatToolFunction{
+			Name:        name,
+			Description: description,
+			Parameters: map[string]interface{}{
+				"type":       "object",
+				"properties": properties,
+				"required":   required,
+			},
+		},
+	}
+}
+
+// Handle processes a tool call from the model. Mutating tools are stored as pending instead
+// of executed when confirmActions is set. It returns the logged action and the content of the
+// tool message sent back to the model.
+func (e *AIToolExecutor) Handle(userID string, call ToolCall, confirmActions bool) (*models.AIAction, string) {
+	args := map[string]interface{}{}
+	if strings.TrimSpace(call.Function.Arguments) != "" {
+		if err := json.Unmarshal([]byte(call.Function.Arguments), &args); err != nil {
+			return nil, toolMessage(map[string]interface{}{"error": "invalid arguments: " + err.Error()})
+		}
+	}
+
+	action := &models.AIAction{
+		UserID:    userID,
+		Tool:      call.Function.Name,
+		Arguments: args,
+	}
+
+	if confirmActions && !readOnlyAITools[call.Function.Name] {
+		action.Status = models.AIActionStatusPending
+		if err := e.actionRepo.Create(action); err != nil {
+			log.Printf("⚠️ AI Tools: Failed to save pending action: %v", err)
+			return nil, toolMessage(map[string]interface{}{"error": "failed to queue action"})
+		}
+		return action, toolMessage(map[string]interface{}{
+			"status":    "pending_confirmation",
+			"action_id": action.ID,
+			"message":   "Aksi menunggu konfirmasi user sebelum dijalankan",
+		})
+	}
+
+	e.run(action)
+	if err := e.actionRepo.Create(action); err != nil {
+		log.Printf("⚠️ AI Tools: Failed to log action %s: %v", action.Tool, err)
+	}
+
+	if action.Error != nil {
+		return action, toolMessage(map[string]interface{}{"error": *action.Error})
+	}
+	return action, toolMessage(action.Result)
+}
+
+// Confirm executes a pending action. The action is claimed with a conditional update
+// first, so concurrent confirmations cannot run it twice.
+func (e *AIToolExecutor) Confirm(userID, actionID string) (*models.AIAction, error) {
+	if err := e.claimPending(userID, actionID, models.AIActionStatusRunning); err != nil {
+		return nil, err
+	}
+
+	action, err := e.actionRepo.FindByID(actionID)
+	if err != nil {
+		return nil, err
+	}
+
+	e.run(action)
+	if err := e.actionRepo.Update(action); err != nil {
+		return nil, err
+	}
+	return action, nil
+}
+
+// Reject discards a pending action
+func (e *AIToolExecutor) Reject(userID, actionID string) (*models.AIAction, error) {
+	if err := e.claimPending(userID, actionID, models.AIActionStatusRejected); err != nil {
+		return nil, err
+	}
+	return e.actionRepo.FindByID(actionID)
+}
+
+// aiActionRunTimeout is how long a confirmed action may stay running. After it the
+// process that claimed it is assumed dead and the action is marked failed; it is not
+// run again because the tool may already have taken effect.
+const aiActionRunTimeout = 5 * time.Minute
+
+// ListActions returns the user's action log, newest first. Interrupted actions are
+// recovered first so they do not stay running forever.
+func (e *AIToolExecutor) ListActions(userID, status string, limit int) ([]models.AIAction, error) {
+	if n, err := e.actionRepo.FailStaleRunning(userID, time.Now().Add(-aiActionRunTimeout), "interrupted before completion"); err != nil {
+		log.Printf("⚠️ AI Tools: Failed to recover interrupted actions of user %s: %v", userID, err)
+	} else if n > 0 {
+		log.Printf("⚠️ AI Tools: Marked %d interrupted actions of user %s as failed", n, userID)
+	}
+	return e.actionRepo.FindByUserID(userID, status, limit)
+}
+
+// claimPending moves a pending action of the user to status; only one caller can win
+func (e *AIToolExecutor) claimPending(userID, actionID string, status models.AIActionStatus) error {
+	claimed, err := e.actionRepo.ClaimPending(actionID, userID, status)
+	if err != nil {
+		return err
+	}
+	if claimed {
+		return nil
+	}
+
+	action, err := e.actionRepo.FindByID(actionID)
+	if err != nil || action.UserID != userID {
+		return ErrAIActionNotFound
+	}
+	return ErrAIActionNotPending
+}
+
+// run executes the action and records its outcome on it
+func (e *AIToolExecutor) run(action *models.AIAction) {
+	result, err := e.execute(action.UserID, action.Tool, action.Arguments)
+
+	now := time.Now()
+	action.ExecutedAt = &now
+	if err != nil {
+		errMsg := err.Error()
+		action.Status = models.AIActionStatusFailed
+		action.Error = &errMsg
+		log.Printf("⚠️ AI Tools: %s failed for user %s: %v", action.Tool, action.UserID, err)
+		return
+	}
+
+	action.Status = models.AIActionStatusExecuted
+	action.Error = nil
+	action.Result = result
+	log.Printf("✅ AI Tools: %s executed for user %s", action.Tool, action.UserID)
+}
+
+func (e *AIToolExecutor) execute(userID, tool string, args map[string]interface{}) (map[string]interface{}, error) {
+	loc, err := e.userLocation(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch tool {
+	case AIToolCreateTask:
+		data := CreateTaskDTO{
+			Title:           argString(args, "title"),
+			Description:     argOptionalString(args, "description"),
+			DurationMinutes: argOptionalInt(args, "duration_minutes"),
+			ReminderMinutes: argOptionalInt(args, "reminder_minutes"),
+			Difficulty:      argOptionalString(args, "difficulty"),
+			Priority:        argOptionalString(args, "priority"),
+			RepeatType:      models.RepeatNone,
+			RepeatInterval:  1,
+		}
+		if raw := argString(args, "deadline"); raw != "" {
+			deadline, err := parseAIDateTime(raw, loc)
+			if err != nil {
+				return nil, err
+			}
+			data.Deadline = &deadline
+		}
+
+		task, err := e.taskService.CreateTask(userID, data)
+		if err != nil {
+			return nil, err
+		}
+		return taskSummary(task, loc), nil
+
+	case AIToolRescheduleTask:
+		deadline, err := parseAIDateTime(argString(args, "deadline"), loc)
+		if err != nil {
+			return nil, err
+		}
+
+		task, err := e.taskService.UpdateTask(userID, argString(args, "task_id"), UpdateTaskDTO{Deadline: &deadline})
+		if err != nil {
+			return nil, err
+		}
+		return taskSummary(task, loc), nil
+
+	case AIToolCompleteTask:
+		taskID := argString(args, "task_id")
+		task, err := e.taskService.GetTaskByID(userID, taskID)
+		if err != nil {
+			return nil, err
+		}
+		if !task.IsCompleted {
+			// ToggleTaskComplete also schedules the next occurrence of repeating tasks
+			if task, err = e.taskService.ToggleTaskComplete(userID, taskID); err != nil {
+				return nil, err
+			}
+		}
+		return taskSummary(task, loc), nil
+
+	case AIToolListTasks:
+		date, err := parseAIDateTime(argString(args, "date"), loc)
+		if err != nil {
+			return nil, err
+		}
+
+		tasks, err := e.taskService.GetTasksByDate(userID, date)
+		if err != nil {
+			return nil, err
+		}
+
+		items := make([]map[string]interface{}, 0, len(tasks))
+		for i := range tasks {
+			items = append(items, taskSummary(&tasks[i], loc))
+		}
+		return map[string]interface{}{
+			"date":  date.Format("2006-01-02"),
+			"count": len(items),
+			"tasks": items,
+		}, nil
+
+	case AIToolRequestLeave:
+		date, err := parseAIDateTime(argString(args, "date"), loc)
+		if err != nil {
+			return nil, err
+		}
+		reason := argString(args, "reason")
+		if reason == "" {
+			return nil, errors.New("reason is required")
+		}
+
+		leave, err := e.leaveService.CreateLeave(userID, date, reason)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{
+			"leave_id": leave.ID,
+			"date":     date.Format("2006-01-02"),
+			"reason":   reason,
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("unknown tool: %s", tool)
+	}
+}
+
+// aiDateTimeLayouts are the date formats accepted from the model, most specific first
+var aiDateTimeLayouts = []string{
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// userLocation returns the timezone the user's dates are given in
+func (e *AIToolExecutor) userLocation(userID string) (*time.Location, error) {
+	user, err := e.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+	return user.Location(), nil
+}
+
+// parseAIDateTime parses a date/time from the model; values without a zone are in loc,
+// the user's timezone
+func parseAIDateTime(value string, loc *time.Location) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	for _, layout := range aiDateTimeLayouts {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date: %q", value)
+}
+
+// taskSummary describes a task for the model; the deadline is in loc, the user's timezone
+func taskSummary(task *models.Task, loc *time.Location) map[string]interface{} {
+	summary := map[string]interface{}{
+		"task_id":      task.ID,
+		"title":        task.Title,
+		"is_completed": task.IsCompleted,
+		"priority":     task.Priority,
+	}
+	if task.Deadline != nil {
+		summary["deadline"] = task.Deadline.In(loc).Format("2006-01-02T15:04")
+	}
+	if task.DurationMinutes != nil {
+		summary["duration_minutes"] = *task.DurationMinutes
+	}
+	return summary
+}
+
+func toolMessage(content interface{}) string {
+	data, err := json.Marshal(content)
+	if err != nil {
+		return `{"error":"failed to encode result"}`
+	}
+	return string(data)
+}
+
+func argString(args map[string]interface{}, key string) string {
+	value, _ := args[key].(string)
+	return strings.TrimSpace(value)
+}
+
+func argOptionalString(args map[string]interface{}, key string) *string {
+	if value := argString(args, key); value != "" {
+		return &value
+	}
+	return nil
+}
+
+func argOptionalInt(args map[string]interface{}, key string) *int {
+	// JSON numbers decode as float64
+	if value, ok := args[key].(float64); ok && value > 0 {
+		n := int(value)
+		return &n
+	}
+	return nil
+}
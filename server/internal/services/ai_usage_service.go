@@ -0,0 +1,325 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/workradar/server/internal/models"
+	"github.com/workradar/server/internal/repository"
+)
+
+// ErrAIQuotaExceeded is returned when the user's or the global AI token budget is used up
+var ErrAIQuotaExceeded = errors.New("kuota AI sudah habis")
+
+// AIUsageLimits are token budgets; 0 means unlimited
+type AIUsageLimits struct {
+	DailyTokensPerUser   int64
+	MonthlyTokensPerUser int64
+	GlobalMonthlyTokens  int64
+	// Prices in USD per million tokens, used for cost accounting
+	InputPricePerMTok  float64
+	OutputPricePerMTok float64
+}
+
+// AIQuota is a user's remaining AI budget. Remaining values are nil when unlimited.
+type AIQuota struct {
+	DailyUsed        int64     `json:"daily_used"`
+	DailyLimit       int64     `json:"daily_limit"`
+	DailyRemaining   *int64    `json:"daily_remaining"`
+	MonthlyUsed      int64     `json:"monthly_used"`
+	MonthlyLimit     int64     `json:"monthly_limit"`
+	MonthlyRemaining *int64    `json:"monthly_remaining"`
+	ResetsAt         time.Time `json:"resets_at"`
+	// GlobalExhausted is set when the service-wide monthly budget is used up
+	GlobalExhausted bool `json:"global_exhausted,omitempty"`
+}
+
+// Exceeded reports whether no more AI requests are allowed
+func (q *AIQuota) Exceeded() bool {
+	return q.GlobalExhausted ||
+		(q.DailyRemaining != nil && *q.DailyRemaining <= 0) ||
+		(q.MonthlyRemaining != nil && *q.MonthlyRemaining <= 0)
+}
+
+// AIUsageStats is the admin overview of AI consumption
+type AIUsageStats struct {
+	Since    time.Time                  `json:"since"`
+	Totals   *repository.AIUsageTotals  `json:"totals"`
+	ByModel  []repository.AIUsageByKey  `json:"by_model"`
+	ByDay    []repository.AIUsageByKey  `json:"by_day"`
+	TopUsers []repository.AIUsageByUser `json:"top_users"`
+	Global   map[string]interface{}     `json:"global_budget"`
+}
+
+// AIUsageService records token usage and enforces per-user and global budgets
+type AIUsageService struct {
+	repo   *repository.AIUsageRepository
+	limits AIUsageLimits
+}
+
+func NewAIUsageService(repo *repository.AIUsageRepository, limits AIUsageLimits) *AIUsageService {
+	return &AIUsageService{
+		repo:   repo,
+		limits: limits,
+	}
+}
+
+// GetQuota returns the user's current usage and remaining budget
+func (s *AIUsageService) GetQuota(userID string) (*AIQuota, error) {
+	now := time.Now()
+	dayStart := startOfDay(now)
+	monthStart := startOfMonth(now)
+
+	dailyUsed, err := s.repo.SumTokensByUser(userID, dayStart)
+	if err != nil {
+		return nil, err
+	}
+	monthlyUsed, err := s.repo.SumTokensByUser(userID, monthStart)
+	if err != nil {
+		return nil, err
+	}
+
+	quota := &AIQuota{
+		DailyUsed:        dailyUsed,
+		DailyLimit:       s.limits.DailyTokensPerUser,
+		DailyRemaining:   remainingTokens(s.limits.DailyTokensPerUser, dailyUsed),
+		MonthlyUsed:      monthlyUsed,
+		MonthlyLimit:     s.limits.MonthlyTokensPerUser,
+		MonthlyRemaining: remainingTokens(s.limits.MonthlyTokensPerUser, monthlyUsed),
+		ResetsAt:         dayStart.AddDate(0, 0, 1),
+	}
+	if quota.MonthlyRemaining != nil && *quota.MonthlyRemaining <= 0 {
+		quota.ResetsAt = monthStart.AddDate(0, 1, 0)
+	}
+
+	if s.limits.GlobalMonthlyTokens > 0 {
+		globalUsed, err := s.repo.SumTokens(monthStart)
+		if err != nil {
+			return nil, err
+		}
+		if globalUsed >= s.limits.GlobalMonthlyTokens {
+			quota.GlobalExhausted = true
+			quota.ResetsAt = monthStart.AddDate(0, 1, 0)
+		}
+	}
+
+	return quota, nil
+}
+
+// CheckQuota returns the quota, or ErrAIQuotaExceeded (with the quota) when it is used up
+func (s *AIUsageService) CheckQuota(userID string) (*AIQuota, error) {
+	quota, err := s.GetQuota(userID)
+	if err != nil {
+		// Do not lock users out of the assistant because accounting is unavailable
+		log.Printf("⚠️ AI Usage: Failed to load quota for %s: %v", userID, err)
+		return nil, nil
+	}
+	if quota.Exceeded() {
+		return quota, ErrAIQuotaExceeded
+	}
+	return quota, nil
+}
+
+// AIReservation holds the estimated tokens of one request until it is settled
+type AIReservation struct {
+	usage *models.AIUsage
+}
+
+// Reserve holds the worst-case tokens of req (estimated prompt plus MaxTokens) before it
+// is sent. The reservation is stored first and the budget checked afterwards, so
+// concurrent requests and tool rounds all see each other. When the budget does not cover
+// it, the reservation is dropped and ErrAIQuotaExceeded is returned with the quota. A nil
+// reservation (accounting unavailable) is valid for Settle and Release.
+func (s *AIUsageService) Reserve(userID string, kind models.AIUsageKind, req ChatRequest) (*AIReservation, *AIQuota, error) {
+	usage := &models.AIUsage{
+		UserID:      userID,
+		Kind:        kind,
+		Model:       req.Model,
+		TotalTokens: estimatePromptTokens(req) + req.MaxTokens,
+		Estimated:   true,
+		Reserved:    true,
+	}
+	if err := s.repo.Create(usage); err != nil {
+		// Do not lock users out of the assistant because accounting is unavailable
+		log.Printf("⚠️ AI Usage: Failed to reserve tokens for %s: %v", userID, err)
+		return nil, nil, nil
+	}
+	reservation := &AIReservation{usage: usage}
+
+	quota, err := s.GetQuota(userID)
+	if err != nil {
+		log.Printf("⚠️ AI Usage: Failed to load quota for %s: %v", userID, err)
+		return reservation, nil, nil
+	}
+	if s.overBudget(quota) {
+		s.Release(reservation)
+		quota, _ = s.GetQuota(userID)
+		return nil, quota, ErrAIQuotaExceeded
+	}
+	return reservation, quota, nil
+}
+
+// overBudget reports whether usage including reservations is above any limit
+func (s *AIUsageService) overBudget(quota *AIQuota) bool {
+	return quota.GlobalExhausted ||
+		(s.limits.DailyTokensPerUser > 0 && quota.DailyUsed > s.limits.DailyTokensPerUser) ||
+		(s.limits.MonthlyTokensPerUser > 0 && quota.MonthlyUsed > s.limits.MonthlyTokensPerUser)
+}
+
+// Release drops a reservation whose request consumed nothing
+func (s *AIUsageService) Release(reservation *AIReservation) {
+	if reservation == nil {
+		return
+	}
+	if err := s.repo.Delete(reservation.usage.ID); err != nil {
+		log.Printf("⚠️ AI Usage: Failed to release reservation %s: %v", reservation.usage.ID, err)
+	}
+}
+
+// settle replaces the reservation with the tokens actually consumed
+func (s *AIUsageService) settle(reservation *AIReservation, userID string, kind models.AIUsageKind, model string, promptTokens, completionTokens int, estimated bool) {
+	usage := s.newUsage(userID, kind, model, promptTokens, completionTokens, estimated)
+	if reservation == nil {
+		if err := s.repo.Create(usage); err != nil {
+			log.Printf("⚠️ AI Usage: Failed to record usage for %s: %v", userID, err)
+		}
+		return
+	}
+
+	usage.ID = reservation.usage.ID
+	usage.CreatedAt = reservation.usage.CreatedAt
+	if err := s.repo.Update(usage); err != nil {
+		log.Printf("⚠️ AI Usage: Failed to settle usage for %s: %v", userID, err)
+	}
+}
+
+func (s *AIUsageService) newUsage(userID string, kind models.AIUsageKind, model string, promptTokens, completionTokens int, estimated bool) *models.AIUsage {
+	return &models.AIUsage{
+		UserID:           userID,
+		Kind:             kind,
+		Model:            model,
+		PromptTokens:     promptTokens,
+		CompletionTokens: completionTokens,
+		TotalTokens:      promptTokens + completionTokens,
+		Estimated:        estimated,
+		CostUSD: (float64(promptTokens)*s.limits.InputPricePerMTok +
+			float64(completionTokens)*s.limits.OutputPricePerMTok) / 1_000_000,
+	}
+}
+
+// Record stores the tokens consumed by one request
+func (s *AIUsageService) Record(userID string, kind models.AIUsageKind, model string, promptTokens, completionTokens int, estimated bool) {
+	s.settle(nil, userID, kind, model, promptTokens, completionTokens, estimated)
+}
+
+// SettleCompletion stores the tokens of one completion in place of its reservation,
+// estimating them when the provider did not report usage
+func (s *AIUsageService) SettleCompletion(reservation *AIReservation, userID string, kind models.AIUsageKind, req ChatRequest, resp *ChatResponse) {
+	if resp == nil {
+		s.Release(reservation)
+		return
+	}
+
+	if resp.Usage != nil {
+		s.settle(reservation, userID, kind, resp.Model, resp.Usage.PromptTokens, resp.Usage.CompletionTokens, false)
+		return
+	}
+
+	completion := 0
+	if len(resp.Choices) > 0 {
+		msg := resp.Choices[0].Message
+		completion = estimateMessageTokens(ChatMessage{Content: msg.Content, ToolCalls: msg.ToolCalls})
+	}
+	s.settle(reservation, userID, kind, resp.Model, estimatePromptTokens(req), completion, true)
+}
+
+// RecordCompletion stores the tokens of one completion that had no reservation
+func (s *AIUsageService) RecordCompletion(userID string, kind models.AIUsageKind, req ChatRequest, resp *ChatResponse) {
+	s.SettleCompletion(nil, userID, kind, req, resp)
+}
+
+// SettleStream stores the tokens of a streamed reply in place of its reservation
+func (s *AIUsageService) SettleStream(reservation *AIReservation, userID string, req ChatRequest, result StreamResult) {
+	if result.Usage != nil {
+		s.settle(reservation, userID, models.AIUsageKindStream, result.Model, result.Usage.PromptTokens, result.Usage.CompletionTokens, false)
+		return
+	}
+	s.settle(reservation, userID, models.AIUsageKindStream, result.Model, estimatePromptTokens(req), EstimateTokens(result.Content), true)
+}
+
+// RecordStream stores the tokens of a streamed reply that had no reservation
+func (s *AIUsageService) RecordStream(userID string, req ChatRequest, result StreamResult) {
+	s.SettleStream(nil, userID, req, result)
+}
+
+// GetStats returns usage of the last days for admins
+func (s *AIUsageService) GetStats(days int) (*AIUsageStats, error) {
+	if days <= 0 {
+		days = 30
+	}
+	since := startOfDay(time.Now()).AddDate(0, 0, -(days - 1))
+
+	totals, err := s.repo.Totals(since)
+	if err != nil {
+		return nil, err
+	}
+	byModel, err := s.repo.ByModel(since)
+	if err != nil {
+		return nil, err
+	}
+	byDay, err := s.repo.ByDay(since)
+	if err != nil {
+		return nil, err
+	}
+	topUsers, err := s.repo.TopUsers(since, 20)
+	if err != nil {
+		return nil, err
+	}
+
+	monthUsed, err := s.repo.SumTokens(startOfMonth(time.Now()))
+	if err != nil {
+		return nil, err
+	}
+
+	return &AIUsageStats{
+		Since:    since,
+		Totals:   totals,
+		ByModel:  byModel,
+		ByDay:    byDay,
+		TopUsers: topUsers,
+		Global: map[string]interface{}{
+			"monthly_used":      monthUsed,
+			"monthly_limit":     s.limits.GlobalMonthlyTokens,
+			"monthly_remaining": remainingTokens(s.limits.GlobalMonthlyTokens, monthUsed),
+		},
+	}, nil
+}
+
+func estimatePromptTokens(req ChatRequest) int {
+	tokens := 0
+	for _, msg := range req.Messages {
+		tokens += estimateMessageTokens(msg)
+	}
+	return tokens
+}
+
+// remainingTokens returns limit-used (floored at 0), or nil when unlimited
+func remainingTokens(limit, used int64) *int64 {
+	if limit <= 0 {
+		return nil
+	}
+	remaining := limit - used
+	if remaining < 0 {
+		remaining = 0
+	}
+	return &remaining
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+func startOfMonth(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+}
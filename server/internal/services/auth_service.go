@@ -2,6 +2,7 @@ package services
 
 import (
 	"errors"
+	"fmt"
 	"log"
 	"time"
 
@@ -17,6 +18,8 @@ type AuthService struct {
 	passwordResetRepo     *repository.PasswordResetRepository
 	emailVerificationRepo *repository.EmailVerificationRepository
 	emailService          *EmailService
+	sessionService        *SessionManagementService
+	userState             *UserStateService
 }
 
 func NewAuthService(
@@ -24,16 +27,185 @@ func NewAuthService(
 	categoryRepo *repository.CategoryRepository,
 	passwordResetRepo *repository.PasswordResetRepository,
 	emailVerificationRepo *repository.EmailVerificationRepository,
+	emailService *EmailService,
+	sessionService *SessionManagementService,
+	userState *UserStateService,
 ) *AuthService {
 	return &AuthService{
 		userRepo:              userRepo,
 		categoryRepo:          categoryRepo,
 		passwordResetRepo:     passwordResetRepo,
 		emailVerificationRepo: emailVerificationRepo,
-		emailService:          NewEmailService(),
+		emailService:          emailService,
+		sessionService:        sessionService,
+		userState:             userState,
 	}
 }
 
+// ClientInfo describes the device a session is opened from
+type ClientInfo struct {
+	IPAddress string
+	UserAgent string
+	Location  string
+}
+
+// startSession opens a server-side session and issues its access and refresh tokens
+func (s *AuthService) startSession(user *models.User, client ClientInfo) (string, string, error) {
+	sessionID := NewSessionID()
+	userType := string(user.UserType)
+
+	accessToken, err := utils.GenerateAccessToken(user.ID, user.Email, userType, sessionID)
+	if err != nil {
+		return "", "", err
+	}
+	refreshToken, err := utils.GenerateRefreshToken(user.ID, user.Email, userType, sessionID)
+	if err != nil {
+		return "", "", err
+	}
+
+	if _, err := s.sessionService.CreateSession(
+		sessionID,
+		user.ID,
+		utils.HashToken(refreshToken),
+		ParseDeviceInfo(client.UserAgent),
+		client.IPAddress,
+		client.UserAgent,
+		client.Location,
+		utils.RefreshTokenTTL,
+	); err != nil {
+		return "", "", fmt.Errorf("failed to create session: %w", err)
+	}
+
+	return accessToken, refreshToken, nil
+}
+
+// RefreshTokens rotates a refresh token: it returns a new access token and a new refresh
+// token, and the presented one stops working. Reusing a rotated token ends the session and
+// alerts the user, since either copy may belong to an attacker.
+func (s *AuthService) RefreshTokens(refreshToken string, client ClientInfo) (string, string, error) {
+	claims, err := utils.ValidateToken(refreshToken)
+	if err != nil {
+		return "", "", ErrInvalidRefreshToken
+	}
+	if claims.Type != "refresh" || claims.SessionID == "" {
+		return "", "", errors.New("invalid token type")
+	}
+	if GetTokenBlacklistService().IsBlacklisted(claims.ID) {
+		return "", "", errors.New("refresh token has been revoked")
+	}
+
+	// New tokens carry the current user type; a password change or lockout stops refreshing
+	state, err := s.userState.Get(claims.UserID)
+	if err != nil {
+		return "", "", err
+	}
+	now := time.Now()
+	if claims.IssuedAt == nil || state.IssuedBeforePasswordChange(claims.IssuedAt.Time) {
+		return "", "", ErrPasswordChanged
+	}
+	if state.IsLocked(now) {
+		return "", "", ErrAccountLocked
+	}
+	userType := string(state.EffectiveUserType(now))
+
+	newRefreshToken, err := utils.GenerateRefreshToken(claims.UserID, claims.Email, userType, claims.SessionID)
+	if err != nil {
+		return "", "", err
+	}
+	sealed, err := sealRefreshSuccessor(refreshToken, newRefreshToken)
+	if err != nil {
+		return "", "", err
+	}
+
+	session, sealedCurrent, err := s.sessionService.RotateRefreshToken(
+		claims.SessionID,
+		utils.HashToken(refreshToken),
+		utils.HashToken(newRefreshToken),
+		sealed,
+		utils.RefreshTokenTTL,
+		client.IPAddress,
+		client.UserAgent,
+	)
+	if errors.Is(err, ErrRefreshTokenReused) {
+		s.alertRefreshTokenReuse(session, client)
+		return "", "", err
+	}
+	if errors.Is(err, ErrSessionNotFound) {
+		return "", "", errors.New("session has been revoked, please login again")
+	}
+	if err != nil {
+		return "", "", err
+	}
+
+	// A concurrent refresh already rotated this token: return the refresh token it got
+	if sealedCurrent != "" {
+		newRefreshToken, err = openRefreshSuccessor(refreshToken, sealedCurrent)
+		if err != nil || utils.HashToken(newRefreshToken) != session.TokenHash {
+			return "", "", ErrInvalidRefreshToken
+		}
+	}
+
+	accessToken, err := utils.GenerateAccessToken(claims.UserID, claims.Email, userType, claims.SessionID)
+	if err != nil {
+		return "", "", err
+	}
+	return accessToken, newRefreshToken, nil
+}
+
+// sealRefreshSuccessor encrypts the token a refresh token is rotated to with a key derived
+// from the rotated token, so only a client presenting that token can read it back
+func sealRefreshSuccessor(presented, successor string) (string, error) {
+	enc, err := utils.NewEncryptionService("refresh-successor:" + presented)
+	if err != nil {
+		return "", err
+	}
+	return enc.Encrypt(successor)
+}
+
+// openRefreshSuccessor decrypts a token sealed by sealRefreshSuccessor
+func openRefreshSuccessor(presented, sealed string) (string, error) {
+	enc, err := utils.NewEncryptionService("refresh-successor:" + presented)
+	if err != nil {
+		return "", err
+	}
+	return enc.Decrypt(sealed)
+}
+
+// alertRefreshTokenReuse emails the user that a session was ended because its refresh
+// token was used twice
+func (s *AuthService) alertRefreshTokenReuse(session *models.ActiveSession, client ClientInfo) {
+	user, err := s.userRepo.FindByID(session.UserID)
+	if err != nil {
+		log.Printf("⚠️ Failed to load user %s for token reuse alert: %v", session.UserID, err)
+		return
+	}
+
+	err = s.emailService.Queue(EmailJob{
+		Kind:      EmailKindRefreshTokenReuse,
+		To:        user.Email,
+		Lang:      user.Language,
+		UserName:  user.Username,
+		Device:    session.DeviceInfo,
+		IPAddress: client.IPAddress,
+		Time:      time.Now().Format("02 Jan 2006 15:04 MST"),
+	})
+	if err != nil {
+		log.Printf("⚠️ Failed to send token reuse alert to user %s: %v", user.ID, err)
+	}
+}
+
+// EndSession logs out the session of a token
+func (s *AuthService) EndSession(claims *utils.Claims) error {
+	if claims.SessionID == "" {
+		return nil
+	}
+	err := s.sessionService.InvalidateSession(claims.UserID, claims.SessionID)
+	if errors.Is(err, ErrSessionNotFound) {
+		return nil
+	}
+	return err
+}
+
 // Register membuat user baru dengan default categories
 // REVISED: Does NOT auto-login. Returns user without token.
 // User must verify email via OTP before they can login.
@@ -82,14 +254,15 @@ func (s *AuthService) Register(email, username, password string) (*models.User,
 
 // LoginResult represents the result of a login attempt
 type LoginResult struct {
-	User        *models.User `json:"user,omitempty"`
-	Token       string       `json:"token,omitempty"`
-	RequiresMFA bool         `json:"requires_mfa"`
-	MFAToken    string       `json:"mfa_token,omitempty"` // Temporary token for MFA verification
+	User         *models.User `json:"user,omitempty"`
+	Token        string       `json:"token,omitempty"`
+	RefreshToken string       `json:"refresh_token,omitempty"`
+	RequiresMFA  bool         `json:"requires_mfa"`
+	MFAToken     string       `json:"mfa_token,omitempty"` // Temporary token for MFA verification
 }
 
 // Login mengautentikasi user
-func (s *AuthService) Login(email, password string) (*models.User, string, error) {
+func (s *AuthService) Login(email, password string, client ClientInfo) (*models.User, string, error) {
 	user, err := s.userRepo.FindByEmail(email)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -104,7 +277,7 @@ func (s *AuthService) Login(email, password string) (*models.User, string, error
 	}
 
 	// Generate JWT token
-	token, err := utils.GenerateToken(user.ID, user.Email, string(user.UserType))
+	token, _, err := s.startSession(user, client)
 	if err != nil {
 		return nil, "", err
 	}
@@ -113,7 +286,7 @@ func (s *AuthService) Login(email, password string) (*models.User, string, error
 }
 
 // LoginWithMFA handles login with MFA support
-func (s *AuthService) LoginWithMFA(email, password string) (*LoginResult, error) {
+func (s *AuthService) LoginWithMFA(email, password string, client ClientInfo) (*LoginResult, error) {
 	user, err := s.userRepo.FindByEmail(email)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -129,7 +302,7 @@ func (s *AuthService) LoginWithMFA(email, password string) (*LoginResult, error)
 
 	// Check if account is locked
 	if user.LockedUntil != nil && user.LockedUntil.After(time.Now()) {
-		return nil, errors.New("account is temporarily locked. Please try again later")
+		return nil, ErrAccountLocked
 	}
 
 	// Check password
@@ -141,6 +314,7 @@ func (s *AuthService) LoginWithMFA(email, password string) (*LoginResult, error)
 		if user.FailedLoginAttempts >= 9 { // Will be 10 after increment
 			lockUntil := time.Now().Add(1 * time.Minute) // Reduced from 30 min to 1 min for testing
 			s.userRepo.LockAccount(user.ID, &lockUntil)
+			s.userState.Invalidate(user.ID)
 			return nil, errors.New("too many failed attempts. Account locked for 1 minute")
 		}
 
@@ -162,40 +336,40 @@ func (s *AuthService) LoginWithMFA(email, password string) (*LoginResult, error)
 		}, nil
 	}
 
-	// No MFA - generate full token
-	token, err := utils.GenerateToken(user.ID, user.Email, string(user.UserType))
+	// No MFA - start a session
+	token, refreshToken, err := s.startSession(user, client)
 	if err != nil {
 		return nil, err
 	}
 
 	// Update last login
-	ip := "" // Will be set by handler
-	s.userRepo.UpdateLastLogin(user.ID, ip)
+	s.userRepo.UpdateLastLogin(user.ID, client.IPAddress)
 
 	return &LoginResult{
-		User:        user,
-		Token:       token,
-		RequiresMFA: false,
+		User:         user,
+		Token:        token,
+		RefreshToken: refreshToken,
+		RequiresMFA:  false,
 	}, nil
 }
 
 // CompleteMFALogin completes login after MFA verification
-func (s *AuthService) CompleteMFALogin(userID string) (*models.User, string, error) {
+func (s *AuthService) CompleteMFALogin(userID string, client ClientInfo) (*models.User, string, string, error) {
 	user, err := s.userRepo.FindByID(userID)
 	if err != nil {
-		return nil, "", errors.New("user not found")
+		return nil, "", "", errors.New("user not found")
 	}
 
-	// Generate JWT token
-	token, err := utils.GenerateToken(user.ID, user.Email, string(user.UserType))
+	// Start a session
+	token, refreshToken, err := s.startSession(user, client)
 	if err != nil {
-		return nil, "", err
+		return nil, "", "", err
 	}
 
 	// Reset failed login and update last login
-	s.userRepo.UpdateLastLogin(user.ID, "")
+	s.userRepo.UpdateLastLogin(user.ID, client.IPAddress)
 
-	return user, token, nil
+	return user, token, refreshToken, nil
 }
 
 // ForgotPassword membuat verification code dan mengirim ke email
@@ -213,7 +387,7 @@ func (s *AuthService) ForgotPassword(email string) (string, error) {
 		UserID:           user.ID,
 		Email:            email,
 		VerificationCode: code,
-		ExpiresAt:        time.Now().Add(10 * time.Minute), // Extended to 10 minutes
+		ExpiresAt:        time.Now().Add(emailCodeTTL),
 		Used:             false,
 	}
 
@@ -222,7 +396,7 @@ func (s *AuthService) ForgotPassword(email string) (string, error) {
 	}
 
 	// Send verification code via email (PWD-XXXXXX format)
-	if err := s.emailService.SendPasswordResetCode(email, code); err != nil {
+	if err := s.emailService.Queue(EmailJob{Kind: EmailKindPasswordReset, To: email, Lang: user.Language, Code: code}); err != nil {
 		log.Printf("⚠️ Failed to send verification email: %v", err)
 		// Don't return error - still allow development mode where email isn't configured
 	}
@@ -258,8 +432,7 @@ func (s *AuthService) ResetPassword(code, newPassword string) error {
 		return err
 	}
 
-	user.PasswordHash = hashedPassword
-	if err := s.userRepo.Update(user); err != nil {
+	if err := s.setPassword(user, hashedPassword); err != nil {
 		return err
 	}
 
@@ -268,7 +441,7 @@ func (s *AuthService) ResetPassword(code, newPassword string) error {
 }
 
 // UpdateProfile memperbarui profile user
-func (s *AuthService) UpdateProfile(userID, username string, profilePicture *string) (*models.User, error) {
+func (s *AuthService) UpdateProfile(userID, username string, profilePicture, language, timezone *string) (*models.User, error) {
 	user, err := s.userRepo.FindByID(userID)
 	if err != nil {
 		return nil, err
@@ -278,6 +451,18 @@ func (s *AuthService) UpdateProfile(userID, username string, profilePicture *str
 	if profilePicture != nil {
 		user.ProfilePicture = profilePicture
 	}
+	if language != nil {
+		if *language != EmailLanguageID && *language != EmailLanguageEN {
+			return nil, errors.New("language must be id or en")
+		}
+		user.Language = *language
+	}
+	if timezone != nil {
+		if _, err := time.LoadLocation(*timezone); err != nil || *timezone == "Local" {
+			return nil, errors.New("timezone must be an IANA name such as Asia/Jakarta")
+		}
+		user.Timezone = *timezone
+	}
 
 	if err := s.userRepo.Update(user); err != nil {
 		return nil, err
@@ -286,30 +471,51 @@ func (s *AuthService) UpdateProfile(userID, username string, profilePicture *str
 	return user, nil
 }
 
-// ChangePassword mengubah password user (untuk edit profile)
-func (s *AuthService) ChangePassword(userID, oldPassword, newPassword string) error {
+// ChangePassword mengubah password user (untuk edit profile). Every session is ended and
+// a new one is started for the current device; its access and refresh tokens are returned.
+func (s *AuthService) ChangePassword(userID, oldPassword, newPassword string, client ClientInfo) (string, string, error) {
 	user, err := s.userRepo.FindByID(userID)
 	if err != nil {
-		return err
+		return "", "", err
 	}
 
 	// Verify old password
 	if !utils.CheckPasswordHash(oldPassword, user.PasswordHash) {
-		return errors.New("invalid old password")
+		return "", "", errors.New("invalid old password")
 	}
 
 	// Hash new password
 	hashedPassword, err := utils.HashPassword(newPassword)
 	if err != nil {
-		return err
+		return "", "", err
 	}
 
+	if err := s.setPassword(user, hashedPassword); err != nil {
+		return "", "", err
+	}
+	return s.startSession(user, client)
+}
+
+// setPassword stores a new password and ends every session of the user. Tokens issued
+// before PasswordChangedAt are rejected from now on.
+func (s *AuthService) setPassword(user *models.User, hashedPassword string) error {
+	// MySQL keeps milliseconds and would round the rest, possibly into the next second
+	now := time.Now().Truncate(time.Millisecond)
 	user.PasswordHash = hashedPassword
-	return s.userRepo.Update(user)
+	user.PasswordChangedAt = &now
+	if err := s.userRepo.Update(user); err != nil {
+		return err
+	}
+	s.userState.Invalidate(user.ID)
+
+	if _, err := s.sessionService.InvalidateAllSessions(user.ID, ""); err != nil {
+		return fmt.Errorf("failed to end sessions: %w", err)
+	}
+	return nil
 }
 
 // GoogleOAuthLogin handles Google OAuth login/registration
-func (s *AuthService) GoogleOAuthLogin(googleID, email, username, picture string) (*models.User, string, string, bool, error) {
+func (s *AuthService) GoogleOAuthLogin(googleID, email, username, picture string, client ClientInfo) (*models.User, string, string, bool, error) {
 	// Find or create user
 	user, isNew, err := s.userRepo.FindOrCreateGoogleUser(googleID, email, username, picture)
 	if err != nil {
@@ -323,14 +529,8 @@ func (s *AuthService) GoogleOAuthLogin(googleID, email, username, picture string
 		}
 	}
 
-	// Generate JWT access token
-	token, err := utils.GenerateToken(user.ID, user.Email, string(user.UserType))
-	if err != nil {
-		return nil, "", "", false, err
-	}
-
-	// Generate refresh token
-	refreshToken, err := utils.GenerateRefreshToken(user.ID, user.Email, string(user.UserType))
+	// Start a session with access and refresh tokens
+	token, refreshToken, err := s.startSession(user, client)
 	if err != nil {
 		return nil, "", "", false, err
 	}
@@ -363,7 +563,7 @@ func (s *AuthService) SendVerificationOTP(email string) (string, error) {
 		UserID:           user.ID,
 		Email:            email,
 		VerificationCode: code,
-		ExpiresAt:        time.Now().Add(10 * time.Minute), // Extended to 10 minutes
+		ExpiresAt:        time.Now().Add(emailCodeTTL),
 		Used:             false,
 		FailedAttempts:   0,
 	}
@@ -375,7 +575,7 @@ func (s *AuthService) SendVerificationOTP(email string) (string, error) {
 	}
 
 	// Send verification code via email (REG-XXXXXX format)
-	if err := s.emailService.SendAccountVerificationCode(email, code); err != nil {
+	if err := s.emailService.Queue(EmailJob{Kind: EmailKindAccountVerification, To: email, Lang: user.Language, Code: code}); err != nil {
 		log.Printf("⚠️ Failed to send verification email: %v", err)
 		// Don't return error - still allow development mode where email isn't configured
 	}
@@ -0,0 +1,222 @@
+package services
+
+import (
+	"sort"
+	"time"
+
+	"github.com/workradar/server/internal/models"
+)
+
+const (
+	// maxFocusMinutesPerDay caps auto-scheduled "focus" work per day
+	maxFocusMinutesPerDay = 240
+	// defaultScheduleDays is how far ahead tasks without deadline may be scheduled
+	defaultScheduleDays = 14
+	maxScheduleDays     = 60
+	// scheduleSlotStep aligns scheduled starts
+	scheduleSlotStep = 15 * time.Minute
+)
+
+// ScheduleInput describes a user's calendar for auto-scheduling
+type ScheduleInput struct {
+	Now      time.Time
+	Until    time.Time // Last moment work may be scheduled
+	WorkDays map[time.Weekday]WorkHours
+	// DaysOff maps YYYY-MM-DD to the reason (holiday, leave)
+	DaysOff map[string]string
+	// Blocked are tasks that already have a time block
+	Blocked []models.Task
+	// Tasks are scheduled in earliest-deadline-first order
+	Tasks []models.Task
+}
+
+// ScheduleAssignment is a proposed time block for a task
+type ScheduleAssignment struct {
+	TaskID          string     `json:"task_id"`
+	Title           string     `json:"title"`
+	Start           time.Time  `json:"start"`
+	End             time.Time  `json:"end"`
+	DurationMinutes int        `json:"duration_minutes"`
+	Difficulty      string     `json:"difficulty,omitempty"`
+	Deadline        *time.Time `json:"deadline,omitempty"`
+}
+
+// ScheduleSkip is a task that could not be scheduled
+type ScheduleSkip struct {
+	TaskID   string     `json:"task_id"`
+	Title    string     `json:"title"`
+	Deadline *time.Time `json:"deadline,omitempty"`
+	Reason   string     `json:"reason"`
+}
+
+// SchedulePreview is the outcome of auto-scheduling
+type SchedulePreview struct {
+	From        time.Time            `json:"from"`
+	Until       time.Time            `json:"until"`
+	Assignments []ScheduleAssignment `json:"assignments"`
+	Unscheduled []ScheduleSkip       `json:"unscheduled"`
+}
+
+type busyBlock struct {
+	start, end time.Time
+	focus      bool
+}
+
+// AutoSchedule assigns start times to tasks inside work hours, skipping days off and
+// existing time blocks. Tasks are taken earliest deadline first (higher priority, then harder
+// work first among equal deadlines) and each gets the earliest free slot that ends before its deadline.
+// Focus tasks keep a break's distance from other focus blocks and are capped per day.
+func AutoSchedule(in ScheduleInput) *SchedulePreview {
+	from := alignSlot(in.Now)
+	preview := &SchedulePreview{
+		From:        from,
+		Until:       in.Until,
+		Assignments: []ScheduleAssignment{},
+		Unscheduled: []ScheduleSkip{},
+	}
+
+	var busy []busyBlock
+	focusMinutes := map[string]int{}
+	for _, t := range in.Blocked {
+		if t.ScheduledStart == nil {
+			continue
+		}
+		block := busyBlock{
+			start: *t.ScheduledStart,
+			end:   t.ScheduledStart.Add(time.Duration(planTaskMinutes(t)) * time.Minute),
+			focus: isFocusTask(t),
+		}
+		busy = append(busy, block)
+		if block.focus {
+			focusMinutes[block.start.Format("2006-01-02")] += planTaskMinutes(t)
+		}
+	}
+
+	tasks := append([]models.Task(nil), in.Tasks...)
+	sort.SliceStable(tasks, func(i, j int) bool {
+		a, b := tasks[i], tasks[j]
+		if a.Deadline == nil || b.Deadline == nil {
+			if (a.Deadline == nil) != (b.Deadline == nil) {
+				return a.Deadline != nil
+			}
+		} else if !a.Deadline.Equal(*b.Deadline) {
+			return a.Deadline.Before(*b.Deadline)
+		}
+		if pa, pb := priorityRank(a.Priority), priorityRank(b.Priority); pa != pb {
+			return pa > pb
+		}
+		return difficultyRank(a.Difficulty) > difficultyRank(b.Difficulty)
+	})
+
+	for _, task := range tasks {
+		minutes := planTaskMinutes(task)
+		limit := in.Until
+		if task.Deadline != nil && task.Deadline.Before(limit) {
+			limit = *task.Deadline
+		}
+
+		start, ok := findSlot(in, busy, focusMinutes, from, limit, minutes, isFocusTask(task))
+		if !ok {
+			reason := "Tidak ada waktu kosong di jam kerja sebelum deadline"
+			if task.Deadline != nil && !task.Deadline.After(from) {
+				reason = "Deadline sudah lewat"
+			} else if task.Deadline == nil {
+				reason = "Tidak ada waktu kosong di jam kerja dalam rentang jadwal"
+			}
+			preview.Unscheduled = append(preview.Unscheduled, ScheduleSkip{
+				TaskID:   task.ID,
+				Title:    task.Title,
+				Deadline: task.Deadline,
+				Reason:   reason,
+			})
+			continue
+		}
+
+		end := start.Add(time.Duration(minutes) * time.Minute)
+		busy = append(busy, busyBlock{start: start, end: end, focus: isFocusTask(task)})
+		if isFocusTask(task) {
+			focusMinutes[start.Format("2006-01-02")] += minutes
+		}
+
+		assignment := ScheduleAssignment{
+			TaskID:          task.ID,
+			Title:           task.Title,
+			Start:           start,
+			End:             end,
+			DurationMinutes: minutes,
+			Deadline:        task.Deadline,
+		}
+		if task.Difficulty != nil {
+			assignment.Difficulty = *task.Difficulty
+		}
+		preview.Assignments = append(preview.Assignments, assignment)
+	}
+
+	sort.SliceStable(preview.Assignments, func(i, j int) bool {
+		return preview.Assignments[i].Start.Before(preview.Assignments[j].Start)
+	})
+	return preview
+}
+
+// findSlot returns the earliest start in [from, limit-duration] that is inside work hours
+// and does not collide with busy blocks
+func findSlot(in ScheduleInput, busy []busyBlock, focusMinutes map[string]int, from, limit time.Time, minutes int, focus bool) (time.Time, bool) {
+	duration := time.Duration(minutes) * time.Minute
+	gap := time.Duration(planBreakMinutes) * time.Minute
+
+	for day := startOfDay(from); !day.After(limit); day = day.AddDate(0, 0, 1) {
+		key := day.Format("2006-01-02")
+		if _, off := in.DaysOff[key]; off {
+			continue
+		}
+		if focus && focusMinutes[key]+minutes > maxFocusMinutesPerDay {
+			continue
+		}
+
+		workStart, workEnd, ok := in.WorkDays[day.Weekday()].WorkWindow(day)
+		if !ok {
+			continue
+		}
+
+		start := workStart
+		if start.Before(from) {
+			start = from
+		}
+		end := workEnd
+		if end.After(limit) {
+			end = limit
+		}
+
+		for !start.Add(duration).After(end) {
+			candidateEnd := start.Add(duration)
+			moved := false
+			for _, b := range busy {
+				blockStart, blockEnd := b.start, b.end
+				if focus && b.focus {
+					// Keep a break between two focus blocks
+					blockStart = blockStart.Add(-gap)
+					blockEnd = blockEnd.Add(gap)
+				}
+				if start.Before(blockEnd) && candidateEnd.After(blockStart) {
+					start = alignSlot(blockEnd)
+					moved = true
+					break
+				}
+			}
+			if !moved {
+				return start, true
+			}
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// alignSlot rounds t up to the next scheduleSlotStep
+func alignSlot(t time.Time) time.Time {
+	aligned := t.Truncate(scheduleSlotStep)
+	if aligned.Before(t) {
+		aligned = aligned.Add(scheduleSlotStep)
+	}
+	return aligned
+}
@@ -9,11 +9,12 @@ import (
 )
 
 type BotMessageService struct {
-	repo *repository.BotMessageRepository
+	repo   *repository.BotMessageRepository
+	events *EventHub
 }
 
-func NewBotMessageService(repo *repository.BotMessageRepository) *BotMessageService {
-	return &BotMessageService{repo: repo}
+func NewBotMessageService(repo *repository.BotMessageRepository, events *EventHub) *BotMessageService {
+	return &BotMessageService{repo: repo, events: events}
 }
 
 // SendMessage creates and saves a new bot message
@@ -32,6 +33,7 @@ func (s *BotMessageService) SendMessage(userID string, msgType models.MessageTyp
 		return nil, err
 	}
 
+	s.events.Publish(userID, EventMessageCreated, message)
 	return message, nil
 }
 
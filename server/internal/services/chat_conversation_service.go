@@ -0,0 +1,316 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/workradar/server/internal/models"
+	"github.com/workradar/server/internal/repository"
+	"gorm.io/gorm"
+)
+
+const (
+	// defaultConversationTitle is replaced by a title derived from the first message
+	defaultConversationTitle = "Percakapan baru"
+	// legacyConversationTitle holds messages sent before conversations existed
+	legacyConversationTitle = "Percakapan sebelumnya"
+	maxConversationTitleLen = 60
+)
+
+var ErrConversationNotFound = errors.New("conversation not found")
+
+// ChatConversationService manages AI chat threads
+type ChatConversationService struct {
+	conversationRepo *repository.ChatConversationRepository
+	chatRepo         *repository.ChatRepository
+	taskRepo         *repository.TaskRepository
+	categoryRepo     *repository.CategoryRepository
+}
+
+func NewChatConversationService(
+	conversationRepo *repository.ChatConversationRepository,
+	chatRepo *repository.ChatRepository,
+	taskRepo *repository.TaskRepository,
+	categoryRepo *repository.CategoryRepository,
+) *ChatConversationService {
+	return &ChatConversationService{
+		conversationRepo: conversationRepo,
+		chatRepo:         chatRepo,
+		taskRepo:         taskRepo,
+		categoryRepo:     categoryRepo,
+	}
+}
+
+type CreateConversationDTO struct {
+	Title            string  `json:"title"`
+	PinnedTaskID     *string `json:"pinned_task_id"`
+	PinnedCategoryID *string `json:"pinned_category_id"`
+}
+
+// UpdateConversationDTO updates only the provided fields; an empty pinned ID unpins
+type UpdateConversationDTO struct {
+	Title            *string `json:"title"`
+	IsArchived       *bool   `json:"is_archived"`
+	PinnedTaskID     *string `json:"pinned_task_id"`
+	PinnedCategoryID *string `json:"pinned_category_id"`
+}
+
+// CreateConversation starts a new thread
+func (s *ChatConversationService) CreateConversation(userID string, data CreateConversationDTO) (*models.ChatConversation, error) {
+	conversation := &models.ChatConversation{
+		UserID: userID,
+		Title:  defaultConversationTitle,
+	}
+
+	if title := strings.TrimSpace(data.Title); title != "" {
+		conversation.Title = truncateTitle(title)
+	}
+	if err := s.applyPins(userID, conversation, data.PinnedTaskID, data.PinnedCategoryID); err != nil {
+		return nil, err
+	}
+
+	if err := s.conversationRepo.Create(conversation); err != nil {
+		return nil, err
+	}
+	return conversation, nil
+}
+
+// ListConversations returns active (or archived) threads, most recently active first
+func (s *ChatConversationService) ListConversations(userID string, archived bool) ([]models.ChatConversation, error) {
+	return s.conversationRepo.FindByUserID(userID, archived)
+}
+
+// GetConversation returns a thread owned by the user
+func (s *ChatConversationService) GetConversation(userID, conversationID string) (*models.ChatConversation, error) {
+	conversation, err := s.conversationRepo.FindByID(conversationID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrConversationNotFound
+		}
+		return nil, err
+	}
+	if conversation.UserID != userID {
+		return nil, ErrConversationNotFound
+	}
+	return conversation, nil
+}
+
+// GetMessages returns the latest messages of a thread in chronological order
+func (s *ChatConversationService) GetMessages(userID, conversationID string, limit int) ([]models.ChatMessage, error) {
+	if _, err := s.GetConversation(userID, conversationID); err != nil {
+		return nil, err
+	}
+	return s.chatRepo.FindByConversationID(conversationID, limit)
+}
+
+// UpdateConversation renames, archives/unarchives or re-pins a thread
+func (s *ChatConversationService) UpdateConversation(userID, conversationID string, data UpdateConversationDTO) (*models.ChatConversation, error) {
+	conversation, err := s.GetConversation(userID, conversationID)
+	if err != nil {
+		return nil, err
+	}
+
+	if data.Title != nil {
+		title := strings.TrimSpace(*data.Title)
+		if title == "" {
+			return nil, errors.New("title cannot be empty")
+		}
+		conversation.Title = truncateTitle(title)
+	}
+
+	if data.IsArchived != nil {
+		conversation.IsArchived = *data.IsArchived
+	}
+
+	if err := s.applyPins(userID, conversation, data.PinnedTaskID, data.PinnedCategoryID); err != nil {
+		return nil, err
+	}
+
+	if err := s.conversationRepo.UpdateSettings(conversation); err != nil {
+		return nil, err
+	}
+	return conversation, nil
+}
+
+// DeleteConversation removes a thread and its messages
+func (s *ChatConversationService) DeleteConversation(userID, conversationID string) error {
+	if _, err := s.GetConversation(userID, conversationID); err != nil {
+		return err
+	}
+	return s.conversationRepo.Delete(conversationID)
+}
+
+// ResolveForChat returns the thread a new message belongs to. Without an ID the most
+// recently active thread is continued, so clients unaware of threads keep their context;
+// a user's first thread adopts any messages sent before threads existed.
+func (s *ChatConversationService) ResolveForChat(userID, conversationID string) (*models.ChatConversation, error) {
+	if conversationID != "" {
+		return s.GetConversation(userID, conversationID)
+	}
+
+	conversation, err := s.conversationRepo.FindLatestActive(userID)
+	if err == nil {
+		return conversation, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	legacyCount, _ := s.chatRepo.CountWithoutConversation(userID)
+	if legacyCount == 0 {
+		return s.CreateConversation(userID, CreateConversationDTO{})
+	}
+
+	conversation, err = s.CreateConversation(userID, CreateConversationDTO{Title: legacyConversationTitle})
+	if err != nil {
+		return nil, err
+	}
+	if err := s.chatRepo.AssignWithoutConversation(userID, conversation.ID); err != nil {
+		return nil, err
+	}
+	return conversation, nil
+}
+
+// RecordMessage marks the thread active and names it after its first message
+func (s *ChatConversationService) RecordMessage(conversation *models.ChatConversation, userMessage string) {
+	now := time.Now()
+	conversation.LastMessageAt = &now
+
+	if conversation.Title == defaultConversationTitle {
+		if title := GenerateConversationTitle(userMessage); title != "" {
+			conversation.Title = title
+		}
+	}
+
+	_ = s.conversationRepo.UpdateActivity(conversation.ID, conversation.Title, now)
+}
+
+// SaveSummary stores the rolling summary of a thread's messages up to until
+func (s *ChatConversationService) SaveSummary(conversationID, summary string, until time.Time) error {
+	return s.conversationRepo.UpdateSummary(conversationID, summary, until)
+}
+
+// ClearAll removes every thread of a user
+func (s *ChatConversationService) ClearAll(userID string) error {
+	return s.conversationRepo.DeleteByUserID(userID)
+}
+
+// PinnedContext describes the thread's pinned task or category for the system prompt
+func (s *ChatConversationService) PinnedContext(conversation *models.ChatConversation) string {
+	var sb strings.Builder
+
+	if conversation.PinnedTaskID != nil {
+		task, err := s.taskRepo.FindByID(*conversation.PinnedTaskID)
+		if err == nil && task.UserID == conversation.UserID {
+			sb.WriteString("Percakapan ini membahas tugas berikut:\n")
+			sb.WriteString(describeTask(task))
+		}
+	}
+
+	if conversation.PinnedCategoryID != nil {
+		category, err := s.categoryRepo.FindByID(*conversation.PinnedCategoryID)
+		if err == nil && category.UserID == conversation.UserID {
+			sb.WriteString(fmt.Sprintf("Percakapan ini membahas kategori \"%s\".\n", category.Name))
+
+			tasks, _ := s.taskRepo.FindByUserIDAndCategory(conversation.UserID, category.ID)
+			listed := 0
+			for i := range tasks {
+				if tasks[i].IsCompleted || listed >= 10 {
+					continue
+				}
+				if listed == 0 {
+					sb.WriteString("Tugas pending di kategori ini:\n")
+				}
+				sb.WriteString(describeTask(&tasks[i]))
+				listed++
+			}
+		}
+	}
+
+	return sb.String()
+}
+
+// applyPins validates ownership of the pinned task/category; an empty ID unpins
+func (s *ChatConversationService) applyPins(userID string, conversation *models.ChatConversation, taskID, categoryID *string) error {
+	if taskID != nil {
+		if *taskID == "" {
+			conversation.PinnedTaskID = nil
+		} else {
+			task, err := s.taskRepo.FindByID(*taskID)
+			if err != nil || task.UserID != userID {
+				return errors.New("invalid task")
+			}
+			conversation.PinnedTaskID = &task.ID
+		}
+	}
+
+	if categoryID != nil {
+		if *categoryID == "" {
+			conversation.PinnedCategoryID = nil
+		} else {
+			category, err := s.categoryRepo.FindByID(*categoryID)
+			if err != nil || category.UserID != userID {
+				return errors.New("invalid category")
+			}
+			conversation.PinnedCategoryID = &category.ID
+		}
+	}
+
+	return nil
+}
+
+// GenerateConversationTitle derives a short thread title from the first message
+func GenerateConversationTitle(message string) string {
+	title := strings.Join(strings.Fields(message), " ")
+	title = strings.TrimRight(title, "?!.,;: ")
+	if title == "" {
+		return ""
+	}
+
+	// Capitalize the first letter
+	first, size := utf8.DecodeRuneInString(title)
+	title = strings.ToUpper(string(first)) + title[size:]
+
+	return truncateTitle(title)
+}
+
+// truncateTitle shortens a title at a word boundary
+func truncateTitle(title string) string {
+	if utf8.RuneCountInString(title) <= maxConversationTitleLen {
+		return title
+	}
+
+	runes := []rune(title)
+	cut := string(runes[:maxConversationTitleLen-1])
+	if idx := strings.LastIndex(cut, " "); idx > maxConversationTitleLen/2 {
+		cut = cut[:idx]
+	}
+	return strings.TrimRight(cut, " ,.;:") + "…"
+}
+
+func describeTask(task *models.Task) string {
+	status := "pending"
+	if task.IsCompleted {
+		status = "selesai"
+	}
+
+	line := fmt.Sprintf("- %s [id: %s, status: %s", task.Title, task.ID, status)
+	if task.Deadline != nil {
+		line += ", deadline: " + task.Deadline.Format("02 Jan 2006 15:04")
+	}
+	if task.DurationMinutes != nil {
+		line += fmt.Sprintf(", durasi: %d menit", *task.DurationMinutes)
+	}
+	if task.Difficulty != nil {
+		line += ", tingkat: " + *task.Difficulty
+	}
+	line += "]\n"
+
+	if task.Description != nil && *task.Description != "" {
+		line += "  Deskripsi: " + *task.Description + "\n"
+	}
+	return line
+}
@@ -0,0 +1,152 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"os"
+	"strings"
+	"sync"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/workradar/server/internal/templates"
+)
+
+// Supported email languages; DefaultEmailLanguage is used when a template is missing
+const (
+	EmailLanguageID      = "id"
+	EmailLanguageEN      = "en"
+	DefaultEmailLanguage = EmailLanguageID
+)
+
+// EmailData is passed to every email template
+type EmailData struct {
+	UserName             string
+	Code                 string
+	Plan                 string
+	Title                string
+	Message              string
+	Device               string
+	IPAddress            string
+	Time                 string
+	ExpiresInMinutes     int
+	BackupCodesRemaining int
+	Year                 int
+}
+
+// RenderedEmail is a rendered email ready to be sent
+type RenderedEmail struct {
+	Subject string
+	HTML    string
+	Text    string
+}
+
+type emailTemplate struct {
+	html *htmltemplate.Template
+	text *texttemplate.Template
+}
+
+// EmailRenderer renders email/<kind>.<lang>.html inside the shared layout and
+// email/<kind>.<lang>.txt, whose "subject" block is the subject line.
+// Parsed templates are cached per kind and language.
+type EmailRenderer struct {
+	fsys  fs.FS
+	mu    sync.Mutex
+	cache map[string]*emailTemplate
+}
+
+// NewEmailRenderer uses the templates embedded in the binary, or the email/
+// directory under dir when set so copy can be edited without a rebuild
+func NewEmailRenderer(dir string) *EmailRenderer {
+	var fsys fs.FS = templates.Email
+	if dir != "" {
+		fsys = os.DirFS(dir)
+	}
+	return NewEmailRendererFS(fsys)
+}
+
+// NewEmailRendererFS renders templates from fsys, which must contain an email/ directory
+func NewEmailRendererFS(fsys fs.FS) *EmailRenderer {
+	return &EmailRenderer{fsys: fsys, cache: make(map[string]*emailTemplate)}
+}
+
+// NormalizeEmailLanguage maps a user language such as "en-US" to a supported email language
+func NormalizeEmailLanguage(lang string) string {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if i := strings.IndexAny(lang, "-_"); i >= 0 {
+		lang = lang[:i]
+	}
+	switch lang {
+	case EmailLanguageID, EmailLanguageEN:
+		return lang
+	default:
+		return DefaultEmailLanguage
+	}
+}
+
+// Render renders the email kind in lang, falling back to the default language
+func (r *EmailRenderer) Render(kind, lang string, data EmailData) (*RenderedEmail, error) {
+	if data.Year == 0 {
+		data.Year = time.Now().Year()
+	}
+
+	tmpl, err := r.load(kind, NormalizeEmailLanguage(lang))
+	if err != nil {
+		return nil, err
+	}
+
+	var subject, text, html bytes.Buffer
+	if err := tmpl.text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return nil, fmt.Errorf("failed to render %s subject: %w", kind, err)
+	}
+	if err := tmpl.text.Execute(&text, data); err != nil {
+		return nil, fmt.Errorf("failed to render %s text: %w", kind, err)
+	}
+	if err := tmpl.html.Execute(&html, data); err != nil {
+		return nil, fmt.Errorf("failed to render %s html: %w", kind, err)
+	}
+
+	return &RenderedEmail{
+		Subject: strings.TrimSpace(subject.String()),
+		HTML:    html.String(),
+		Text:    strings.TrimSpace(text.String()) + "\n",
+	}, nil
+}
+
+func (r *EmailRenderer) load(kind, lang string) (*emailTemplate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := kind + "." + lang
+	if tmpl, ok := r.cache[key]; ok {
+		return tmpl, nil
+	}
+
+	tmpl, err := r.parse(kind, lang)
+	if err != nil && lang != DefaultEmailLanguage {
+		tmpl, err = r.parse(kind, DefaultEmailLanguage)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	r.cache[key] = tmpl
+	return tmpl, nil
+}
+
+func (r *EmailRenderer) parse(kind, lang string) (*emailTemplate, error) {
+	base := fmt.Sprintf("email/%s.%s", kind, lang)
+
+	html, err := htmltemplate.ParseFS(r.fsys, base+".html", "email/layout.html", "email/footer."+lang+".html")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s template: %w", kind, err)
+	}
+	text, err := texttemplate.ParseFS(r.fsys, base+".txt")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s template: %w", kind, err)
+	}
+
+	return &emailTemplate{html: html, text: text}, nil
+}
@@ -1,443 +1,282 @@
 package services
 
 import (
-	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"html/template"
 	"log"
+	"net/mail"
+	"strings"
+	"time"
 
-	"github.com/resend/resend-go/v2"
 	"github.com/workradar/server/internal/config"
+	"github.com/workradar/server/internal/models"
+	"github.com/workradar/server/internal/repository"
+	"github.com/workradar/server/pkg/utils"
 )
 
-// EmailService handles all email sending operations
+// emailCodeTTL is how long password reset and account verification codes stay valid
+const emailCodeTTL = 10 * time.Minute
+
+// EmailService renders emails from templates and sends them through a mail transport.
+// With an outbox and a job queue, emails are stored first and sent by the email job so
+// a provider outage is retried.
 type EmailService struct {
-	resendClient *resend.Client
-	fromEmail    string
-	fromName     string
-	apiKey       string
+	transport  MailTransport
+	renderer   *EmailRenderer
+	outboxRepo *repository.EmailOutboxRepository
+	jobQueue   *JobQueue
+	from       string
 }
 
-// NewEmailService creates a new email service instance
-func NewEmailService() *EmailService {
-	apiKey := config.AppConfig.ResendAPIKey
+// NewEmailService creates a new email service; a nil transport disables sending
+func NewEmailService(
+	transport MailTransport,
+	renderer *EmailRenderer,
+	outboxRepo *repository.EmailOutboxRepository,
+	jobQueue *JobQueue,
+) *EmailService {
+	from := &mail.Address{Name: config.AppConfig.SMTPFromName, Address: config.AppConfig.SMTPFromEmail}
 	return &EmailService{
-		resendClient: resend.NewClient(apiKey),
-		fromEmail:    config.AppConfig.SMTPFromEmail,
-		fromName:     config.AppConfig.SMTPFromName,
-		apiKey:       apiKey,
+		transport:  transport,
+		renderer:   renderer,
+		outboxRepo: outboxRepo,
+		jobQueue:   jobQueue,
+		from:       from.String(),
+	}
+}
+
+// NewMailTransportFromConfig picks the transport from EMAIL_TRANSPORT. When it is empty,
+// Resend is used if RESEND_API_KEY is set; otherwise nil is returned and emails are skipped.
+func NewMailTransportFromConfig() (MailTransport, error) {
+	cfg := config.AppConfig
+	switch cfg.EmailTransport {
+	case EmailTransportResend:
+		if cfg.ResendAPIKey == "" {
+			return nil, errors.New("EMAIL_TRANSPORT=resend requires RESEND_API_KEY")
+		}
+		return NewResendTransport(cfg.ResendAPIKey), nil
+	case EmailTransportSMTP:
+		if cfg.SMTPHost == "" {
+			return nil, errors.New("EMAIL_TRANSPORT=smtp requires SMTP_HOST")
+		}
+		return NewSMTPTransport(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword), nil
+	case EmailTransportFile:
+		return NewFileTransport(cfg.EmailMailboxDir), nil
+	case "":
+		if cfg.ResendAPIKey != "" {
+			return NewResendTransport(cfg.ResendAPIKey), nil
+		}
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown EMAIL_TRANSPORT %q", cfg.EmailTransport)
 	}
 }
 
-// IsConfigured checks if Resend API is properly configured
+// IsConfigured checks if a mail transport is available
 func (s *EmailService) IsConfigured() bool {
-	return s.apiKey != ""
+	return s.transport != nil
 }
 
-// SendPasswordResetCode sends password reset verification code via email (6 digits)
+// SendPasswordResetCode queues the password reset code email (PWD-XXXXXX)
 func (s *EmailService) SendPasswordResetCode(toEmail, code string) error {
-	if !s.IsConfigured() {
-		log.Println("⚠️ Resend API not configured, skipping email send")
-		return nil // Return nil untuk development mode
-	}
-
-	subject := "Workradar - Kode Reset Password"
+	return s.Queue(EmailJob{Kind: EmailKindPasswordReset, To: toEmail, Code: code})
+}
 
-	// HTML email template for PASSWORD RESET
-	htmlTemplate := `
-<!DOCTYPE html>
-<html>
-<head>
-    <meta charset="UTF-8">
-    <meta name="viewport" content="width=device-width, initial-scale=1.0">
-</head>
-<body style="margin: 0; padding: 0; font-family: 'Segoe UI', Tahoma, Geneva, Verdana, sans-serif; background-color: #f5f5f5;">
-    <table width="100%" cellpadding="0" cellspacing="0" style="background-color: #f5f5f5; padding: 40px 0;">
-        <tr>
-            <td align="center">
-                <table width="600" cellpadding="0" cellspacing="0" style="background-color: #ffffff; border-radius: 16px; box-shadow: 0 4px 20px rgba(0,0,0,0.1);">
-                    <!-- Header - RED for Password Reset -->
-                    <tr>
-                        <td style="background: linear-gradient(135deg, #EF4444 0%, #DC2626 100%); padding: 40px; border-radius: 16px 16px 0 0; text-align: center;">
-                            <h1 style="color: #ffffff; margin: 0; font-size: 28px; font-weight: 700;">
-                                🔐 Reset Password
-                            </h1>
-                            <p style="color: rgba(255,255,255,0.9); margin: 10px 0 0 0; font-size: 14px;">
-                                Workradar - Task Management App
-                            </p>
-                        </td>
-                    </tr>
-                    
-                    <!-- Content -->
-                    <tr>
-                        <td style="padding: 40px;">
-                            <h2 style="color: #1f2937; margin: 0 0 20px 0; font-size: 22px;">
-                                Lupa Password?
-                            </h2>
-                            <p style="color: #6b7280; line-height: 1.6; margin: 0 0 30px 0;">
-                                Kami menerima permintaan untuk reset password akun Workradar Anda. 
-                                Gunakan kode 6 digit di bawah ini:
-                            </p>
-                            
-                            <!-- Verification Code Box - 6 DIGITS -->
-                            <div style="background: linear-gradient(135deg, #FEE2E2 0%, #FECACA 100%); border-radius: 12px; padding: 30px; text-align: center; margin: 0 0 30px 0; border: 2px solid #EF4444;">
-                                <p style="color: #DC2626; font-size: 14px; margin: 0 0 10px 0; text-transform: uppercase; letter-spacing: 1px;">
-                                    Kode Reset Password (6 Digit)
-                                </p>
-                                <h1 style="color: #B91C1C; font-size: 48px; letter-spacing: 12px; margin: 0; font-weight: 700; font-family: monospace;">
-                                    {{.Code}}
-                                </h1>
-                            </div>
-                            
-                            <!-- Warning -->
-                            <div style="background-color: #FEF3C7; border-left: 4px solid #F59E0B; padding: 15px; border-radius: 0 8px 8px 0; margin: 0 0 30px 0;">
-                                <p style="color: #92400E; margin: 0; font-size: 14px;">
-                                    ⚠️ <strong>Penting:</strong> Kode ini akan kadaluarsa dalam <strong>2 menit</strong>. 
-                                    Jangan bagikan kode ini kepada siapapun.
-                                </p>
-                            </div>
-                            
-                            <p style="color: #6b7280; line-height: 1.6; margin: 0;">
-                                Jika Anda tidak meminta reset password, abaikan email ini. 
-                                Akun Anda tetap aman.
-                            </p>
-                        </td>
-                    </tr>
-                    
-                    <!-- Footer -->
-                    <tr>
-                        <td style="background-color: #f9fafb; padding: 30px; border-radius: 0 0 16px 16px; text-align: center;">
-                            <p style="color: #9ca3af; font-size: 12px; margin: 0 0 10px 0;">
-                                Email ini dikirim otomatis oleh Workradar. Mohon jangan membalas email ini.
-                            </p>
-                            <p style="color: #9ca3af; font-size: 12px; margin: 0;">
-                                © 2026 Workradar. All rights reserved.
-                            </p>
-                        </td>
-                    </tr>
-                </table>
-            </td>
-        </tr>
-    </table>
-</body>
-</html>
-`
+// SendAccountVerificationCode queues the account verification code email (REG-XXXXXX)
+func (s *EmailService) SendAccountVerificationCode(toEmail, code string) error {
+	return s.Queue(EmailJob{Kind: EmailKindAccountVerification, To: toEmail, Code: code})
+}
 
-	// Parse template
-	tmpl, err := template.New("email").Parse(htmlTemplate)
-	if err != nil {
-		return fmt.Errorf("failed to parse email template: %w", err)
+// SendVerificationCode is deprecated, use SendPasswordResetCode or SendAccountVerificationCode
+func (s *EmailService) SendVerificationCode(toEmail, code string) error {
+	// For backward compatibility, determine type by code prefix
+	if strings.HasPrefix(code, utils.OTPTypeRegistration+"-") {
+		return s.SendAccountVerificationCode(toEmail, code)
 	}
+	return s.SendPasswordResetCode(toEmail, code)
+}
 
-	// Execute template with data
-	var body bytes.Buffer
-	data := struct {
-		Code string
-	}{
-		Code: code,
-	}
-	if err := tmpl.Execute(&body, data); err != nil {
-		return fmt.Errorf("failed to execute email template: %w", err)
-	}
+// SendWelcomeEmail queues the welcome email for new users
+func (s *EmailService) SendWelcomeEmail(toEmail, userName string) error {
+	return s.Queue(EmailJob{Kind: EmailKindWelcome, To: toEmail, UserName: userName})
+}
 
-	// Send email via Resend
-	return s.sendViaResend(toEmail, subject, body.String())
+// SendVIPUpgradeEmail queues the confirmation email after VIP upgrade
+func (s *EmailService) SendVIPUpgradeEmail(toEmail, userName, plan string) error {
+	return s.Queue(EmailJob{Kind: EmailKindVIPUpgrade, To: toEmail, UserName: userName, Plan: plan})
 }
 
-// SendAccountVerificationCode sends account verification code via email (4 digits)
-func (s *EmailService) SendAccountVerificationCode(toEmail, code string) error {
-	if !s.IsConfigured() {
-		log.Println("⚠️ Resend API not configured, skipping email send")
-		return nil // Return nil untuk development mode
-	}
+// SendNotificationEmail queues a user notification routed to the email channel
+func (s *EmailService) SendNotificationEmail(toEmail, userName, title, message string) error {
+	return s.Queue(EmailJob{Kind: EmailKindNotification, To: toEmail, UserName: userName, Subject: title, Body: message})
+}
 
-	subject := "Workradar - Verifikasi Akun Anda"
+// Email kinds; each kind has email/<kind>.<lang>.html and .txt templates
+const (
+	EmailKindPasswordReset       = "password_reset"
+	EmailKindAccountVerification = "account_verification"
+	EmailKindWelcome             = "welcome"
+	EmailKindVIPUpgrade          = "vip_upgrade"
+	EmailKindNotification        = "notification"
+	EmailKindRefreshTokenReuse   = "refresh_token_reuse"
+	EmailKindBackupCodeUsed      = "backup_code_used"
+)
 
-	// HTML email template for ACCOUNT VERIFICATION
-	htmlTemplate := `
-<!DOCTYPE html>
-<html>
-<head>
-    <meta charset="UTF-8">
-    <meta name="viewport" content="width=device-width, initial-scale=1.0">
-</head>
-<body style="margin: 0; padding: 0; font-family: 'Segoe UI', Tahoma, Geneva, Verdana, sans-serif; background-color: #f5f5f5;">
-    <table width="100%" cellpadding="0" cellspacing="0" style="background-color: #f5f5f5; padding: 40px 0;">
-        <tr>
-            <td align="center">
-                <table width="600" cellpadding="0" cellspacing="0" style="background-color: #ffffff; border-radius: 16px; box-shadow: 0 4px 20px rgba(0,0,0,0.1);">
-                    <!-- Header - PURPLE for Account Verification -->
-                    <tr>
-                        <td style="background: linear-gradient(135deg, #6366F1 0%, #8B5CF6 100%); padding: 40px; border-radius: 16px 16px 0 0; text-align: center;">
-                            <h1 style="color: #ffffff; margin: 0; font-size: 28px; font-weight: 700;">
-                                ✉️ Verifikasi Email
-                            </h1>
-                            <p style="color: rgba(255,255,255,0.9); margin: 10px 0 0 0; font-size: 14px;">
-                                Workradar - Task Management App
-                            </p>
-                        </td>
-                    </tr>
-                    
-                    <!-- Content -->
-                    <tr>
-                        <td style="padding: 40px;">
-                            <h2 style="color: #1f2937; margin: 0 0 20px 0; font-size: 22px;">
-                                Selamat Datang! 🎉
-                            </h2>
-                            <p style="color: #6b7280; line-height: 1.6; margin: 0 0 30px 0;">
-                                Terima kasih telah mendaftar di Workradar! 
-                                Masukkan kode 4 digit di bawah ini untuk memverifikasi email Anda:
-                            </p>
-                            
-                            <!-- Verification Code Box - 4 DIGITS -->
-                            <div style="background: linear-gradient(135deg, #EEF2FF 0%, #E0E7FF 100%); border-radius: 12px; padding: 30px; text-align: center; margin: 0 0 30px 0; border: 2px solid #6366F1;">
-                                <p style="color: #6366F1; font-size: 14px; margin: 0 0 10px 0; text-transform: uppercase; letter-spacing: 1px;">
-                                    Kode Verifikasi (4 Digit)
-                                </p>
-                                <h1 style="color: #4F46E5; font-size: 56px; letter-spacing: 16px; margin: 0; font-weight: 700; font-family: monospace;">
-                                    {{.Code}}
-                                </h1>
-                            </div>
-                            
-                            <!-- Warning -->
-                            <div style="background-color: #FEF3C7; border-left: 4px solid #F59E0B; padding: 15px; border-radius: 0 8px 8px 0; margin: 0 0 30px 0;">
-                                <p style="color: #92400E; margin: 0; font-size: 14px;">
-                                    ⚠️ <strong>Penting:</strong> Kode ini akan kadaluarsa dalam <strong>2 menit</strong>. 
-                                    Jangan bagikan kode ini kepada siapapun.
-                                </p>
-                            </div>
-                            
-                            <p style="color: #6b7280; line-height: 1.6; margin: 0;">
-                                Jika Anda tidak membuat akun di Workradar, abaikan email ini.
-                            </p>
-                        </td>
-                    </tr>
-                    
-                    <!-- Footer -->
-                    <tr>
-                        <td style="background-color: #f9fafb; padding: 30px; border-radius: 0 0 16px 16px; text-align: center;">
-                            <p style="color: #9ca3af; font-size: 12px; margin: 0 0 10px 0;">
-                                Email ini dikirim otomatis oleh Workradar. Mohon jangan membalas email ini.
-                            </p>
-                            <p style="color: #9ca3af; font-size: 12px; margin: 0;">
-                                © 2026 Workradar. All rights reserved.
-                            </p>
-                        </td>
-                    </tr>
-                </table>
-            </td>
-        </tr>
-    </table>
-</body>
-</html>
-`
+// EmailJob describes an email to render and send
+type EmailJob struct {
+	Kind     string `json:"kind"`
+	To       string `json:"to"`
+	Lang     string `json:"lang,omitempty"`
+	Code     string `json:"code,omitempty"`
+	UserName string `json:"user_name,omitempty"`
+	Plan     string `json:"plan,omitempty"`
+	Subject  string `json:"subject,omitempty"`
+	Body     string `json:"body,omitempty"`
+	// Security alerts: the device and request that triggered them
+	Device    string `json:"device,omitempty"`
+	IPAddress string `json:"ip_address,omitempty"`
+	Time      string `json:"time,omitempty"`
+	// Backup code alerts: how many unused codes are left
+	BackupCodesRemaining int `json:"backup_codes_remaining,omitempty"`
+}
 
-	// Parse template
-	tmpl, err := template.New("verification").Parse(htmlTemplate)
-	if err != nil {
-		return fmt.Errorf("failed to parse email template: %w", err)
+// UniqueKey identifies emails carrying a code so the same code is not queued twice;
+// other emails have no key and are queued every time
+func (j EmailJob) UniqueKey() string {
+	if j.Code == "" {
+		return ""
 	}
+	return fmt.Sprintf("email:%s:%s:%s", j.Kind, j.To, j.Code)
+}
 
-	// Execute template with data
-	var body bytes.Buffer
-	data := struct {
-		Code string
-	}{
-		Code: code,
+func (j EmailJob) data() EmailData {
+	return EmailData{
+		UserName:         j.UserName,
+		Code:             j.Code,
+		Plan:             j.Plan,
+		Title:            j.Subject,
+		Message:          j.Body,
+		Device:           j.Device,
+		IPAddress:        j.IPAddress,
+		Time:             j.Time,
+		ExpiresInMinutes: int(emailCodeTTL / time.Minute),
 	}
-	if err := tmpl.Execute(&body, data); err != nil {
-		return fmt.Errorf("failed to execute email template: %w", err)
-	}
-
-	// Send email via Resend
-	return s.sendViaResend(toEmail, subject, body.String())
 }
 
-// SendVerificationCode - DEPRECATED: Use SendPasswordResetCode or SendAccountVerificationCode instead
-func (s *EmailService) SendVerificationCode(toEmail, code string) error {
-	// For backward compatibility, determine type by code length
-	if len(code) == 4 {
-		return s.SendAccountVerificationCode(toEmail, code)
-	}
-	return s.SendPasswordResetCode(toEmail, code)
+// emailJobPayload is the payload of JobTypeEmail; jobs queued before the outbox carry
+// the EmailJob itself
+type emailJobPayload struct {
+	OutboxID string `json:"outbox_id"`
+	EmailJob
 }
 
-// SendWelcomeEmail sends welcome email to new users
-func (s *EmailService) SendWelcomeEmail(toEmail, userName string) error {
+// Queue stores the rendered email in the outbox and leaves sending to the email job.
+// Without an outbox or a job queue the email is sent right away.
+func (s *EmailService) Queue(job EmailJob) error {
 	if !s.IsConfigured() {
-		log.Println("⚠️ Resend API not configured, skipping welcome email")
-		return nil
+		log.Printf("⚠️ Email transport not configured, skipping %s email", job.Kind)
+		return nil // Return nil untuk development mode
+	}
+	if s.outboxRepo == nil || s.jobQueue == nil {
+		return s.Send(job)
 	}
 
-	subject := "Selamat Datang di Workradar! 🎉"
-
-	htmlTemplate := `
-<!DOCTYPE html>
-<html>
-<head>
-    <meta charset="UTF-8">
-</head>
-<body style="margin: 0; padding: 0; font-family: 'Segoe UI', Tahoma, Geneva, Verdana, sans-serif; background-color: #f5f5f5;">
-    <table width="100%" cellpadding="0" cellspacing="0" style="background-color: #f5f5f5; padding: 40px 0;">
-        <tr>
-            <td align="center">
-                <table width="600" cellpadding="0" cellspacing="0" style="background-color: #ffffff; border-radius: 16px; box-shadow: 0 4px 20px rgba(0,0,0,0.1);">
-                    <tr>
-                        <td style="background: linear-gradient(135deg, #6366F1 0%, #8B5CF6 100%); padding: 40px; border-radius: 16px 16px 0 0; text-align: center;">
-                            <h1 style="color: #ffffff; margin: 0; font-size: 28px;">🎉 Selamat Datang!</h1>
-                        </td>
-                    </tr>
-                    <tr>
-                        <td style="padding: 40px;">
-                            <h2 style="color: #1f2937; margin: 0 0 20px 0;">Halo, {{.UserName}}!</h2>
-                            <p style="color: #6b7280; line-height: 1.6;">
-                                Terima kasih telah bergabung dengan <strong>Workradar</strong>. 
-                                Kami senang Anda memilih kami untuk membantu meningkatkan produktivitas Anda!
-                            </p>
-                            <p style="color: #6b7280; line-height: 1.6;">
-                                Mulai sekarang, Anda dapat:
-                            </p>
-                            <ul style="color: #6b7280; line-height: 1.8;">
-                                <li>📋 Membuat dan mengelola tugas harian</li>
-                                <li>📊 Memantau beban kerja dengan grafik</li>
-                                <li>🔔 Menerima pengingat otomatis</li>
-                                <li>📅 Mengatur kalender personal</li>
-                            </ul>
-                            <p style="color: #6b7280; line-height: 1.6; margin-top: 30px;">
-                                Selamat berproduktivitas! 💪
-                            </p>
-                        </td>
-                    </tr>
-                    <tr>
-                        <td style="background-color: #f9fafb; padding: 30px; border-radius: 0 0 16px 16px; text-align: center;">
-                            <p style="color: #9ca3af; font-size: 12px; margin: 0;">
-                                © 2026 Workradar. All rights reserved.
-                            </p>
-                        </td>
-                    </tr>
-                </table>
-            </td>
-        </tr>
-    </table>
-</body>
-</html>
-`
-
-	tmpl, err := template.New("welcome").Parse(htmlTemplate)
+	rendered, err := s.renderer.Render(job.Kind, job.Lang, job.data())
 	if err != nil {
-		return fmt.Errorf("failed to parse welcome template: %w", err)
+		return err
+	}
+
+	email := &models.EmailOutbox{
+		Kind:    job.Kind,
+		To:      job.To,
+		Lang:    NormalizeEmailLanguage(job.Lang),
+		Subject: rendered.Subject,
+		HTML:    rendered.HTML,
+		Text:    rendered.Text,
+	}
+	if key := job.UniqueKey(); key != "" {
+		email.UniqueKey = &key
+	}
+	if job.Code != "" {
+		expiresAt := time.Now().Add(emailCodeTTL)
+		email.ExpiresAt = &expiresAt
 	}
 
-	var body bytes.Buffer
-	data := struct {
-		UserName string
-	}{
-		UserName: userName,
+	created, err := s.outboxRepo.Create(email)
+	if err != nil {
+		return fmt.Errorf("failed to store email: %w", err)
 	}
-	if err := tmpl.Execute(&body, data); err != nil {
-		return fmt.Errorf("failed to execute welcome template: %w", err)
+	if !created {
+		return nil // Already queued
 	}
 
-	return s.sendViaResend(toEmail, subject, body.String())
+	_, err = s.jobQueue.Enqueue(JobTypeEmail, emailJobPayload{OutboxID: email.ID}, JobOptions{UniqueKey: "email:" + email.ID})
+	return err
 }
 
-// SendVIPUpgradeEmail sends confirmation email after VIP upgrade
-func (s *EmailService) SendVIPUpgradeEmail(toEmail, userName, plan string) error {
+// Send renders and sends the email immediately, bypassing the outbox
+func (s *EmailService) Send(job EmailJob) error {
 	if !s.IsConfigured() {
-		log.Println("⚠️ Resend API not configured, skipping VIP upgrade email")
-		return nil
+		log.Printf("⚠️ Email transport not configured, skipping %s email", job.Kind)
+		return nil // Return nil untuk development mode
 	}
 
-	subject := "Selamat! Anda sekarang VIP Member 👑"
-
-	htmlTemplate := `
-<!DOCTYPE html>
-<html>
-<head>
-    <meta charset="UTF-8">
-</head>
-<body style="margin: 0; padding: 0; font-family: 'Segoe UI', Tahoma, Geneva, Verdana, sans-serif; background-color: #f5f5f5;">
-    <table width="100%" cellpadding="0" cellspacing="0" style="background-color: #f5f5f5; padding: 40px 0;">
-        <tr>
-            <td align="center">
-                <table width="600" cellpadding="0" cellspacing="0" style="background-color: #ffffff; border-radius: 16px; box-shadow: 0 4px 20px rgba(0,0,0,0.1);">
-                    <tr>
-                        <td style="background: linear-gradient(135deg, #F59E0B 0%, #D97706 100%); padding: 40px; border-radius: 16px 16px 0 0; text-align: center;">
-                            <h1 style="color: #ffffff; margin: 0; font-size: 28px;">👑 VIP Member</h1>
-                        </td>
-                    </tr>
-                    <tr>
-                        <td style="padding: 40px;">
-                            <h2 style="color: #1f2937; margin: 0 0 20px 0;">Halo, {{.UserName}}!</h2>
-                            <p style="color: #6b7280; line-height: 1.6;">
-                                Selamat! Akun Anda telah berhasil di-upgrade ke <strong>VIP Member</strong> 
-                                dengan paket <strong>{{.Plan}}</strong>.
-                            </p>
-                            <p style="color: #6b7280; line-height: 1.6;">
-                                Sekarang Anda dapat menikmati fitur eksklusif:
-                            </p>
-                            <ul style="color: #6b7280; line-height: 1.8;">
-                                <li>📊 Grafik Mingguan & Bulanan</li>
-                                <li>🔄 Pengaturan Repeat Task dengan End Date</li>
-                                <li>⏰ Pilihan Reminder Fleksibel (5/10/15/30 menit)</li>
-                                <li>☀️ Integrasi Cuaca</li>
-                                <li>💪 Rekomendasi Kesehatan</li>
-                            </ul>
-                            <p style="color: #6b7280; line-height: 1.6; margin-top: 30px;">
-                                Terima kasih atas dukungan Anda! 🙏
-                            </p>
-                        </td>
-                    </tr>
-                    <tr>
-                        <td style="background-color: #f9fafb; padding: 30px; border-radius: 0 0 16px 16px; text-align: center;">
-                            <p style="color: #9ca3af; font-size: 12px; margin: 0;">
-                                © 2026 Workradar. All rights reserved.
-                            </p>
-                        </td>
-                    </tr>
-                </table>
-            </td>
-        </tr>
-    </table>
-</body>
-</html>
-`
-
-	tmpl, err := template.New("vip").Parse(htmlTemplate)
+	rendered, err := s.renderer.Render(job.Kind, job.Lang, job.data())
 	if err != nil {
-		return fmt.Errorf("failed to parse VIP template: %w", err)
+		return err
 	}
+	return s.deliver(job.To, rendered)
+}
 
-	var body bytes.Buffer
-	data := struct {
-		UserName string
-		Plan     string
-	}{
-		UserName: userName,
-		Plan:     plan,
+// HandleJob is the job queue handler for JobTypeEmail
+func (s *EmailService) HandleJob(ctx context.Context, payload []byte) error {
+	var p emailJobPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return PermanentJobError(err)
+	}
+	if !s.IsConfigured() {
+		return PermanentJobError(errors.New("email transport not configured"))
 	}
-	if err := tmpl.Execute(&body, data); err != nil {
-		return fmt.Errorf("failed to execute VIP template: %w", err)
+	if p.OutboxID == "" {
+		return s.Send(p.EmailJob)
 	}
 
-	return s.sendViaResend(toEmail, subject, body.String())
-}
+	email, err := s.outboxRepo.FindByID(p.OutboxID)
+	if err != nil {
+		return err
+	}
+	if email.Status == models.EmailOutboxSent {
+		return nil
+	}
+	// A retry after the code expired would only deliver a code that no longer works
+	if email.ExpiresAt != nil && time.Now().After(*email.ExpiresAt) {
+		log.Printf("⚠️ Outbox email %s expired before delivery", email.ID)
+		return s.outboxRepo.MarkExpired(email.ID)
+	}
 
-// sendViaResend sends an HTML email using Resend API
-func (s *EmailService) sendViaResend(to, subject, htmlBody string) error {
-	req := &resend.SendEmailRequest{
-		From:    fmt.Sprintf("%s <%s>", s.fromName, s.fromEmail),
-		To:      []string{to},
-		Subject: subject,
-		Html:    htmlBody,
+	err = s.deliver(email.To, &RenderedEmail{Subject: email.Subject, HTML: email.HTML, Text: email.Text})
+	if err != nil {
+		if markErr := s.outboxRepo.MarkFailed(email.ID, err.Error()); markErr != nil {
+			log.Printf("⚠️ Failed to update outbox email %s: %v", email.ID, markErr)
+		}
+		return err
 	}
+	return s.outboxRepo.MarkSent(email.ID, time.Now())
+}
 
-	sent, err := s.resendClient.Emails.Send(req)
+func (s *EmailService) deliver(to string, rendered *RenderedEmail) error {
+	err := s.transport.Send(MailMessage{
+		From:    s.from,
+		To:      to,
+		Subject: rendered.Subject,
+		HTML:    rendered.HTML,
+		Text:    rendered.Text,
+	})
 	if err != nil {
-		log.Printf("❌ Failed to send email to %s via Resend: %v", to, err)
+		log.Printf("❌ Failed to send email to %s: %v", to, err)
 		return fmt.Errorf("failed to send email: %w", err)
 	}
-
-	log.Printf("✅ Email sent successfully to %s (ID: %s)", to, sent.Id)
 	return nil
 }
@@ -0,0 +1,218 @@
+package services
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"log"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/resend/resend-go/v2"
+)
+
+// Supported mail transports (EMAIL_TRANSPORT)
+const (
+	EmailTransportResend = "resend"
+	EmailTransportSMTP   = "smtp"
+	EmailTransportFile   = "file"
+)
+
+// MailMessage is one outgoing email with an HTML and a plain text body
+type MailMessage struct {
+	From    string
+	To      string
+	Subject string
+	HTML    string
+	Text    string
+}
+
+// MailTransport hands a message to a mail provider
+type MailTransport interface {
+	Send(msg MailMessage) error
+}
+
+// ResendTransport sends email through the Resend API
+type ResendTransport struct {
+	client *resend.Client
+}
+
+func NewResendTransport(apiKey string) *ResendTransport {
+	return &ResendTransport{client: resend.NewClient(apiKey)}
+}
+
+func (t *ResendTransport) Send(msg MailMessage) error {
+	sent, err := t.client.Emails.Send(&resend.SendEmailRequest{
+		From:    msg.From,
+		To:      []string{msg.To},
+		Subject: msg.Subject,
+		Html:    msg.HTML,
+		Text:    msg.Text,
+	})
+	if err != nil {
+		return fmt.Errorf("resend: %w", err)
+	}
+	log.Printf("✅ Email sent to %s via Resend (ID: %s)", msg.To, sent.Id)
+	return nil
+}
+
+// SMTPTransport sends email through an SMTP relay. Port 465 uses implicit TLS; other
+// ports upgrade with STARTTLS when the server offers it.
+type SMTPTransport struct {
+	host     string
+	port     string
+	username string
+	password string
+}
+
+func NewSMTPTransport(host, port, username, password string) *SMTPTransport {
+	return &SMTPTransport{host: host, port: port, username: username, password: password}
+}
+
+func (t *SMTPTransport) Send(msg MailMessage) error {
+	from, err := mail.ParseAddress(msg.From)
+	if err != nil {
+		return fmt.Errorf("smtp: invalid sender: %w", err)
+	}
+	body, err := BuildMIMEMessage(msg, time.Now())
+	if err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if t.username != "" {
+		auth = smtp.PlainAuth("", t.username, t.password, t.host)
+	}
+
+	addr := net.JoinHostPort(t.host, t.port)
+	if t.port == "465" {
+		err = t.sendImplicitTLS(addr, auth, from.Address, msg.To, body)
+	} else {
+		err = smtp.SendMail(addr, auth, from.Address, []string{msg.To}, body)
+	}
+	if err != nil {
+		return fmt.Errorf("smtp: %w", err)
+	}
+	log.Printf("✅ Email sent to %s via SMTP", msg.To)
+	return nil
+}
+
+func (t *SMTPTransport) sendImplicitTLS(addr string, auth smtp.Auth, from, to string, body []byte) error {
+	conn, err := tls.Dial("tcp", addr, &tls.Config{ServerName: t.host})
+	if err != nil {
+		return err
+	}
+	client, err := smtp.NewClient(conn, t.host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if auth != nil {
+		if err := client.Auth(auth); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(from); err != nil {
+		return err
+	}
+	if err := client.Rcpt(to); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(body); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// FileTransport writes every email as an .eml file into a local mailbox directory instead
+// of sending it (development and tests only)
+type FileTransport struct {
+	dir string
+}
+
+func NewFileTransport(dir string) *FileTransport {
+	return &FileTransport{dir: dir}
+}
+
+func (t *FileTransport) Send(msg MailMessage) error {
+	now := time.Now()
+	body, err := BuildMIMEMessage(msg, now)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(t.dir, 0o755); err != nil {
+		return fmt.Errorf("mailbox: %w", err)
+	}
+
+	name := fmt.Sprintf("%s-%s.eml", now.Format("20060102T150405"), uuid.New().String()[:8])
+	if err := os.WriteFile(filepath.Join(t.dir, name), body, 0o644); err != nil {
+		return fmt.Errorf("mailbox: %w", err)
+	}
+	log.Printf("📬 Email to %s saved to %s", msg.To, filepath.Join(t.dir, name))
+	return nil
+}
+
+// BuildMIMEMessage encodes msg as a multipart/alternative message with quoted-printable
+// text and HTML parts
+func BuildMIMEMessage(msg MailMessage, date time.Time) ([]byte, error) {
+	var parts bytes.Buffer
+	mw := multipart.NewWriter(&parts)
+
+	for _, part := range []struct{ contentType, body string }{
+		{"text/plain; charset=UTF-8", msg.Text},
+		{"text/html; charset=UTF-8", msg.HTML},
+	} {
+		w, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write([]byte(part.body)); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	headers := []struct{ key, value string }{
+		{"From", msg.From},
+		{"To", msg.To},
+		{"Subject", mime.QEncoding.Encode("UTF-8", msg.Subject)},
+		{"Date", date.Format(time.RFC1123Z)},
+		{"Message-ID", fmt.Sprintf("<%s@workradar>", uuid.New().String())},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", fmt.Sprintf("multipart/alternative; boundary=%q", mw.Boundary())},
+	}
+	for _, h := range headers {
+		fmt.Fprintf(&buf, "%s: %s\r\n", h.key, h.value)
+	}
+	buf.WriteString("\r\n")
+	buf.Write(parts.Bytes())
+	return buf.Bytes(), nil
+}
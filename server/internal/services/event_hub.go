@@ -0,0 +1,362 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/workradar/server/internal/models"
+	"github.com/workradar/server/internal/repository"
+)
+
+// Real-time event types
+const (
+	EventTaskCreated         = "task.created"
+	EventTaskUpdated         = "task.updated"
+	EventTaskDeleted         = "task.deleted"
+	EventTaskScheduled       = "task.scheduled"
+	EventMessageCreated      = "message.created"
+	EventPaymentUpdated      = "payment.updated"
+	EventNotificationCreated = "notification.created"
+)
+
+// Supported event brokers (EVENT_BROKER)
+const (
+	EventBrokerMemory   = "memory"
+	EventBrokerDatabase = "database"
+)
+
+const (
+	// maxStreamsPerUser limits concurrent event streams (devices, tabs) of one user
+	maxStreamsPerUser = 5
+	// eventBufferSize is how many events a slow stream may lag behind before events are dropped
+	eventBufferSize = 64
+	// eventRetention is how long the database broker keeps events for lagging replicas
+	eventRetention = time.Hour
+	// eventBatchSize is how many events the database broker reads per poll
+	eventBatchSize = 500
+	// eventLookback is how long the database broker keeps re-reading events below its cursor,
+	// which catches inserts that committed after a higher ID was already read
+	eventLookback = 10 * time.Second
+)
+
+var (
+	ErrTooManyStreams = errors.New("too many open event streams")
+	ErrEventHubClosed = errors.New("event hub is closed")
+)
+
+// Event is pushed to every open stream of a user
+type Event struct {
+	ID     string          `json:"id"`
+	UserID string          `json:"-"`
+	Type   string          `json:"type"`
+	Data   json.RawMessage `json:"data"`
+	At     time.Time       `json:"at"`
+}
+
+// EventBroker relays events to every server replica, including the publishing one
+type EventBroker interface {
+	Publish(event Event) error
+	// Subscribe sets the callback for all events; call it once before publishing
+	Subscribe(deliver func(Event))
+	// Close stops the broker; the callback is not called once Close has returned
+	Close() error
+}
+
+// EventSubscription is one open stream of a user
+type EventSubscription struct {
+	UserID string
+	C      <-chan Event
+	ch     chan Event
+}
+
+// EventHub fans events out to the open streams of each user. Events go through the
+// broker so users connected to another replica receive them too.
+type EventHub struct {
+	broker EventBroker
+	mu     sync.RWMutex
+	subs   map[string]map[*EventSubscription]struct{}
+	closed bool
+}
+
+func NewEventHub(broker EventBroker) *EventHub {
+	h := &EventHub{
+		broker: broker,
+		subs:   make(map[string]map[*EventSubscription]struct{}),
+	}
+	broker.Subscribe(h.dispatch)
+	return h
+}
+
+// Publish sends an event to all streams of the user. Failures are logged, never returned:
+// real-time updates are best effort and clients re-fetch on reconnect. A nil hub is a no-op.
+func (h *EventHub) Publish(userID, eventType string, data interface{}) {
+	if h == nil || userID == "" {
+		return
+	}
+
+	body, err := json.Marshal(data)
+	if err != nil {
+		log.Printf("⚠️ Failed to encode %s event: %v", eventType, err)
+		return
+	}
+
+	event := Event{
+		ID:     uuid.New().String(),
+		UserID: userID,
+		Type:   eventType,
+		Data:   body,
+		At:     time.Now(),
+	}
+	if err := h.broker.Publish(event); err != nil {
+		log.Printf("⚠️ Failed to publish %s event for user %s: %v", eventType, userID, err)
+	}
+}
+
+// Subscribe opens a stream for the user
+func (h *EventHub) Subscribe(userID string) (*EventSubscription, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return nil, ErrEventHubClosed
+	}
+	if len(h.subs[userID]) >= maxStreamsPerUser {
+		return nil, ErrTooManyStreams
+	}
+
+	ch := make(chan Event, eventBufferSize)
+	sub := &EventSubscription{UserID: userID, C: ch, ch: ch}
+	if h.subs[userID] == nil {
+		h.subs[userID] = make(map[*EventSubscription]struct{})
+	}
+	h.subs[userID][sub] = struct{}{}
+	return sub, nil
+}
+
+// Unsubscribe closes a stream; safe to call more than once
+func (h *EventHub) Unsubscribe(sub *EventSubscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	streams := h.subs[sub.UserID]
+	if _, ok := streams[sub]; !ok {
+		return
+	}
+	delete(streams, sub)
+	if len(streams) == 0 {
+		delete(h.subs, sub.UserID)
+	}
+	close(sub.ch)
+}
+
+// StreamCount returns the number of open streams on this replica
+func (h *EventHub) StreamCount() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	count := 0
+	for _, streams := range h.subs {
+		count += len(streams)
+	}
+	return count
+}
+
+// Close stops the broker, then closes every open stream. Streams are closed only after the
+// broker has stopped, so no delivery can race with closing their channels.
+func (h *EventHub) Close() error {
+	err := h.broker.Close()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return err
+	}
+	h.closed = true
+	for userID, streams := range h.subs {
+		for sub := range streams {
+			close(sub.ch)
+		}
+		delete(h.subs, userID)
+	}
+	return err
+}
+
+// dispatch hands an event from the broker to the user's local streams without blocking;
+// a stream whose buffer is full misses the event
+func (h *EventHub) dispatch(event Event) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if h.closed {
+		return
+	}
+	for sub := range h.subs[event.UserID] {
+		select {
+		case sub.ch <- event:
+		default:
+			log.Printf("⚠️ Event stream of user %s is lagging, dropped %s", event.UserID, event.Type)
+		}
+	}
+}
+
+// MemoryEventBroker delivers events within this process; use it with a single replica
+type MemoryEventBroker struct {
+	mu      sync.RWMutex
+	deliver func(Event)
+}
+
+func NewMemoryEventBroker() *MemoryEventBroker {
+	return &MemoryEventBroker{}
+}
+
+// Publish delivers synchronously; the hub's dispatch never blocks
+func (b *MemoryEventBroker) Publish(event Event) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if b.deliver != nil {
+		b.deliver(event)
+	}
+	return nil
+}
+
+func (b *MemoryEventBroker) Subscribe(deliver func(Event)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.deliver = deliver
+}
+
+func (b *MemoryEventBroker) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.deliver = nil
+	return nil
+}
+
+// DatabaseEventBroker relays events between replicas through the realtime_events table:
+// Publish inserts a row and every replica polls for rows newer than the last one it read.
+// It needs no extra infrastructure at the cost of up to one poll interval of latency.
+//
+// IDs are assigned on insert but become visible on commit, so a row may appear below the
+// cursor after a higher ID was read. Each poll therefore also re-reads the rows created in
+// the last eventLookback and delivers those it has not seen, by EventID. A row committing
+// later than that, or stamped by a replica whose clock is off by more, is missed; clients
+// re-fetch on reconnect as for any other dropped event.
+type DatabaseEventBroker struct {
+	repo     *repository.RealtimeEventRepository
+	interval time.Duration
+	stopChan chan struct{}
+	wg       sync.WaitGroup
+	once     sync.Once
+}
+
+func NewDatabaseEventBroker(repo *repository.RealtimeEventRepository, interval time.Duration) *DatabaseEventBroker {
+	if interval <= 0 {
+		interval = time.Second
+	}
+	return &DatabaseEventBroker{
+		repo:     repo,
+		interval: interval,
+		stopChan: make(chan struct{}),
+	}
+}
+
+func (b *DatabaseEventBroker) Publish(event Event) error {
+	return b.repo.Create(&models.RealtimeEvent{
+		EventID:   event.ID,
+		UserID:    event.UserID,
+		Type:      event.Type,
+		Data:      string(event.Data),
+		CreatedAt: event.At,
+	})
+}
+
+// Subscribe starts polling; only events published after this call are delivered
+func (b *DatabaseEventBroker) Subscribe(deliver func(Event)) {
+	lastID, err := b.repo.LatestID()
+	if err != nil {
+		log.Printf("⚠️ Failed to read latest event ID: %v", err)
+	}
+
+	b.wg.Add(1)
+	go b.poll(lastID, time.Now(), deliver)
+}
+
+func (b *DatabaseEventBroker) Close() error {
+	b.once.Do(func() { close(b.stopChan) })
+	b.wg.Wait()
+	return nil
+}
+
+func (b *DatabaseEventBroker) poll(lastID uint64, started time.Time, deliver func(Event)) {
+	defer b.wg.Done()
+
+	// seen holds the EventIDs delivered within the lookback window, with their creation time
+	seen := make(map[string]time.Time)
+	deliverOnce := func(e models.RealtimeEvent, since time.Time) {
+		if _, ok := seen[e.EventID]; ok {
+			return
+		}
+		if !e.CreatedAt.Before(since) {
+			seen[e.EventID] = e.CreatedAt
+		}
+		deliver(Event{
+			ID:     e.EventID,
+			UserID: e.UserID,
+			Type:   e.Type,
+			Data:   json.RawMessage(e.Data),
+			At:     e.CreatedAt,
+		})
+	}
+
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+
+	cleanup := time.NewTicker(eventRetention)
+	defer cleanup.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			// Events from before Subscribe are not delivered, late or not
+			since := time.Now().Add(-eventLookback)
+			if since.Before(started) {
+				since = started
+			}
+			for id, at := range seen {
+				if at.Before(since) {
+					delete(seen, id)
+				}
+			}
+
+			late, err := b.repo.FindLate(lastID, since)
+			if err != nil {
+				log.Printf("❌ Failed to poll events: %v", err)
+				continue
+			}
+			for _, e := range late {
+				deliverOnce(e, since)
+			}
+
+			events, err := b.repo.FindAfter(lastID, eventBatchSize)
+			if err != nil {
+				log.Printf("❌ Failed to poll events: %v", err)
+				continue
+			}
+			for _, e := range events {
+				deliverOnce(e, since)
+				lastID = e.ID
+			}
+		case <-cleanup.C:
+			if _, err := b.repo.DeleteBefore(time.Now().Add(-eventRetention)); err != nil {
+				log.Printf("❌ Failed to clean up events: %v", err)
+			}
+		case <-b.stopChan:
+			return
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package services
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/workradar/server/internal/models"
+)
+
+// fakeGatewayServerKey signs notifications produced by the fake gateway
+const fakeGatewayServerKey = "fake-server-key"
+
+// FakePaymentGateway is an in-process gateway used for tests and local development.
+// Checkouts are recorded in memory and payments are completed via Simulate instead of
+// a real payment page, so the whole upgrade flow runs without network access.
+type FakePaymentGateway struct {
+	mu           sync.RWMutex
+	transactions map[string]*GatewayTransactionStatus
+	checkErr     error
+}
+
+// NewFakePaymentGateway creates an empty fake gateway
+func NewFakePaymentGateway() *FakePaymentGateway {
+	return &FakePaymentGateway{
+		transactions: make(map[string]*GatewayTransactionStatus),
+	}
+}
+
+// Name returns the provider identifier
+func (f *FakePaymentGateway) Name() string {
+	return PaymentGatewayFake
+}
+
+// CreateCheckout records a pending transaction and returns a fake Snap token
+func (f *FakePaymentGateway) CreateCheckout(req CheckoutRequest) (*CheckoutSession, error) {
+	if req.OrderID == "" || req.Amount <= 0 {
+		return nil, errors.New("invalid checkout request")
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.transactions[req.OrderID] = &GatewayTransactionStatus{
+		OrderID:           req.OrderID,
+		TransactionStatus: "pending",
+		GrossAmount:       formatGrossAmount(req.Amount),
+	}
+
+	return &CheckoutSession{
+		Token:       "fake-" + uuid.New().String(),
+		RedirectURL: "/api/payments/simulate/" + req.OrderID,
+	}, nil
+}
+
+// SetTransactionStatus records the state the gateway will report for an order
+func (f *FakePaymentGateway) SetTransactionStatus(orderID, transactionStatus, fraudStatus, paymentType string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	grossAmount := ""
+	if existing, exists := f.transactions[orderID]; exists {
+		grossAmount = existing.GrossAmount
+	}
+
+	f.transactions[orderID] = &GatewayTransactionStatus{
+		OrderID:           orderID,
+		TransactionStatus: transactionStatus,
+		FraudStatus:       fraudStatus,
+		PaymentType:       paymentType,
+		GrossAmount:       grossAmount,
+	}
+}
+
+// SetCheckError makes every CheckTransaction call fail with err (nil clears it)
+func (f *FakePaymentGateway) SetCheckError(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.checkErr = err
+}
+
+// CheckTransaction returns the recorded state of an order
+func (f *FakePaymentGateway) CheckTransaction(orderID string) (*GatewayTransactionStatus, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if f.checkErr != nil {
+		return nil, f.checkErr
+	}
+
+	status, exists := f.transactions[orderID]
+	if !exists {
+		return nil, ErrGatewayTransactionNotFound
+	}
+
+	copied := *status
+	return &copied, nil
+}
+
+// VerifySignature validates a signature produced by Simulate
+func (f *FakePaymentGateway) VerifySignature(orderID, statusCode, grossAmount, signatureKey string) bool {
+	expected := notificationSignature(orderID, statusCode, grossAmount, fakeGatewayServerKey)
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(signatureKey)) == 1
+}
+
+// Refund marks a settled order as refunded
+func (f *FakePaymentGateway) Refund(orderID string, amount int64, reason string) (*RefundResult, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	status, exists := f.transactions[orderID]
+	if !exists {
+		return nil, ErrGatewayTransactionNotFound
+	}
+	if MapGatewayStatus(status.TransactionStatus, status.FraudStatus) != models.TransactionStatusSettlement {
+		return nil, errors.New("only settled transactions can be refunded")
+	}
+
+	status.TransactionStatus = "refund"
+
+	return &RefundResult{
+		OrderID:           orderID,
+		RefundKey:         "REFUND-" + uuid.New().String(),
+		Amount:            amount,
+		TransactionStatus: status.TransactionStatus,
+	}, nil
+}
+
+// Simulate moves an order to a new state and returns the signed notification payload
+// Midtrans would have sent to /api/webhooks/midtrans
+func (f *FakePaymentGateway) Simulate(orderID, transactionStatus, fraudStatus, paymentType string) (map[string]interface{}, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	status, exists := f.transactions[orderID]
+	if !exists {
+		return nil, ErrGatewayTransactionNotFound
+	}
+
+	status.TransactionStatus = transactionStatus
+	status.FraudStatus = fraudStatus
+	status.PaymentType = paymentType
+
+	statusCode := "200"
+	if transactionStatus == "pending" {
+		statusCode = "201"
+	} else if MapGatewayStatus(transactionStatus, fraudStatus) != models.TransactionStatusSettlement {
+		statusCode = "202"
+	}
+
+	return map[string]interface{}{
+		"order_id":           orderID,
+		"status_code":        statusCode,
+		"gross_amount":       status.GrossAmount,
+		"transaction_status": transactionStatus,
+		"fraud_status":       fraudStatus,
+		"payment_type":       paymentType,
+		"signature_key":      notificationSignature(orderID, statusCode, status.GrossAmount, fakeGatewayServerKey),
+	}, nil
+}
+
+// formatGrossAmount formats an amount the way Midtrans reports gross_amount
+func formatGrossAmount(amount int64) string {
+	return fmt.Sprintf("%d.00", amount)
+}
@@ -0,0 +1,326 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/workradar/server/internal/models"
+	"github.com/workradar/server/internal/repository"
+)
+
+// Job types
+const (
+	JobTypeTaskReminder = "task_reminder"
+	JobTypeWeatherAlert = "weather_alert"
+	JobTypeHealthCheck  = "health_check"
+	JobTypeDailyPlan    = "daily_plan"
+	JobTypeWeeklyReview = "weekly_review"
+	JobTypeEmail        = "email"
+	// Notifications deferred by quiet hours, and daily digests
+	JobTypeNotification       = "notification"
+	JobTypeNotificationDigest = "notification_digest"
+)
+
+const (
+	jobBackoffBase = 30 * time.Second
+	jobBackoffMax  = time.Hour
+	// jobRetentionDays is how long succeeded jobs are kept
+	jobRetentionDays = 7
+)
+
+// JobHandler runs one job; returning an error retries it, a PermanentJobError dead-letters it
+type JobHandler func(ctx context.Context, payload []byte) error
+
+// JobOptions tune a single enqueue
+type JobOptions struct {
+	// UniqueKey makes the enqueue idempotent: a second job with the same key is dropped
+	UniqueKey string
+	// RunAt delays the job; zero means now
+	RunAt time.Time
+	// MaxAttempts overrides the queue default
+	MaxAttempts int
+}
+
+// permanentJobError marks failures that retrying cannot fix
+type permanentJobError struct{ err error }
+
+func (e *permanentJobError) Error() string { return e.err.Error() }
+func (e *permanentJobError) Unwrap() error { return e.err }
+
+// PermanentJobError wraps err so the job goes to the dead letter without further retries
+func PermanentJobError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentJobError{err: err}
+}
+
+// JobBackoff is the delay before retry number attempt (1-based): 30s, 1m, 2m, ... capped at 1h
+func JobBackoff(attempt int) time.Duration {
+	delay := jobBackoffBase
+	for i := 1; i < attempt && delay < jobBackoffMax; i++ {
+		delay *= 2
+	}
+	if delay > jobBackoffMax {
+		delay = jobBackoffMax
+	}
+	return delay
+}
+
+// JobRetryDecision decides what happens after a failed attempt: retry after the returned
+// delay, or dead-letter the job
+func JobRetryDecision(attempts, maxAttempts int, err error) (time.Duration, bool) {
+	var permanent *permanentJobError
+	if errors.As(err, &permanent) || attempts >= maxAttempts {
+		return 0, true
+	}
+	return JobBackoff(attempts), false
+}
+
+// JobQueueStats summarizes the queue for admins
+type JobQueueStats struct {
+	Counts  map[models.JobStatus]int64 `json:"counts"`
+	Workers int                        `json:"workers"`
+}
+
+// JobQueue is a database-backed job queue. Jobs are leased with row locks, so several
+// server replicas can share the queue without running a job twice; a worker that dies
+// loses its lease and the job is picked up again. A fixed pool of workers bounds concurrency.
+type JobQueue struct {
+	repo         *repository.JobRepository
+	handlers     map[string]JobHandler
+	workerID     string
+	workers      int
+	pollInterval time.Duration
+	lease        time.Duration
+	maxAttempts  int
+	mu           sync.RWMutex
+	stopChan     chan struct{}
+	wg           sync.WaitGroup
+}
+
+// NewJobQueue creates a job queue; call Register for every job type before Start
+func NewJobQueue(repo *repository.JobRepository, workers int, pollInterval, lease time.Duration, maxAttempts int) *JobQueue {
+	if workers < 1 {
+		workers = 1
+	}
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	hostname, _ := os.Hostname()
+	return &JobQueue{
+		repo:         repo,
+		handlers:     make(map[string]JobHandler),
+		workerID:     fmt.Sprintf("%s-%s", hostname, uuid.New().String()[:8]),
+		workers:      workers,
+		pollInterval: pollInterval,
+		lease:        lease,
+		maxAttempts:  maxAttempts,
+		stopChan:     make(chan struct{}),
+	}
+}
+
+// Register sets the handler for a job type
+func (q *JobQueue) Register(jobType string, handler JobHandler) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.handlers[jobType] = handler
+}
+
+// Enqueue stores a job; payload is encoded as JSON. With a UniqueKey that already exists
+// the call is a no-op and returns (nil, nil).
+func (q *JobQueue) Enqueue(jobType string, payload interface{}, opts JobOptions) (*models.Job, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode job payload: %w", err)
+	}
+
+	job := &models.Job{
+		Type:        jobType,
+		Payload:     string(body),
+		RunAt:       opts.RunAt,
+		MaxAttempts: opts.MaxAttempts,
+	}
+	if job.RunAt.IsZero() {
+		job.RunAt = time.Now()
+	}
+	if job.MaxAttempts == 0 {
+		job.MaxAttempts = q.maxAttempts
+	}
+	if opts.UniqueKey != "" {
+		key := opts.UniqueKey
+		job.UniqueKey = &key
+	}
+
+	created, err := q.repo.Enqueue(job)
+	if err != nil {
+		return nil, err
+	}
+	if !created {
+		return nil, nil
+	}
+	return job, nil
+}
+
+// Start launches the worker pool and the cleanup loop
+func (q *JobQueue) Start() {
+	log.Printf("🧵 Starting job queue (%d workers, id %s)", q.workers, q.workerID)
+
+	for i := 0; i < q.workers; i++ {
+		q.wg.Add(1)
+		go q.worker()
+	}
+
+	q.wg.Add(1)
+	go q.cleanupLoop()
+}
+
+// Stop waits for running jobs to finish
+func (q *JobQueue) Stop() {
+	close(q.stopChan)
+	q.wg.Wait()
+	log.Println("🧵 Job queue stopped")
+}
+
+// Stats returns job counts per status
+func (q *JobQueue) Stats() (*JobQueueStats, error) {
+	counts, err := q.repo.CountByStatus()
+	if err != nil {
+		return nil, err
+	}
+	return &JobQueueStats{Counts: counts, Workers: q.workers}, nil
+}
+
+// List returns jobs for admins
+func (q *JobQueue) List(status, jobType string, limit, offset int) ([]models.Job, int64, error) {
+	return q.repo.List(status, jobType, limit, offset)
+}
+
+// Retry moves a dead-lettered job back to the queue
+func (q *JobQueue) Retry(id string) (*models.Job, error) {
+	updated, err := q.repo.Retry(id, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if updated == 0 {
+		return nil, errors.New("job not found or not dead")
+	}
+	return q.repo.FindByID(id)
+}
+
+func (q *JobQueue) worker() {
+	defer q.wg.Done()
+
+	for {
+		ran := q.runNext()
+
+		// Keep draining while there is work; otherwise wait for the next poll
+		if ran {
+			select {
+			case <-q.stopChan:
+				return
+			default:
+				continue
+			}
+		}
+
+		select {
+		case <-time.After(q.pollInterval):
+		case <-q.stopChan:
+			return
+		}
+	}
+}
+
+// runNext leases and runs a single job; false means nothing was runnable
+func (q *JobQueue) runNext() bool {
+	q.mu.RLock()
+	types := make([]string, 0, len(q.handlers))
+	for jobType := range q.handlers {
+		types = append(types, jobType)
+	}
+	q.mu.RUnlock()
+
+	job, err := q.repo.Lease(q.workerID, types, q.lease, time.Now())
+	if err != nil {
+		log.Printf("❌ Failed to lease job: %v", err)
+		return false
+	}
+	if job == nil {
+		return false
+	}
+
+	q.mu.RLock()
+	handler := q.handlers[job.Type]
+	q.mu.RUnlock()
+
+	err = q.execute(handler, job)
+	if err == nil {
+		if err := q.repo.Complete(job.ID, q.workerID, time.Now()); err != nil {
+			log.Printf("❌ Failed to complete job %s: %v", job.ID, err)
+		}
+		return true
+	}
+
+	delay, dead := JobRetryDecision(job.Attempts, job.MaxAttempts, err)
+	// Jitter spreads retries of jobs that failed together (e.g. during an FCM outage)
+	if delay > 0 {
+		delay += time.Duration(rand.Int63n(int64(delay) / 5))
+	}
+	if failErr := q.repo.Fail(job.ID, q.workerID, err.Error(), time.Now().Add(delay), dead); failErr != nil {
+		log.Printf("❌ Failed to record failure of job %s: %v", job.ID, failErr)
+	}
+
+	if dead {
+		log.Printf("💀 Job %s (%s) dead-lettered after %d attempts: %v", job.ID, job.Type, job.Attempts, err)
+	} else {
+		log.Printf("⚠️ Job %s (%s) failed, retry in %v: %v", job.ID, job.Type, delay.Round(time.Second), err)
+	}
+	return true
+}
+
+// execute runs the handler within the lease, turning panics into errors
+func (q *JobQueue) execute(handler JobHandler, job *models.Job) (err error) {
+	if handler == nil {
+		return PermanentJobError(fmt.Errorf("no handler for job type %q", job.Type))
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), q.lease)
+	defer cancel()
+	return handler(ctx, []byte(job.Payload))
+}
+
+func (q *JobQueue) cleanupLoop() {
+	defer q.wg.Done()
+
+	ticker := time.NewTicker(24 * time.Hour)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			removed, err := q.repo.DeleteSucceededBefore(time.Now().AddDate(0, 0, -jobRetentionDays))
+			if err != nil {
+				log.Printf("❌ Failed to clean up jobs: %v", err)
+			} else if removed > 0 {
+				log.Printf("🧹 Removed %d finished jobs", removed)
+			}
+		case <-q.stopChan:
+			return
+		}
+	}
+}
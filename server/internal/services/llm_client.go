@@ -0,0 +1,451 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/workradar/server/internal/config"
+)
+
+// ErrLLMUnavailable is returned when every provider in the chain failed
+var ErrLLMUnavailable = errors.New("all AI providers failed")
+
+// LLMProvider is a chat completion backend
+type LLMProvider interface {
+	// Name returns the provider identifier (e.g. "groq")
+	Name() string
+	// DefaultModel is used when a request does not select a model this provider supports
+	DefaultModel() string
+	// SupportsModel reports whether a request may select model on this provider
+	SupportsModel(model string) bool
+	// Complete returns a full (non-streamed) completion
+	Complete(ctx context.Context, req ChatRequest) (*ChatResponse, error)
+	// Stream calls onDelta for every content fragment and returns the full text (also the
+	// partial text on error). It returns ErrAIStreamAborted when onDelta fails.
+	Stream(ctx context.Context, req ChatRequest, onDelta func(string) error) (StreamResult, error)
+}
+
+// StreamResult is the outcome of a streamed completion
+type StreamResult struct {
+	Content string
+	Model   string
+	// Usage is nil when the provider did not report token usage
+	Usage *ChatUsage
+}
+
+// LLMError is a failed provider call
+type LLMError struct {
+	Provider   string
+	StatusCode int // 0 for network errors
+	Type       string
+	Message    string
+}
+
+func (e *LLMError) Error() string {
+	if e.StatusCode == 0 {
+		return fmt.Sprintf("%s: %s", e.Provider, e.Message)
+	}
+	return fmt.Sprintf("%s: HTTP %d: %s", e.Provider, e.StatusCode, e.Message)
+}
+
+// Retryable reports whether the same call may succeed later (network errors, rate limits, 5xx)
+func (e *LLMError) Retryable() bool {
+	return e.StatusCode == 0 ||
+		e.StatusCode == http.StatusTooManyRequests ||
+		e.StatusCode >= http.StatusInternalServerError ||
+		isRateLimitError(e.Type, e.Message)
+}
+
+// isRetryableLLMError reports whether err is worth retrying on the same provider
+func isRetryableLLMError(err error) bool {
+	var llmErr *LLMError
+	return errors.As(err, &llmErr) && llmErr.Retryable()
+}
+
+// isRateLimitError reports whether a provider error is a rate limit
+func isRateLimitError(errType, errMsg string) bool {
+	return strings.Contains(strings.ToLower(errType), "rate_limit") || strings.Contains(strings.ToLower(errMsg), "rate limit")
+}
+
+// LLMClient sends chat requests through an ordered chain of providers. Each provider is
+// retried with exponential backoff on transient errors before the next one is tried.
+type LLMClient struct {
+	providers  []LLMProvider
+	maxRetries int
+	backoff    time.Duration
+}
+
+func NewLLMClient(providers []LLMProvider, maxRetries int, backoff time.Duration) *LLMClient {
+	if maxRetries < 0 {
+		maxRetries = 0
+	}
+	return &LLMClient{
+		providers:  providers,
+		maxRetries: maxRetries,
+		backoff:    backoff,
+	}
+}
+
+// NewLLMClientFromConfig builds the provider chain from configuration
+func NewLLMClientFromConfig(cfgs []config.LLMProviderConfig, maxRetries int, backoff, timeout time.Duration) *LLMClient {
+	providers := make([]LLMProvider, 0, len(cfgs))
+	for _, cfg := range cfgs {
+		providers = append(providers, NewOpenAICompatibleProvider(cfg, timeout))
+		log.Printf("🤖 AI provider enabled: %s (%s, default model %s)", cfg.Name, cfg.BaseURL, cfg.DefaultModel)
+	}
+	if len(providers) == 0 {
+		log.Println("⚠️ No AI provider configured - AI chat will answer with a setup message")
+	}
+	return NewLLMClient(providers, maxRetries, backoff)
+}
+
+// Enabled reports whether at least one provider is configured
+func (c *LLMClient) Enabled() bool {
+	return len(c.providers) > 0
+}
+
+// SupportsModel reports whether any provider accepts model ("" selects the defaults)
+func (c *LLMClient) SupportsModel(model string) bool {
+	if model == "" {
+		return true
+	}
+	for _, p := range c.providers {
+		if p.SupportsModel(model) {
+			return true
+		}
+	}
+	return false
+}
+
+// LLMProviderModels lists the models a request may select on one provider
+type LLMProviderModels struct {
+	Provider string   `json:"provider"`
+	Default  string   `json:"default"`
+	Models   []string `json:"models"`
+}
+
+// Models lists the selectable models per provider, in fallback order
+func (c *LLMClient) Models() []LLMProviderModels {
+	result := make([]LLMProviderModels, 0, len(c.providers))
+	for _, p := range c.providers {
+		models := []string{p.DefaultModel()}
+		if lister, ok := p.(interface{ Models() []string }); ok {
+			models = lister.Models()
+		}
+		result = append(result, LLMProviderModels{
+			Provider: p.Name(),
+			Default:  p.DefaultModel(),
+			Models:   models,
+		})
+	}
+	return result
+}
+
+// Complete returns the first successful completion from the provider chain
+func (c *LLMClient) Complete(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+	var lastErr error
+
+	for _, p := range c.providers {
+		providerReq := c.requestFor(p, req)
+
+		var resp *ChatResponse
+		err := c.withRetry(ctx, p, func() error {
+			var callErr error
+			resp, callErr = p.Complete(ctx, providerReq)
+			return callErr
+		})
+		if err == nil {
+			return resp, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		log.Printf("⚠️ AI provider %s failed, trying next: %v", p.Name(), err)
+		lastErr = err
+	}
+
+	return nil, fmt.Errorf("%w: %v", ErrLLMUnavailable, lastErr)
+}
+
+// Stream streams from the first provider that answers. Once content has reached the
+// client the chain is not continued, since the partial reply cannot be taken back.
+func (c *LLMClient) Stream(ctx context.Context, req ChatRequest, onDelta func(string) error) (StreamResult, error) {
+	var lastErr error
+
+	for _, p := range c.providers {
+		providerReq := c.requestFor(p, req)
+		started := false
+		trackingDelta := func(delta string) error {
+			started = true
+			return onDelta(delta)
+		}
+
+		var full StreamResult
+		err := c.withRetry(ctx, p, func() error {
+			var callErr error
+			full, callErr = p.Stream(ctx, providerReq, trackingDelta)
+			if started && callErr != nil && !errors.Is(callErr, ErrAIStreamAborted) {
+				// Not retryable: the client already received part of this reply
+				return fmt.Errorf("%s: stream interrupted: %v", p.Name(), callErr)
+			}
+			return callErr
+		})
+		if err == nil {
+			return full, nil
+		}
+		if started || errors.Is(err, ErrAIStreamAborted) || ctx.Err() != nil {
+			return full, err
+		}
+
+		log.Printf("⚠️ AI provider %s failed, trying next: %v", p.Name(), err)
+		lastErr = err
+	}
+
+	return StreamResult{}, fmt.Errorf("%w: %v", ErrLLMUnavailable, lastErr)
+}
+
+// requestFor applies the provider's default model unless the request selected one it supports
+func (c *LLMClient) requestFor(p LLMProvider, req ChatRequest) ChatRequest {
+	if req.Model == "" || !p.SupportsModel(req.Model) {
+		req.Model = p.DefaultModel()
+	}
+	return req
+}
+
+// withRetry runs call, retrying transient errors with exponential backoff
+func (c *LLMClient) withRetry(ctx context.Context, p LLMProvider, call func() error) error {
+	delay := c.backoff
+	for attempt := 0; ; attempt++ {
+		err := call()
+		if err == nil || attempt >= c.maxRetries || !isRetryableLLMError(err) {
+			return err
+		}
+
+		log.Printf("⏳ AI provider %s: attempt %d failed (%v), retrying in %v", p.Name(), attempt+1, err, delay)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// OpenAICompatibleProvider talks to any endpoint implementing the OpenAI
+// /chat/completions API (Groq, Ollama, llama.cpp server, vLLM, ...)
+type OpenAICompatibleProvider struct {
+	name         string
+	baseURL      string
+	apiKey       string
+	defaultModel string
+	models       []string
+	timeout      time.Duration
+}
+
+func NewOpenAICompatibleProvider(cfg config.LLMProviderConfig, timeout time.Duration) *OpenAICompatibleProvider {
+	return &OpenAICompatibleProvider{
+		name:         cfg.Name,
+		baseURL:      strings.TrimRight(cfg.BaseURL, "/"),
+		apiKey:       cfg.APIKey,
+		defaultModel: cfg.DefaultModel,
+		models:       cfg.Models,
+		timeout:      timeout,
+	}
+}
+
+// Name returns the provider identifier
+func (p *OpenAICompatibleProvider) Name() string {
+	return p.name
+}
+
+// DefaultModel returns the model used when none is selected
+func (p *OpenAICompatibleProvider) DefaultModel() string {
+	return p.defaultModel
+}
+
+// Models returns the selectable models, default first
+func (p *OpenAICompatibleProvider) Models() []string {
+	models := []string{p.defaultModel}
+	for _, m := range p.models {
+		if m != p.defaultModel {
+			models = append(models, m)
+		}
+	}
+	return models
+}
+
+// SupportsModel reports whether model is the default or listed for this provider
+func (p *OpenAICompatibleProvider) SupportsModel(model string) bool {
+	for _, m := range p.Models() {
+		if m == model {
+			return true
+		}
+	}
+	return false
+}
+
+// Complete sends a non-streamed chat completion request
+func (p *OpenAICompatibleProvider) Complete(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+	req.Stream = false
+
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	resp, err := p.do(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, &LLMError{Provider: p.name, Message: fmt.Sprintf("gagal membaca respons AI: %v", err)}
+	}
+
+	var chatResp ChatResponse
+	if err := json.Unmarshal(body, &chatResp); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return nil, &LLMError{Provider: p.name, StatusCode: resp.StatusCode, Message: string(body)}
+		}
+		return nil, &LLMError{Provider: p.name, StatusCode: resp.StatusCode, Message: fmt.Sprintf("gagal memproses respons AI: %v", err)}
+	}
+
+	if chatResp.Error != nil || resp.StatusCode != http.StatusOK {
+		return nil, p.responseError(resp.StatusCode, &chatResp, body)
+	}
+
+	return &chatResp, nil
+}
+
+// Stream sends a streamed chat completion request and relays content deltas. Usage is
+// requested for the final chunk; providers that ignore stream_options leave it nil and
+// the caller estimates the tokens instead.
+func (p *OpenAICompatibleProvider) Stream(ctx context.Context, req ChatRequest, onDelta func(string) error) (StreamResult, error) {
+	req.Stream = true
+	req.StreamOptions = &ChatStreamOptions{IncludeUsage: true}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// No overall timeout: long answers stream for a while; the context bounds it instead
+	result := StreamResult{Model: req.Model}
+
+	resp, err := p.do(ctx, req)
+	if err != nil {
+		return result, err
+	}
+	defer resp.Body.Close()
+
+	// Provider rejected the request before streaming started
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		var chatResp ChatResponse
+		_ = json.Unmarshal(body, &chatResp)
+		return result, p.responseError(resp.StatusCode, &chatResp, body)
+	}
+
+	var full strings.Builder
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "data:") {
+			continue // Blank separators and SSE comments
+		}
+
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "[DONE]" {
+			break
+		}
+
+		var chunk chatStreamChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			log.Printf("⚠️ AI Stream: Skipping malformed chunk: %v", err)
+			continue
+		}
+
+		if chunk.Model != "" {
+			result.Model = chunk.Model
+		}
+		if chunk.Usage != nil {
+			result.Usage = chunk.Usage
+		} else if chunk.XGroq != nil && chunk.XGroq.Usage != nil {
+			result.Usage = chunk.XGroq.Usage
+		}
+
+		// Provider error reported mid-stream
+		if chunk.Error != nil {
+			result.Content = full.String()
+			return result, &LLMError{Provider: p.name, StatusCode: http.StatusOK, Type: chunk.Error.Type, Message: chunk.Error.Message}
+		}
+
+		if len(chunk.Choices) == 0 || chunk.Choices[0].Delta.Content == "" {
+			continue
+		}
+
+		delta := chunk.Choices[0].Delta.Content
+		full.WriteString(delta)
+
+		if err := onDelta(delta); err != nil {
+			result.Content = full.String()
+			return result, ErrAIStreamAborted
+		}
+	}
+
+	result.Content = full.String()
+	if err := scanner.Err(); err != nil {
+		if ctx.Err() != nil {
+			return result, ErrAIStreamAborted
+		}
+		return result, &LLMError{Provider: p.name, Message: fmt.Sprintf("koneksi ke AI terputus: %v", err)}
+	}
+
+	return result, nil
+}
+
+// do posts req to /chat/completions
+func (p *OpenAICompatibleProvider) do(ctx context.Context, req ChatRequest) (*http.Response, error) {
+	jsonData, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("gagal memproses request: %v", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+"/chat/completions", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("gagal membuat request: %v", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if req.Stream {
+		httpReq.Header.Set("Accept", "text/event-stream")
+	}
+	if p.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+
+	log.Printf("🤖 AI: Calling %s with model %s", p.name, req.Model)
+	resp, err := http.DefaultClient.Do(httpReq)
+	if err != nil {
+		return nil, &LLMError{Provider: p.name, Message: fmt.Sprintf("gagal menghubungi AI: %v", err)}
+	}
+	return resp, nil
+}
+
+func (p *OpenAICompatibleProvider) responseError(statusCode int, chatResp *ChatResponse, body []byte) *LLMError {
+	llmErr := &LLMError{Provider: p.name, StatusCode: statusCode, Message: string(body)}
+	if chatResp.Error != nil {
+		llmErr.Type = chatResp.Error.Type
+		llmErr.Message = chatResp.Error.Message
+	}
+	return llmErr
+}
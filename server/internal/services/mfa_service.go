@@ -6,26 +6,70 @@ import (
 	"crypto/sha1"
 	"encoding/base32"
 	"encoding/binary"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"log"
 	"strings"
 	"time"
 
 	"github.com/workradar/server/internal/models"
 	"github.com/workradar/server/internal/repository"
+	"github.com/workradar/server/pkg/utils"
 )
 
-// MFAService handles Multi-Factor Authentication using TOTP
+const (
+	// BackupCodeCount is how many backup codes a user gets when enabling MFA or regenerating
+	BackupCodeCount = 10
+	// maxMFALoginAttempts is how many codes may be tried with one MFA token
+	maxMFALoginAttempts = 5
+)
+
+var (
+	ErrInvalidMFAToken    = errors.New("invalid or expired MFA token, please login again")
+	ErrTooManyMFAAttempts = errors.New("too many invalid MFA codes, please login again")
+)
+
+// MFAService handles Multi-Factor Authentication using TOTP, with single-use backup
+// codes for when the authenticator app is not available
 type MFAService struct {
-	userRepo *repository.UserRepository
+	userRepo       *repository.UserRepository
+	backupCodeRepo *repository.MFABackupCodeRepository
+	emailService   *EmailService
+	auditService   *AuditService
+	store          StateStore
 }
 
 // NewMFAService creates a new MFA service instance
-func NewMFAService(userRepo *repository.UserRepository) *MFAService {
+func NewMFAService(
+	userRepo *repository.UserRepository,
+	backupCodeRepo *repository.MFABackupCodeRepository,
+	emailService *EmailService,
+	auditService *AuditService,
+	store StateStore,
+) *MFAService {
 	return &MFAService{
-		userRepo: userRepo,
+		userRepo:       userRepo,
+		backupCodeRepo: backupCodeRepo,
+		emailService:   emailService,
+		auditService:   auditService,
+		store:          store,
 	}
 }
 
+// MFAStatus is the MFA state shown to the user
+type MFAStatus struct {
+	MFAEnabled           bool  `json:"mfa_enabled"`
+	HasSecret            bool  `json:"has_secret"`
+	BackupCodesRemaining int64 `json:"backup_codes_remaining"`
+}
+
+// MFALoginResult tells whether a backup code was used for the login
+type MFALoginResult struct {
+	UsedBackupCode       bool
+	BackupCodesRemaining int64
+}
+
 // MFASetupResponse contains the data needed for MFA setup
 type MFASetupResponse struct {
 	Secret     string `json:"secret"`
@@ -71,28 +115,68 @@ func (s *MFAService) GenerateSecret(userID string) (*MFASetupResponse, error) {
 	}, nil
 }
 
-// VerifyAndEnable verifies a TOTP code and enables MFA if correct
-func (s *MFAService) VerifyAndEnable(userID, code string) error {
+// VerifyAndEnable verifies a TOTP code and enables MFA if correct. It returns the
+// backup codes, which are shown to the user only this once.
+func (s *MFAService) VerifyAndEnable(userID, code string) ([]string, error) {
 	user, err := s.userRepo.GetByID(userID)
 	if err != nil {
-		return fmt.Errorf("user not found: %w", err)
+		return nil, fmt.Errorf("user not found: %w", err)
 	}
 
 	if user.MFASecret == nil || *user.MFASecret == "" {
-		return fmt.Errorf("MFA not set up - please generate secret first")
+		return nil, fmt.Errorf("MFA not set up - please generate secret first")
 	}
 
 	// Verify the code
 	if !s.VerifyCode(*user.MFASecret, code) {
-		return fmt.Errorf("invalid verification code")
+		return nil, fmt.Errorf("invalid verification code")
 	}
 
 	// Enable MFA
 	if err := s.userRepo.EnableMFA(userID); err != nil {
-		return fmt.Errorf("failed to enable MFA: %w", err)
+		return nil, fmt.Errorf("failed to enable MFA: %w", err)
 	}
 
-	return nil
+	return s.issueBackupCodes(userID)
+}
+
+// RegenerateBackupCodes replaces all backup codes of the user after verifying a current
+// TOTP code; the old codes stop working
+func (s *MFAService) RegenerateBackupCodes(userID, code string) ([]string, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("user not found: %w", err)
+	}
+
+	if !user.MFAEnabled || user.MFASecret == nil {
+		return nil, fmt.Errorf("MFA is not enabled")
+	}
+
+	if !s.VerifyCode(*user.MFASecret, code) {
+		return nil, fmt.Errorf("invalid verification code")
+	}
+
+	return s.issueBackupCodes(userID)
+}
+
+// issueBackupCodes generates new backup codes and stores their hashes
+func (s *MFAService) issueBackupCodes(userID string) ([]string, error) {
+	codes, err := GenerateBackupCodes(BackupCodeCount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate backup codes: %w", err)
+	}
+
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		if hashes[i], err = HashBackupCode(code); err != nil {
+			return nil, fmt.Errorf("failed to hash backup codes: %w", err)
+		}
+	}
+	if err := s.backupCodeRepo.ReplaceForUser(userID, hashes); err != nil {
+		return nil, fmt.Errorf("failed to save backup codes: %w", err)
+	}
+
+	return codes, nil
 }
 
 // VerifyCode verifies a TOTP code against the secret
@@ -112,26 +196,120 @@ func (s *MFAService) VerifyCode(secret, code string) bool {
 	return false
 }
 
-// VerifyLogin verifies TOTP code during login
-func (s *MFAService) VerifyLogin(userID, code string) error {
+// VerifyPendingLogin checks the MFA token issued after the password step together with
+// a TOTP or backup code and returns the user ID. One token allows maxMFALoginAttempts
+// codes and cannot be used again once verified.
+func (s *MFAService) VerifyPendingLogin(mfaToken, code string, client ClientInfo) (string, *MFALoginResult, error) {
+	claims, err := utils.ValidateMFAToken(mfaToken)
+	if err != nil {
+		return "", nil, ErrInvalidMFAToken
+	}
+
+	blacklist := GetTokenBlacklistService()
+	if blacklist.IsBlacklisted(claims.ID) {
+		return "", nil, ErrInvalidMFAToken
+	}
+
+	attempts, err := s.store.Incr("mfa:attempts:"+claims.ID, time.Until(claims.ExpiresAt.Time))
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to count MFA attempts: %w", err)
+	}
+	if attempts > maxMFALoginAttempts {
+		return "", nil, ErrTooManyMFAAttempts
+	}
+
+	result, err := s.VerifyLogin(claims.UserID, code, client)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if err := blacklist.AddToken(claims.ID, claims.ExpiresAt.Time); err != nil {
+		return "", nil, fmt.Errorf("failed to revoke MFA token: %w", err)
+	}
+	return claims.UserID, result, nil
+}
+
+// VerifyLogin verifies a TOTP code or a backup code during login. A backup code is
+// used up and the user is alerted.
+func (s *MFAService) VerifyLogin(userID, code string, client ClientInfo) (*MFALoginResult, error) {
 	user, err := s.userRepo.GetByID(userID)
 	if err != nil {
-		return fmt.Errorf("user not found: %w", err)
+		return nil, fmt.Errorf("user not found: %w", err)
 	}
 
 	if !user.MFAEnabled {
-		return fmt.Errorf("MFA not enabled for this user")
+		return nil, fmt.Errorf("MFA not enabled for this user")
 	}
 
 	if user.MFASecret == nil || *user.MFASecret == "" {
-		return fmt.Errorf("MFA secret not found")
+		return nil, fmt.Errorf("MFA secret not found")
 	}
 
-	if !s.VerifyCode(*user.MFASecret, code) {
-		return fmt.Errorf("invalid MFA code")
+	if IsTOTPCode(code) {
+		if !s.VerifyCode(*user.MFASecret, code) {
+			return nil, fmt.Errorf("invalid MFA code")
+		}
+		return &MFALoginResult{}, nil
 	}
 
-	return nil
+	used, err := s.useBackupCode(userID, code)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify backup code: %w", err)
+	}
+	if !used {
+		return nil, fmt.Errorf("invalid MFA code")
+	}
+
+	remaining, err := s.backupCodeRepo.CountRemaining(userID)
+	if err != nil {
+		log.Printf("⚠️ Failed to count backup codes of user %s: %v", userID, err)
+	}
+	s.alertBackupCodeUsed(user, remaining, client)
+
+	return &MFALoginResult{UsedBackupCode: true, BackupCodesRemaining: remaining}, nil
+}
+
+// useBackupCode marks the unused backup code matching code as used. Codes are salted,
+// so each stored hash is compared in turn.
+func (s *MFAService) useBackupCode(userID, code string) (bool, error) {
+	codes, err := s.backupCodeRepo.FindUnused(userID)
+	if err != nil {
+		return false, err
+	}
+
+	for _, stored := range codes {
+		if CheckBackupCode(code, stored.CodeHash) {
+			// Another request may have used the same code in the meantime
+			return s.backupCodeRepo.Use(stored.ID, time.Now())
+		}
+	}
+	return false, nil
+}
+
+// alertBackupCodeUsed records the use of a backup code and emails the user
+func (s *MFAService) alertBackupCodeUsed(user *models.User, remaining int64, client ClientInfo) {
+	s.auditService.LogSecurityEvent(
+		models.EventMFABackupCodeUsed,
+		models.SeverityWarning,
+		&user.ID,
+		client.IPAddress,
+		fmt.Sprintf("MFA backup code used, %d remaining", remaining),
+		client.UserAgent,
+	)
+
+	err := s.emailService.Queue(EmailJob{
+		Kind:                 EmailKindBackupCodeUsed,
+		To:                   user.Email,
+		Lang:                 user.Language,
+		UserName:             user.Username,
+		Device:               ParseDeviceInfo(client.UserAgent),
+		IPAddress:            client.IPAddress,
+		Time:                 time.Now().Format("02 Jan 2006 15:04 MST"),
+		BackupCodesRemaining: int(remaining),
+	})
+	if err != nil {
+		log.Printf("⚠️ Failed to send backup code alert to user %s: %v", user.ID, err)
+	}
 }
 
 // DisableMFA disables MFA for a user after verifying current code
@@ -155,6 +333,11 @@ func (s *MFAService) DisableMFA(userID, code string) error {
 		return fmt.Errorf("failed to disable MFA: %w", err)
 	}
 
+	// Backup codes belong to this MFA setup
+	if err := s.backupCodeRepo.DeleteByUser(userID); err != nil {
+		return fmt.Errorf("failed to delete backup codes: %w", err)
+	}
+
 	return nil
 }
 
@@ -168,8 +351,22 @@ func (s *MFAService) IsMFAEnabled(userID string) (bool, error) {
 }
 
 // GetMFAStatus returns MFA status for a user
-func (s *MFAService) GetMFAStatus(userID string) (*models.User, error) {
-	return s.userRepo.GetByID(userID)
+func (s *MFAService) GetMFAStatus(userID string) (*MFAStatus, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &MFAStatus{
+		MFAEnabled: user.MFAEnabled,
+		HasSecret:  user.MFASecret != nil && *user.MFASecret != "",
+	}
+	if user.MFAEnabled {
+		if status.BackupCodesRemaining, err = s.backupCodeRepo.CountRemaining(userID); err != nil {
+			return nil, err
+		}
+	}
+	return status, nil
 }
 
 // generateTOTP generates a 6-digit TOTP code
@@ -211,16 +408,50 @@ func formatSecretForDisplay(secret string) string {
 	return formatted.String()
 }
 
-// GenerateBackupCodes generates backup codes for account recovery
-// Note: This is an optional enhancement for future implementation
-func (s *MFAService) GenerateBackupCodes(userID string, count int) ([]string, error) {
+// GenerateBackupCodes generates random backup codes formatted as xxxxx-xxxxx
+func GenerateBackupCodes(count int) ([]string, error) {
 	codes := make([]string, count)
 	for i := 0; i < count; i++ {
-		code := make([]byte, 4)
+		code := make([]byte, 5)
 		if _, err := rand.Read(code); err != nil {
 			return nil, err
 		}
-		codes[i] = fmt.Sprintf("%08x", code)
+		hexCode := hex.EncodeToString(code)
+		codes[i] = hexCode[:5] + "-" + hexCode[5:]
 	}
 	return codes, nil
 }
+
+// NormalizeBackupCode ignores case, spaces and dashes so codes can be typed loosely
+func NormalizeBackupCode(code string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToLower(strings.TrimSpace(code)))
+}
+
+// HashBackupCode hashes a backup code with bcrypt, like a password. Codes carry only
+// 40 bits, so a fast unsalted hash could be brute-forced from a database dump.
+func HashBackupCode(code string) (string, error) {
+	return utils.HashPassword(NormalizeBackupCode(code))
+}
+
+// CheckBackupCode compares a typed backup code with a stored hash
+func CheckBackupCode(code, hash string) bool {
+	return utils.CheckPasswordHash(NormalizeBackupCode(code), hash)
+}
+
+// IsTOTPCode checks if code looks like a 6-digit authenticator code
+func IsTOTPCode(code string) bool {
+	if len(code) != 6 {
+		return false
+	}
+	for _, r := range code {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,154 @@
+package services
+
+import (
+	"crypto/subtle"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/midtrans/midtrans-go"
+	"github.com/midtrans/midtrans-go/coreapi"
+	"github.com/midtrans/midtrans-go/snap"
+)
+
+// MidtransGateway implements PaymentGateway using Midtrans Snap and Core API
+type MidtransGateway struct {
+	serverKey  string
+	snapClient snap.Client
+	apiClient  coreapi.Client
+}
+
+// NewMidtransGateway initializes Midtrans clients for the given environment
+func NewMidtransGateway(serverKey string, isProduction bool) (*MidtransGateway, error) {
+	// Validate server key
+	if serverKey == "" {
+		return nil, errors.New("MIDTRANS_SERVER_KEY is not set in environment variables")
+	}
+
+	// Set environment based on production flag
+	env := midtrans.Sandbox
+	if isProduction {
+		env = midtrans.Production
+	}
+
+	// Debug logging (masked for security)
+	serverKeyPrefix := "unknown"
+	if len(serverKey) > 10 {
+		serverKeyPrefix = serverKey[:10]
+	}
+	log.Printf("🔧 Midtrans Config - Environment: %v, IsProduction: %v, ServerKey prefix: %s..., Key length: %d",
+		env, isProduction, serverKeyPrefix, len(serverKey))
+
+	g := &MidtransGateway{serverKey: serverKey}
+	g.snapClient.New(serverKey, env)
+	g.apiClient.New(serverKey, env)
+
+	log.Printf("✅ Midtrans initialized in %v mode", env)
+	return g, nil
+}
+
+// Name returns the provider identifier
+func (g *MidtransGateway) Name() string {
+	return PaymentGatewayMidtrans
+}
+
+// CreateCheckout requests a Snap token for the order
+func (g *MidtransGateway) CreateCheckout(req CheckoutRequest) (*CheckoutSession, error) {
+	snapReq := &snap.Request{
+		TransactionDetails: midtrans.TransactionDetails{
+			OrderID:  req.OrderID,
+			GrossAmt: req.Amount,
+		},
+		CreditCard: &snap.CreditCardDetails{
+			Secure: true,
+		},
+		CustomerDetail: &midtrans.CustomerDetails{
+			FName: req.CustomerName,
+			Email: req.CustomerEmail,
+		},
+		Items: &[]midtrans.ItemDetails{
+			{
+				ID:    req.ItemID,
+				Name:  req.ItemName,
+				Price: req.Amount,
+				Qty:   1,
+			},
+		},
+	}
+
+	snapResp, mErr := g.snapClient.CreateTransaction(snapReq)
+
+	// Check response validity FIRST (fix for Go interface nil gotcha)
+	// Midtrans SDK sometimes returns non-nil error interface with nil value
+	// If response is valid with token, treat as success regardless of error
+	if snapResp == nil {
+		log.Printf("❌ Midtrans returned nil response - OrderID: %s, Error: %v", req.OrderID, mErr)
+		return nil, errors.New("payment gateway returned nil response")
+	}
+
+	if snapResp.Token == "" {
+		log.Printf("❌ Midtrans returned empty token - OrderID: %s, Error: %v", req.OrderID, mErr)
+		return nil, errors.New("payment gateway returned empty token")
+	}
+
+	return &CheckoutSession{
+		Token:       snapResp.Token,
+		RedirectURL: snapResp.RedirectURL,
+	}, nil
+}
+
+// CheckTransaction fetches the transaction status from Midtrans
+func (g *MidtransGateway) CheckTransaction(orderID string) (*GatewayTransactionStatus, error) {
+	resp, mErr := g.apiClient.CheckTransaction(orderID)
+	// Compare the concrete pointer, not an error interface (Go interface nil gotcha)
+	if mErr != nil {
+		if mErr.GetStatusCode() == http.StatusNotFound {
+			return nil, ErrGatewayTransactionNotFound
+		}
+		return nil, mErr
+	}
+
+	// Midtrans may answer HTTP 200 with status_code "404" in the body
+	if resp == nil || resp.StatusCode == "404" {
+		return nil, ErrGatewayTransactionNotFound
+	}
+
+	return &GatewayTransactionStatus{
+		OrderID:           resp.OrderID,
+		TransactionStatus: resp.TransactionStatus,
+		FraudStatus:       resp.FraudStatus,
+		PaymentType:       resp.PaymentType,
+		GrossAmount:       resp.GrossAmount,
+	}, nil
+}
+
+// VerifySignature verifies the signature from a Midtrans webhook
+func (g *MidtransGateway) VerifySignature(orderID, statusCode, grossAmount, signatureKey string) bool {
+	expected := notificationSignature(orderID, statusCode, grossAmount, g.serverKey)
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(signatureKey)) == 1
+}
+
+// Refund refunds a settled Midtrans transaction
+func (g *MidtransGateway) Refund(orderID string, amount int64, reason string) (*RefundResult, error) {
+	refundKey := "REFUND-" + uuid.New().String()
+
+	resp, mErr := g.apiClient.RefundTransaction(orderID, &coreapi.RefundReq{
+		RefundKey: refundKey,
+		Amount:    amount,
+		Reason:    reason,
+	})
+	if mErr != nil {
+		return nil, mErr
+	}
+	if resp == nil {
+		return nil, errors.New("payment gateway returned nil refund response")
+	}
+
+	return &RefundResult{
+		OrderID:           orderID,
+		RefundKey:         refundKey,
+		Amount:            amount,
+		TransactionStatus: resp.TransactionStatus,
+	}, nil
+}
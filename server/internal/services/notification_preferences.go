@@ -0,0 +1,261 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/workradar/server/internal/models"
+)
+
+type NotificationPriority string
+
+const (
+	NotificationPriorityLow    NotificationPriority = "low"    // May wait for the daily digest
+	NotificationPriorityNormal NotificationPriority = "normal" // Waits for the end of quiet hours
+	NotificationPriorityHigh   NotificationPriority = "high"   // Breaks through quiet hours if the user allows it
+)
+
+// maxDigestLines is how many notifications the digest lists before summarizing the rest
+const maxDigestLines = 5
+
+// Notification is a channel-independent notification; NotificationService.Notify decides
+// where and when it is delivered
+type Notification struct {
+	Type     models.NotificationType `json:"type"`
+	Priority NotificationPriority    `json:"priority"`
+	Title    string                  `json:"title"`
+	Body     string                  `json:"body"`
+	Data     map[string]string       `json:"data,omitempty"`
+	Color    string                  `json:"color,omitempty"` // Android accent color
+	// InAppDelivered means the caller already stored a bot message with the full content,
+	// so the in-app channel has nothing left to do
+	InAppDelivered bool `json:"in_app_delivered,omitempty"`
+}
+
+type RouteAction string
+
+const (
+	RouteDeliver RouteAction = "deliver" // Send now on Channel
+	RouteDrop    RouteAction = "drop"    // The user turned this type off
+	RouteDefer   RouteAction = "defer"   // Quiet hours; send again at At
+	RouteDigest  RouteAction = "digest"  // Hold for the digest at At
+)
+
+// NotificationRoute is the routing decision for one notification
+type NotificationRoute struct {
+	Action  RouteAction                `json:"action"`
+	Channel models.NotificationChannel `json:"channel,omitempty"`
+	At      time.Time                  `json:"at,omitempty"`
+}
+
+// UpdateNotificationPreferencesDTO is a partial update; nil fields are left unchanged and
+// Channels only replaces the listed types
+type UpdateNotificationPreferencesDTO struct {
+	Channels              map[models.NotificationType]models.NotificationChannel `json:"channels"`
+	QuietHoursEnabled     *bool                                                  `json:"quiet_hours_enabled"`
+	QuietHoursStart       *string                                                `json:"quiet_hours_start"`
+	QuietHoursEnd         *string                                                `json:"quiet_hours_end"`
+	QuietHoursAllowUrgent *bool                                                  `json:"quiet_hours_allow_urgent"`
+	DigestEnabled         *bool                                                  `json:"digest_enabled"`
+	DigestTime            *string                                                `json:"digest_time"`
+}
+
+// DefaultNotificationPreference is what a user who never changed anything gets: every type
+// as push, no quiet hours and no digest
+func DefaultNotificationPreference(userID string) *models.NotificationPreference {
+	pref := &models.NotificationPreference{
+		UserID:          userID,
+		QuietHoursStart: "22:00",
+		QuietHoursEnd:   "07:00",
+		DigestTime:      "18:00",
+	}
+	fillNotificationChannels(pref)
+	return pref
+}
+
+// fillNotificationChannels sets missing types to push so clients always see every type
+func fillNotificationChannels(pref *models.NotificationPreference) {
+	if pref.Channels == nil {
+		pref.Channels = make(map[models.NotificationType]models.NotificationChannel, len(models.NotificationTypes))
+	}
+	for _, t := range models.NotificationTypes {
+		if !pref.Channels[t].IsValid() {
+			pref.Channels[t] = models.NotificationChannelPush
+		}
+	}
+}
+
+// NotificationChannelFor returns the channel the user chose for a notification type
+func NotificationChannelFor(pref *models.NotificationPreference, t models.NotificationType) models.NotificationChannel {
+	if channel := pref.Channels[t]; channel.IsValid() {
+		return channel
+	}
+	return models.NotificationChannelPush
+}
+
+// ParseClock parses "HH:MM" into minutes after midnight
+func ParseClock(value string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(value))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, use HH:MM", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// atClock returns the day of now at the given minutes after midnight
+func atClock(now time.Time, minutes int) time.Time {
+	return time.Date(now.Year(), now.Month(), now.Day(), minutes/60, minutes%60, 0, 0, now.Location())
+}
+
+// QuietHoursEnd reports whether now falls in the user's quiet hours and, if so, when they end
+func QuietHoursEnd(pref *models.NotificationPreference, now time.Time) (time.Time, bool) {
+	if !pref.QuietHoursEnabled {
+		return time.Time{}, false
+	}
+	start, err := ParseClock(pref.QuietHoursStart)
+	if err != nil {
+		return time.Time{}, false
+	}
+	end, err := ParseClock(pref.QuietHoursEnd)
+	if err != nil || start == end {
+		return time.Time{}, false
+	}
+
+	current := now.Hour()*60 + now.Minute()
+	if start < end {
+		if current >= start && current < end {
+			return atClock(now, end), true
+		}
+		return time.Time{}, false
+	}
+
+	// The window spans midnight, e.g. 22:00-07:00
+	if current >= start {
+		return atClock(now.AddDate(0, 0, 1), end), true
+	}
+	if current < end {
+		return atClock(now, end), true
+	}
+	return time.Time{}, false
+}
+
+// NextDigestTime is the next time the user's digest goes out
+func NextDigestTime(pref *models.NotificationPreference, now time.Time) time.Time {
+	minutes, err := ParseClock(pref.DigestTime)
+	if err != nil {
+		minutes = 18 * 60
+	}
+	next := atClock(now, minutes)
+	if !next.After(now) {
+		next = atClock(now.AddDate(0, 0, 1), minutes)
+	}
+	return next
+}
+
+// RouteNotification decides what happens with a notification: dropped when its type is
+// off, held for the digest when it is low priority and the user wants a digest, deferred
+// when it would be pushed during quiet hours, otherwise delivered on the chosen channel.
+// Only pushes are deferred; email and bot messages do not interrupt anyone.
+func RouteNotification(pref *models.NotificationPreference, n Notification, now time.Time) NotificationRoute {
+	channel := NotificationChannelFor(pref, n.Type)
+	if channel == models.NotificationChannelOff {
+		return NotificationRoute{Action: RouteDrop}
+	}
+
+	if pref.DigestEnabled && n.Priority == NotificationPriorityLow && n.Type != models.NotificationTypeDigest {
+		return NotificationRoute{Action: RouteDigest, Channel: channel, At: NextDigestTime(pref, now)}
+	}
+
+	if channel == models.NotificationChannelPush {
+		urgent := n.Priority == NotificationPriorityHigh && pref.QuietHoursAllowUrgent
+		if end, quiet := QuietHoursEnd(pref, now); quiet && !urgent {
+			return NotificationRoute{Action: RouteDefer, Channel: channel, At: end}
+		}
+	}
+
+	return NotificationRoute{Action: RouteDeliver, Channel: channel}
+}
+
+// ApplyNotificationPreferences validates dto and applies it to pref
+func ApplyNotificationPreferences(pref *models.NotificationPreference, dto UpdateNotificationPreferencesDTO) error {
+	for t, channel := range dto.Channels {
+		if !t.IsValid() {
+			return fmt.Errorf("unknown notification type %q", t)
+		}
+		if !channel.IsValid() {
+			return fmt.Errorf("invalid channel %q for %s", channel, t)
+		}
+		if t == models.NotificationTypeDigest && channel == models.NotificationChannelOff {
+			return errors.New("digest cannot be turned off; disable digest_enabled instead")
+		}
+	}
+
+	clocks := []struct {
+		value  *string
+		target *string
+	}{
+		{dto.QuietHoursStart, &pref.QuietHoursStart},
+		{dto.QuietHoursEnd, &pref.QuietHoursEnd},
+		{dto.DigestTime, &pref.DigestTime},
+	}
+	for _, clock := range clocks {
+		if clock.value == nil {
+			continue
+		}
+		minutes, err := ParseClock(*clock.value)
+		if err != nil {
+			return err
+		}
+		*clock.target = fmt.Sprintf("%02d:%02d", minutes/60, minutes%60)
+	}
+
+	fillNotificationChannels(pref)
+	for t, channel := range dto.Channels {
+		pref.Channels[t] = channel
+	}
+	if dto.QuietHoursEnabled != nil {
+		pref.QuietHoursEnabled = *dto.QuietHoursEnabled
+	}
+	if dto.QuietHoursAllowUrgent != nil {
+		pref.QuietHoursAllowUrgent = *dto.QuietHoursAllowUrgent
+	}
+	if dto.DigestEnabled != nil {
+		pref.DigestEnabled = *dto.DigestEnabled
+	}
+
+	if pref.QuietHoursEnabled && pref.QuietHoursStart == pref.QuietHoursEnd {
+		return errors.New("quiet hours start and end must differ")
+	}
+	return nil
+}
+
+// BuildDigestNotification combines held notifications into one
+func BuildDigestNotification(items []models.NotificationDigestItem) Notification {
+	var sb strings.Builder
+	for i, item := range items {
+		if i == maxDigestLines {
+			sb.WriteString(fmt.Sprintf("\n...dan %d lainnya", len(items)-maxDigestLines))
+			break
+		}
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		sb.WriteString("• " + item.Title)
+		if item.Body != "" {
+			sb.WriteString(": " + item.Body)
+		}
+	}
+
+	return Notification{
+		Type:     models.NotificationTypeDigest,
+		Priority: NotificationPriorityNormal,
+		Title:    fmt.Sprintf("📬 Ringkasan Notifikasi (%d)", len(items)),
+		Body:     sb.String(),
+		Data: map[string]string{
+			"count": fmt.Sprintf("%d", len(items)),
+		},
+		Color: "#FF6B35",
+	}
+}
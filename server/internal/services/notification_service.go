@@ -2,96 +2,149 @@ package services
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
-	firebase "firebase.google.com/go/v4"
-	"firebase.google.com/go/v4/messaging"
+	"github.com/workradar/server/internal/models"
 	"github.com/workradar/server/internal/repository"
-	"google.golang.org/api/option"
 )
 
+// Notification failures that retrying cannot fix
+var (
+	ErrFCMNotConfigured = errors.New("FCM not configured")
+	ErrNoFCMToken       = errors.New("user has no FCM token registered")
+)
+
+const (
+	// maxDevicesPerUser keeps the most recently seen devices of a user
+	maxDevicesPerUser = 10
+	// activeDeviceDays drops devices that have not checked in for a while from fan-out
+	activeDeviceDays = 90
+	// notificationHistoryDays is how long users can look back at sent notifications
+	notificationHistoryDays = 90
+)
+
+// DeviceRegistration describes the device calling RegisterDevice
+type DeviceRegistration struct {
+	FCMToken   string                `json:"fcm_token"`
+	Platform   models.DevicePlatform `json:"platform"`
+	AppVersion string                `json:"app_version"`
+	Locale     string                `json:"locale"`
+}
+
 type NotificationService struct {
-	userRepo        *repository.UserRepository
-	messagingClient *messaging.Client
-	ctx             context.Context
+	deviceRepo     *repository.DeviceRepository
+	userRepo       *repository.UserRepository
+	preferenceRepo *repository.NotificationPreferenceRepository
+	jobQueue       *JobQueue
+	events         *EventHub
+	notifiers      map[models.NotificationChannel]Notifier
 }
 
-func NewNotificationService(userRepo *repository.UserRepository, projectID, credentialsPath string) (*NotificationService, error) {
-	ctx := context.Background()
+// NewNotificationService creates the service; notifiers maps each channel to its delivery.
+// A missing push notifier means FCM is not configured. Without a preference repository
+// (tests) everyone gets the default preferences and no history is recorded; without a user
+// repository quiet hours and digests use the server timezone.
+func NewNotificationService(
+	deviceRepo *repository.DeviceRepository,
+	userRepo *repository.UserRepository,
+	preferenceRepo *repository.NotificationPreferenceRepository,
+	jobQueue *JobQueue,
+	events *EventHub,
+	notifiers map[models.NotificationChannel]Notifier,
+) *NotificationService {
+	s := &NotificationService{
+		deviceRepo:     deviceRepo,
+		userRepo:       userRepo,
+		preferenceRepo: preferenceRepo,
+		jobQueue:       jobQueue,
+		events:         events,
+		notifiers:      notifiers,
+	}
 
-	// Skip initialization if credentials not provided
-	if projectID == "" || credentialsPath == "" {
-		log.Println("⚠️ Firebase credentials not configured - notifications disabled")
-		return &NotificationService{
-			userRepo:        userRepo,
-			messagingClient: nil,
-			ctx:             ctx,
-		}, nil
+	// Deferred (quiet hours) and digest notifications run on the job queue
+	if jobQueue != nil {
+		jobQueue.Register(JobTypeNotification, s.handleNotificationJob)
+		jobQueue.Register(JobTypeNotificationDigest, s.handleDigestJob)
 	}
 
-	// Initialize Firebase App
-	opt := option.WithCredentialsFile(credentialsPath)
-	app, err := firebase.NewApp(ctx, &firebase.Config{
-		ProjectID: projectID,
-	}, opt)
-	if err != nil {
-		return nil, fmt.Errorf("failed to initialize Firebase app: %w", err)
+	if notifiers[models.NotificationChannelPush] == nil {
+		log.Println("⚠️ Firebase credentials not configured - push notifications disabled")
 	}
+	return s
+}
 
-	// Get Messaging client
-	client, err := app.Messaging(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get messaging client: %w", err)
+// RegisterDevice registers (or refreshes) a device of the user; call it on every app start
+func (s *NotificationService) RegisterDevice(userID string, reg DeviceRegistration) (*models.Device, error) {
+	token := strings.TrimSpace(reg.FCMToken)
+	if token == "" {
+		return nil, errors.New("FCM token is required")
 	}
 
-	log.Println("✅ Firebase Cloud Messaging initialized successfully")
+	switch reg.Platform {
+	case "", models.DevicePlatformAndroid, models.DevicePlatformIOS, models.DevicePlatformWeb:
+	default:
+		return nil, errors.New("invalid platform")
+	}
 
-	return &NotificationService{
-		userRepo:        userRepo,
-		messagingClient: client,
-		ctx:             ctx,
-	}, nil
-}
+	device := &models.Device{
+		UserID:     userID,
+		Token:      token,
+		Platform:   reg.Platform,
+		AppVersion: reg.AppVersion,
+		Locale:     reg.Locale,
+		LastSeenAt: time.Now(),
+	}
+	if err := s.deviceRepo.Upsert(device); err != nil {
+		return nil, err
+	}
 
-// RegisterDevice registers a device FCM token for a user
-func (s *NotificationService) RegisterDevice(userID, fcmToken string) error {
-	user, err := s.userRepo.FindByID(userID)
-	if err != nil {
-		return err
+	if err := s.deviceRepo.DeleteOldest(userID, maxDevicesPerUser); err != nil {
+		log.Printf("⚠️ Failed to trim devices of user %s: %v", userID, err)
 	}
+	return device, nil
+}
 
-	user.FCMToken = &fcmToken
-	return s.userRepo.Update(user)
+// ListDevices returns the registered devices of the user
+func (s *NotificationService) ListDevices(userID string) ([]models.Device, error) {
+	return s.deviceRepo.FindByUserID(userID)
 }
 
-// UnregisterDevice removes FCM token from user
-func (s *NotificationService) UnregisterDevice(userID string) error {
-	user, err := s.userRepo.FindByID(userID)
+// UnregisterDevice removes one device by its FCM token, or all devices of the user when
+// token is empty (e.g. "log out everywhere")
+func (s *NotificationService) UnregisterDevice(userID, token string) error {
+	if token == "" {
+		return s.deviceRepo.DeleteByUserID(userID)
+	}
+
+	removed, err := s.deviceRepo.DeleteByToken(userID, token)
 	if err != nil {
 		return err
 	}
-
-	user.FCMToken = nil
-	return s.userRepo.Update(user)
-}
-
-// SendTaskReminder sends a reminder notification for an upcoming task
-func (s *NotificationService) SendTaskReminder(userID, taskTitle string, deadline time.Time) error {
-	if s.messagingClient == nil {
-		return fmt.Errorf("FCM not configured")
+	if removed == 0 {
+		return errors.New("device not found")
 	}
+	return nil
+}
 
-	user, err := s.userRepo.FindByID(userID)
+// RemoveDevice removes one device of the user by ID
+func (s *NotificationService) RemoveDevice(userID, deviceID string) error {
+	removed, err := s.deviceRepo.DeleteByID(userID, deviceID)
 	if err != nil {
 		return err
 	}
-
-	if user.FCMToken == nil || *user.FCMToken == "" {
-		return fmt.Errorf("user has no FCM token registered")
+	if removed == 0 {
+		return errors.New("device not found")
 	}
+	return nil
+}
 
+// SendTaskReminder sends a reminder notification for an upcoming task
+func (s *NotificationService) SendTaskReminder(userID, taskID, taskTitle string, deadline time.Time, priority models.TaskPriority) error {
 	timeUntil := time.Until(deadline)
 	var timeStr string
 	if timeUntil.Hours() < 1 {
@@ -102,142 +155,366 @@ func (s *NotificationService) SendTaskReminder(userID, taskTitle string, deadlin
 		timeStr = fmt.Sprintf("%d hari lagi", int(timeUntil.Hours()/24))
 	}
 
-	message := &messaging.Message{
-		Token: *user.FCMToken,
-		Notification: &messaging.Notification{
-			Title: "⏰ Pengingat Tugas",
-			Body:  fmt.Sprintf("'%s' deadline %s!", taskTitle, timeStr),
-		},
+	title := "⏰ Pengingat Tugas"
+	notificationPriority := NotificationPriorityNormal
+	if priority == models.PriorityHigh {
+		title = "⏰ Pengingat Tugas Penting"
+		notificationPriority = NotificationPriorityHigh
+	}
+	if priority == "" {
+		priority = models.PriorityMedium
+	}
+
+	return s.Notify(userID, Notification{
+		Type:     models.NotificationTypeTaskReminder,
+		Priority: notificationPriority,
+		Title:    title,
+		Body:     fmt.Sprintf("'%s' deadline %s!", taskTitle, timeStr),
 		Data: map[string]string{
-			"type":     "task_reminder",
-			"task_id":  taskTitle,
+			"task_id":  taskID,
 			"deadline": deadline.Format(time.RFC3339),
+			"priority": string(priority),
 		},
-		Android: &messaging.AndroidConfig{
-			Priority: "high",
-			Notification: &messaging.AndroidNotification{
-				Sound: "default",
-				Color: "#FF6B35",
-			},
-		},
-		APNS: &messaging.APNSConfig{
-			Payload: &messaging.APNSPayload{
-				Aps: &messaging.Aps{
-					Sound: "default",
-				},
-			},
+		Color: "#FF6B35",
+	})
+}
+
+// SendWeatherAlert sends a weather-related notification
+func (s *NotificationService) SendWeatherAlert(userID, city, condition string, temperature float64) error {
+	return s.Notify(userID, Notification{
+		Type:     models.NotificationTypeWeather,
+		Priority: NotificationPriorityLow,
+		Title:    fmt.Sprintf("🌤️ Cuaca di %s", city),
+		Body:     fmt.Sprintf("%s, %.1f°C. %s", condition, temperature, getWeatherAdvice(condition)),
+		Data: map[string]string{
+			"city":        city,
+			"condition":   condition,
+			"temperature": fmt.Sprintf("%.1f", temperature),
 		},
-	}
+		Color: "#4A90E2",
+	})
+}
 
-	_, err = s.messagingClient.Send(s.ctx, message)
-	if err != nil {
-		return fmt.Errorf("failed to send notification: %w", err)
+// SendHealthRecommendation sends health/productivity recommendation
+func (s *NotificationService) SendHealthRecommendation(userID, recommendation string, workloadHours float64) error {
+	var title string
+	var emoji string
+	priority := NotificationPriorityLow
+
+	if workloadHours > 12 {
+		title = "⚠️ Beban Kerja Sangat Tinggi!"
+		emoji = "😰"
+		priority = NotificationPriorityNormal
+	} else if workloadHours > 10 {
+		title = "🔔 Peringatan Beban Kerja"
+		emoji = "😓"
+	} else {
+		title = "💡 Saran Produktivitas"
+		emoji = "😊"
 	}
 
-	log.Printf("✅ Task reminder sent to user %s for task '%s'", userID, taskTitle)
-	return nil
+	return s.Notify(userID, Notification{
+		Type:     models.NotificationTypeHealth,
+		Priority: priority,
+		Title:    title,
+		Body:     fmt.Sprintf("%s Anda sudah bekerja %.1f jam hari ini. %s", emoji, workloadHours, recommendation),
+		Data: map[string]string{
+			"workload_hours": fmt.Sprintf("%.1f", workloadHours),
+		},
+		Color: "#50C878",
+	})
 }
 
-// SendWeatherAlert sends a weather-related notification
-func (s *NotificationService) SendWeatherAlert(userID, city, condition string, temperature float64) error {
-	if s.messagingClient == nil {
-		return fmt.Errorf("FCM not configured")
+// SendDailyPlan notifies the user that today's plan is ready; the plan itself is already a bot message
+func (s *NotificationService) SendDailyPlan(userID string, taskCount int, firstTask string) error {
+	body := fmt.Sprintf("%d tugas sudah dijadwalkan hari ini.", taskCount)
+	if firstTask != "" {
+		body += fmt.Sprintf(" Mulai dengan '%s'.", firstTask)
 	}
 
-	user, err := s.userRepo.FindByID(userID)
-	if err != nil {
-		return err
+	return s.Notify(userID, Notification{
+		Type:     models.NotificationTypeDailyPlan,
+		Priority: NotificationPriorityNormal,
+		Title:    "🗓️ Rencana Kerja Hari Ini",
+		Body:     body,
+		Data: map[string]string{
+			"task_count": fmt.Sprintf("%d", taskCount),
+		},
+		InAppDelivered: true,
+	})
+}
+
+// SendWeeklyReview notifies the user that last week's review is ready; the review is already a bot message
+func (s *NotificationService) SendWeeklyReview(userID string, completed, planned int) error {
+	return s.Notify(userID, Notification{
+		Type:     models.NotificationTypeWeeklyReview,
+		Priority: NotificationPriorityLow,
+		Title:    "📊 Review Mingguan",
+		Body:     fmt.Sprintf("Minggu lalu kamu menyelesaikan %d dari %d tugas. Lihat saran lengkapnya di pesan.", completed, planned),
+		Data: map[string]string{
+			"completed": fmt.Sprintf("%d", completed),
+			"planned":   fmt.Sprintf("%d", planned),
+		},
+		InAppDelivered: true,
+	})
+}
+
+// SendOverdueNudge reminds the user of a task past its deadline; later nudges are more insistent
+func (s *NotificationService) SendOverdueNudge(userID, taskID, taskTitle string, deadline time.Time, nudge int) error {
+	late := time.Since(deadline)
+	var lateStr string
+	if late < 24*time.Hour {
+		lateStr = fmt.Sprintf("%d jam", int(late.Hours()))
+	} else {
+		lateStr = fmt.Sprintf("%d hari", int(late.Hours()/24))
 	}
 
-	if user.FCMToken == nil || *user.FCMToken == "" {
-		return fmt.Errorf("user has no FCM token registered")
+	title := "⚠️ Tugas Terlambat"
+	body := fmt.Sprintf("'%s' sudah melewati deadline. Yuk selesaikan atau jadwalkan ulang.", taskTitle)
+	if nudge >= 2 {
+		title = "🚨 Tugas Masih Terlambat"
+		body = fmt.Sprintf("'%s' sudah terlambat %s. Selesaikan sekarang atau ubah deadline-nya.", taskTitle, lateStr)
 	}
 
-	message := &messaging.Message{
-		Token: *user.FCMToken,
-		Notification: &messaging.Notification{
-			Title: fmt.Sprintf("🌤️ Cuaca di %s", city),
-			Body:  fmt.Sprintf("%s, %.1f°C. %s", condition, temperature, getWeatherAdvice(condition)),
-		},
+	return s.Notify(userID, Notification{
+		Type:     models.NotificationTypeTaskOverdue,
+		Priority: NotificationPriorityNormal,
+		Title:    title,
+		Body:     body,
 		Data: map[string]string{
-			"type":        "weather_alert",
-			"city":        city,
-			"condition":   condition,
-			"temperature": fmt.Sprintf("%.1f", temperature),
+			"task_id":  taskID,
+			"deadline": deadline.Format(time.RFC3339),
+			"nudge":    fmt.Sprintf("%d", nudge),
 		},
-		Android: &messaging.AndroidConfig{
-			Priority: "normal",
-			Notification: &messaging.AndroidNotification{
-				Sound: "default",
-				Color: "#4A90E2",
-			},
+		Color: "#FF6B35",
+	})
+}
+
+// SendTaskNote sends an absolute-time reminder for a task without deadline
+func (s *NotificationService) SendTaskNote(userID, taskID, taskTitle string) error {
+	return s.Notify(userID, Notification{
+		Type:     models.NotificationTypeTaskReminder,
+		Priority: NotificationPriorityNormal,
+		Title:    "⏰ Pengingat Tugas",
+		Body:     fmt.Sprintf("Jangan lupa: '%s'", taskTitle),
+		Data: map[string]string{
+			"task_id": taskID,
 		},
+		Color: "#FF6B35",
+	})
+}
+
+// GetPreferences returns the user's notification preferences, defaults if never set
+func (s *NotificationService) GetPreferences(userID string) (*models.NotificationPreference, error) {
+	if s.preferenceRepo == nil {
+		return DefaultNotificationPreference(userID), nil
+	}
+	pref, err := s.preferenceRepo.FindByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if pref == nil {
+		return DefaultNotificationPreference(userID), nil
+	}
+	fillNotificationChannels(pref)
+	return pref, nil
+}
+
+// UpdatePreferences applies a partial update to the user's notification preferences
+func (s *NotificationService) UpdatePreferences(userID string, dto UpdateNotificationPreferencesDTO) (*models.NotificationPreference, error) {
+	pref, err := s.GetPreferences(userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := ApplyNotificationPreferences(pref, dto); err != nil {
+		return nil, err
+	}
+	if s.preferenceRepo == nil {
+		return pref, nil
+	}
+	if err := s.preferenceRepo.Upsert(pref); err != nil {
+		return nil, err
 	}
+	return pref, nil
+}
 
-	_, err = s.messagingClient.Send(s.ctx, message)
+// Notify is the single entry point for user notifications. It applies the user's
+// preferences: the channel per type, quiet hours and the daily digest. Quiet hours and the
+// digest time are clock times in the user's timezone.
+func (s *NotificationService) Notify(userID string, n Notification) error {
+	pref, err := s.GetPreferences(userID)
 	if err != nil {
-		return fmt.Errorf("failed to send notification: %w", err)
+		return fmt.Errorf("failed to load notification preferences: %w", err)
+	}
+
+	route := RouteNotification(pref, n, time.Now().In(s.userLocation(userID)))
+	if (s.jobQueue == nil || s.preferenceRepo == nil) && (route.Action == RouteDefer || route.Action == RouteDigest) {
+		route.Action = RouteDeliver // Nothing to schedule with; deliver right away
+	}
+
+	switch route.Action {
+	case RouteDrop:
+		s.recordHistory(userID, n, route.Channel, models.NotificationStatusDropped, nil)
+		return nil
+	case RouteDefer:
+		if _, err := s.jobQueue.Enqueue(JobTypeNotification, notificationJob{UserID: userID, Notification: n}, JobOptions{
+			RunAt: route.At,
+		}); err != nil {
+			return fmt.Errorf("failed to defer notification: %w", err)
+		}
+		s.recordHistory(userID, n, route.Channel, models.NotificationStatusDeferred, nil)
+		log.Printf("🌙 %s notification for user %s deferred to %s (quiet hours)", n.Type, userID, route.At.Format("15:04"))
+		return nil
+	case RouteDigest:
+		if err := s.holdForDigest(userID, n, route.At); err != nil {
+			return err
+		}
+		s.recordHistory(userID, n, route.Channel, models.NotificationStatusDigested, nil)
+		return nil
 	}
 
-	log.Printf("✅ Weather alert sent to user %s for %s", userID, city)
+	if err := s.deliver(userID, n, route.Channel); err != nil {
+		s.recordHistory(userID, n, route.Channel, models.NotificationStatusFailed, err)
+		return err
+	}
+	s.recordHistory(userID, n, route.Channel, models.NotificationStatusSent, nil)
+	log.Printf("✅ %s notification sent to user %s via %s", n.Type, userID, route.Channel)
 	return nil
 }
 
-// SendHealthRecommendation sends health/productivity recommendation
-func (s *NotificationService) SendHealthRecommendation(userID, recommendation string, workloadHours float64) error {
-	if s.messagingClient == nil {
-		return fmt.Errorf("FCM not configured")
+// GetHistory returns the notifications sent to the user, newest first
+func (s *NotificationService) GetHistory(userID string, limit, offset int) ([]models.NotificationHistory, int64, error) {
+	if s.preferenceRepo == nil {
+		return nil, 0, nil
+	}
+	return s.preferenceRepo.FindHistory(userID, limit, offset)
+}
+
+// PruneHistory removes notification history older than notificationHistoryDays
+func (s *NotificationService) PruneHistory() (int64, error) {
+	if s.preferenceRepo == nil {
+		return 0, nil
+	}
+	return s.preferenceRepo.DeleteHistoryBefore(time.Now().AddDate(0, 0, -notificationHistoryDays))
+}
+
+// recordHistory stores one delivery attempt; failing to record never fails the notification
+func (s *NotificationService) recordHistory(userID string, n Notification, channel models.NotificationChannel, status models.NotificationStatus, sendErr error) {
+	if s.preferenceRepo == nil {
+		return
+	}
+	entry := &models.NotificationHistory{
+		UserID:  userID,
+		Type:    n.Type,
+		Channel: channel,
+		Status:  status,
+		Title:   n.Title,
+		Body:    n.Body,
+	}
+	if sendErr != nil {
+		msg := sendErr.Error()
+		entry.Error = &msg
+	}
+	if err := s.preferenceRepo.AddHistory(entry); err != nil {
+		log.Printf("⚠️ Failed to record notification history for user %s: %v", userID, err)
+		return
 	}
+	s.events.Publish(userID, EventNotificationCreated, entry)
+}
 
+// userLocation returns the user's timezone, the server timezone if it cannot be loaded
+func (s *NotificationService) userLocation(userID string) *time.Location {
+	if s.userRepo == nil {
+		return time.Local
+	}
 	user, err := s.userRepo.FindByID(userID)
 	if err != nil {
-		return err
+		log.Printf("⚠️ Failed to load timezone of user %s: %v", userID, err)
+		return time.Local
 	}
+	return user.Location()
+}
 
-	if user.FCMToken == nil || *user.FCMToken == "" {
-		return fmt.Errorf("user has no FCM token registered")
+// holdForDigest stores n and makes sure the user's next digest is scheduled
+func (s *NotificationService) holdForDigest(userID string, n Notification, at time.Time) error {
+	if err := s.preferenceRepo.AddDigestItem(&models.NotificationDigestItem{
+		UserID: userID,
+		Type:   n.Type,
+		Title:  n.Title,
+		Body:   n.Body,
+	}); err != nil {
+		return fmt.Errorf("failed to hold notification for digest: %w", err)
 	}
 
-	var title string
-	var emoji string
+	if _, err := s.jobQueue.Enqueue(JobTypeNotificationDigest, notificationDigestJob{UserID: userID}, JobOptions{
+		UniqueKey: fmt.Sprintf("digest:%s:%s", userID, at.Format("2006-01-02")),
+		RunAt:     at,
+	}); err != nil {
+		return fmt.Errorf("failed to schedule digest: %w", err)
+	}
+	return nil
+}
 
-	if workloadHours > 12 {
-		title = "⚠️ Beban Kerja Sangat Tinggi!"
-		emoji = "😰"
-	} else if workloadHours > 10 {
-		title = "🔔 Peringatan Beban Kerja"
-		emoji = "😓"
-	} else {
-		title = "💡 Saran Produktivitas"
-		emoji = "😊"
+// deliver sends n on a channel now
+func (s *NotificationService) deliver(userID string, n Notification, channel models.NotificationChannel) error {
+	if channel == models.NotificationChannelInApp && n.InAppDelivered {
+		return nil
 	}
 
-	message := &messaging.Message{
-		Token: *user.FCMToken,
-		Notification: &messaging.Notification{
-			Title: title,
-			Body:  fmt.Sprintf("%s Anda sudah bekerja %.1f jam hari ini. %s", emoji, workloadHours, recommendation),
-		},
-		Data: map[string]string{
-			"type":           "health_recommendation",
-			"workload_hours": fmt.Sprintf("%.1f", workloadHours),
-		},
-		Android: &messaging.AndroidConfig{
-			Priority: "normal",
-			Notification: &messaging.AndroidNotification{
-				Sound: "default",
-				Color: "#50C878",
-			},
-		},
+	notifier := s.notifiers[channel]
+	if notifier == nil {
+		if channel == models.NotificationChannelPush {
+			return ErrFCMNotConfigured
+		}
+		return fmt.Errorf("no notifier for channel %s", channel)
+	}
+	return notifier.Send(userID, n)
+}
+
+// notificationJob is the payload of a notification deferred by quiet hours
+type notificationJob struct {
+	UserID       string       `json:"user_id"`
+	Notification Notification `json:"notification"`
+}
+
+// notificationDigestJob is the payload of a user's daily digest
+type notificationDigestJob struct {
+	UserID string `json:"user_id"`
+}
+
+// handleNotificationJob routes a deferred notification again; preferences may have changed since
+func (s *NotificationService) handleNotificationJob(ctx context.Context, payload []byte) error {
+	var job notificationJob
+	if err := json.Unmarshal(payload, &job); err != nil {
+		return PermanentJobError(err)
 	}
+	return notificationJobError(s.Notify(job.UserID, job.Notification))
+}
 
-	_, err = s.messagingClient.Send(s.ctx, message)
+// handleDigestJob sends everything held for the user's digest as one notification
+func (s *NotificationService) handleDigestJob(ctx context.Context, payload []byte) error {
+	var job notificationDigestJob
+	if err := json.Unmarshal(payload, &job); err != nil {
+		return PermanentJobError(err)
+	}
+
+	items, err := s.preferenceRepo.FindDigestItems(job.UserID)
 	if err != nil {
-		return fmt.Errorf("failed to send notification: %w", err)
+		return err
+	}
+	if len(items) == 0 {
+		return nil
 	}
 
-	log.Printf("✅ Health recommendation sent to user %s (%.1fh workload)", userID, workloadHours)
-	return nil
+	if err := s.Notify(job.UserID, BuildDigestNotification(items)); err != nil {
+		return notificationJobError(err)
+	}
+
+	ids := make([]string, len(items))
+	for i, item := range items {
+		ids[i] = item.ID
+	}
+	return s.preferenceRepo.DeleteDigestItems(ids)
 }
 
 // Helper function for weather advice
@@ -0,0 +1,291 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	firebase "firebase.google.com/go/v4"
+	"firebase.google.com/go/v4/messaging"
+	"github.com/workradar/server/internal/models"
+	"github.com/workradar/server/internal/repository"
+	"google.golang.org/api/option"
+)
+
+// Supported notification sinks (NOTIFICATION_SINK); a sink replaces push and email
+const (
+	NotificationSinkMemory = "memory"
+	NotificationSinkFile   = "file"
+)
+
+// maxSinkEntries bounds the notifications a SinkNotifier keeps in memory
+const maxSinkEntries = 1000
+
+// Notifier delivers a notification to a user on one channel
+type Notifier interface {
+	Send(userID string, n Notification) error
+}
+
+// FCMNotifier pushes notifications to all active devices of a user via Firebase
+type FCMNotifier struct {
+	deviceRepo *repository.DeviceRepository
+	client     *messaging.Client
+	ctx        context.Context
+}
+
+// NewFCMNotifier initializes Firebase Cloud Messaging with a service account file
+func NewFCMNotifier(deviceRepo *repository.DeviceRepository, projectID, credentialsPath string) (*FCMNotifier, error) {
+	ctx := context.Background()
+
+	// Initialize Firebase App
+	opt := option.WithCredentialsFile(credentialsPath)
+	app, err := firebase.NewApp(ctx, &firebase.Config{
+		ProjectID: projectID,
+	}, opt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize Firebase app: %w", err)
+	}
+
+	// Get Messaging client
+	client, err := app.Messaging(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get messaging client: %w", err)
+	}
+
+	log.Println("✅ Firebase Cloud Messaging initialized successfully")
+
+	return NewFCMNotifierWithClient(deviceRepo, client), nil
+}
+
+// NewFCMNotifierWithClient uses an existing messaging client, e.g. one pointed at a fake FCM endpoint
+func NewFCMNotifierWithClient(deviceRepo *repository.DeviceRepository, client *messaging.Client) *FCMNotifier {
+	return &FCMNotifier{deviceRepo: deviceRepo, client: client, ctx: context.Background()}
+}
+
+// Send fans the notification out to all active devices of the user. Tokens that FCM
+// reports as unregistered or invalid are removed. Succeeds if any device received it.
+func (f *FCMNotifier) Send(userID string, n Notification) error {
+	devices, err := f.deviceRepo.FindActiveByUserID(userID, time.Now().AddDate(0, 0, -activeDeviceDays))
+	if err != nil {
+		return err
+	}
+	if len(devices) == 0 {
+		return ErrNoFCMToken
+	}
+
+	message := pushMessage(n)
+	message.Tokens = make([]string, len(devices))
+	for i, device := range devices {
+		message.Tokens[i] = device.Token
+	}
+
+	resp, err := f.client.SendEachForMulticast(f.ctx, message)
+	if err != nil {
+		return fmt.Errorf("failed to send notification: %w", err)
+	}
+
+	var invalid []string
+	var lastErr error
+	for i, r := range resp.Responses {
+		if r.Error == nil {
+			continue
+		}
+		if isInvalidTokenError(r.Error) {
+			invalid = append(invalid, message.Tokens[i])
+		} else {
+			lastErr = r.Error
+		}
+	}
+
+	if len(invalid) > 0 {
+		if err := f.deviceRepo.DeleteTokens(invalid); err != nil {
+			log.Printf("⚠️ Failed to prune %d invalid tokens of user %s: %v", len(invalid), userID, err)
+		} else {
+			log.Printf("🧹 Pruned %d invalid device tokens of user %s", len(invalid), userID)
+		}
+	}
+
+	if resp.SuccessCount == 0 {
+		if lastErr != nil {
+			return fmt.Errorf("failed to send notification: %w", lastErr)
+		}
+		return ErrNoFCMToken // Every token was invalid and has been pruned
+	}
+	return nil
+}
+
+// isInvalidTokenError reports FCM errors that mean the token will never work again
+func isInvalidTokenError(err error) bool {
+	if messaging.IsUnregistered(err) || messaging.IsSenderIDMismatch(err) {
+		return true
+	}
+	// INVALID_ARGUMENT is also used for bad payloads; only drop the token when it is the cause
+	return messaging.IsInvalidArgument(err) && strings.Contains(strings.ToLower(err.Error()), "registration token")
+}
+
+// pushMessage builds the FCM message for n
+func pushMessage(n Notification) *messaging.MulticastMessage {
+	data := map[string]string{"type": string(n.Type)}
+	for k, v := range n.Data {
+		data[k] = v
+	}
+
+	androidPriority := "normal"
+	if n.Priority == NotificationPriorityHigh || n.Type == models.NotificationTypeTaskReminder {
+		androidPriority = "high"
+	}
+	color := n.Color
+	if color == "" {
+		color = "#FF6B35"
+	}
+
+	return &messaging.MulticastMessage{
+		Notification: &messaging.Notification{
+			Title: n.Title,
+			Body:  n.Body,
+		},
+		Data: data,
+		Android: &messaging.AndroidConfig{
+			Priority: androidPriority,
+			Notification: &messaging.AndroidNotification{
+				Sound: "default",
+				Color: color,
+			},
+		},
+		APNS: &messaging.APNSConfig{
+			Payload: &messaging.APNSPayload{
+				Aps: &messaging.Aps{
+					Sound: "default",
+				},
+			},
+		},
+	}
+}
+
+// EmailNotifier sends notifications to the user's email address
+type EmailNotifier struct {
+	userRepo     *repository.UserRepository
+	emailService *EmailService
+}
+
+func NewEmailNotifier(userRepo *repository.UserRepository, emailService *EmailService) *EmailNotifier {
+	return &EmailNotifier{userRepo: userRepo, emailService: emailService}
+}
+
+func (e *EmailNotifier) Send(userID string, n Notification) error {
+	user, err := e.userRepo.FindByID(userID)
+	if err != nil {
+		return err
+	}
+	return e.emailService.Queue(EmailJob{
+		Kind:     EmailKindNotification,
+		To:       user.Email,
+		Lang:     user.Language,
+		UserName: user.Username,
+		Subject:  n.Title,
+		Body:     n.Body,
+	})
+}
+
+// InAppNotifier stores notifications as bot messages
+type InAppNotifier struct {
+	botMessageService *BotMessageService
+}
+
+func NewInAppNotifier(botMessageService *BotMessageService) *InAppNotifier {
+	return &InAppNotifier{botMessageService: botMessageService}
+}
+
+func (i *InAppNotifier) Send(userID string, n Notification) error {
+	metadata := map[string]interface{}{"notification_type": n.Type}
+	for k, v := range n.Data {
+		metadata[k] = v
+	}
+	_, err := i.botMessageService.SendMessage(userID, inAppMessageType(n.Type), n.Title, n.Body, metadata)
+	return err
+}
+
+// inAppMessageType picks the bot message type shown for a notification type
+func inAppMessageType(t models.NotificationType) models.MessageType {
+	switch t {
+	case models.NotificationTypeHealth:
+		return models.MessageTypeTip
+	case models.NotificationTypeDailyPlan:
+		return models.MessageTypePlan
+	case models.NotificationTypeWeeklyReview:
+		return models.MessageTypeReview
+	case models.NotificationTypeDigest:
+		return models.MessageTypeUpdate
+	default:
+		return models.MessageTypeAlert
+	}
+}
+
+// SentNotification is one notification captured by a SinkNotifier
+type SentNotification struct {
+	UserID       string                     `json:"user_id"`
+	Channel      models.NotificationChannel `json:"channel"`
+	Notification Notification               `json:"notification"`
+	SentAt       time.Time                  `json:"sent_at"`
+}
+
+// SinkNotifier captures notifications instead of delivering them, for tests and local
+// development. Notifications are kept in memory and, with a path, appended to that file
+// as JSON lines.
+type SinkNotifier struct {
+	channel models.NotificationChannel
+	path    string
+	mu      sync.Mutex
+	sent    []SentNotification
+}
+
+// NewSinkNotifier creates a sink for a channel; an empty path keeps notifications in memory only
+func NewSinkNotifier(channel models.NotificationChannel, path string) *SinkNotifier {
+	return &SinkNotifier{channel: channel, path: path}
+}
+
+func (s *SinkNotifier) Send(userID string, n Notification) error {
+	entry := SentNotification{UserID: userID, Channel: s.channel, Notification: n, SentAt: time.Now()}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sent = append(s.sent, entry)
+	if len(s.sent) > maxSinkEntries {
+		s.sent = s.sent[len(s.sent)-maxSinkEntries:]
+	}
+
+	if s.path == "" {
+		return nil
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open notification sink: %w", err)
+	}
+	defer file.Close()
+	_, err = file.Write(append(line, '\n'))
+	return err
+}
+
+// Sent returns the captured notifications, oldest first
+func (s *SinkNotifier) Sent() []SentNotification {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]SentNotification(nil), s.sent...)
+}
+
+// Reset forgets the captured notifications
+func (s *SinkNotifier) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sent = nil
+}
@@ -0,0 +1,109 @@
+package services
+
+import (
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+
+	"github.com/workradar/server/internal/models"
+)
+
+// Supported payment gateway providers
+const (
+	PaymentGatewayMidtrans = "midtrans"
+	PaymentGatewayFake     = "fake"
+)
+
+// ErrGatewayTransactionNotFound is returned when the gateway has no record of an order,
+// e.g. the user never opened the Snap payment page
+var ErrGatewayTransactionNotFound = errors.New("transaction not found in payment gateway")
+
+// GatewayTransactionStatus is the provider-agnostic snapshot of a transaction
+// as reported by the payment gateway
+type GatewayTransactionStatus struct {
+	OrderID           string `json:"order_id"`
+	TransactionStatus string `json:"transaction_status"`
+	FraudStatus       string `json:"fraud_status"`
+	PaymentType       string `json:"payment_type"`
+	GrossAmount       string `json:"gross_amount"`
+}
+
+// CheckoutRequest holds what a gateway needs to open a checkout page
+type CheckoutRequest struct {
+	OrderID       string
+	Amount        int64
+	ItemID        string
+	ItemName      string
+	CustomerName  string
+	CustomerEmail string
+}
+
+// CheckoutSession is the gateway's answer to a checkout request
+type CheckoutSession struct {
+	Token       string `json:"token"`
+	RedirectURL string `json:"redirect_url"`
+}
+
+// RefundResult is the gateway's answer to a refund request
+type RefundResult struct {
+	OrderID           string `json:"order_id"`
+	RefundKey         string `json:"refund_key"`
+	Amount            int64  `json:"amount"`
+	TransactionStatus string `json:"transaction_status"`
+}
+
+// PaymentStatusChecker queries the current state of an order from the payment gateway
+type PaymentStatusChecker interface {
+	CheckTransaction(orderID string) (*GatewayTransactionStatus, error)
+}
+
+// PaymentGateway abstracts a payment provider (Midtrans, or a fake for tests/local runs)
+type PaymentGateway interface {
+	PaymentStatusChecker
+
+	// Name returns the provider identifier (e.g. "midtrans")
+	Name() string
+	// CreateCheckout opens a hosted checkout for an order
+	CreateCheckout(req CheckoutRequest) (*CheckoutSession, error)
+	// VerifySignature validates the signature of an inbound notification
+	VerifySignature(orderID, statusCode, grossAmount, signatureKey string) bool
+	// Refund returns amount (full amount when 0) of a settled order to the customer
+	Refund(orderID string, amount int64, reason string) (*RefundResult, error)
+}
+
+// MapGatewayStatus converts a gateway transaction/fraud status pair into our TransactionStatus.
+// Expired payments keep their own status; they used to be stored as cancel.
+func MapGatewayStatus(transactionStatus, fraudStatus string) models.TransactionStatus {
+	switch transactionStatus {
+	case "capture":
+		switch fraudStatus {
+		case "accept":
+			return models.TransactionStatusSettlement
+		case "deny":
+			return models.TransactionStatusDeny
+		}
+		// Challenge (or unknown fraud status) stays pending until reviewed
+		return models.TransactionStatusPending
+	case "settlement":
+		return models.TransactionStatusSettlement
+	case "deny":
+		return models.TransactionStatusDeny
+	case "cancel":
+		return models.TransactionStatusCancel
+	case "expire":
+		return models.TransactionStatusExpire
+	case "refund":
+		return models.TransactionStatusRefund
+	case "partial_refund":
+		return models.TransactionStatusPartialRefund
+	default:
+		return models.TransactionStatusPending
+	}
+}
+
+// notificationSignature computes the Midtrans-style notification signature:
+// SHA512(order_id + status_code + gross_amount + server_key)
+func notificationSignature(orderID, statusCode, grossAmount, serverKey string) string {
+	hash := sha512.Sum512([]byte(orderID + statusCode + grossAmount + serverKey))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,209 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/workradar/server/internal/models"
+	"github.com/workradar/server/internal/repository"
+)
+
+// reconciliationReportRetention is how long stored reports are kept for admins
+const reconciliationReportRetention = 30 * 24 * time.Hour
+
+// reconciliationBatchSize limits how many pending transactions are checked per run
+const reconciliationBatchSize = 200
+
+// PaymentReconciliationService periodically re-checks stale pending transactions against
+// the payment gateway so a lost webhook does not leave an order pending forever
+type PaymentReconciliationService struct {
+	transactionRepo *repository.TransactionRepository
+	paymentService  *PaymentService
+	gateway         PaymentStatusChecker
+	interval        time.Duration
+	staleAfter      time.Duration
+	expireAfter     time.Duration
+	reportRepo      *repository.PaymentReconciliationRepository
+	runMu           sync.Mutex
+	stopChan        chan struct{}
+	wg              sync.WaitGroup
+}
+
+// NewPaymentReconciliationService creates a new payment reconciler
+func NewPaymentReconciliationService(
+	transactionRepo *repository.TransactionRepository,
+	reportRepo *repository.PaymentReconciliationRepository,
+	paymentService *PaymentService,
+	gateway PaymentStatusChecker,
+	interval, staleAfter, expireAfter time.Duration,
+) *PaymentReconciliationService {
+	return &PaymentReconciliationService{
+		transactionRepo: transactionRepo,
+		paymentService:  paymentService,
+		gateway:         gateway,
+		interval:        interval,
+		staleAfter:      staleAfter,
+		expireAfter:     expireAfter,
+		reportRepo:      reportRepo,
+		stopChan:        make(chan struct{}),
+	}
+}
+
+// Start begins the periodic reconciliation loop
+func (s *PaymentReconciliationService) Start() {
+	s.wg.Add(1)
+	go s.run()
+	log.Printf("💳 Payment reconciliation started (interval: %v, stale after: %v, expire after: %v)",
+		s.interval, s.staleAfter, s.expireAfter)
+}
+
+// Stop gracefully stops the reconciliation loop
+func (s *PaymentReconciliationService) Stop() {
+	close(s.stopChan)
+	s.wg.Wait()
+	log.Println("💳 Payment reconciliation stopped")
+}
+
+// run is the main reconciliation loop
+func (s *PaymentReconciliationService) run() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := s.Reconcile(); err != nil {
+				log.Printf("❌ Payment reconciliation failed: %v", err)
+			}
+		case <-s.stopChan:
+			return
+		}
+	}
+}
+
+// Reconcile checks every stale pending transaction against the gateway and applies
+// the same state machine as the Midtrans webhook
+func (s *PaymentReconciliationService) Reconcile() (*models.PaymentReconciliationReport, error) {
+	// Never run two reconciliations at once in this process (ticker + manual admin trigger).
+	// Other replicas may still overlap; applyTransactionStatus settles each order only once.
+	s.runMu.Lock()
+	defer s.runMu.Unlock()
+
+	now := time.Now()
+	report := models.PaymentReconciliationReport{
+		StartedAt:  now,
+		Mismatches: make([]models.ReconciliationMismatch, 0),
+	}
+
+	pending, err := s.transactionRepo.FindStalePending(now.Add(-s.staleAfter), reconciliationBatchSize)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range pending {
+		trx := &pending[i]
+		report.Checked++
+
+		gatewayStatus, checkErr := s.gateway.CheckTransaction(trx.OrderID)
+		target, action := EvaluateReconciliation(trx, gatewayStatus, checkErr, now, s.expireAfter)
+
+		mismatch := models.ReconciliationMismatch{
+			OrderID:        trx.OrderID,
+			UserID:         trx.UserID,
+			LocalStatus:    trx.Status,
+			ResolvedStatus: target,
+			Action:         action,
+		}
+		if gatewayStatus != nil {
+			mismatch.GatewayStatus = gatewayStatus.TransactionStatus
+		}
+
+		switch action {
+		case models.ReconciliationActionNone:
+			report.StillPending++
+			continue
+		case models.ReconciliationActionError:
+			report.Failed++
+			mismatch.Error = checkErr.Error()
+			report.Mismatches = append(report.Mismatches, mismatch)
+			continue
+		}
+
+		paymentType := ""
+		if gatewayStatus != nil {
+			paymentType = gatewayStatus.PaymentType
+		}
+
+		applied, err := s.paymentService.applyTransactionStatus(trx, target, paymentType)
+		if err != nil {
+			log.Printf("❌ Reconciliation failed to apply %s to order %s: %v", target, trx.OrderID, err)
+			report.Failed++
+			mismatch.Action = models.ReconciliationActionError
+			mismatch.Error = err.Error()
+		} else if !applied {
+			report.Skipped++
+			mismatch.Action = models.ReconciliationActionSkipped
+		} else if action == models.ReconciliationActionExpired {
+			report.Expired++
+		} else {
+			report.Updated++
+		}
+
+		report.Mismatches = append(report.Mismatches, mismatch)
+	}
+
+	report.CompletedAt = time.Now()
+	if err := s.reportRepo.Create(&report); err != nil {
+		log.Printf("⚠️ Failed to store payment reconciliation report: %v", err)
+	}
+	if _, err := s.reportRepo.DeleteOlderThan(now.Add(-reconciliationReportRetention)); err != nil {
+		log.Printf("⚠️ Failed to prune payment reconciliation reports: %v", err)
+	}
+
+	log.Printf("💳 Payment reconciliation done: checked=%d updated=%d expired=%d pending=%d skipped=%d failed=%d",
+		report.Checked, report.Updated, report.Expired, report.StillPending, report.Skipped, report.Failed)
+
+	return &report, nil
+}
+
+// EvaluateReconciliation decides the target status for a locally pending transaction
+// given the gateway's answer. It has no side effects.
+func EvaluateReconciliation(
+	trx *models.Transaction,
+	gatewayStatus *GatewayTransactionStatus,
+	checkErr error,
+	now time.Time,
+	expireAfter time.Duration,
+) (models.TransactionStatus, models.ReconciliationAction) {
+	if checkErr != nil {
+		// The gateway never saw this order: the user abandoned checkout
+		if errors.Is(checkErr, ErrGatewayTransactionNotFound) {
+			if now.Sub(trx.CreatedAt) > expireAfter {
+				return models.TransactionStatusExpire, models.ReconciliationActionExpired
+			}
+			return trx.Status, models.ReconciliationActionNone
+		}
+		return trx.Status, models.ReconciliationActionError
+	}
+
+	target := MapGatewayStatus(gatewayStatus.TransactionStatus, gatewayStatus.FraudStatus)
+	if target == trx.Status {
+		return trx.Status, models.ReconciliationActionNone
+	}
+	if target == models.TransactionStatusExpire {
+		return target, models.ReconciliationActionExpired
+	}
+	return target, models.ReconciliationActionUpdated
+}
+
+// GetReports returns the most recent stored reconciliation reports, newest first
+func (s *PaymentReconciliationService) GetReports(limit int) ([]models.PaymentReconciliationReport, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+	return s.reportRepo.FindRecent(limit)
+}
@@ -1,16 +1,10 @@
 package services
 
 import (
-	"crypto/sha512"
-	"encoding/hex"
 	"errors"
 	"log"
 
 	"github.com/google/uuid"
-	"github.com/midtrans/midtrans-go"
-	"github.com/midtrans/midtrans-go/coreapi"
-	"github.com/midtrans/midtrans-go/snap"
-	"github.com/workradar/server/internal/config"
 	"github.com/workradar/server/internal/models"
 	"github.com/workradar/server/internal/repository"
 )
@@ -20,8 +14,8 @@ type PaymentService struct {
 	userRepo          *repository.UserRepository
 	subService        *SubscriptionService
 	botMessageService *BotMessageService
-	snapClient        snap.Client
-	apiClient         coreapi.Client
+	gateway           PaymentGateway
+	events            *EventHub
 }
 
 func NewPaymentService(
@@ -29,46 +23,26 @@ func NewPaymentService(
 	userRepo *repository.UserRepository,
 	subService *SubscriptionService,
 	botMessageService *BotMessageService,
+	gateway PaymentGateway,
+	events *EventHub,
 ) *PaymentService {
-	// Initialize Midtrans Snap Client
-	var s snap.Client
-	var c coreapi.Client
-
-	// Validate server key
-	if config.AppConfig.MidtransServerKey == "" {
-		log.Fatal("MIDTRANS_SERVER_KEY is not set in environment variables")
-	}
-
-	// Set environment based on production flag
-	env := midtrans.Sandbox
-	if config.AppConfig.MidtransIsProduction {
-		env = midtrans.Production
-	}
-	
-	// Debug logging (masked for security)
-	serverKeyPrefix := "unknown"
-	if len(config.AppConfig.MidtransServerKey) > 10 {
-		serverKeyPrefix = config.AppConfig.MidtransServerKey[:10]
-	}
-	log.Printf("🔧 Midtrans Config - Environment: %v, IsProduction: %v, ServerKey prefix: %s..., Key length: %d", 
-		env, config.AppConfig.MidtransIsProduction, serverKeyPrefix, len(config.AppConfig.MidtransServerKey))
-	
-	// Initialize clients with proper environment
-	s.New(config.AppConfig.MidtransServerKey, env)
-	c.New(config.AppConfig.MidtransServerKey, env)
-	
-	log.Printf("✅ Midtrans initialized in %v mode", env)
+	log.Printf("💳 Payment gateway: %s", gateway.Name())
 
 	return &PaymentService{
 		transactionRepo:   transactionRepo,
 		userRepo:          userRepo,
 		subService:        subService,
 		botMessageService: botMessageService,
-		snapClient:        s,
-		apiClient:         c,
+		gateway:           gateway,
+		events:            events,
 	}
 }
 
+// Gateway returns the payment gateway used by this service
+func (s *PaymentService) Gateway() PaymentGateway {
+	return s.gateway
+}
+
 // CreateSnapToken creates a transaction and returns Snap Token, Redirect URL, and Order ID
 func (s *PaymentService) CreateSnapToken(userID string, planType models.PlanType) (string, string, string, error) {
 	// 1. Validate User
@@ -95,64 +69,37 @@ func (s *PaymentService) CreateSnapToken(userID string, planType models.PlanType
 	// 3. Generate Order ID with UUID (prevent collision)
 	orderID := "ORDER-" + uuid.New().String()
 
-	// 4. Create Snap Request
-	req := &snap.Request{
-		TransactionDetails: midtrans.TransactionDetails{
-			OrderID:  orderID,
-			GrossAmt: int64(amount),
-		},
-		CreditCard: &snap.CreditCardDetails{
-			Secure: true,
-		},
-		CustomerDetail: &midtrans.CustomerDetails{
-			FName: user.Username,
-			Email: user.Email,
-		},
-		Items: &[]midtrans.ItemDetails{
-			{
-				ID:    string(planType),
-				Name:  planName,
-				Price: int64(amount),
-				Qty:   1,
-			},
-		},
-	}
-
-	// 5. Request Snap Token
-	log.Printf("📤 Creating Midtrans transaction - OrderID: %s, Amount: %.0f, User: %s (%s)", orderID, amount, user.Username, user.Email)
-	snapResp, err := s.snapClient.CreateTransaction(req)
-
-	// Check response validity FIRST (fix for Go interface nil gotcha)
-	// Midtrans SDK sometimes returns non-nil error interface with nil value
-	// If response is valid with token, treat as success regardless of error
-	if snapResp == nil {
-		log.Printf("❌ Midtrans returned nil response - OrderID: %s, Error: %v", orderID, err)
-		return "", "", "", errors.New("payment gateway returned nil response")
-	}
-
-	if snapResp.Token == "" {
-		log.Printf("❌ Midtrans returned empty token - OrderID: %s, Error: %v", orderID, err)
-		return "", "", "", errors.New("payment gateway returned empty token")
+	// 4. Request checkout from the payment gateway
+	log.Printf("📤 Creating %s transaction - OrderID: %s, Amount: %.0f, User: %s (%s)", s.gateway.Name(), orderID, amount, user.Username, user.Email)
+	session, err := s.gateway.CreateCheckout(CheckoutRequest{
+		OrderID:       orderID,
+		Amount:        int64(amount),
+		ItemID:        string(planType),
+		ItemName:      planName,
+		CustomerName:  user.Username,
+		CustomerEmail: user.Email,
+	})
+	if err != nil {
+		return "", "", "", err
 	}
 
-	// If we have valid response with token, transaction is successful
-	log.Printf("✅ Midtrans transaction SUCCESS - OrderID: %s, Token: %s, RedirectURL: %s", orderID, snapResp.Token, snapResp.RedirectURL)
+	log.Printf("✅ %s transaction SUCCESS - OrderID: %s, Token: %s, RedirectURL: %s", s.gateway.Name(), orderID, session.Token, session.RedirectURL)
 
-	// 6. Save Transaction to DB
+	// 5. Save Transaction to DB
 	trx := &models.Transaction{
 		OrderID:   orderID,
 		UserID:    userID,
 		PlanType:  planType,
 		Amount:    amount,
 		Status:    models.TransactionStatusPending,
-		SnapToken: snapResp.Token,
+		SnapToken: session.Token,
 	}
 
 	if err := s.transactionRepo.Create(trx); err != nil {
 		return "", "", "", err
 	}
 
-	return snapResp.Token, snapResp.RedirectURL, orderID, nil
+	return session.Token, session.RedirectURL, orderID, nil
 }
 
 // HandleNotification processes Midtrans webhook
@@ -172,61 +119,60 @@ func (s *PaymentService) HandleNotification(notificationPayload map[string]inter
 		return errRepo
 	}
 
-	// 3. IDEMPOTENCY CHECK: Skip if already settled
-	if trx.Status == models.TransactionStatusSettlement {
-		log.Printf("⏭️  Transaction %s already settled, skipping webhook processing", orderID)
-		return nil // Return OK so Midtrans doesn't retry
-	}
-
-	// 4. Check Transaction Status from Midtrans
-	transactionStatusResp, err := s.apiClient.CheckTransaction(orderID)
+	// 3. Check Transaction Status from the gateway
+	gatewayStatus, err := s.gateway.CheckTransaction(orderID)
 	if err != nil {
-		log.Printf("❌ Error checking transaction with Midtrans: %v", err)
+		log.Printf("❌ Error checking transaction with payment gateway: %v", err)
 		return err
 	}
 
-	if transactionStatusResp == nil {
-		log.Printf("❌ Transaction not found in Midtrans: %s", orderID)
-		return errors.New("transaction not found")
-	}
+	// 4. Map gateway status
+	status := MapGatewayStatus(gatewayStatus.TransactionStatus, gatewayStatus.FraudStatus)
+	log.Printf("💳 Transaction %s status: %s → %s", orderID, gatewayStatus.TransactionStatus, status)
 
-	// 5. Update Status based on Midtrans Response
-	var status models.TransactionStatus
-	transactionStatus := transactionStatusResp.TransactionStatus
-	fraudStatus := transactionStatusResp.FraudStatus
+	// 5. IDEMPOTENCY CHECK: a settled transaction only moves on to a refund
+	if trx.Status == models.TransactionStatusSettlement &&
+		status != models.TransactionStatusRefund && status != models.TransactionStatusPartialRefund {
+		log.Printf("⏭️  Transaction %s already settled, skipping webhook processing", orderID)
+		return nil // Return OK so Midtrans doesn't retry
+	}
 
-	if transactionStatus == "capture" {
-		if fraudStatus == "challenge" {
-			status = models.TransactionStatusPending // Challenge pending
-		} else if fraudStatus == "accept" {
-			status = models.TransactionStatusSettlement
-		}
-	} else if transactionStatus == "settlement" {
-		status = models.TransactionStatusSettlement
-	} else if transactionStatus == "deny" {
-		status = models.TransactionStatusDeny
-	} else if transactionStatus == "cancel" || transactionStatus == "expire" {
-		status = models.TransactionStatusCancel
-	} else if transactionStatus == "pending" {
-		status = models.TransactionStatusPending
-	} else {
-		status = models.TransactionStatusPending
+	// Determine payment type from notification, falling back to the gateway response
+	paymentType, _ := notificationPayload["payment_type"].(string)
+	if paymentType == "" {
+		paymentType = gatewayStatus.PaymentType
 	}
 
-	log.Printf("💳 Transaction %s status: %s → %s", orderID, transactionStatus, status)
+	_, err = s.applyTransactionStatus(trx, status, paymentType)
+	return err
+}
+
+// applyTransactionStatus moves a transaction from the status it was read with to a new
+// status and runs its side effects (subscription activation, bot messages). Shared by
+// webhooks and the reconciler, which may race on other replicas: the update is a
+// compare-and-set and only the caller that wins it runs the side effects. Returns false
+// when there was nothing to do or another process changed the status first.
+func (s *PaymentService) applyTransactionStatus(trx *models.Transaction, status models.TransactionStatus, paymentType string) (bool, error) {
+	orderID := trx.OrderID
+	if status == trx.Status {
+		return false, nil
+	}
 
 	// Update transaction status
-	if err := s.transactionRepo.UpdateStatus(orderID, status); err != nil {
-		return err
+	applied, err := s.transactionRepo.UpdateStatus(orderID, trx.Status, status)
+	if err != nil {
+		return false, err
 	}
+	if !applied {
+		log.Printf("⏭️  Transaction %s changed concurrently, skipping %s → %s", orderID, trx.Status, status)
+		return false, nil
+	}
+	s.publishPaymentStatus(trx, status)
 
-	// 6. If Success (Settlement), Activate Subscription & Send Success Message
+	// If Success (Settlement), Activate Subscription & Send Success Message
 	if status == models.TransactionStatusSettlement {
 		log.Printf("Payment success for order: %s. Upgrading user...", orderID)
 
-		// Determine payment type from notification
-		paymentType, _ := notificationPayload["payment_type"].(string)
-
 		// Update payment method in transaction
 		if paymentType != "" {
 			_ = s.transactionRepo.UpdatePaymentMethod(orderID, paymentType)
@@ -236,7 +182,11 @@ func (s *PaymentService) HandleNotification(notificationPayload map[string]inter
 		_, err := s.subService.CreateSubscription(trx.UserID, trx.PlanType, paymentType, orderID)
 		if err != nil {
 			log.Printf("Failed to upgrade subscription for order %s: %v", orderID, err)
-			return err
+			// Put the previous status back so a webhook retry or the reconciler can settle it again
+			if _, rollbackErr := s.transactionRepo.UpdateStatus(orderID, status, trx.Status); rollbackErr != nil {
+				log.Printf("Failed to restore status of order %s: %v", orderID, rollbackErr)
+			}
+			return false, err
 		}
 
 		// Send success bot message
@@ -246,6 +196,12 @@ func (s *PaymentService) HandleNotification(notificationPayload map[string]inter
 				// Don't return error, payment was successful
 			}
 		}
+	} else if status == models.TransactionStatusRefund {
+		// Refunded payments no longer grant VIP
+		if err := s.subService.RevokeSubscription(trx.UserID, orderID); err != nil {
+			log.Printf("Failed to revoke subscription for refunded order %s: %v", orderID, err)
+			return true, err
+		}
 	} else if status == models.TransactionStatusDeny || status == models.TransactionStatusCancel || status == models.TransactionStatusExpire {
 		// Send failure bot message for denied, cancelled, or expired payments
 		if s.botMessageService != nil {
@@ -263,7 +219,7 @@ func (s *PaymentService) HandleNotification(notificationPayload map[string]inter
 		}
 	}
 
-	return nil
+	return true, nil
 }
 
 // GetPaymentHistory retrieves all transactions for a user
@@ -283,8 +239,26 @@ func (s *PaymentService) CancelTransaction(orderID string) error {
 		return errors.New("only pending transactions can be cancelled")
 	}
 
-	// Update status to cancelled
-	return s.transactionRepo.UpdateStatus(orderID, models.TransactionStatusCancel)
+	// Update status to cancelled (unless a payment notification got there first)
+	applied, err := s.transactionRepo.UpdateStatus(orderID, models.TransactionStatusPending, models.TransactionStatusCancel)
+	if err != nil {
+		return err
+	}
+	if !applied {
+		return errors.New("only pending transactions can be cancelled")
+	}
+	s.publishPaymentStatus(trx, models.TransactionStatusCancel)
+	return nil
+}
+
+// publishPaymentStatus tells the user's open apps that a transaction changed status
+func (s *PaymentService) publishPaymentStatus(trx *models.Transaction, status models.TransactionStatus) {
+	s.events.Publish(trx.UserID, EventPaymentUpdated, map[string]interface{}{
+		"order_id":  trx.OrderID,
+		"status":    status,
+		"plan_type": trx.PlanType,
+		"amount":    trx.Amount,
+	})
 }
 
 // GetTransactionByOrderID retrieves a transaction by order ID
@@ -292,7 +266,7 @@ func (s *PaymentService) GetTransactionByOrderID(orderID string) (*models.Transa
 	return s.transactionRepo.FindByOrderID(orderID)
 }
 
-// VerifyNotificationSignature verifies the signature from Midtrans webhook
+// VerifyNotificationSignature verifies the signature from the gateway webhook
 // This prevents fake webhook attacks
 func (s *PaymentService) VerifyNotificationSignature(
 	orderID string,
@@ -300,17 +274,65 @@ func (s *PaymentService) VerifyNotificationSignature(
 	grossAmount string,
 	signatureKey string,
 ) bool {
-	// Midtrans signature formula: SHA512(order_id + status_code + gross_amount + server_key)
-	input := orderID + statusCode + grossAmount + config.AppConfig.MidtransServerKey
-	hash := sha512.Sum512([]byte(input))
-	calculatedSignature := hex.EncodeToString(hash[:])
-
-	isValid := calculatedSignature == signatureKey
+	isValid := s.gateway.VerifySignature(orderID, statusCode, grossAmount, signatureKey)
 	if !isValid {
-		log.Printf("❌ Invalid signature for order %s. Expected: %s, Got: %s",
-			orderID, calculatedSignature[:32]+"...", signatureKey[:32]+"...")
+		log.Printf("❌ Invalid signature for order %s", orderID)
 	} else {
 		log.Printf("✅ Valid signature for order %s", orderID)
 	}
 	return isValid
 }
+
+// SimulatePayment drives an order through the fake gateway and processes the resulting
+// notification exactly like the Midtrans webhook. Only available with the fake gateway.
+func (s *PaymentService) SimulatePayment(userID, orderID, transactionStatus, fraudStatus, paymentType string) (*models.Transaction, error) {
+	fakeGateway, ok := s.gateway.(*FakePaymentGateway)
+	if !ok {
+		return nil, errors.New("payment simulation is only available with the fake gateway")
+	}
+
+	trx, err := s.transactionRepo.FindByOrderID(orderID)
+	if err != nil || trx.UserID != userID {
+		return nil, errors.New("transaction not found")
+	}
+
+	payload, err := fakeGateway.Simulate(orderID, transactionStatus, fraudStatus, paymentType)
+	if err != nil {
+		return nil, err
+	}
+
+	if !s.VerifyNotificationSignature(orderID, payload["status_code"].(string), payload["gross_amount"].(string), payload["signature_key"].(string)) {
+		return nil, errors.New("invalid signature")
+	}
+
+	if err := s.HandleNotification(payload); err != nil {
+		return nil, err
+	}
+
+	return s.transactionRepo.FindByOrderID(orderID)
+}
+
+// RefundTransaction refunds a settled transaction through the gateway and revokes its subscription
+func (s *PaymentService) RefundTransaction(orderID, reason string) (*RefundResult, error) {
+	trx, err := s.transactionRepo.FindByOrderID(orderID)
+	if err != nil {
+		return nil, errors.New("transaction not found")
+	}
+
+	if trx.Status != models.TransactionStatusSettlement {
+		return nil, errors.New("only settled transactions can be refunded")
+	}
+
+	result, err := s.gateway.Refund(orderID, int64(trx.Amount), reason)
+	if err != nil {
+		log.Printf("❌ Refund failed for order %s: %v", orderID, err)
+		return nil, err
+	}
+
+	if _, err := s.applyTransactionStatus(trx, models.TransactionStatusRefund, ""); err != nil {
+		return nil, err
+	}
+
+	log.Printf("💸 Transaction %s refunded (%d)", orderID, result.Amount)
+	return result, nil
+}
@@ -0,0 +1,393 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/workradar/server/internal/models"
+)
+
+const (
+	// defaultPlanTaskMinutes is used for tasks without DurationMinutes
+	defaultPlanTaskMinutes = 30
+	// planBreakMinutes is inserted after focus work or long stretches without rest
+	planBreakMinutes = 15
+	// planMaxStretchMinutes of continuous work before a break is planned
+	planMaxStretchMinutes = 120
+	// planHorizonDays limits the plan to tasks due soon (plus tasks without deadline)
+	planHorizonDays = 7
+)
+
+const (
+	PlanItemTask  = "task"
+	PlanItemBreak = "break"
+)
+
+// WorkHours is one day of the User.WorkDays configuration
+type WorkHours struct {
+	IsWorkDay bool   `json:"is_work_day"`
+	Start     string `json:"start"` // HH:MM
+	End       string `json:"end"`   // HH:MM
+}
+
+// PlanItem is a scheduled task or break of a daily plan
+type PlanItem struct {
+	Kind            string     `json:"kind"` // task, break
+	TaskID          string     `json:"task_id,omitempty"`
+	Title           string     `json:"title"`
+	Start           time.Time  `json:"start"`
+	End             time.Time  `json:"end"`
+	DurationMinutes int        `json:"duration_minutes"`
+	Difficulty      string     `json:"difficulty,omitempty"`
+	Deadline        *time.Time `json:"deadline,omitempty"`
+	// AtRisk is set when the slot ends after the task's deadline
+	AtRisk bool `json:"at_risk,omitempty"`
+}
+
+// DailyPlan is the ordered schedule of a user's work day
+type DailyPlan struct {
+	Date             string     `json:"date"` // YYYY-MM-DD
+	IsWorkDay        bool       `json:"is_work_day"`
+	DayOffReason     string     `json:"day_off_reason,omitempty"`
+	WorkStart        time.Time  `json:"work_start"`
+	WorkEnd          time.Time  `json:"work_end"`
+	Items            []PlanItem `json:"items"`
+	Unscheduled      []PlanItem `json:"unscheduled"` // Did not fit in today's work hours
+	PlannedMinutes   int        `json:"planned_minutes"`
+	AvailableMinutes int        `json:"available_minutes"`
+	Note             string     `json:"note,omitempty"`
+	Source           string     `json:"source"` // ai, rule
+}
+
+// defaultWorkHours applies when the user has not configured work days: Mon-Fri 09:00-17:00
+var defaultWorkHours = WorkHours{IsWorkDay: true, Start: "09:00", End: "17:00"}
+
+// ParseWorkDays reads User.WorkDays, keyed "0" (Monday) to "6" (Sunday). Missing or
+// invalid configuration falls back to Monday-Friday 09:00-17:00.
+func ParseWorkDays(raw *string) map[time.Weekday]WorkHours {
+	days := make(map[time.Weekday]WorkHours, 7)
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if d != time.Saturday && d != time.Sunday {
+			days[d] = defaultWorkHours
+		} else {
+			days[d] = WorkHours{}
+		}
+	}
+
+	if raw == nil || *raw == "" {
+		return days
+	}
+
+	var config map[string]WorkHours
+	if err := json.Unmarshal([]byte(*raw), &config); err != nil {
+		return days
+	}
+
+	for key, hours := range config {
+		index, err := strconv.Atoi(key)
+		if err != nil || index < 0 || index > 6 {
+			continue
+		}
+		days[time.Weekday((index+1)%7)] = hours
+	}
+	return days
+}
+
+// WorkWindow returns the start and end of the work hours on date; ok is false on days off
+func (h WorkHours) WorkWindow(date time.Time) (start, end time.Time, ok bool) {
+	if !h.IsWorkDay {
+		return time.Time{}, time.Time{}, false
+	}
+
+	startClock, err1 := time.Parse("15:04", h.Start)
+	endClock, err2 := time.Parse("15:04", h.End)
+	if err1 != nil || err2 != nil {
+		startClock, _ = time.Parse("15:04", defaultWorkHours.Start)
+		endClock, _ = time.Parse("15:04", defaultWorkHours.End)
+	}
+
+	start = time.Date(date.Year(), date.Month(), date.Day(), startClock.Hour(), startClock.Minute(), 0, 0, date.Location())
+	end = time.Date(date.Year(), date.Month(), date.Day(), endClock.Hour(), endClock.Minute(), 0, 0, date.Location())
+	return start, end, end.After(start)
+}
+
+// PlanCandidates returns the pending tasks worth planning for the day, most urgent first:
+// overdue and soon-due tasks, then harder work (done while energy is high), then the
+// earliest deadline. Tasks without deadline come last.
+func PlanCandidates(tasks []models.Task, now time.Time) []models.Task {
+	horizon := now.AddDate(0, 0, planHorizonDays)
+
+	var candidates []models.Task
+	for _, t := range tasks {
+		if t.IsCompleted {
+			continue
+		}
+		if t.Deadline != nil && t.Deadline.After(horizon) {
+			continue
+		}
+		candidates = append(candidates, t)
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if ua, ub := taskUrgency(a, now), taskUrgency(b, now); ua != ub {
+			return ua > ub
+		}
+		if da, db := difficultyRank(a.Difficulty), difficultyRank(b.Difficulty); da != db {
+			return da > db
+		}
+		if a.Deadline == nil || b.Deadline == nil {
+			return a.Deadline != nil
+		}
+		return a.Deadline.Before(*b.Deadline)
+	})
+	return candidates
+}
+
+// BuildDailyPlan fits ordered tasks into the work window. Work starts at the later of the
+// window start and now; a break follows focus tasks and long stretches, and two focus tasks
+// are never scheduled back to back when a lighter task can go in between.
+func BuildDailyPlan(ordered []models.Task, workStart, workEnd, now time.Time) *DailyPlan {
+	plan := &DailyPlan{
+		Date:        workStart.Format("2006-01-02"),
+		IsWorkDay:   true,
+		WorkStart:   workStart,
+		WorkEnd:     workEnd,
+		Items:       []PlanItem{},
+		Unscheduled: []PlanItem{},
+		Source:      "rule",
+	}
+
+	cursor := workStart
+	if now.After(cursor) {
+		// Round up to the next 5 minutes
+		cursor = now.Truncate(5 * time.Minute)
+		if cursor.Before(now) {
+			cursor = cursor.Add(5 * time.Minute)
+		}
+	}
+	if workEnd.After(cursor) {
+		plan.AvailableMinutes = int(workEnd.Sub(cursor).Minutes())
+	}
+
+	queue := append([]models.Task(nil), ordered...)
+	stretch := 0
+	lastFocus := false
+
+	for len(queue) > 0 {
+		// Avoid back-to-back focus work by pulling the next lighter task forward
+		next := 0
+		if lastFocus && isFocusTask(queue[0]) {
+			for i := 1; i < len(queue); i++ {
+				if !isFocusTask(queue[i]) {
+					next = i
+					break
+				}
+			}
+		}
+		task := queue[next]
+		queue = append(queue[:next], queue[next+1:]...)
+
+		minutes := planTaskMinutes(task)
+		item := newPlanTaskItem(task, minutes)
+
+		if stretch > 0 && (lastFocus || stretch+minutes > planMaxStretchMinutes) {
+			breakEnd := cursor.Add(planBreakMinutes * time.Minute)
+			if !breakEnd.Add(time.Duration(minutes) * time.Minute).After(workEnd) {
+				plan.Items = append(plan.Items, PlanItem{
+					Kind:            PlanItemBreak,
+					Title:           "Istirahat",
+					Start:           cursor,
+					End:             breakEnd,
+					DurationMinutes: planBreakMinutes,
+				})
+				cursor = breakEnd
+				stretch = 0
+			}
+		}
+
+		end := cursor.Add(time.Duration(minutes) * time.Minute)
+		if end.After(workEnd) {
+			plan.Unscheduled = append(plan.Unscheduled, item)
+			continue
+		}
+
+		item.Start = cursor
+		item.End = end
+		item.AtRisk = task.Deadline != nil && end.After(*task.Deadline)
+		plan.Items = append(plan.Items, item)
+		plan.PlannedMinutes += minutes
+
+		cursor = end
+		stretch += minutes
+		lastFocus = isFocusTask(task)
+	}
+
+	return plan
+}
+
+// ReorderTasks puts the tasks listed in ids first, in that order, followed by the remaining
+// tasks in their original order. Unknown and duplicate IDs are ignored.
+func ReorderTasks(tasks []models.Task, ids []string) []models.Task {
+	index := make(map[string]int, len(tasks))
+	for i, t := range tasks {
+		index[t.ID] = i
+	}
+
+	used := make(map[int]bool, len(tasks))
+	ordered := make([]models.Task, 0, len(tasks))
+	for _, id := range ids {
+		i, ok := index[id]
+		if !ok || used[i] {
+			continue
+		}
+		used[i] = true
+		ordered = append(ordered, tasks[i])
+	}
+	for i, t := range tasks {
+		if !used[i] {
+			ordered = append(ordered, t)
+		}
+	}
+	return ordered
+}
+
+// WeeklyReview compares the tasks due in a week with what got done
+type WeeklyReview struct {
+	WeekStart      time.Time `json:"week_start"`
+	WeekEnd        time.Time `json:"week_end"`
+	PlannedTasks   int       `json:"planned_tasks"`   // Tasks due this week
+	CompletedTasks int       `json:"completed_tasks"` // Of the planned tasks
+	OverdueTasks   int       `json:"overdue_tasks"`   // Planned, still pending after the deadline
+	CompletionRate float64   `json:"completion_rate"` // Percent
+	OvertimeTasks  int       `json:"overtime_tasks"`
+	OvertimeHours  float64   `json:"overtime_hours"`
+	WeekendTasks   int       `json:"weekend_tasks"`
+	WeekendHours   float64   `json:"weekend_hours"`
+	CalculatedLoad float64   `json:"calculated_load"`
+	Summary        string    `json:"summary"`
+	Suggestions    []string  `json:"suggestions"`
+	Source         string    `json:"source"` // ai, rule
+}
+
+// BuildWeeklyReview summarizes the tasks due between weekStart and weekEnd, with overtime
+// figures from WorkloadService, and adds rule-based suggestions
+func BuildWeeklyReview(tasks []models.Task, stats *WorkloadStats, weekStart, weekEnd, now time.Time) *WeeklyReview {
+	review := &WeeklyReview{
+		WeekStart:   weekStart,
+		WeekEnd:     weekEnd,
+		Suggestions: []string{},
+		Source:      "rule",
+	}
+
+	for _, t := range tasks {
+		if t.Deadline == nil || t.Deadline.Before(weekStart) || t.Deadline.After(weekEnd) {
+			continue
+		}
+		review.PlannedTasks++
+		if t.IsCompleted {
+			review.CompletedTasks++
+		} else if t.Deadline.Before(now) {
+			review.OverdueTasks++
+		}
+	}
+	if review.PlannedTasks > 0 {
+		review.CompletionRate = float64(review.CompletedTasks) * 100 / float64(review.PlannedTasks)
+	}
+
+	if stats != nil {
+		review.OvertimeTasks = stats.OvertimeTasks
+		review.OvertimeHours = stats.OvertimeHours
+		review.WeekendTasks = stats.WeekendTasks
+		review.WeekendHours = stats.WeekendHours
+		review.CalculatedLoad = stats.CalculatedLoad
+	}
+
+	review.Summary = fmt.Sprintf("%d dari %d tugas minggu ini selesai (%.0f%%).",
+		review.CompletedTasks, review.PlannedTasks, review.CompletionRate)
+	review.Suggestions = weeklySuggestions(review)
+	return review
+}
+
+// weeklySuggestions gives rule-based advice for a review
+func weeklySuggestions(r *WeeklyReview) []string {
+	var suggestions []string
+
+	switch {
+	case r.PlannedTasks == 0:
+		suggestions = append(suggestions, "Belum ada tugas dengan deadline minggu ini. Tetapkan target mingguan agar progres lebih terukur.")
+	case r.CompletionRate >= 80:
+		suggestions = append(suggestions, "Kerja bagus! Sebagian besar tugas selesai tepat waktu. Pertahankan ritme ini.")
+	case r.CompletionRate < 50:
+		suggestions = append(suggestions, "Kurang dari separuh tugas selesai. Pecah tugas besar menjadi langkah kecil dan isi durasi yang realistis.")
+	}
+
+	if r.OverdueTasks > 0 {
+		suggestions = append(suggestions, fmt.Sprintf("Ada %d tugas terlambat. Jadwalkan ulang atau selesaikan di awal minggu depan.", r.OverdueTasks))
+	}
+	if r.OvertimeHours > 5 {
+		suggestions = append(suggestions, fmt.Sprintf("Kamu lembur sekitar %.1f jam minggu ini. Batasi pekerjaan di luar jam kerja agar tidak kelelahan.", r.OvertimeHours))
+	}
+	if r.WeekendTasks > 0 {
+		suggestions = append(suggestions, fmt.Sprintf("Ada %d tugas dikerjakan saat akhir pekan atau hari libur. Sisihkan waktu istirahat penuh.", r.WeekendTasks))
+	}
+
+	return suggestions
+}
+
+// workDaysConfig converts work hours back to the User.WorkDays map WorkloadService expects
+func workDaysConfig(days map[time.Weekday]WorkHours) map[string]interface{} {
+	config := make(map[string]interface{}, len(days))
+	for day, hours := range days {
+		key := strconv.Itoa((int(day) + 6) % 7) // Monday = "0"
+		config[key] = map[string]interface{}{
+			"is_work_day": hours.IsWorkDay,
+			"start":       hours.Start,
+			"end":         hours.End,
+		}
+	}
+	return config
+}
+
+func newPlanTaskItem(task models.Task, minutes int) PlanItem {
+	item := PlanItem{
+		Kind:            PlanItemTask,
+		TaskID:          task.ID,
+		Title:           task.Title,
+		DurationMinutes: minutes,
+		Deadline:        task.Deadline,
+	}
+	if task.Difficulty != nil {
+		item.Difficulty = *task.Difficulty
+	}
+	return item
+}
+
+func planTaskMinutes(task models.Task) int {
+	if task.DurationMinutes == nil || *task.DurationMinutes <= 0 {
+		return defaultPlanTaskMinutes
+	}
+	return *task.DurationMinutes
+}
+
+func isFocusTask(task models.Task) bool {
+	return task.Difficulty != nil && *task.Difficulty == "focus"
+}
+
+// difficultyRank orders focus > normal > relaxed
+func difficultyRank(difficulty *string) int {
+	if difficulty == nil {
+		return 1
+	}
+	switch *difficulty {
+	case "focus":
+		return 2
+	case "relaxed":
+		return 0
+	default:
+		return 1
+	}
+}
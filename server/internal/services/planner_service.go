@@ -0,0 +1,387 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/workradar/server/internal/models"
+	"github.com/workradar/server/internal/repository"
+)
+
+// plannerTimeout bounds a single LLM call of the planner; the rule-based plan is used after it
+const plannerTimeout = 45 * time.Second
+
+// PlannerService builds daily plans and weekly reviews. The assistant orders the day and
+// writes the advice; without an AI provider (or quota) the rule-based planner is used.
+type PlannerService struct {
+	userRepo            *repository.UserRepository
+	taskRepo            *repository.TaskRepository
+	holidayRepo         *repository.HolidayRepository
+	leaveRepo           *repository.LeaveRepository
+	workloadService     *WorkloadService
+	botMessageService   *BotMessageService
+	notificationService *NotificationService
+	usage               *AIUsageService
+	llm                 *LLMClient
+}
+
+func NewPlannerService(
+	userRepo *repository.UserRepository,
+	taskRepo *repository.TaskRepository,
+	holidayRepo *repository.HolidayRepository,
+	leaveRepo *repository.LeaveRepository,
+	workloadService *WorkloadService,
+	botMessageService *BotMessageService,
+	notificationService *NotificationService,
+	usage *AIUsageService,
+	llm *LLMClient,
+) *PlannerService {
+	return &PlannerService{
+		userRepo:            userRepo,
+		taskRepo:            taskRepo,
+		holidayRepo:         holidayRepo,
+		leaveRepo:           leaveRepo,
+		workloadService:     workloadService,
+		botMessageService:   botMessageService,
+		notificationService: notificationService,
+		usage:               usage,
+		llm:                 llm,
+	}
+}
+
+// UserLocation returns the user's timezone, or the server timezone when the user is unknown
+func (s *PlannerService) UserLocation(userID string) *time.Location {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return time.Local
+	}
+	return user.Location()
+}
+
+// GenerateDailyPlan plans the user's work day on date, in the user's timezone
+func (s *PlannerService) GenerateDailyPlan(userID string, date time.Time) (*DailyPlan, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	loc := user.Location()
+	day := startOfDay(date.In(loc))
+	hours := ParseWorkDays(user.WorkDays)[day.Weekday()]
+	workStart, workEnd, ok := hours.WorkWindow(day)
+
+	dayOff := &DailyPlan{
+		Date:        day.Format("2006-01-02"),
+		Items:       []PlanItem{},
+		Unscheduled: []PlanItem{},
+		Source:      "rule",
+	}
+	if !ok {
+		dayOff.DayOffReason = "Bukan hari kerja"
+		return dayOff, nil
+	}
+	if holiday, _ := s.holidayRepo.IsHolidayOnDate(&userID, day); holiday {
+		dayOff.DayOffReason = "Hari libur"
+		return dayOff, nil
+	}
+	if leave, _ := s.leaveRepo.IsLeaveOnDate(userID, day); leave {
+		dayOff.DayOffReason = "Sedang cuti"
+		return dayOff, nil
+	}
+
+	tasks, err := s.taskRepo.FindByUserIDAndComplete(userID, false)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().In(loc)
+	candidates := PlanCandidates(tasks, now)
+
+	order, note, aiOK := s.aiDailyOrder(userID, candidates, workStart, workEnd, now)
+	if aiOK {
+		candidates = ReorderTasks(candidates, order)
+	}
+
+	plan := BuildDailyPlan(candidates, workStart, workEnd, now)
+	if aiOK {
+		plan.Source = "ai"
+		plan.Note = note
+	} else {
+		plan.Note = dailyPlanNote(plan)
+	}
+	return plan, nil
+}
+
+// GenerateWeeklyReview reviews the week (Monday-Sunday) containing weekStart, in the user's timezone
+func (s *PlannerService) GenerateWeeklyReview(userID string, weekStart time.Time) (*WeeklyReview, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	loc := user.Location()
+	weekStart = startOfWeek(weekStart.In(loc))
+	weekEnd := weekStart.AddDate(0, 0, 7).Add(-time.Second)
+
+	tasks, err := s.taskRepo.FindByUserIDAndDateRange(userID, weekStart, weekEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	var holidays []time.Time
+	if list, err := s.holidayRepo.FindByDateRange(&userID, weekStart, weekEnd); err == nil {
+		for _, h := range list {
+			holidays = append(holidays, h.Date)
+		}
+	}
+
+	stats, err := s.workloadService.CalculateWorkloadWithMultipliers(
+		userID, weekStart, weekEnd, workDaysConfig(ParseWorkDays(user.WorkDays)), holidays,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	review := BuildWeeklyReview(tasks, stats, weekStart, weekEnd, time.Now().In(loc))
+	if summary, suggestions, ok := s.aiWeeklyNotes(userID, review); ok {
+		review.Summary = summary
+		review.Suggestions = suggestions
+		review.Source = "ai"
+	}
+	return review, nil
+}
+
+// DeliverDailyPlan sends today's plan as a bot message and push notification.
+// Nothing is sent on days off or when there is nothing to plan.
+func (s *PlannerService) DeliverDailyPlan(userID string) error {
+	plan, err := s.GenerateDailyPlan(userID, time.Now())
+	if err != nil {
+		return err
+	}
+	if !plan.IsWorkDay || (len(plan.Items) == 0 && len(plan.Unscheduled) == 0) {
+		return nil
+	}
+
+	if _, err := s.botMessageService.SendMessage(userID, models.MessageTypePlan,
+		"Rencana Kerja Hari Ini 🗓️", formatDailyPlan(plan), map[string]interface{}{"plan": plan}); err != nil {
+		return err
+	}
+
+	taskCount := 0
+	firstTask := ""
+	for _, item := range plan.Items {
+		if item.Kind != PlanItemTask {
+			continue
+		}
+		if taskCount == 0 {
+			firstTask = item.Title
+		}
+		taskCount++
+	}
+	if err := s.notificationService.SendDailyPlan(userID, taskCount, firstTask); err != nil {
+		log.Printf("⚠️ Daily plan push not sent to user %s: %v", userID, err)
+	}
+	return nil
+}
+
+// DeliverWeeklyReview sends the review of last week as a bot message and push notification
+func (s *PlannerService) DeliverWeeklyReview(userID string) error {
+	review, err := s.GenerateWeeklyReview(userID, time.Now().AddDate(0, 0, -7))
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.botMessageService.SendMessage(userID, models.MessageTypeReview,
+		"Review Mingguan 📊", formatWeeklyReview(review), map[string]interface{}{"review": review}); err != nil {
+		return err
+	}
+
+	if err := s.notificationService.SendWeeklyReview(userID, review.CompletedTasks, review.PlannedTasks); err != nil {
+		log.Printf("⚠️ Weekly review push not sent to user %s: %v", userID, err)
+	}
+	return nil
+}
+
+// aiDailyOrder asks the assistant to order the candidate tasks. ok is false when no
+// provider or quota is available, or the answer cannot be used.
+func (s *PlannerService) aiDailyOrder(userID string, tasks []models.Task, workStart, workEnd, now time.Time) ([]string, string, bool) {
+	if len(tasks) == 0 {
+		return nil, "", false
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Jam kerja hari ini: %s-%s. Sekarang: %s.\n",
+		workStart.Format("15:04"), workEnd.Format("15:04"), now.Format("02 Jan 2006 15:04")))
+	sb.WriteString("Tugas pending:\n")
+	for _, t := range tasks {
+		sb.WriteString(describeTask(&t))
+	}
+
+	var answer struct {
+		Order []string `json:"order"`
+		Note  string   `json:"note"`
+	}
+	if !s.completeJSON(userID, "Kamu adalah perencana kerja Workradar. Susun urutan tugas terbaik untuk hari ini: "+
+		"dahulukan tugas terlambat dan deadline terdekat, kerjakan tugas 'focus' saat energi masih tinggi, "+
+		"dan selingi dengan tugas ringan. Jawab HANYA dengan JSON: "+
+		`{"order": ["<id tugas>", ...], "note": "<saran singkat untuk hari ini, maksimal 2 kalimat>"}`,
+		sb.String(), &answer) {
+		return nil, "", false
+	}
+	if len(answer.Order) == 0 {
+		return nil, "", false
+	}
+	return answer.Order, strings.TrimSpace(answer.Note), true
+}
+
+// aiWeeklyNotes asks the assistant for the review summary and suggestions
+func (s *PlannerService) aiWeeklyNotes(userID string, review *WeeklyReview) (string, []string, bool) {
+	data, _ := json.Marshal(review)
+
+	var answer struct {
+		Summary     string   `json:"summary"`
+		Suggestions []string `json:"suggestions"`
+	}
+	if !s.completeJSON(userID, "Kamu adalah asisten produktivitas Workradar. Buat review mingguan dari data berikut "+
+		"(tugas direncanakan vs selesai, lembur, kerja akhir pekan). Gunakan bahasa Indonesia yang santai dan suportif. "+
+		`Jawab HANYA dengan JSON: {"summary": "<ringkasan 1-2 kalimat>", "suggestions": ["<saran>", ...]} dengan 2-4 saran.`,
+		string(data), &answer) {
+		return "", nil, false
+	}
+	if strings.TrimSpace(answer.Summary) == "" || len(answer.Suggestions) == 0 {
+		return "", nil, false
+	}
+	return strings.TrimSpace(answer.Summary), answer.Suggestions, true
+}
+
+// completeJSON runs one planner completion and decodes the JSON object in the reply
+func (s *PlannerService) completeJSON(userID, system, user string, out interface{}) bool {
+	if s.llm == nil || !s.llm.Enabled() {
+		return false
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), plannerTimeout)
+	defer cancel()
+
+	req := ChatRequest{
+		Messages: []ChatMessage{
+			{Role: "system", Content: system},
+			{Role: "user", Content: user},
+		},
+		Temperature: 0.3,
+		MaxTokens:   600,
+	}
+	var reservation *AIReservation
+	if s.usage != nil {
+		var err error
+		if reservation, _, err = s.usage.Reserve(userID, models.AIUsageKindPlanner, req); err != nil {
+			return false
+		}
+	}
+
+	resp, err := s.llm.Complete(ctx, req)
+	if err != nil {
+		if s.usage != nil {
+			s.usage.Release(reservation)
+		}
+		log.Printf("⚠️ Planner: AI unavailable, using rule-based planner: %v", err)
+		return false
+	}
+	if s.usage != nil {
+		s.usage.SettleCompletion(reservation, userID, models.AIUsageKindPlanner, req, resp)
+	}
+	if len(resp.Choices) == 0 {
+		return false
+	}
+
+	content := resp.Choices[0].Message.Content
+	start, end := strings.Index(content, "{"), strings.LastIndex(content, "}")
+	if start < 0 || end <= start {
+		return false
+	}
+	if err := json.Unmarshal([]byte(content[start:end+1]), out); err != nil {
+		log.Printf("⚠️ Planner: Invalid AI answer, using rule-based planner: %v", err)
+		return false
+	}
+	return true
+}
+
+// dailyPlanNote is the rule-based advice for a plan
+func dailyPlanNote(plan *DailyPlan) string {
+	atRisk := 0
+	for _, item := range plan.Items {
+		if item.AtRisk {
+			atRisk++
+		}
+	}
+
+	switch {
+	case len(plan.Items) == 0 && len(plan.Unscheduled) == 0:
+		return "Tidak ada tugas mendesak hari ini. Manfaatkan untuk mencicil pekerjaan berikutnya."
+	case atRisk > 0:
+		return fmt.Sprintf("%d tugas berisiko melewati deadline. Kerjakan sesuai urutan dan kabari pihak terkait jika perlu.", atRisk)
+	case len(plan.Unscheduled) > 0:
+		return fmt.Sprintf("%d tugas belum muat di jam kerja hari ini. Pertimbangkan menjadwalkan ulang atau mendelegasikannya.", len(plan.Unscheduled))
+	default:
+		return "Semua tugas muat di jam kerja. Jangan lupa istirahat di sela pekerjaan."
+	}
+}
+
+func formatDailyPlan(plan *DailyPlan) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Jam kerja: %s - %s\n\n", plan.WorkStart.Format("15:04"), plan.WorkEnd.Format("15:04")))
+
+	for _, item := range plan.Items {
+		line := fmt.Sprintf("%s - %s  %s", item.Start.Format("15:04"), item.End.Format("15:04"), item.Title)
+		if item.Kind == PlanItemBreak {
+			line = fmt.Sprintf("%s - %s  ☕ %s", item.Start.Format("15:04"), item.End.Format("15:04"), item.Title)
+		}
+		if item.AtRisk {
+			line += " ⚠️ melewati deadline"
+		}
+		sb.WriteString(line + "\n")
+	}
+
+	if len(plan.Unscheduled) > 0 {
+		sb.WriteString("\nBelum masuk jadwal:\n")
+		for _, item := range plan.Unscheduled {
+			sb.WriteString("- " + item.Title + "\n")
+		}
+	}
+
+	if plan.Note != "" {
+		sb.WriteString("\n💡 " + plan.Note)
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+func formatWeeklyReview(review *WeeklyReview) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Minggu %s - %s\n\n", review.WeekStart.Format("02 Jan"), review.WeekEnd.Format("02 Jan 2006")))
+	sb.WriteString(review.Summary + "\n\n")
+	sb.WriteString(fmt.Sprintf("✅ Selesai: %d/%d tugas\n", review.CompletedTasks, review.PlannedTasks))
+	if review.OverdueTasks > 0 {
+		sb.WriteString(fmt.Sprintf("⏰ Terlambat: %d tugas\n", review.OverdueTasks))
+	}
+	sb.WriteString(fmt.Sprintf("🌙 Lembur: %.1f jam\n", review.OvertimeHours))
+	if review.WeekendHours > 0 {
+		sb.WriteString(fmt.Sprintf("📅 Akhir pekan/libur: %.1f jam\n", review.WeekendHours))
+	}
+
+	if len(review.Suggestions) > 0 {
+		sb.WriteString("\nSaran:\n")
+		for _, suggestion := range review.Suggestions {
+			sb.WriteString("- " + suggestion + "\n")
+		}
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// startOfWeek returns Monday 00:00 of t's week
+func startOfWeek(t time.Time) time.Time {
+	offset := (int(t.Weekday()) + 6) % 7
+	return startOfDay(t).AddDate(0, 0, -offset)
+}
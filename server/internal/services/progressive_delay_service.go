@@ -4,12 +4,15 @@ import (
 	"crypto/rand"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"math"
+	"strconv"
 	"sync"
 	"time"
 
 	"github.com/workradar/server/internal/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // ============================================
@@ -43,131 +46,98 @@ func DefaultProgressiveDelayConfig() ProgressiveDelayConfig {
 	}
 }
 
-// AttemptRecord tracks login attempts
-type AttemptRecord struct {
-	Attempts     int
-	LastAttempt  time.Time
-	FirstAttempt time.Time
-	LockedUntil  *time.Time
-	CurrentDelay time.Duration
-}
+// Attempt counters live in the state store under these prefixes. A counter has a
+// lock key (the lockout end) and a wait key (when the next attempt is allowed) next to it.
+const (
+	delayEmailKeyPrefix = "delay:email:"
+	delayIPKeyPrefix    = "delay:ip:"
+	delayLockSuffix     = ":lock"
+	delayWaitSuffix     = ":wait"
+)
 
-// ProgressiveDelayService manages progressive delays
+// ProgressiveDelayService manages progressive delays. Failed attempts are counted with
+// the state store's atomic Incr, so concurrent failures on every replica all count.
+// A series of failures is forgotten ResetAfter after its first failure.
 type ProgressiveDelayService struct {
-	config        ProgressiveDelayConfig
-	ipAttempts    map[string]*AttemptRecord
-	emailAttempts map[string]*AttemptRecord
-	mu            sync.RWMutex
-	db            *gorm.DB
-	auditService  *AuditService
+	config       ProgressiveDelayConfig
+	store        StateStore
+	auditService *AuditService
 }
 
 // NewProgressiveDelayService creates a new service
-func NewProgressiveDelayService(db *gorm.DB, auditService *AuditService, config ...ProgressiveDelayConfig) *ProgressiveDelayService {
+func NewProgressiveDelayService(store StateStore, auditService *AuditService, config ...ProgressiveDelayConfig) *ProgressiveDelayService {
 	cfg := DefaultProgressiveDelayConfig()
 	if len(config) > 0 {
 		cfg = config[0]
 	}
 
-	service := &ProgressiveDelayService{
-		config:        cfg,
-		ipAttempts:    make(map[string]*AttemptRecord),
-		emailAttempts: make(map[string]*AttemptRecord),
-		db:            db,
-		auditService:  auditService,
+	return &ProgressiveDelayService{
+		config:       cfg,
+		store:        store,
+		auditService: auditService,
 	}
+}
 
-	// Start cleanup goroutine
-	go service.cleanupExpiredRecords()
-
-	return service
+// trackedKeys returns the counter keys for an email and IP
+func (s *ProgressiveDelayService) trackedKeys(email, ip string) []string {
+	var keys []string
+	if s.config.EnableEmailTracking && email != "" {
+		keys = append(keys, delayEmailKeyPrefix+email)
+	}
+	if s.config.EnableIPTracking && ip != "" {
+		keys = append(keys, delayIPKeyPrefix+ip)
+	}
+	return keys
 }
 
 // RecordFailedAttempt records a failed login attempt
-func (s *ProgressiveDelayService) RecordFailedAttempt(email, ip string) (delay time.Duration, isLocked bool, lockUntil *time.Time) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
+func (s *ProgressiveDelayService) RecordFailedAttempt(email, ip string) (delay time.Duration, isLocked bool, lockUntil *time.Time, err error) {
 	now := time.Now()
-	var maxDelay time.Duration
 
-	// Track by email
-	if s.config.EnableEmailTracking && email != "" {
-		record := s.getOrCreateRecord(s.emailAttempts, email, now)
-		d, locked, until := s.processAttempt(record, now)
-		if d > maxDelay {
-			maxDelay = d
+	for _, key := range s.trackedKeys(email, ip) {
+		until, err := s.loadTime(key + delayLockSuffix)
+		if err != nil {
+			return 0, false, nil, err
 		}
-		if locked {
-			isLocked = true
-			lockUntil = until
+		if until != nil && now.Before(*until) {
+			isLocked, lockUntil = true, until
+			continue
 		}
-	}
 
-	// Track by IP
-	if s.config.EnableIPTracking && ip != "" {
-		record := s.getOrCreateRecord(s.ipAttempts, ip, now)
-		d, locked, until := s.processAttempt(record, now)
-		if d > maxDelay {
-			maxDelay = d
-		}
-		if locked {
-			isLocked = true
-			lockUntil = until
+		attempts, err := s.store.Incr(key, s.config.ResetAfter)
+		if err != nil {
+			return 0, false, nil, err
 		}
-	}
-
-	return maxDelay, isLocked, lockUntil
-}
 
-// getOrCreateRecord gets or creates an attempt record
-func (s *ProgressiveDelayService) getOrCreateRecord(records map[string]*AttemptRecord, key string, now time.Time) *AttemptRecord {
-	record, exists := records[key]
-	if !exists {
-		record = &AttemptRecord{
-			FirstAttempt: now,
+		if attempts >= int64(s.config.LockoutThreshold) {
+			lockTime := now.Add(s.config.LockoutDuration)
+			if err := s.store.Set(key+delayLockSuffix, lockTime.Format(time.RFC3339Nano), s.config.LockoutDuration); err != nil {
+				return 0, false, nil, err
+			}
+			// Attempts start over once the lockout ends
+			if err := s.store.Delete(key); err != nil {
+				return 0, false, nil, err
+			}
+			isLocked, lockUntil = true, &lockTime
+			continue
 		}
-		records[key] = record
-	}
 
-	// Reset if too old
-	if now.Sub(record.LastAttempt) > s.config.ResetAfter {
-		record.Attempts = 0
-		record.FirstAttempt = now
-		record.LockedUntil = nil
-		record.CurrentDelay = 0
+		d := s.delayFor(attempts)
+		if err := s.store.Set(key+delayWaitSuffix, now.Add(d).Format(time.RFC3339Nano), d); err != nil {
+			return 0, false, nil, err
+		}
+		if d > delay {
+			delay = d
+		}
 	}
 
-	return record
+	return delay, isLocked, lockUntil, nil
 }
 
-// processAttempt processes a failed attempt
-func (s *ProgressiveDelayService) processAttempt(record *AttemptRecord, now time.Time) (delay time.Duration, isLocked bool, lockUntil *time.Time) {
-	// Check if currently locked
-	if record.LockedUntil != nil && now.Before(*record.LockedUntil) {
-		return 0, true, record.LockedUntil
-	}
-
-	// Clear lockout if expired
-	if record.LockedUntil != nil && now.After(*record.LockedUntil) {
-		record.LockedUntil = nil
-		record.Attempts = 0
-	}
-
-	// Increment attempts
-	record.Attempts++
-	record.LastAttempt = now
-
-	// Check for lockout
-	if record.Attempts >= s.config.LockoutThreshold {
-		lockTime := now.Add(s.config.LockoutDuration)
-		record.LockedUntil = &lockTime
-		return 0, true, &lockTime
-	}
-
-	// Calculate exponential delay
-	// delay = baseDelay * (multiplier ^ (attempts - 1))
-	exponent := float64(record.Attempts - 1)
+// delayFor calculates the exponential delay after a number of failed attempts:
+// delay = baseDelay * (multiplier ^ (attempts - 1)), capped at MaxDelaySeconds
+func (s *ProgressiveDelayService) delayFor(attempts int64) time.Duration {
+	exponent := float64(attempts - 1)
 	delaySeconds := s.config.BaseDelaySeconds * math.Pow(s.config.DelayMultiplier, exponent)
 
 	// Cap at max delay
@@ -175,107 +145,89 @@ func (s *ProgressiveDelayService) processAttempt(record *AttemptRecord, now time
 		delaySeconds = s.config.MaxDelaySeconds
 	}
 
-	delay = time.Duration(delaySeconds * float64(time.Second))
-	record.CurrentDelay = delay
-
-	return delay, false, nil
+	return time.Duration(delaySeconds * float64(time.Second))
 }
 
-// RecordSuccessfulLogin resets the counters for successful login
-func (s *ProgressiveDelayService) RecordSuccessfulLogin(email, ip string) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// loadTime returns nil when the key is missing
+func (s *ProgressiveDelayService) loadTime(key string) (*time.Time, error) {
+	value, found, err := s.store.Get(key)
+	if err != nil || !found {
+		return nil, err
+	}
 
-	if email != "" {
-		delete(s.emailAttempts, email)
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return nil, err
 	}
-	if ip != "" {
-		delete(s.ipAttempts, ip)
+	return &t, nil
+}
+
+// RecordSuccessfulLogin resets the counters for successful login
+func (s *ProgressiveDelayService) RecordSuccessfulLogin(email, ip string) error {
+	for _, key := range []string{delayEmailKeyPrefix + email, delayIPKeyPrefix + ip} {
+		if key == delayEmailKeyPrefix || key == delayIPKeyPrefix {
+			continue
+		}
+		for _, k := range []string{key, key + delayLockSuffix, key + delayWaitSuffix} {
+			if err := s.store.Delete(k); err != nil {
+				return err
+			}
+		}
 	}
+	return nil
 }
 
 // CheckIfLocked checks if email or IP is locked
-func (s *ProgressiveDelayService) CheckIfLocked(email, ip string) (isLocked bool, lockUntil *time.Time, remainingDelay time.Duration) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-
+func (s *ProgressiveDelayService) CheckIfLocked(email, ip string) (isLocked bool, lockUntil *time.Time, remainingDelay time.Duration, err error) {
 	now := time.Now()
 
-	// Check email lockout
-	if record, exists := s.emailAttempts[email]; exists {
-		if record.LockedUntil != nil && now.Before(*record.LockedUntil) {
-			return true, record.LockedUntil, 0
+	for _, key := range []string{delayEmailKeyPrefix + email, delayIPKeyPrefix + ip} {
+		until, err := s.loadTime(key + delayLockSuffix)
+		if err != nil {
+			return false, nil, 0, err
 		}
-		if record.CurrentDelay > 0 {
-			timeSinceAttempt := now.Sub(record.LastAttempt)
-			if timeSinceAttempt < record.CurrentDelay {
-				remainingDelay = record.CurrentDelay - timeSinceAttempt
-			}
+		if until != nil && now.Before(*until) {
+			return true, until, 0, nil
 		}
-	}
 
-	// Check IP lockout
-	if record, exists := s.ipAttempts[ip]; exists {
-		if record.LockedUntil != nil && now.Before(*record.LockedUntil) {
-			return true, record.LockedUntil, 0
+		next, err := s.loadTime(key + delayWaitSuffix)
+		if err != nil {
+			return false, nil, 0, err
 		}
-		if record.CurrentDelay > 0 {
-			timeSinceAttempt := now.Sub(record.LastAttempt)
-			if timeSinceAttempt < record.CurrentDelay {
-				d := record.CurrentDelay - timeSinceAttempt
-				if d > remainingDelay {
-					remainingDelay = d
-				}
+		if next != nil {
+			if d := next.Sub(now); d > remainingDelay {
+				remainingDelay = d
 			}
 		}
 	}
 
-	return false, nil, remainingDelay
+	return false, nil, remainingDelay, nil
 }
 
 // GetAttemptStats returns statistics for an email/IP
-func (s *ProgressiveDelayService) GetAttemptStats(email, ip string) map[string]interface{} {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-
+func (s *ProgressiveDelayService) GetAttemptStats(email, ip string) (map[string]interface{}, error) {
 	stats := map[string]interface{}{}
+	now := time.Now()
 
-	if record, exists := s.emailAttempts[email]; exists {
-		stats["email_attempts"] = record.Attempts
-		stats["email_locked"] = record.LockedUntil != nil && time.Now().Before(*record.LockedUntil)
-	}
-
-	if record, exists := s.ipAttempts[ip]; exists {
-		stats["ip_attempts"] = record.Attempts
-		stats["ip_locked"] = record.LockedUntil != nil && time.Now().Before(*record.LockedUntil)
-	}
-
-	return stats
-}
-
-// cleanupExpiredRecords periodically cleans up expired records
-func (s *ProgressiveDelayService) cleanupExpiredRecords() {
-	ticker := time.NewTicker(5 * time.Minute)
-	for range ticker.C {
-		s.mu.Lock()
-		now := time.Now()
-
-		// Cleanup email records
-		for key, record := range s.emailAttempts {
-			if now.Sub(record.LastAttempt) > s.config.ResetAfter {
-				delete(s.emailAttempts, key)
-			}
+	for name, key := range map[string]string{"email": delayEmailKeyPrefix + email, "ip": delayIPKeyPrefix + ip} {
+		value, found, err := s.store.Get(key)
+		if err != nil {
+			return nil, err
 		}
-
-		// Cleanup IP records
-		for key, record := range s.ipAttempts {
-			if now.Sub(record.LastAttempt) > s.config.ResetAfter {
-				delete(s.ipAttempts, key)
-			}
+		until, err := s.loadTime(key + delayLockSuffix)
+		if err != nil {
+			return nil, err
+		}
+		if !found && until == nil {
+			continue
 		}
 
-		s.mu.Unlock()
+		attempts, _ := strconv.Atoi(value)
+		stats[name+"_attempts"] = attempts
+		stats[name+"_locked"] = until != nil && now.Before(*until)
 	}
+
+	return stats, nil
 }
 
 // ============================================
@@ -283,6 +235,19 @@ func (s *ProgressiveDelayService) cleanupExpiredRecords() {
 // Minggu 7: Session Management
 // ============================================
 
+// sessionActivityInterval limits how often LastActivity is written for a busy session
+const sessionActivityInterval = time.Minute
+
+// refreshTokenReuseGrace is how long a rotated refresh token may still be presented by a
+// concurrent refresh (two tabs, a retried request) and get the token it was rotated to
+const refreshTokenReuseGrace = 30 * time.Second
+
+var (
+	ErrSessionNotFound     = errors.New("session not found or expired")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected, please login again")
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+)
+
 // SessionInfo represents an active session
 type SessionInfo struct {
 	ID           string    `json:"id"`
@@ -297,27 +262,12 @@ type SessionInfo struct {
 	IsCurrent    bool      `json:"is_current,omitempty"`
 }
 
-// ActiveSession database model
-type ActiveSession struct {
-	ID           string    `gorm:"type:varchar(36);primaryKey" json:"id"`
-	UserID       string    `gorm:"type:varchar(36);index" json:"user_id"`
-	TokenHash    string    `gorm:"type:varchar(64);uniqueIndex" json:"-"` // SHA-256 of token
-	DeviceInfo   string    `gorm:"type:varchar(255)" json:"device_info"`
-	IPAddress    string    `gorm:"type:varchar(45)" json:"ip_address"`
-	UserAgent    string    `gorm:"type:text" json:"user_agent"`
-	Location     string    `gorm:"type:varchar(255)" json:"location,omitempty"`
-	CreatedAt    time.Time `json:"created_at"`
-	LastActivity time.Time `json:"last_activity"`
-	ExpiresAt    time.Time `json:"expires_at"`
-}
-
 // SessionManagementService manages user sessions
 type SessionManagementService struct {
-	db             *gorm.DB
-	auditService   *AuditService
-	mu             sync.RWMutex
-	activeSessions map[string][]SessionInfo // userID -> sessions
-	maxSessions    int
+	db           *gorm.DB
+	auditService *AuditService
+	mu           sync.Mutex
+	maxSessions  int
 }
 
 // NewSessionManagementService creates a new session management service
@@ -327,90 +277,86 @@ func NewSessionManagementService(db *gorm.DB, auditService *AuditService, maxSes
 	}
 
 	service := &SessionManagementService{
-		db:             db,
-		auditService:   auditService,
-		activeSessions: make(map[string][]SessionInfo),
-		maxSessions:    maxSessions,
+		db:           db,
+		auditService: auditService,
+		maxSessions:  maxSessions,
 	}
 
-	// Auto-migrate
-	db.AutoMigrate(&ActiveSession{})
-
 	// Start cleanup goroutine
 	go service.cleanupExpiredSessions()
 
 	return service
 }
 
-// CreateSession creates a new session
-func (s *SessionManagementService) CreateSession(userID, tokenHash, deviceInfo, ipAddress, userAgent string, ttl time.Duration) (*SessionInfo, error) {
+// NewSessionID generates the ID of a session before its tokens are signed
+func NewSessionID() string {
+	return generateSessionID()
+}
+
+// CreateSession creates a new session; the oldest session is removed when the user
+// already has the maximum number of sessions
+func (s *SessionManagementService) CreateSession(sessionID, userID, tokenHash, deviceInfo, ipAddress, userAgent, location string, ttl time.Duration) (*SessionInfo, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	now := time.Now()
-	sessionID := generateSessionID()
-
-	session := ActiveSession{
+	session := models.ActiveSession{
 		ID:           sessionID,
 		UserID:       userID,
 		TokenHash:    tokenHash,
 		DeviceInfo:   deviceInfo,
 		IPAddress:    ipAddress,
 		UserAgent:    userAgent,
+		Location:     location,
 		CreatedAt:    now,
 		LastActivity: now,
 		ExpiresAt:    now.Add(ttl),
 	}
 
-	// Check session limit
-	var count int64
-	s.db.Model(&ActiveSession{}).Where("user_id = ?", userID).Count(&count)
-
-	if int(count) >= s.maxSessions {
-		// Remove oldest session
-		var oldest ActiveSession
-		s.db.Where("user_id = ?", userID).Order("created_at ASC").First(&oldest)
-		s.db.Delete(&oldest)
-	}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// Check session limit
+		var count int64
+		tx.Model(&models.ActiveSession{}).Where("user_id = ? AND expires_at > ?", userID, now).Count(&count)
+
+		if int(count) >= s.maxSessions {
+			// Remove least recently used session
+			var oldest models.ActiveSession
+			if err := tx.Where("user_id = ?", userID).Order("last_activity ASC").First(&oldest).Error; err == nil {
+				if _, err := deleteSessions(tx, "id = ?", oldest.ID); err != nil {
+					return err
+				}
+			}
+		}
 
-	// Save new session
-	if err := s.db.Create(&session).Error; err != nil {
+		// Save new session with the first refresh token of its family
+		if err := tx.Create(&session).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.RefreshToken{
+			FamilyID:  sessionID,
+			UserID:    userID,
+			TokenHash: tokenHash,
+			ExpiresAt: session.ExpiresAt,
+		}).Error
+	})
+	if err != nil {
 		return nil, err
 	}
 
 	// Log session creation
-	if s.auditService != nil {
-		details := map[string]interface{}{
-			"session_id":  sessionID,
-			"device_info": deviceInfo,
-			"ip_address":  ipAddress,
-		}
-		detailsJSON, _ := json.Marshal(details)
-		s.auditService.LogSecurityEvent(
-			models.SecurityEventType("SESSION_CREATED"),
-			models.SeverityInfo,
-			&userID,
-			ipAddress,
-			string(detailsJSON),
-			userAgent,
-		)
-	}
-
-	return &SessionInfo{
-		ID:           session.ID,
-		UserID:       session.UserID,
-		DeviceInfo:   session.DeviceInfo,
-		IPAddress:    session.IPAddress,
-		UserAgent:    session.UserAgent,
-		CreatedAt:    session.CreatedAt,
-		LastActivity: session.LastActivity,
-		ExpiresAt:    session.ExpiresAt,
-	}, nil
+	s.logSessionEvent(models.EventSessionCreated, userID, ipAddress, userAgent, map[string]interface{}{
+		"session_id":  sessionID,
+		"device_info": deviceInfo,
+		"ip_address":  ipAddress,
+	})
+
+	info := toSessionInfo(session, "")
+	return &info, nil
 }
 
-// GetUserSessions returns all sessions for a user
-func (s *SessionManagementService) GetUserSessions(userID string, currentTokenHash string) ([]SessionInfo, error) {
-	var sessions []ActiveSession
+// GetUserSessions returns all sessions for a user, marking the one with currentSessionID
+func (s *SessionManagementService) GetUserSessions(userID string, currentSessionID string) ([]SessionInfo, error) {
+	var sessions []models.ActiveSession
 	if err := s.db.Where("user_id = ? AND expires_at > ?", userID, time.Now()).
 		Order("last_activity DESC").
 		Find(&sessions).Error; err != nil {
@@ -419,66 +365,217 @@ func (s *SessionManagementService) GetUserSessions(userID string, currentTokenHa
 
 	result := make([]SessionInfo, len(sessions))
 	for i, sess := range sessions {
-		result[i] = SessionInfo{
-			ID:           sess.ID,
-			UserID:       sess.UserID,
-			DeviceInfo:   sess.DeviceInfo,
-			IPAddress:    sess.IPAddress,
-			UserAgent:    sess.UserAgent,
-			Location:     sess.Location,
-			CreatedAt:    sess.CreatedAt,
-			LastActivity: sess.LastActivity,
-			ExpiresAt:    sess.ExpiresAt,
-			IsCurrent:    sess.TokenHash == currentTokenHash,
-		}
+		result[i] = toSessionInfo(sess, currentSessionID)
 	}
 
 	return result, nil
 }
 
-// InvalidateSession invalidates a specific session
+// InvalidateSession invalidates a specific session and its refresh tokens
 func (s *SessionManagementService) InvalidateSession(userID, sessionID string) error {
-	return s.db.Where("id = ? AND user_id = ?", sessionID, userID).Delete(&ActiveSession{}).Error
+	var removed int64
+	err := s.db.Transaction(func(tx *gorm.DB) (err error) {
+		removed, err = deleteSessions(tx, "id = ? AND user_id = ?", sessionID, userID)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if removed == 0 {
+		return ErrSessionNotFound
+	}
+
+	s.logSessionEvent(models.EventSessionRevoked, userID, "", "", map[string]interface{}{
+		"session_id": sessionID,
+	})
+	return nil
+}
+
+// InvalidateAllSessions invalidates all sessions for a user except exceptSessionID
+func (s *SessionManagementService) InvalidateAllSessions(userID string, exceptSessionID string) (int64, error) {
+	var revoked int64
+	err := s.db.Transaction(func(tx *gorm.DB) (err error) {
+		revoked, err = deleteSessions(tx, "user_id = ? AND id != ?", userID, exceptSessionID)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if revoked > 0 {
+		s.logSessionEvent(models.EventSessionRevoked, userID, "", "", map[string]interface{}{
+			"revoked":      revoked,
+			"kept_session": exceptSessionID,
+		})
+	}
+	return revoked, nil
 }
 
-// InvalidateAllSessions invalidates all sessions for a user
-func (s *SessionManagementService) InvalidateAllSessions(userID string, exceptTokenHash string) error {
-	query := s.db.Where("user_id = ?", userID)
-	if exceptTokenHash != "" {
-		query = query.Where("token_hash != ?", exceptTokenHash)
+// RotateRefreshToken replaces the refresh token oldHash of a session with newHash and
+// extends the session; sealedNew is the new token sealed for a concurrent refresh.
+// Presenting a token that was rotated within refreshTokenReuseGrace into the current
+// token returns the sealed current token instead of rotating again. Presenting any
+// other token that was already rotated revokes the session with all its tokens and
+// returns ErrRefreshTokenReused together with the revoked session.
+func (s *SessionManagementService) RotateRefreshToken(sessionID, oldHash, newHash, sealedNew string, ttl time.Duration, ipAddress, userAgent string) (*models.ActiveSession, string, error) {
+	now := time.Now()
+	var session models.ActiveSession
+	reused := false
+	sealedCurrent := ""
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var token models.RefreshToken
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token_hash = ? AND family_id = ?", oldHash, sessionID).
+			First(&token).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidRefreshToken
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Where("id = ?", sessionID).First(&session).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrSessionNotFound
+			}
+			return err
+		}
+
+		if token.UsedAt != nil {
+			if now.Sub(*token.UsedAt) <= refreshTokenReuseGrace &&
+				token.ReplacedByHash == session.TokenHash && token.ReplacedBySealed != "" {
+				// A concurrent refresh rotated it moments ago; hand back the same token
+				sealedCurrent = token.ReplacedBySealed
+				return nil
+			}
+
+			// Either the legitimate client or an attacker holds a newer token; end them both
+			reused = true
+			_, err := deleteSessions(tx, "id = ?", sessionID)
+			return err
+		}
+		if token.ExpiresAt.Before(now) || session.ExpiresAt.Before(now) {
+			return ErrInvalidRefreshToken
+		}
+
+		if err := tx.Model(&token).Updates(map[string]interface{}{
+			"used_at":            now,
+			"replaced_by_hash":   newHash,
+			"replaced_by_sealed": sealedNew,
+		}).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&models.RefreshToken{
+			FamilyID:  sessionID,
+			UserID:    session.UserID,
+			TokenHash: newHash,
+			ExpiresAt: now.Add(ttl),
+		}).Error; err != nil {
+			return err
+		}
+
+		session.TokenHash = newHash
+		session.LastActivity = now
+		session.ExpiresAt = now.Add(ttl)
+		return tx.Model(&session).Updates(map[string]interface{}{
+			"token_hash":    newHash,
+			"last_activity": now,
+			"expires_at":    session.ExpiresAt,
+		}).Error
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	if reused {
+		detailsJSON, _ := json.Marshal(map[string]interface{}{
+			"session_id":  sessionID,
+			"device_info": session.DeviceInfo,
+			"session_ip":  session.IPAddress,
+		})
+		if s.auditService != nil {
+			s.auditService.LogSecurityEvent(
+				models.EventRefreshTokenReuse,
+				models.SeverityHigh,
+				&session.UserID,
+				ipAddress,
+				string(detailsJSON),
+				userAgent,
+			)
+		}
+		return &session, "", ErrRefreshTokenReused
 	}
-	return query.Delete(&ActiveSession{}).Error
+	return &session, sealedCurrent, nil
 }
 
-// ValidateSession validates a session by token hash
-func (s *SessionManagementService) ValidateSession(tokenHash string) (*ActiveSession, error) {
-	var session ActiveSession
-	if err := s.db.Where("token_hash = ? AND expires_at > ?", tokenHash, time.Now()).
-		First(&session).Error; err != nil {
+// ValidateSession returns the session if it is still active and records the activity
+func (s *SessionManagementService) ValidateSession(sessionID string) (*models.ActiveSession, error) {
+	var session models.ActiveSession
+	err := s.db.Where("id = ? AND expires_at > ?", sessionID, time.Now()).First(&session).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrSessionNotFound
+	}
+	if err != nil {
 		return nil, err
 	}
 
 	// Update last activity
-	s.db.Model(&session).Update("last_activity", time.Now())
+	if now := time.Now(); now.Sub(session.LastActivity) > sessionActivityInterval {
+		s.db.Model(&session).Update("last_activity", now)
+		session.LastActivity = now
+	}
 
 	return &session, nil
 }
 
-// RefreshSession extends session expiry
-func (s *SessionManagementService) RefreshSession(tokenHash string, ttl time.Duration) error {
-	return s.db.Model(&ActiveSession{}).
-		Where("token_hash = ?", tokenHash).
-		Updates(map[string]interface{}{
-			"last_activity": time.Now(),
-			"expires_at":    time.Now().Add(ttl),
-		}).Error
+func (s *SessionManagementService) logSessionEvent(eventType models.SecurityEventType, userID, ipAddress, userAgent string, details map[string]interface{}) {
+	if s.auditService == nil {
+		return
+	}
+	detailsJSON, _ := json.Marshal(details)
+	s.auditService.LogSecurityEvent(eventType, models.SeverityInfo, &userID, ipAddress, string(detailsJSON), userAgent)
+}
+
+// deleteSessions removes the sessions matching the conditions together with their
+// refresh tokens and returns how many sessions were removed
+func deleteSessions(tx *gorm.DB, query string, args ...interface{}) (int64, error) {
+	var ids []string
+	if err := tx.Model(&models.ActiveSession{}).Where(query, args...).Pluck("id", &ids).Error; err != nil {
+		return 0, err
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	if err := tx.Where("family_id IN ?", ids).Delete(&models.RefreshToken{}).Error; err != nil {
+		return 0, err
+	}
+	result := tx.Where("id IN ?", ids).Delete(&models.ActiveSession{})
+	return result.RowsAffected, result.Error
+}
+
+func toSessionInfo(sess models.ActiveSession, currentSessionID string) SessionInfo {
+	return SessionInfo{
+		ID:           sess.ID,
+		UserID:       sess.UserID,
+		DeviceInfo:   sess.DeviceInfo,
+		IPAddress:    sess.IPAddress,
+		UserAgent:    sess.UserAgent,
+		Location:     sess.Location,
+		CreatedAt:    sess.CreatedAt,
+		LastActivity: sess.LastActivity,
+		ExpiresAt:    sess.ExpiresAt,
+		IsCurrent:    sess.ID == currentSessionID,
+	}
 }
 
 // cleanupExpiredSessions periodically cleans up expired sessions
 func (s *SessionManagementService) cleanupExpiredSessions() {
 	ticker := time.NewTicker(1 * time.Hour)
 	for range ticker.C {
-		s.db.Where("expires_at < ?", time.Now()).Delete(&ActiveSession{})
+		now := time.Now()
+		s.db.Where("expires_at < ?", now).Delete(&models.ActiveSession{})
+		s.db.Where("expires_at < ?", now).Delete(&models.RefreshToken{})
 	}
 }
 
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const redisCommandTimeout = 5 * time.Second
+
+// redisIncrScript increments a counter and sets its expiry only when it was created,
+// so a fixed window is not extended by later increments
+var redisIncrScript = redis.NewScript(`local v = redis.call('INCR', KEYS[1])
+if v == 1 then redis.call('PEXPIRE', KEYS[1], ARGV[1]) end
+return v`)
+
+// RedisStateStore keeps state in Redis (or a Redis-compatible server such as Valkey or
+// KeyDB)
+type RedisStateStore struct {
+	client *redis.Client
+}
+
+// NewRedisStateStore connects to redis://[user:password@]host:port/db; rediss:// uses TLS
+func NewRedisStateStore(rawURL string) (*RedisStateStore, error) {
+	opts, err := redis.ParseURL(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid REDIS_URL: %w", err)
+	}
+
+	s := &RedisStateStore{client: redis.NewClient(opts)}
+
+	// Fail at startup rather than on the first request
+	if err := s.Ping(); err != nil {
+		s.client.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *RedisStateStore) Ping() error {
+	ctx, cancel := context.WithTimeout(context.Background(), redisCommandTimeout)
+	defer cancel()
+	return s.client.Ping(ctx).Err()
+}
+
+func (s *RedisStateStore) Get(key string) (string, bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisCommandTimeout)
+	defer cancel()
+
+	value, err := s.client.Get(ctx, key).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return value, true, nil
+}
+
+func (s *RedisStateStore) Set(key, value string, ttl time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), redisCommandTimeout)
+	defer cancel()
+	return s.client.Set(ctx, key, value, redisTTL(ttl)).Err()
+}
+
+func (s *RedisStateStore) Delete(key string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), redisCommandTimeout)
+	defer cancel()
+	return s.client.Del(ctx, key).Err()
+}
+
+func (s *RedisStateStore) Incr(key string, ttl time.Duration) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisCommandTimeout)
+	defer cancel()
+	return redisIncrScript.Run(ctx, s.client, []string{key}, redisTTL(ttl).Milliseconds()).Int64()
+}
+
+// Close closes the connection pool
+func (s *RedisStateStore) Close() error {
+	return s.client.Close()
+}
+
+// redisTTL rounds ttl up to whole milliseconds. Redis expiries have millisecond
+// precision and reject 0, which would make a TTL under 1ms fail the command; go-redis
+// would also silently drop an expiry of 0 and keep the key forever.
+func redisTTL(ttl time.Duration) time.Duration {
+	if rounded := ttl.Truncate(time.Millisecond); rounded < ttl {
+		ttl = rounded + time.Millisecond
+	}
+	if ttl < time.Millisecond {
+		return time.Millisecond
+	}
+	return ttl
+}
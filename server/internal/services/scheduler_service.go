@@ -1,6 +1,10 @@
 package services
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"sync"
 	"time"
@@ -15,8 +19,11 @@ type SchedulerService struct {
 	db                  *gorm.DB
 	userRepo            *repository.UserRepository
 	taskRepo            *repository.TaskRepository
+	reminderRepo        *repository.TaskReminderRepository
 	notificationService *NotificationService
 	weatherService      *WeatherService
+	plannerService      *PlannerService
+	jobQueue            *JobQueue
 	stopChan            chan struct{}
 	wg                  sync.WaitGroup
 }
@@ -26,17 +33,60 @@ func NewSchedulerService(
 	db *gorm.DB,
 	userRepo *repository.UserRepository,
 	taskRepo *repository.TaskRepository,
+	reminderRepo *repository.TaskReminderRepository,
 	notificationService *NotificationService,
 	weatherService *WeatherService,
+	plannerService *PlannerService,
+	jobQueue *JobQueue,
 ) *SchedulerService {
-	return &SchedulerService{
+	s := &SchedulerService{
 		db:                  db,
 		userRepo:            userRepo,
 		taskRepo:            taskRepo,
+		reminderRepo:        reminderRepo,
 		notificationService: notificationService,
 		weatherService:      weatherService,
+		plannerService:      plannerService,
+		jobQueue:            jobQueue,
 		stopChan:            make(chan struct{}),
 	}
+
+	jobQueue.Register(JobTypeTaskReminder, s.handleTaskReminderJob)
+	jobQueue.Register(JobTypeWeatherAlert, s.handleWeatherAlertJob)
+	jobQueue.Register(JobTypeHealthCheck, s.handleHealthCheckJob)
+	jobQueue.Register(JobTypeDailyPlan, s.handleDailyPlanJob)
+	jobQueue.Register(JobTypeWeeklyReview, s.handleWeeklyReviewJob)
+	return s
+}
+
+// Job payloads
+type taskReminderJob struct {
+	DeliveryID string `json:"delivery_id"`
+	TaskID     string `json:"task_id"`
+	Kind       string `json:"kind"`
+	Nudge      int    `json:"nudge,omitempty"`
+}
+
+type weatherAlertJob struct {
+	UserID string `json:"user_id"`
+	City   string `json:"city"`
+}
+
+type healthCheckJob struct {
+	UserID string `json:"user_id"`
+}
+
+type plannerJob struct {
+	UserID string `json:"user_id"`
+	Date   string `json:"date"`
+}
+
+// notificationJobError dead-letters failures that a retry cannot fix
+func notificationJobError(err error) error {
+	if errors.Is(err, ErrFCMNotConfigured) || errors.Is(err, ErrNoFCMToken) {
+		return PermanentJobError(err)
+	}
+	return err
 }
 
 // Start begins all scheduler routines
@@ -51,10 +101,14 @@ func (s *SchedulerService) Start() {
 	s.wg.Add(1)
 	go s.weatherNotificationScheduler()
 
-	// Start task reminder scheduler (runs every 5 minutes)
+	// Start task reminder scheduler (runs every minute)
 	s.wg.Add(1)
 	go s.taskReminderScheduler()
 
+	// Start daily plan & weekly review scheduler (7 AM in each user's timezone)
+	s.wg.Add(1)
+	go s.plannerScheduler()
+
 	log.Println("✅ Scheduler Service started successfully")
 }
 
@@ -93,31 +147,43 @@ func (s *SchedulerService) healthRecommendationScheduler() {
 func (s *SchedulerService) checkAllUsersWorkload() {
 	log.Println("📋 Running health recommendation check...")
 
-	// Get all users with FCM tokens
+	// Get all users with a registered device
 	var users []models.User
-	if err := s.db.Where("fcm_token IS NOT NULL AND fcm_token != ''").Find(&users).Error; err != nil {
+	if err := s.db.Where("id IN (?)", s.db.Model(&models.Device{}).Select("user_id")).Find(&users).Error; err != nil {
 		log.Printf("❌ Failed to fetch users for health check: %v", err)
 		return
 	}
 
+	hour := time.Now().Format("2006-01-02T15")
 	for _, user := range users {
-		go s.checkUserWorkload(user)
+		if _, err := s.jobQueue.Enqueue(JobTypeHealthCheck, healthCheckJob{UserID: user.ID}, JobOptions{
+			UniqueKey: fmt.Sprintf("health:%s:%s", user.ID, hour),
+		}); err != nil {
+			log.Printf("❌ Failed to queue health check for user %s: %v", user.ID, err)
+		}
 	}
 
-	log.Printf("✅ Health check initiated for %d users", len(users))
+	log.Printf("✅ Health check queued for %d users", len(users))
+}
+
+func (s *SchedulerService) handleHealthCheckJob(ctx context.Context, payload []byte) error {
+	var job healthCheckJob
+	if err := json.Unmarshal(payload, &job); err != nil {
+		return PermanentJobError(err)
+	}
+	return notificationJobError(s.checkUserWorkload(job.UserID))
 }
 
 // checkUserWorkload analyzes a single user's workload and sends notification if needed
-func (s *SchedulerService) checkUserWorkload(user models.User) {
+func (s *SchedulerService) checkUserWorkload(userID string) error {
 	// Get today's tasks
 	now := time.Now()
 	startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
 	endOfDay := startOfDay.Add(24*time.Hour - time.Second)
 
-	tasks, err := s.taskRepo.FindByUserIDAndDateRange(user.ID, startOfDay, endOfDay)
+	tasks, err := s.taskRepo.FindByUserIDAndDateRange(userID, startOfDay, endOfDay)
 	if err != nil {
-		log.Printf("❌ Failed to fetch tasks for user %s: %v", user.ID, err)
-		return
+		return fmt.Errorf("failed to fetch tasks: %w", err)
 	}
 
 	taskCount := len(tasks)
@@ -129,12 +195,12 @@ func (s *SchedulerService) checkUserWorkload(user models.User) {
 	if taskCount > 15 || estimatedHours > 12 {
 		recommendation := s.getHealthRecommendation(taskCount, estimatedHours)
 
-		if err := s.notificationService.SendHealthRecommendation(user.ID, recommendation, estimatedHours); err != nil {
-			log.Printf("❌ Failed to send health recommendation to user %s: %v", user.ID, err)
-		} else {
-			log.Printf("✅ Health recommendation sent to user %s (tasks: %d, hours: %.1f)", user.ID, taskCount, estimatedHours)
+		if err := s.notificationService.SendHealthRecommendation(userID, recommendation, estimatedHours); err != nil {
+			return err
 		}
+		log.Printf("✅ Health recommendation sent to user %s (tasks: %d, hours: %.1f)", userID, taskCount, estimatedHours)
 	}
+	return nil
 }
 
 // calculateEstimatedWorkHours calculates total estimated work hours from tasks
@@ -224,11 +290,12 @@ func (s *SchedulerService) weatherNotificationScheduler() {
 func (s *SchedulerService) sendWeatherNotificationsToVIPUsers() {
 	log.Println("🌤️ Running weather notification for VIP users...")
 
-	// Get all VIP users with FCM tokens
+	// Get all VIP users with a registered device
 	var vipUsers []models.User
 	if err := s.db.Where(
-		"user_type = ? AND fcm_token IS NOT NULL AND fcm_token != '' AND (vip_expires_at IS NULL OR vip_expires_at > ?)",
+		"user_type = ? AND id IN (?) AND (vip_expires_at IS NULL OR vip_expires_at > ?)",
 		models.UserTypeVIP,
+		s.db.Model(&models.Device{}).Select("user_id"),
 		time.Now(),
 	).Find(&vipUsers).Error; err != nil {
 		log.Printf("❌ Failed to fetch VIP users for weather notification: %v", err)
@@ -236,37 +303,49 @@ func (s *SchedulerService) sendWeatherNotificationsToVIPUsers() {
 	}
 
 	if len(vipUsers) == 0 {
-		log.Println("ℹ️ No VIP users with FCM tokens found for weather notification")
+		log.Println("ℹ️ No VIP users with devices found for weather notification")
 		return
 	}
 
 	// Default city for Indonesian users
 	defaultCity := "Jakarta"
 
+	today := time.Now().Format("2006-01-02")
 	for _, user := range vipUsers {
-		go s.sendWeatherToUser(user, defaultCity)
+		if _, err := s.jobQueue.Enqueue(JobTypeWeatherAlert, weatherAlertJob{UserID: user.ID, City: defaultCity}, JobOptions{
+			UniqueKey: fmt.Sprintf("weather:%s:%s", user.ID, today),
+		}); err != nil {
+			log.Printf("❌ Failed to queue weather alert for user %s: %v", user.ID, err)
+		}
 	}
 
-	log.Printf("✅ Weather notifications initiated for %d VIP users", len(vipUsers))
+	log.Printf("✅ Weather notifications queued for %d VIP users", len(vipUsers))
+}
+
+func (s *SchedulerService) handleWeatherAlertJob(ctx context.Context, payload []byte) error {
+	var job weatherAlertJob
+	if err := json.Unmarshal(payload, &job); err != nil {
+		return PermanentJobError(err)
+	}
+	return notificationJobError(s.sendWeatherToUser(job.UserID, job.City))
 }
 
 // sendWeatherToUser sends weather notification to a single user
-func (s *SchedulerService) sendWeatherToUser(user models.User, city string) {
+func (s *SchedulerService) sendWeatherToUser(userID, city string) error {
 	// Get current weather
 	weather, err := s.weatherService.GetWeatherByCity(city)
 	if err != nil {
-		log.Printf("❌ Failed to fetch weather for user %s: %v", user.ID, err)
-		return
+		return fmt.Errorf("failed to fetch weather: %w", err)
 	}
 
 	// Check if weather condition warrants notification
 	if s.shouldSendWeatherAlert(weather) {
-		if err := s.notificationService.SendWeatherAlert(user.ID, city, weather.Description, weather.Temperature); err != nil {
-			log.Printf("❌ Failed to send weather alert to user %s: %v", user.ID, err)
-		} else {
-			log.Printf("✅ Weather alert sent to user %s: %s, %.1f°C", user.ID, weather.Description, weather.Temperature)
+		if err := s.notificationService.SendWeatherAlert(userID, city, weather.Description, weather.Temperature); err != nil {
+			return err
 		}
+		log.Printf("✅ Weather alert sent to user %s: %s, %.1f°C", userID, weather.Description, weather.Temperature)
 	}
+	return nil
 }
 
 // shouldSendWeatherAlert determines if weather condition warrants a notification
@@ -308,17 +387,34 @@ func (s *SchedulerService) shouldSendWeatherAlert(weather *CurrentWeather) bool
 
 // ==================== TASK REMINDER SCHEDULER ====================
 
-// taskReminderScheduler runs every 5 minutes to check for upcoming task deadlines
+// taskReminderScheduler runs every minute to send due task reminders and overdue nudges
 func (s *SchedulerService) taskReminderScheduler() {
 	defer s.wg.Done()
 
-	ticker := time.NewTicker(5 * time.Minute)
+	// Catch up on reminders missed while the server was down
+	s.checkUpcomingDeadlines()
+
+	ticker := time.NewTicker(1 * time.Minute)
 	defer ticker.Stop()
 
+	cleanup := time.NewTicker(24 * time.Hour)
+	defer cleanup.Stop()
+
 	for {
 		select {
 		case <-ticker.C:
 			s.checkUpcomingDeadlines()
+		case <-cleanup.C:
+			if n, err := s.reminderRepo.DeleteDeliveriesBefore(time.Now().AddDate(0, 0, -reminderHistoryDays)); err != nil {
+				log.Printf("❌ Failed to clean up reminder history: %v", err)
+			} else if n > 0 {
+				log.Printf("🧹 Removed %d old reminder deliveries", n)
+			}
+			if n, err := s.notificationService.PruneHistory(); err != nil {
+				log.Printf("❌ Failed to clean up notification history: %v", err)
+			} else if n > 0 {
+				log.Printf("🧹 Removed %d old notification history entries", n)
+			}
 		case <-s.stopChan:
 			log.Println("⏰ Task reminder scheduler stopped")
 			return
@@ -326,48 +422,216 @@ func (s *SchedulerService) taskReminderScheduler() {
 	}
 }
 
-// checkUpcomingDeadlines finds tasks with upcoming deadlines and sends reminders
+// checkUpcomingDeadlines sends every reminder and overdue nudge that is due. Each one is
+// claimed in the delivery log first, so it is sent at most once even across restarts.
 func (s *SchedulerService) checkUpcomingDeadlines() {
 	now := time.Now()
 
-	// Get all tasks with deadlines in the next hour that haven't been completed
-	// We'll check for tasks where deadline - reminder_minutes = now (approximately)
-	var tasks []models.Task
-	if err := s.db.Preload("User").
-		Where("is_completed = ? AND deadline IS NOT NULL AND reminder_minutes IS NOT NULL", false).
-		Where("deadline BETWEEN ? AND ?", now, now.Add(1*time.Hour)).
-		Find(&tasks).Error; err != nil {
+	// Deadline window: far enough ahead for the largest reminder offset in use (plus the
+	// grace period), far enough back for the last nudge
+	maxOffset, err := s.reminderRepo.MaxReminderOffset(now)
+	if err != nil {
+		log.Printf("❌ Failed to fetch reminder offsets: %v", err)
+		return
+	}
+	lastNudge := overdueNudges[len(overdueNudges)-1]
+	tasks, err := s.reminderRepo.FindReminderCandidates(now, time.Duration(maxOffset)*time.Minute+reminderGrace, lastNudge+reminderCatchUp)
+	if err != nil {
 		log.Printf("❌ Failed to fetch upcoming tasks: %v", err)
 		return
 	}
 
+	// High priority reminders go out first
+	SortByPriority(tasks)
+
 	for _, task := range tasks {
-		if task.Deadline == nil || task.ReminderMinutes == nil {
+		due := DueReminders(task, now)
+		if len(due) == 0 {
 			continue
 		}
 
-		// Calculate when reminder should be sent
-		reminderTime := task.Deadline.Add(-time.Duration(*task.ReminderMinutes) * time.Minute)
+		var claimed []*models.ReminderDelivery
+		for _, reminder := range due {
+			delivery := &models.ReminderDelivery{
+				Key:    reminder.Key,
+				TaskID: task.ID,
+				UserID: task.UserID,
+				Kind:   reminder.Kind,
+				FireAt: reminder.FireAt,
+				Status: models.ReminderDeliveryQueued,
+			}
+			ok, err := s.reminderRepo.ClaimDelivery(delivery)
+			if err != nil {
+				log.Printf("❌ Failed to claim reminder %s: %v", reminder.Key, err)
+				continue
+			}
+			if ok {
+				claimed = append(claimed, delivery)
+			}
+		}
+		if len(claimed) == 0 {
+			continue
+		}
 
-		// Check if we're within 5 minutes of the reminder time
-		timeDiff := reminderTime.Sub(now)
-		if timeDiff >= -2*time.Minute && timeDiff <= 5*time.Minute {
-			go s.sendTaskReminder(task)
+		// Several reminders due at once (after downtime): only the latest is sent
+		for _, delivery := range claimed[:len(claimed)-1] {
+			if err := s.reminderRepo.UpdateDeliveryStatus(delivery.ID, models.ReminderDeliverySkipped, nil); err != nil {
+				log.Printf("❌ Failed to update reminder %s: %v", delivery.Key, err)
+			}
+		}
+
+		latest := claimed[len(claimed)-1]
+		job := taskReminderJob{DeliveryID: latest.ID, TaskID: task.ID, Kind: latest.Kind}
+		for _, r := range due {
+			if r.Key == latest.Key {
+				job.Nudge = r.Nudge
+			}
+		}
+		if _, err := s.jobQueue.Enqueue(JobTypeTaskReminder, job, JobOptions{UniqueKey: "reminder:" + latest.Key}); err != nil {
+			msg := err.Error()
+			log.Printf("❌ Failed to queue reminder %s: %v", latest.Key, err)
+			if updateErr := s.reminderRepo.UpdateDeliveryStatus(latest.ID, models.ReminderDeliveryFailed, &msg); updateErr != nil {
+				log.Printf("❌ Failed to update reminder %s: %v", latest.Key, updateErr)
+			}
 		}
 	}
 }
 
-// sendTaskReminder sends a reminder for a specific task
-func (s *SchedulerService) sendTaskReminder(task models.Task) {
-	if task.Deadline == nil {
+func (s *SchedulerService) handleTaskReminderJob(ctx context.Context, payload []byte) error {
+	var job taskReminderJob
+	if err := json.Unmarshal(payload, &job); err != nil {
+		return PermanentJobError(err)
+	}
+
+	task, err := s.taskRepo.FindByID(job.TaskID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil // Task deleted meanwhile
+		}
+		return err
+	}
+	if task.IsCompleted {
+		return s.reminderRepo.UpdateDeliveryStatus(job.DeliveryID, models.ReminderDeliverySkipped, nil)
+	}
+
+	if err := s.sendTaskReminder(*task, job); err != nil {
+		msg := err.Error()
+		if updateErr := s.reminderRepo.UpdateDeliveryStatus(job.DeliveryID, models.ReminderDeliveryFailed, &msg); updateErr != nil {
+			log.Printf("❌ Failed to update reminder %s: %v", job.DeliveryID, updateErr)
+		}
+		return notificationJobError(err)
+	}
+
+	log.Printf("✅ Task %s sent for '%s'", job.Kind, task.Title)
+	return s.reminderRepo.UpdateDeliveryStatus(job.DeliveryID, models.ReminderDeliverySent, nil)
+}
+
+// sendTaskReminder sends a reminder or overdue nudge for a task
+func (s *SchedulerService) sendTaskReminder(task models.Task, job taskReminderJob) error {
+	switch {
+	case job.Kind == ReminderKindOverdue && task.Deadline != nil:
+		return s.notificationService.SendOverdueNudge(task.UserID, task.ID, task.Title, *task.Deadline, job.Nudge)
+	case task.Deadline != nil && task.Deadline.After(time.Now()):
+		return s.notificationService.SendTaskReminder(task.UserID, task.ID, task.Title, *task.Deadline, task.Priority)
+	default:
+		// No deadline, or an absolute reminder after the deadline
+		return s.notificationService.SendTaskNote(task.UserID, task.ID, task.Title)
+	}
+}
+
+// ==================== DAILY PLAN & WEEKLY REVIEW SCHEDULER ====================
+
+// planHour is the local hour from which a user's daily plan is sent
+const planHour = 7
+
+// plannerScheduler checks every 15 minutes which VIP users reached 7 AM in their own
+// timezone and queues their daily plan, plus last week's review on Mondays
+func (s *SchedulerService) plannerScheduler() {
+	defer s.wg.Done()
+
+	// Catch up on plans missed while the server was down
+	s.queueDailyPlans()
+
+	ticker := time.NewTicker(15 * time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.queueDailyPlans()
+		case <-s.stopChan:
+			log.Println("🗓️ Planner scheduler stopped")
+			return
+		}
+	}
+}
+
+// queueDailyPlans enqueues one plan job per VIP user and local date. The unique key keeps
+// every replica from sending the same plan again.
+func (s *SchedulerService) queueDailyPlans() {
+	var vipUsers []models.User
+	if err := s.db.Where(
+		"user_type = ? AND (vip_expires_at IS NULL OR vip_expires_at > ?)",
+		models.UserTypeVIP,
+		time.Now(),
+	).Find(&vipUsers).Error; err != nil {
+		log.Printf("❌ Failed to fetch VIP users for daily plan: %v", err)
 		return
 	}
 
-	if err := s.notificationService.SendTaskReminder(task.UserID, task.Title, *task.Deadline); err != nil {
-		log.Printf("❌ Failed to send task reminder for task %s: %v", task.ID, err)
-	} else {
-		log.Printf("✅ Task reminder sent for '%s' (deadline: %v)", task.Title, task.Deadline.Format("15:04"))
+	queued := 0
+	for _, user := range vipUsers {
+		local := time.Now().In(user.Location())
+		if local.Hour() < planHour {
+			continue
+		}
+
+		date := local.Format("2006-01-02")
+		job := plannerJob{UserID: user.ID, Date: date}
+		if local.Weekday() == time.Monday {
+			if _, err := s.jobQueue.Enqueue(JobTypeWeeklyReview, job, JobOptions{UniqueKey: "review:" + user.ID + ":" + date}); err != nil {
+				log.Printf("❌ Failed to queue weekly review for user %s: %v", user.ID, err)
+			}
+		}
+		created, err := s.jobQueue.Enqueue(JobTypeDailyPlan, job, JobOptions{UniqueKey: "plan:" + user.ID + ":" + date})
+		if err != nil {
+			log.Printf("❌ Failed to queue daily plan for user %s: %v", user.ID, err)
+		} else if created != nil {
+			queued++
+		}
 	}
+
+	if queued > 0 {
+		log.Printf("✅ Daily plans queued for %d VIP users", queued)
+	}
+}
+
+func (s *SchedulerService) handleDailyPlanJob(ctx context.Context, payload []byte) error {
+	var job plannerJob
+	if err := json.Unmarshal(payload, &job); err != nil {
+		return PermanentJobError(err)
+	}
+	// A retry that runs after the user's day ended would send a stale plan
+	if !s.isUserToday(job.UserID, job.Date) {
+		return nil
+	}
+	return s.plannerService.DeliverDailyPlan(job.UserID)
+}
+
+func (s *SchedulerService) handleWeeklyReviewJob(ctx context.Context, payload []byte) error {
+	var job plannerJob
+	if err := json.Unmarshal(payload, &job); err != nil {
+		return PermanentJobError(err)
+	}
+	if !s.isUserToday(job.UserID, job.Date) {
+		return nil
+	}
+	return s.plannerService.DeliverWeeklyReview(job.UserID)
+}
+
+// isUserToday reports whether date (YYYY-MM-DD) is today in the user's timezone
+func (s *SchedulerService) isUserToday(userID, date string) bool {
+	return time.Now().In(s.plannerService.UserLocation(userID)).Format("2006-01-02") == date
 }
 
 // ==================== HELPER FUNCTIONS ====================
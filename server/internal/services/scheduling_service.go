@@ -0,0 +1,151 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/workradar/server/internal/models"
+	"github.com/workradar/server/internal/repository"
+)
+
+// SchedulingService assigns time blocks to tasks in the user's free work time
+type SchedulingService struct {
+	userRepo    *repository.UserRepository
+	taskRepo    *repository.TaskRepository
+	holidayRepo *repository.HolidayRepository
+	leaveRepo   *repository.LeaveRepository
+	events      *EventHub
+}
+
+func NewSchedulingService(
+	userRepo *repository.UserRepository,
+	taskRepo *repository.TaskRepository,
+	holidayRepo *repository.HolidayRepository,
+	leaveRepo *repository.LeaveRepository,
+	events *EventHub,
+) *SchedulingService {
+	return &SchedulingService{
+		userRepo:    userRepo,
+		taskRepo:    taskRepo,
+		holidayRepo: holidayRepo,
+		leaveRepo:   leaveRepo,
+		events:      events,
+	}
+}
+
+// PreviewSchedule proposes time blocks for the user's unscheduled tasks. Tasks without
+// deadline are scheduled within days (default 14).
+func (s *SchedulingService) PreviewSchedule(userID string, days int) (*SchedulePreview, error) {
+	if days <= 0 {
+		days = defaultScheduleDays
+	}
+	if days > maxScheduleDays {
+		days = maxScheduleDays
+	}
+
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	pending, err := s.taskRepo.FindByUserIDAndComplete(userID, false)
+	if err != nil {
+		return nil, err
+	}
+
+	// Work hours and day boundaries are the user's local ones
+	now := time.Now().In(user.Location())
+	input := ScheduleInput{
+		Now:      now,
+		Until:    startOfDay(now).AddDate(0, 0, days),
+		WorkDays: ParseWorkDays(user.WorkDays),
+		DaysOff:  map[string]string{},
+	}
+
+	for _, t := range pending {
+		switch {
+		case t.ScheduledStart == nil:
+			input.Tasks = append(input.Tasks, t)
+		case t.AutoScheduled && t.ScheduledStart.Add(time.Duration(planTaskMinutes(t))*time.Minute).Before(now):
+			// Missed auto-scheduled block: find a new one
+			input.Tasks = append(input.Tasks, t)
+		default:
+			input.Blocked = append(input.Blocked, t)
+		}
+	}
+
+	// Tasks due after the window still need a slot before their deadline
+	for _, t := range input.Tasks {
+		if t.Deadline != nil && t.Deadline.After(input.Until) {
+			input.Until = *t.Deadline
+		}
+	}
+	if maxUntil := startOfDay(now).AddDate(0, 0, maxScheduleDays); input.Until.After(maxUntil) {
+		input.Until = maxUntil
+	}
+
+	holidays, err := s.holidayRepo.FindByDateRange(&userID, startOfDay(now), input.Until)
+	if err != nil {
+		return nil, err
+	}
+	for _, h := range holidays {
+		input.DaysOff[h.Date.Format("2006-01-02")] = h.Name
+	}
+
+	leaves, err := s.leaveRepo.FindUpcoming(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, l := range leaves {
+		input.DaysOff[l.Date.Format("2006-01-02")] = "Cuti"
+	}
+
+	return AutoSchedule(input), nil
+}
+
+// ApplySchedule saves the proposed time blocks. With taskIDs only those tasks are applied.
+func (s *SchedulingService) ApplySchedule(userID string, days int, taskIDs []string) (*SchedulePreview, error) {
+	preview, err := s.PreviewSchedule(userID, days)
+	if err != nil {
+		return nil, err
+	}
+
+	selected := make(map[string]bool, len(taskIDs))
+	for _, id := range taskIDs {
+		selected[id] = true
+	}
+
+	applied := []ScheduleAssignment{}
+	for _, a := range preview.Assignments {
+		if len(selected) > 0 && !selected[a.TaskID] {
+			continue
+		}
+		start := a.Start
+		if err := s.taskRepo.UpdateSchedule(a.TaskID, &start, true); err != nil {
+			return nil, err
+		}
+		applied = append(applied, a)
+	}
+
+	preview.Assignments = applied
+	if len(applied) > 0 {
+		s.events.Publish(userID, EventTaskScheduled, applied)
+	}
+	return preview, nil
+}
+
+// ClearSchedule removes the time block of a task
+func (s *SchedulingService) ClearSchedule(userID, taskID string) (*models.Task, error) {
+	task, err := s.taskRepo.FindByID(taskID)
+	if err != nil || task.UserID != userID {
+		return nil, errors.New("task not found")
+	}
+
+	if err := s.taskRepo.UpdateSchedule(taskID, nil, false); err != nil {
+		return nil, err
+	}
+	task.ScheduledStart = nil
+	task.AutoScheduled = false
+	s.events.Publish(userID, EventTaskUpdated, task)
+	return task, nil
+}
@@ -0,0 +1,183 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/workradar/server/internal/models"
+	"github.com/workradar/server/internal/repository"
+	"github.com/workradar/server/pkg/utils"
+	"gorm.io/gorm"
+)
+
+// Supported state stores (STATE_STORE)
+const (
+	StateStoreMemory   = "memory"
+	StateStoreDatabase = "database"
+	StateStoreRedis    = "redis"
+)
+
+// stateCleanupInterval is how often the memory and database stores drop expired keys
+const stateCleanupInterval = 5 * time.Minute
+
+// StateStore holds short-lived security state (token blacklist, rate limits, login
+// attempt records). The database and Redis stores are shared by every replica and
+// survive restarts; the memory store is for a single replica and tests.
+type StateStore interface {
+	// Get returns the value of key; ok is false when it is missing or expired
+	Get(key string) (value string, ok bool, err error)
+	// Set stores value under key for ttl
+	Set(key, value string, ttl time.Duration) error
+	Delete(key string) error
+	// Incr atomically increments the counter under key. A new or expired counter
+	// starts at 1 and expires after ttl; later increments keep that expiry.
+	Incr(key string, ttl time.Duration) (int64, error)
+}
+
+// ==================== MEMORY ====================
+
+type memoryStateEntry struct {
+	value     string
+	expiresAt time.Time
+}
+
+// MemoryStateStore keeps state in this process; it is lost on restart
+type MemoryStateStore struct {
+	mu      sync.Mutex
+	entries map[string]memoryStateEntry
+}
+
+func NewMemoryStateStore() *MemoryStateStore {
+	s := &MemoryStateStore{entries: make(map[string]memoryStateEntry)}
+	go s.cleanup()
+	return s
+}
+
+func (s *MemoryStateStore) Get(key string) (string, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[key]
+	if !ok || !time.Now().Before(entry.expiresAt) {
+		return "", false, nil
+	}
+	return entry.value, true, nil
+}
+
+func (s *MemoryStateStore) Set(key, value string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries[key] = memoryStateEntry{value: value, expiresAt: time.Now().Add(ttl)}
+	return nil
+}
+
+func (s *MemoryStateStore) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, key)
+	return nil
+}
+
+func (s *MemoryStateStore) Incr(key string, ttl time.Duration) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	entry, ok := s.entries[key]
+	if !ok || !now.Before(entry.expiresAt) {
+		s.entries[key] = memoryStateEntry{value: "1", expiresAt: now.Add(ttl)}
+		return 1, nil
+	}
+
+	n, err := strconv.ParseInt(entry.value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("state key %s is not a counter", key)
+	}
+	n++
+	entry.value = strconv.FormatInt(n, 10)
+	s.entries[key] = entry
+	return n, nil
+}
+
+func (s *MemoryStateStore) cleanup() {
+	ticker := time.NewTicker(stateCleanupInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		s.mu.Lock()
+		now := time.Now()
+		for key, entry := range s.entries {
+			if !now.Before(entry.expiresAt) {
+				delete(s.entries, key)
+			}
+		}
+		s.mu.Unlock()
+	}
+}
+
+// ==================== DATABASE ====================
+
+// maxStateKeyLength is the size of the state_entries key column
+const maxStateKeyLength = 191
+
+// DatabaseStateStore keeps state in the state_entries table so every replica shares it
+type DatabaseStateStore struct {
+	repo *repository.StateEntryRepository
+}
+
+func NewDatabaseStateStore(repo *repository.StateEntryRepository) *DatabaseStateStore {
+	s := &DatabaseStateStore{repo: repo}
+	go s.cleanup()
+	return s
+}
+
+// dbKey replaces keys too long for the key column (e.g. "delay:email:" and a 254
+// character address) with their SHA-256, keeping the prefix readable
+func dbKey(key string) string {
+	if len(key) <= maxStateKeyLength {
+		return key
+	}
+	prefix := key[:maxStateKeyLength-65]
+	return prefix + "#" + utils.HashToken(key)
+}
+
+func (s *DatabaseStateStore) Get(key string) (string, bool, error) {
+	entry, err := s.repo.Find(dbKey(key), time.Now())
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return entry.Value, true, nil
+}
+
+func (s *DatabaseStateStore) Set(key, value string, ttl time.Duration) error {
+	return s.repo.Upsert(&models.StateEntry{Key: dbKey(key), Value: value, ExpiresAt: time.Now().Add(ttl)})
+}
+
+func (s *DatabaseStateStore) Delete(key string) error {
+	return s.repo.Delete(dbKey(key))
+}
+
+func (s *DatabaseStateStore) Incr(key string, ttl time.Duration) (int64, error) {
+	now := time.Now()
+	return s.repo.Increment(dbKey(key), now.Add(ttl), now)
+}
+
+func (s *DatabaseStateStore) cleanup() {
+	ticker := time.NewTicker(stateCleanupInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if _, err := s.repo.DeleteExpired(time.Now()); err != nil {
+			log.Printf("⚠️ Failed to clean up expired state entries: %v", err)
+		}
+	}
+}
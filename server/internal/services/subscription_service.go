@@ -13,17 +13,20 @@ type SubscriptionService struct {
 	userRepo         *repository.UserRepository
 	subscriptionRepo *repository.SubscriptionRepository
 	db               *gorm.DB
+	userState        *UserStateService
 }
 
 func NewSubscriptionService(
 	userRepo *repository.UserRepository,
 	subscriptionRepo *repository.SubscriptionRepository,
 	db *gorm.DB,
+	userState *UserStateService,
 ) *SubscriptionService {
 	return &SubscriptionService{
 		userRepo:         userRepo,
 		subscriptionRepo: subscriptionRepo,
 		db:               db,
+		userState:        userState,
 	}
 }
 
@@ -93,6 +96,7 @@ func (s *SubscriptionService) CreateSubscription(userID string, planType models.
 	if err := tx.Commit().Error; err != nil {
 		return nil, err
 	}
+	s.userState.Invalidate(userID)
 
 	return subscription, nil
 }
@@ -151,6 +155,7 @@ func (s *SubscriptionService) CheckAndDowngradeExpired(userID string) error {
 			if err := s.userRepo.Update(user); err != nil {
 				return err
 			}
+			s.userState.Invalidate(userID)
 
 			// Deactivate expired subscriptions
 			if err := s.subscriptionRepo.DeactivateExpired(userID); err != nil {
@@ -162,6 +167,50 @@ func (s *SubscriptionService) CheckAndDowngradeExpired(userID string) error {
 	return nil
 }
 
+// RevokeSubscription menonaktifkan subscription dari transaksi yang di-refund dan downgrade user
+func (s *SubscriptionService) RevokeSubscription(userID, transactionID string) error {
+	tx := s.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Model(&models.Subscription{}).
+		Where("user_id = ? AND transaction_id = ?", userID, transactionID).
+		Update("is_active", false).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// Downgrade only if no other active subscription remains
+	var remaining int64
+	if err := tx.Model(&models.Subscription{}).
+		Where("user_id = ? AND is_active = ? AND end_date > ?", userID, true, time.Now()).
+		Count(&remaining).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if remaining == 0 {
+		if err := tx.Model(&models.User{}).
+			Where("id = ?", userID).
+			Updates(map[string]interface{}{
+				"user_type":      models.UserTypeRegular,
+				"vip_expires_at": nil,
+			}).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+	s.userState.Invalidate(userID)
+	return nil
+}
+
 // DTOs
 
 type VIPStatusResponse struct {
@@ -0,0 +1,666 @@
+package services
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/workradar/server/internal/models"
+)
+
+const (
+	// quickAddDefaultHour is the deadline time when only a date is given
+	quickAddDefaultHour = 9
+	// quickAddDefaultReminder is used for "ingatkan"/"remind me" without an amount
+	quickAddDefaultReminder = 15
+)
+
+// ParsedPhrase is a part of the input recognized as a task attribute
+type ParsedPhrase struct {
+	Text  string `json:"text"`
+	Field string `json:"field"` // deadline, duration, reminder, category, repeat, priority
+}
+
+// ParsedTask is the result of parsing a quick-add sentence
+type ParsedTask struct {
+	Title           string            `json:"title"`
+	Deadline        *time.Time        `json:"deadline,omitempty"`
+	DurationMinutes *int              `json:"duration_minutes,omitempty"`
+	ReminderMinutes *int              `json:"reminder_minutes,omitempty"`
+	CategoryName    string            `json:"category_name,omitempty"`
+	RepeatType      models.RepeatType `json:"repeat_type"`
+	RepeatInterval  int               `json:"repeat_interval"`
+	Priority        *string           `json:"priority,omitempty"`
+	Phrases         []ParsedPhrase    `json:"phrases"`
+}
+
+var (
+	quickAddWeekdays = map[string]time.Weekday{
+		"senin": time.Monday, "selasa": time.Tuesday, "rabu": time.Wednesday, "kamis": time.Thursday,
+		"jumat": time.Friday, "jum'at": time.Friday, "sabtu": time.Saturday, "minggu": time.Sunday, "ahad": time.Sunday,
+		"monday": time.Monday, "tuesday": time.Tuesday, "wednesday": time.Wednesday, "thursday": time.Thursday,
+		"friday": time.Friday, "saturday": time.Saturday, "sunday": time.Sunday,
+		"mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday, "thu": time.Thursday, "fri": time.Friday,
+	}
+
+	quickAddMonths = map[string]time.Month{
+		"jan": time.January, "januari": time.January, "january": time.January,
+		"feb": time.February, "februari": time.February, "february": time.February,
+		"mar": time.March, "maret": time.March, "march": time.March,
+		"apr": time.April, "april": time.April,
+		"mei": time.May, "may": time.May,
+		"jun": time.June, "juni": time.June, "june": time.June,
+		"jul": time.July, "juli": time.July, "july": time.July,
+		"agu": time.August, "agt": time.August, "agustus": time.August, "aug": time.August, "august": time.August,
+		"sep": time.September, "sept": time.September, "september": time.September,
+		"okt": time.October, "oktober": time.October, "oct": time.October, "october": time.October,
+		"nov": time.November, "november": time.November,
+		"des": time.December, "desember": time.December, "dec": time.December, "december": time.December,
+	}
+
+	quickAddNumberWords = map[string]float64{
+		"satu": 1, "dua": 2, "tiga": 3, "empat": 4, "lima": 5, "enam": 6, "tujuh": 7, "delapan": 8,
+		"sembilan": 9, "sepuluh": 10, "setengah": 0.5,
+		"one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6, "seven": 7, "eight": 8,
+		"nine": 9, "ten": 10, "an": 1,
+	}
+
+	// quickAddConnectors are dropped from the title when they introduce a recognized phrase
+	quickAddConnectors = map[string]bool{
+		"pada": true, "tanggal": true, "tgl": true, "hari": true, "selama": true, "deadline": true,
+		"on": true, "at": true, "by": true, "for": true, "due": true, "and": true, "dan": true,
+	}
+
+	// quickAddPriorities maps "!penting"-style markers to priorities
+	quickAddPriorities = map[string]models.TaskPriority{
+		"penting": models.PriorityHigh, "tinggi": models.PriorityHigh, "high": models.PriorityHigh, "p1": models.PriorityHigh,
+		"sedang": models.PriorityMedium, "medium": models.PriorityMedium, "p2": models.PriorityMedium,
+		"rendah": models.PriorityLow, "low": models.PriorityLow, "p3": models.PriorityLow,
+	}
+
+	quickAddClockPattern   = regexp.MustCompile(`^(\d{1,2})(?:[:.](\d{2}))?(am|pm)?$`)
+	quickAddCompactPattern = regexp.MustCompile(`^(\d+(?:[.,]\d+)?)(m|min|mnt|menit|h|hr|j|jam)$`)
+	quickAddNumDatePattern = regexp.MustCompile(`^(\d{1,2})[/-](\d{1,2})(?:[/-](\d{2,4}))?$`)
+	quickAddISODatePattern = regexp.MustCompile(`^(\d{4})-(\d{1,2})-(\d{1,2})$`)
+)
+
+// quickAddParser scans the words of a quick-add sentence left to right. Recognized
+// phrases are consumed; the remaining words become the title.
+type quickAddParser struct {
+	now      time.Time
+	words    []string // Original words
+	lower    []string // Lowercase words without trailing punctuation
+	consumed []bool
+	result   *ParsedTask
+
+	date        *time.Time // Day of the deadline
+	hour, min   int
+	hasTime     bool
+	relative    *time.Time // "3 jam lagi" / "in 2 hours"
+	repeatDay   *time.Weekday
+	nextWeekday bool // "jumat depan" / "next friday"
+}
+
+// ParseQuickAdd parses sentences like "rapat klien jumat jam 3 sore 1 jam ingatkan 30 menit
+// sebelumnya #Kerja" (Indonesian or English) relative to now
+func ParseQuickAdd(input string, now time.Time) *ParsedTask {
+	p := &quickAddParser{
+		now:    now,
+		words:  strings.Fields(input),
+		result: &ParsedTask{RepeatType: models.RepeatNone, RepeatInterval: 1, Phrases: []ParsedPhrase{}},
+	}
+	p.consumed = make([]bool, len(p.words))
+	for _, w := range p.words {
+		p.lower = append(p.lower, strings.TrimRight(strings.ToLower(w), ",.;!?"))
+	}
+
+	matchers := []struct {
+		field string
+		match func(i int) int
+	}{
+		{"category", p.matchCategory},
+		{"priority", p.matchPriority},
+		{"reminder", p.matchReminder},
+		{"repeat", p.matchRepeat},
+		{"deadline", p.matchRelative},
+		{"deadline", p.matchTime},
+		{"duration", p.matchDuration},
+		{"deadline", p.matchDate},
+	}
+
+	for i := 0; i < len(p.words); {
+		matched := 0
+		for _, m := range matchers {
+			if n := m.match(i); n > 0 {
+				p.consume(i, n, m.field)
+				matched = n
+				break
+			}
+		}
+		if matched == 0 {
+			matched = 1
+		}
+		i += matched
+	}
+
+	p.result.Deadline = p.deadline()
+	p.result.Title = p.title()
+	return p.result
+}
+
+// consume marks words[i:i+n] as a phrase, together with a connector right before it
+func (p *quickAddParser) consume(i, n int, field string) {
+	start := i
+	if i > 0 && !p.consumed[i-1] && quickAddConnectors[p.lower[i-1]] {
+		start = i - 1
+	}
+	for j := start; j < i+n; j++ {
+		p.consumed[j] = true
+	}
+	p.result.Phrases = append(p.result.Phrases, ParsedPhrase{
+		Text:  strings.Join(p.words[start:i+n], " "),
+		Field: field,
+	})
+}
+
+func (p *quickAddParser) word(i int) string {
+	if i < 0 || i >= len(p.lower) || p.consumed[i] {
+		return ""
+	}
+	return p.lower[i]
+}
+
+// matchCategory: "#Kerja"
+func (p *quickAddParser) matchCategory(i int) int {
+	w := strings.TrimRight(p.words[i], ",.;!?")
+	if len(w) < 2 || w[0] != '#' {
+		return 0
+	}
+	p.result.CategoryName = w[1:]
+	return 1
+}
+
+// matchPriority: "!penting", "!rendah", "!p1"
+func (p *quickAddParser) matchPriority(i int) int {
+	w := strings.ToLower(strings.TrimRight(p.words[i], ",.;?"))
+	if len(w) < 2 || w[0] != '!' {
+		return 0
+	}
+	priority, ok := quickAddPriorities[w[1:]]
+	if !ok {
+		return 0
+	}
+	value := string(priority)
+	p.result.Priority = &value
+	return 1
+}
+
+// matchReminder: "ingatkan [saya] 30 menit sebelumnya", "remind me 1 hour before", "pengingat 10 menit"
+func (p *quickAddParser) matchReminder(i int) int {
+	switch p.word(i) {
+	case "ingatkan", "ingetin", "pengingat", "remind", "reminder", "ingatkan:", "reminder:":
+	default:
+		return 0
+	}
+
+	n := 1
+	switch p.word(i + n) {
+	case "saya", "aku", "me", "gue":
+		n++
+	}
+
+	minutes, size := p.amountInMinutes(i + n)
+	if size == 0 {
+		p.result.ReminderMinutes = intPtr(quickAddDefaultReminder)
+		return n
+	}
+	n += size
+
+	switch p.word(i + n) {
+	case "sebelumnya", "sebelum", "before", "earlier", "prior", "duluan":
+		n++
+	}
+
+	p.result.ReminderMinutes = intPtr(minutes)
+	return n
+}
+
+// matchRepeat: "setiap hari", "tiap 2 minggu", "setiap senin", "every monday"
+func (p *quickAddParser) matchRepeat(i int) int {
+	// Adjectives like "mingguan"/"weekly" are left alone: "laporan mingguan" is a title
+	switch p.word(i) {
+	case "setiap", "tiap", "every", "each":
+	default:
+		return 0
+	}
+
+	n := 1
+	interval := 1
+	if value, ok := parseQuickAddNumber(p.word(i + n)); ok && value >= 1 {
+		interval = int(value)
+		n++
+	}
+
+	unit := p.word(i + n)
+	if weekday, ok := quickAddWeekdays[unit]; ok && interval == 1 {
+		p.setRepeat(models.RepeatWeekly, 1)
+		p.repeatDay = &weekday
+		return n + 1
+	}
+
+	switch unit {
+	case "jam", "hour", "hours":
+		p.setRepeat(models.RepeatHourly, interval)
+	case "hari", "day", "days":
+		p.setRepeat(models.RepeatDaily, interval)
+	case "minggu", "pekan", "week", "weeks":
+		p.setRepeat(models.RepeatWeekly, interval)
+	case "bulan", "month", "months":
+		p.setRepeat(models.RepeatMonthly, interval)
+	default:
+		return 0
+	}
+	return n + 1
+}
+
+func (p *quickAddParser) setRepeat(repeatType models.RepeatType, interval int) {
+	p.result.RepeatType = repeatType
+	p.result.RepeatInterval = interval
+}
+
+// matchRelative: "3 jam lagi", "2 hari lagi", "dalam 30 menit", "in 2 days"
+func (p *quickAddParser) matchRelative(i int) int {
+	n := 0
+	switch p.word(i) {
+	case "dalam", "in":
+		n = 1
+	}
+
+	value, ok := parseQuickAddNumber(p.word(i + n))
+	if !ok {
+		return 0
+	}
+	unit := p.word(i + n + 1)
+
+	var offset time.Duration
+	days := 0
+	switch unit {
+	case "menit", "mnt", "min", "mins", "minute", "minutes":
+		offset = time.Duration(value * float64(time.Minute))
+	case "jam", "hour", "hours", "hr", "hrs":
+		offset = time.Duration(value * float64(time.Hour))
+	case "hari", "day", "days":
+		days = int(value)
+	case "minggu", "pekan", "week", "weeks":
+		days = int(value) * 7
+	default:
+		return 0
+	}
+	size := n + 2
+
+	if n == 0 {
+		// Without "dalam"/"in" the phrase needs a trailing "lagi" (else "2 jam" is a duration)
+		if p.word(i+size) != "lagi" {
+			return 0
+		}
+		size++
+	} else if p.word(i+size) == "lagi" {
+		size++
+	}
+
+	if days > 0 {
+		date := startOfDay(p.now).AddDate(0, 0, days)
+		p.date = &date
+		return size
+	}
+	at := p.now.Add(offset).Truncate(time.Minute)
+	p.relative = &at
+	return size
+}
+
+// matchTime: "jam 3 sore", "pukul 14.30", "at 3pm", "15:00", "9am"
+func (p *quickAddParser) matchTime(i int) int {
+	n := 0
+	switch p.word(i) {
+	case "jam", "pukul", "pkl", "at":
+		n = 1
+	}
+
+	token := p.word(i + n)
+	match := quickAddClockPattern.FindStringSubmatch(token)
+	if match == nil {
+		return 0
+	}
+
+	hour, _ := strconv.Atoi(match[1])
+	minute := 0
+	if match[2] != "" {
+		minute, _ = strconv.Atoi(match[2])
+	}
+	meridiem := match[3]
+	size := n + 1
+
+	// A bare number is only a time after "jam"/"pukul"/"at" or with a colon/am/pm
+	if n == 0 && match[2] == "" && meridiem == "" {
+		return 0
+	}
+	if n == 0 && match[2] != "" && strings.Contains(token, ".") && meridiem == "" {
+		return 0 // "1.5" is more likely an amount than 01:05
+	}
+
+	if meridiem == "" {
+		switch p.word(i + size) {
+		case "am", "pagi":
+			meridiem = "am"
+			size++
+		case "pm", "siang", "sore":
+			meridiem = "pm"
+			size++
+		case "malam":
+			if hour >= 6 && hour < 12 {
+				meridiem = "pm"
+			} else if hour == 12 {
+				meridiem = "am"
+			}
+			size++
+		}
+	}
+
+	switch meridiem {
+	case "pm":
+		if hour < 12 {
+			hour += 12
+		}
+	case "am":
+		if hour == 12 {
+			hour = 0
+		}
+	default:
+		// "jam 3" during a work day means 15:00
+		if hour >= 1 && hour <= 6 && match[2] == "" {
+			hour += 12
+		}
+	}
+
+	if hour > 23 || minute > 59 {
+		return 0
+	}
+
+	p.hour, p.min, p.hasTime = hour, minute, true
+	return size
+}
+
+// matchDuration: "1 jam", "30 menit", "1.5 jam", "setengah jam", "2 hours", "45m", "90 min"
+func (p *quickAddParser) matchDuration(i int) int {
+	if p.result.DurationMinutes != nil {
+		return 0
+	}
+
+	minutes, size := p.amountInMinutes(i)
+	if size == 0 {
+		return 0
+	}
+	p.result.DurationMinutes = intPtr(minutes)
+	return size
+}
+
+// amountInMinutes reads "N unit" (or a compact "45m"/"2j") starting at i
+func (p *quickAddParser) amountInMinutes(i int) (int, int) {
+	switch {
+	case p.word(i) == "sejam":
+		return 60, 1
+	case p.word(i) == "half" && p.word(i+1) == "an" && unitMinutes(1, p.word(i+2)) > 0:
+		return unitMinutes(0.5, p.word(i+2)), 3
+	}
+
+	if match := quickAddCompactPattern.FindStringSubmatch(p.word(i)); match != nil {
+		value, _ := strconv.ParseFloat(strings.Replace(match[1], ",", ".", 1), 64)
+		if minutes := unitMinutes(value, match[2]); minutes > 0 {
+			return minutes, 1
+		}
+	}
+
+	value, ok := parseQuickAddNumber(p.word(i))
+	if !ok {
+		return 0, 0
+	}
+	if minutes := unitMinutes(value, p.word(i+1)); minutes > 0 {
+		return minutes, 2
+	}
+	return 0, 0
+}
+
+func unitMinutes(value float64, unit string) int {
+	switch unit {
+	case "menit", "mnt", "m", "min", "mins", "minute", "minutes":
+		return int(value)
+	case "jam", "j", "h", "hr", "hrs", "hour", "hours":
+		return int(value * 60)
+	}
+	return 0
+}
+
+// matchDate: "hari ini", "besok", "lusa", "jumat [depan]", "minggu depan", "25/12", "25 des 2026", "dec 25"
+func (p *quickAddParser) matchDate(i int) int {
+	w := p.word(i)
+	today := startOfDay(p.now)
+
+	switch w {
+	case "today":
+		p.setDate(today)
+		return 1
+	case "besok", "tomorrow":
+		p.setDate(today.AddDate(0, 0, 1))
+		return 1
+	case "lusa":
+		p.setDate(today.AddDate(0, 0, 2))
+		return 1
+	case "hari":
+		if p.word(i+1) == "ini" {
+			p.setDate(today)
+			return 2
+		}
+		return 0
+	case "next":
+		if p.word(i+1) == "week" {
+			p.setDate(today.AddDate(0, 0, 7))
+			return 2
+		}
+		if _, ok := quickAddWeekdays[p.word(i+1)]; ok {
+			p.nextWeekday = true
+			return 1 + p.matchWeekday(i+1)
+		}
+		return 0
+	case "minggu", "pekan":
+		if p.word(i+1) == "depan" {
+			p.setDate(today.AddDate(0, 0, 7))
+			return 2
+		}
+	}
+
+	if n := p.matchWeekday(i); n > 0 {
+		return n
+	}
+	return p.matchCalendarDate(i)
+}
+
+func (p *quickAddParser) matchWeekday(i int) int {
+	weekday, ok := quickAddWeekdays[p.word(i)]
+	if !ok {
+		return 0
+	}
+
+	n := 1
+	if w := p.word(i + 1); w == "depan" || w == "ini" {
+		p.nextWeekday = p.nextWeekday || w == "depan"
+		n++
+	}
+
+	today := startOfDay(p.now)
+	days := (int(weekday) - int(today.Weekday()) + 7) % 7
+	if days == 0 && p.nextWeekday {
+		days = 7
+	}
+	p.setDate(today.AddDate(0, 0, days))
+	return n
+}
+
+func (p *quickAddParser) matchCalendarDate(i int) int {
+	w := p.word(i)
+	year, month, day := 0, time.Month(0), 0
+	size := 0
+
+	if match := quickAddISODatePattern.FindStringSubmatch(w); match != nil {
+		year, _ = strconv.Atoi(match[1])
+		m, _ := strconv.Atoi(match[2])
+		month = time.Month(m)
+		day, _ = strconv.Atoi(match[3])
+		size = 1
+	} else if match := quickAddNumDatePattern.FindStringSubmatch(w); match != nil {
+		day, _ = strconv.Atoi(match[1])
+		m, _ := strconv.Atoi(match[2])
+		month = time.Month(m)
+		if match[3] != "" {
+			year, _ = strconv.Atoi(match[3])
+			if year < 100 {
+				year += 2000
+			}
+		}
+		size = 1
+	} else if d, err := strconv.Atoi(w); err == nil {
+		// "25 des [2026]"
+		m, ok := quickAddMonths[p.word(i+1)]
+		if !ok {
+			return 0
+		}
+		day, month, size = d, m, 2
+		if y, err := strconv.Atoi(p.word(i + 2)); err == nil && y >= 2000 {
+			year = y
+			size++
+		}
+	} else if m, ok := quickAddMonths[w]; ok {
+		// "dec 25 [2026]"
+		d, err := strconv.Atoi(p.word(i + 1))
+		if err != nil {
+			return 0
+		}
+		day, month, size = d, m, 2
+		if y, err := strconv.Atoi(p.word(i + 2)); err == nil && y >= 2000 {
+			year = y
+			size++
+		}
+	} else {
+		return 0
+	}
+
+	if month < time.January || month > time.December || day < 1 || day > 31 {
+		return 0
+	}
+
+	today := startOfDay(p.now)
+	explicitYear := year != 0
+	if !explicitYear {
+		year = today.Year()
+	}
+	date := time.Date(year, month, day, 0, 0, 0, 0, p.now.Location())
+	if date.Day() != day {
+		return 0 // e.g. 31/02
+	}
+	if !explicitYear && date.Before(today) {
+		date = date.AddDate(1, 0, 0)
+	}
+
+	p.setDate(date)
+	return size
+}
+
+func (p *quickAddParser) setDate(date time.Time) {
+	p.date = &date
+}
+
+// deadline combines the recognized date and time
+func (p *quickAddParser) deadline() *time.Time {
+	if p.relative != nil {
+		return p.relative
+	}
+
+	if p.date == nil && p.repeatDay != nil {
+		today := startOfDay(p.now)
+		date := today.AddDate(0, 0, (int(*p.repeatDay)-int(today.Weekday())+7)%7)
+		p.date = &date
+	}
+
+	if p.date == nil && !p.hasTime {
+		return nil
+	}
+
+	hour, minute := quickAddDefaultHour, 0
+	if p.hasTime {
+		hour, minute = p.hour, p.min
+	}
+
+	day := startOfDay(p.now)
+	if p.date != nil {
+		day = *p.date
+	}
+	deadline := time.Date(day.Year(), day.Month(), day.Day(), hour, minute, 0, 0, p.now.Location())
+
+	if deadline.Before(p.now) {
+		switch {
+		case p.repeatDay != nil:
+			// The repeating weekday already passed today: start next week
+			deadline = deadline.AddDate(0, 0, 7)
+		case p.date == nil:
+			// A time alone that already passed means tomorrow
+			deadline = deadline.AddDate(0, 0, 1)
+		case !p.hasTime && sameDay(deadline, p.now):
+			// "hari ini" after the default hour: due by the end of the day
+			deadline = time.Date(day.Year(), day.Month(), day.Day(), 23, 59, 0, 0, p.now.Location())
+		}
+	}
+	return &deadline
+}
+
+// title joins the words that were not recognized as attributes
+func (p *quickAddParser) title() string {
+	var words []string
+	for i, w := range p.words {
+		if !p.consumed[i] {
+			words = append(words, w)
+		}
+	}
+
+	// Drop dangling connectors at the end ("rapat klien pada")
+	for len(words) > 0 && quickAddConnectors[strings.ToLower(words[len(words)-1])] {
+		words = words[:len(words)-1]
+	}
+
+	title := strings.TrimRight(strings.Join(words, " "), ",;: ")
+	if title == "" {
+		return ""
+	}
+	first, size := utf8.DecodeRuneInString(title)
+	return string(unicode.ToUpper(first)) + title[size:]
+}
+
+func sameDay(a, b time.Time) bool {
+	return a.Year() == b.Year() && a.YearDay() == b.YearDay()
+}
+
+func parseQuickAddNumber(w string) (float64, bool) {
+	if value, ok := quickAddNumberWords[w]; ok {
+		return value, true
+	}
+	value, err := strconv.ParseFloat(strings.Replace(w, ",", ".", 1), 64)
+	if err != nil || value <= 0 {
+		return 0, false
+	}
+	return value, true
+}
+
+func intPtr(v int) *int {
+	return &v
+}
@@ -0,0 +1,174 @@
+package services
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/workradar/server/internal/models"
+)
+
+// ErrInvalidPriority is returned for priorities other than low, medium and high
+var ErrInvalidPriority = errors.New("invalid priority")
+
+// TaskUrgency is derived from how close the deadline is
+type TaskUrgency string
+
+// urgentWithin is how close a deadline must be for a task to count as urgent
+const urgentWithin = 48 * time.Hour
+
+const (
+	UrgencyOverdue TaskUrgency = "overdue" // Deadline passed
+	UrgencyUrgent  TaskUrgency = "urgent"  // Due within urgentWithin
+	UrgencySoon    TaskUrgency = "soon"    // Due within a week
+	UrgencyLater   TaskUrgency = "later"   // Due later
+	UrgencyNone    TaskUrgency = "none"    // No deadline
+)
+
+// Eisenhower quadrants
+const (
+	QuadrantDoFirst   = "do_first"  // Important and urgent
+	QuadrantSchedule  = "schedule"  // Important, not urgent
+	QuadrantDelegate  = "delegate"  // Urgent, not important
+	QuadrantEliminate = "eliminate" // Neither
+)
+
+// MatrixTask is a task with its computed urgency
+type MatrixTask struct {
+	models.Task
+	Urgency TaskUrgency `json:"urgency"`
+}
+
+// EisenhowerMatrix groups pending tasks by importance (priority) and urgency (deadline)
+type EisenhowerMatrix struct {
+	DoFirst   []MatrixTask `json:"do_first"`
+	Schedule  []MatrixTask `json:"schedule"`
+	Delegate  []MatrixTask `json:"delegate"`
+	Eliminate []MatrixTask `json:"eliminate"`
+}
+
+// ComputeUrgency classifies a task's deadline relative to now
+func ComputeUrgency(t models.Task, now time.Time) TaskUrgency {
+	if t.Deadline == nil {
+		return UrgencyNone
+	}
+
+	until := t.Deadline.Sub(now)
+	switch {
+	case until < 0:
+		return UrgencyOverdue
+	case until <= urgentWithin:
+		return UrgencyUrgent
+	case until <= 7*24*time.Hour:
+		return UrgencySoon
+	default:
+		return UrgencyLater
+	}
+}
+
+// IsUrgent reports whether the task counts as urgent in the matrix
+func IsUrgent(t models.Task, now time.Time) bool {
+	urgency := ComputeUrgency(t, now)
+	return urgency == UrgencyOverdue || urgency == UrgencyUrgent
+}
+
+// IsImportant reports whether the task counts as important in the matrix
+func IsImportant(t models.Task) bool {
+	return t.Priority == models.PriorityHigh
+}
+
+// Quadrant returns the Eisenhower quadrant of a task
+func Quadrant(t models.Task, now time.Time) string {
+	important, urgent := IsImportant(t), IsUrgent(t, now)
+	switch {
+	case important && urgent:
+		return QuadrantDoFirst
+	case important:
+		return QuadrantSchedule
+	case urgent:
+		return QuadrantDelegate
+	default:
+		return QuadrantEliminate
+	}
+}
+
+// BuildEisenhowerMatrix sorts pending tasks into quadrants, each ordered by deadline
+func BuildEisenhowerMatrix(tasks []models.Task, now time.Time) *EisenhowerMatrix {
+	matrix := &EisenhowerMatrix{
+		DoFirst:   []MatrixTask{},
+		Schedule:  []MatrixTask{},
+		Delegate:  []MatrixTask{},
+		Eliminate: []MatrixTask{},
+	}
+
+	sorted := append([]models.Task(nil), tasks...)
+	SortByPriority(sorted)
+
+	for _, t := range sorted {
+		if t.IsCompleted {
+			continue
+		}
+		item := MatrixTask{Task: t, Urgency: ComputeUrgency(t, now)}
+		switch Quadrant(t, now) {
+		case QuadrantDoFirst:
+			matrix.DoFirst = append(matrix.DoFirst, item)
+		case QuadrantSchedule:
+			matrix.Schedule = append(matrix.Schedule, item)
+		case QuadrantDelegate:
+			matrix.Delegate = append(matrix.Delegate, item)
+		default:
+			matrix.Eliminate = append(matrix.Eliminate, item)
+		}
+	}
+	return matrix
+}
+
+// SortByPriority orders tasks by priority (high first), then earliest deadline
+func SortByPriority(tasks []models.Task) {
+	sort.SliceStable(tasks, func(i, j int) bool {
+		if pi, pj := priorityRank(tasks[i].Priority), priorityRank(tasks[j].Priority); pi != pj {
+			return pi > pj
+		}
+		di, dj := tasks[i].Deadline, tasks[j].Deadline
+		if di == nil || dj == nil {
+			return di != nil && dj == nil
+		}
+		return di.Before(*dj)
+	})
+}
+
+// priorityRank orders high > medium > low; unset counts as medium
+func priorityRank(p models.TaskPriority) int {
+	switch p {
+	case models.PriorityHigh:
+		return 2
+	case models.PriorityLow:
+		return 0
+	default:
+		return 1
+	}
+}
+
+// priorityWeight is added to urgency scores when ranking tasks
+func priorityWeight(p models.TaskPriority) float64 {
+	switch p {
+	case models.PriorityHigh:
+		return 4
+	case models.PriorityLow:
+		return -1
+	default:
+		return 0
+	}
+}
+
+// normalizePriority validates an optional priority; nil means medium
+func normalizePriority(p *string) (models.TaskPriority, error) {
+	if p == nil || *p == "" {
+		return models.PriorityMedium, nil
+	}
+	priority := models.TaskPriority(*p)
+	if !priority.IsValid() {
+		return "", ErrInvalidPriority
+	}
+	return priority, nil
+}
@@ -0,0 +1,171 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/workradar/server/internal/models"
+)
+
+const (
+	maxRemindersPerTask = 10
+	// maxReminderLeadMinutes is the earliest a relative reminder may fire (30 days before)
+	maxReminderLeadMinutes = 30 * 24 * 60
+	// reminderCatchUp is how late a missed reminder (e.g. during a restart) is still sent
+	reminderCatchUp = 6 * time.Hour
+	// reminderGrace lets a reminder due right at the deadline still go out
+	reminderGrace = 5 * time.Minute
+	// reminderHistoryDays is how long sent reminders are kept
+	reminderHistoryDays = 30
+)
+
+// overdueNudges are sent after the deadline passes, further apart each time
+var overdueNudges = []time.Duration{time.Hour, 24 * time.Hour, 3 * 24 * time.Hour}
+
+// Kinds of due reminders
+const (
+	ReminderKindReminder = "reminder"
+	ReminderKindOverdue  = "overdue"
+)
+
+// ReminderDTO is a reminder in task requests: minutes before the deadline or an absolute time
+type ReminderDTO struct {
+	OffsetMinutes *int       `json:"offset_minutes"`
+	RemindAt      *time.Time `json:"remind_at"`
+}
+
+// DueReminder is a reminder or overdue nudge whose time has come
+type DueReminder struct {
+	Key           string // Unique per task, deadline and reminder
+	Kind          string // reminder, overdue
+	FireAt        time.Time
+	OffsetMinutes *int // Relative reminders only
+	Nudge         int  // 1-based overdue nudge number
+}
+
+// DueReminders returns the reminders of a task that are due at now, oldest first.
+// Keys include the deadline, so moving the deadline re-arms relative reminders and nudges.
+func DueReminders(t models.Task, now time.Time) []DueReminder {
+	if t.IsCompleted {
+		return nil
+	}
+
+	due := func(fireAt time.Time) bool {
+		return !fireAt.After(now) && now.Sub(fireAt) <= reminderCatchUp
+	}
+
+	var result []DueReminder
+	if t.Deadline != nil {
+		deadline := *t.Deadline
+		for _, offset := range reminderOffsets(t) {
+			fireAt := deadline.Add(-time.Duration(offset) * time.Minute)
+			if !due(fireAt) || now.After(deadline.Add(reminderGrace)) {
+				continue
+			}
+			result = append(result, DueReminder{
+				Key:           fmt.Sprintf("%s:rel:%d@%d", t.ID, offset, deadline.Unix()),
+				Kind:          ReminderKindReminder,
+				FireAt:        fireAt,
+				OffsetMinutes: intPtr(offset),
+			})
+		}
+
+		for i, after := range overdueNudges {
+			fireAt := deadline.Add(after)
+			if !due(fireAt) {
+				continue
+			}
+			result = append(result, DueReminder{
+				Key:    fmt.Sprintf("%s:overdue:%d@%d", t.ID, i+1, deadline.Unix()),
+				Kind:   ReminderKindOverdue,
+				FireAt: fireAt,
+				Nudge:  i + 1,
+			})
+		}
+	}
+
+	for _, r := range t.Reminders {
+		if r.RemindAt == nil || !due(*r.RemindAt) {
+			continue
+		}
+		result = append(result, DueReminder{
+			Key:    fmt.Sprintf("%s:abs:%d", t.ID, r.RemindAt.Unix()),
+			Kind:   ReminderKindReminder,
+			FireAt: *r.RemindAt,
+		})
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].FireAt.Before(result[j].FireAt)
+	})
+	return result
+}
+
+// reminderOffsets merges ReminderMinutes with the relative reminders, without duplicates
+func reminderOffsets(t models.Task) []int {
+	seen := map[int]bool{}
+	var offsets []int
+	add := func(offset *int) {
+		if offset != nil && !seen[*offset] {
+			seen[*offset] = true
+			offsets = append(offsets, *offset)
+		}
+	}
+
+	add(t.ReminderMinutes)
+	for _, r := range t.Reminders {
+		add(r.OffsetMinutes)
+	}
+	return offsets
+}
+
+// buildReminders validates reminder DTOs against the task's deadline
+func buildReminders(dtos []ReminderDTO, deadline *time.Time) ([]models.TaskReminder, error) {
+	if len(dtos) > maxRemindersPerTask {
+		return nil, fmt.Errorf("too many reminders (max %d)", maxRemindersPerTask)
+	}
+
+	reminders := make([]models.TaskReminder, 0, len(dtos))
+	for _, dto := range dtos {
+		if (dto.OffsetMinutes == nil) == (dto.RemindAt == nil) {
+			return nil, errors.New("reminder needs either offset_minutes or remind_at")
+		}
+		if dto.OffsetMinutes != nil {
+			if err := validateReminderOffset(*dto.OffsetMinutes); err != nil {
+				return nil, err
+			}
+			if deadline == nil {
+				return nil, errors.New("relative reminder requires a deadline")
+			}
+		}
+		reminders = append(reminders, models.TaskReminder{
+			OffsetMinutes: dto.OffsetMinutes,
+			RemindAt:      dto.RemindAt,
+		})
+	}
+	return reminders, nil
+}
+
+func validateReminderOffset(minutes int) error {
+	if minutes < 0 || minutes > maxReminderLeadMinutes {
+		return fmt.Errorf("reminder offset must be between 0 and %d minutes", maxReminderLeadMinutes)
+	}
+	return nil
+}
+
+// shiftReminders copies reminders to the next occurrence of a repeating task;
+// absolute reminders move by the same amount as the deadline
+func shiftReminders(reminders []models.TaskReminder, shift time.Duration) []models.TaskReminder {
+	var shifted []models.TaskReminder
+	for _, r := range reminders {
+		next := models.TaskReminder{OffsetMinutes: r.OffsetMinutes}
+		if r.RemindAt != nil {
+			at := r.RemindAt.Add(shift)
+			next.RemindAt = &at
+		}
+		shifted = append(shifted, next)
+	}
+	return shifted
+}
@@ -3,6 +3,7 @@ package services
 import (
 	"errors"
 	"log"
+	"strings"
 	"time"
 
 	"github.com/workradar/server/internal/models"
@@ -13,15 +14,24 @@ import (
 type TaskService struct {
 	taskRepo     *repository.TaskRepository
 	categoryRepo *repository.CategoryRepository
+	reminderRepo *repository.TaskReminderRepository
+	userRepo     *repository.UserRepository
+	events       *EventHub
 }
 
 func NewTaskService(
 	taskRepo *repository.TaskRepository,
 	categoryRepo *repository.CategoryRepository,
+	reminderRepo *repository.TaskReminderRepository,
+	userRepo *repository.UserRepository,
+	events *EventHub,
 ) *TaskService {
 	return &TaskService{
 		taskRepo:     taskRepo,
 		categoryRepo: categoryRepo,
+		reminderRepo: reminderRepo,
+		userRepo:     userRepo,
+		events:       events,
 	}
 }
 
@@ -40,6 +50,21 @@ func (s *TaskService) CreateTask(userID string, data CreateTaskDTO) (*models.Tas
 		}
 	}
 
+	priority, err := normalizePriority(data.Priority)
+	if err != nil {
+		return nil, err
+	}
+
+	if data.ReminderMinutes != nil {
+		if err := validateReminderOffset(*data.ReminderMinutes); err != nil {
+			return nil, err
+		}
+	}
+	reminders, err := buildReminders(data.Reminders, data.Deadline)
+	if err != nil {
+		return nil, err
+	}
+
 	// Buat task
 	task := &models.Task{
 		UserID:          userID,
@@ -50,6 +75,8 @@ func (s *TaskService) CreateTask(userID string, data CreateTaskDTO) (*models.Tas
 		ReminderMinutes: data.ReminderMinutes,
 		DurationMinutes: data.DurationMinutes, // ✅ FIX: Save duration
 		Difficulty:      data.Difficulty,      // ✅ FIX: Save difficulty
+		Priority:        priority,
+		Reminders:       reminders,
 		RepeatType:      data.RepeatType,
 		RepeatInterval:  data.RepeatInterval,
 		RepeatEndDate:   data.RepeatEndDate,
@@ -65,9 +92,82 @@ func (s *TaskService) CreateTask(userID string, data CreateTaskDTO) (*models.Tas
 		task, _ = s.taskRepo.FindByID(task.ID)
 	}
 
+	s.events.Publish(userID, EventTaskCreated, task)
 	return task, nil
 }
 
+// ParseTaskResult is a quick-add preview, plus the created task when committed
+type ParseTaskResult struct {
+	Preview      CreateTaskDTO  `json:"preview"`
+	CategoryName string         `json:"category_name,omitempty"`
+	Phrases      []ParsedPhrase `json:"phrases"`
+	Warnings     []string       `json:"warnings"`
+	Task         *models.Task   `json:"task,omitempty"`
+}
+
+// ParseTask turns a quick-add sentence into a CreateTaskDTO; with commit the task is created.
+// Dates and times in the sentence are read in the user's timezone.
+func (s *TaskService) ParseTask(userID, text string, commit bool) (*ParseTaskResult, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	parsed := ParseQuickAdd(text, time.Now().In(user.Location()))
+
+	result := &ParseTaskResult{
+		Preview: CreateTaskDTO{
+			Title:           parsed.Title,
+			Deadline:        parsed.Deadline,
+			ReminderMinutes: parsed.ReminderMinutes,
+			DurationMinutes: parsed.DurationMinutes,
+			RepeatType:      parsed.RepeatType,
+			RepeatInterval:  parsed.RepeatInterval,
+			Priority:        parsed.Priority,
+		},
+		Phrases:  parsed.Phrases,
+		Warnings: []string{},
+	}
+
+	if parsed.CategoryName != "" {
+		categories, err := s.categoryRepo.FindByUserID(userID)
+		if err != nil {
+			return nil, err
+		}
+		for _, category := range categories {
+			if strings.EqualFold(category.Name, parsed.CategoryName) {
+				id := category.ID
+				result.Preview.CategoryID = &id
+				result.CategoryName = category.Name
+				break
+			}
+		}
+		if result.Preview.CategoryID == nil {
+			result.Warnings = append(result.Warnings, "Kategori #"+parsed.CategoryName+" tidak ditemukan")
+		}
+	}
+
+	if parsed.Title == "" {
+		result.Warnings = append(result.Warnings, "Judul tugas kosong")
+	}
+	if parsed.ReminderMinutes != nil && parsed.Deadline == nil {
+		result.Warnings = append(result.Warnings, "Pengingat butuh waktu deadline")
+	}
+	if parsed.Deadline != nil && parsed.Deadline.Before(time.Now()) {
+		result.Warnings = append(result.Warnings, "Deadline sudah lewat")
+	}
+
+	if !commit {
+		return result, nil
+	}
+
+	task, err := s.CreateTask(userID, result.Preview)
+	if err != nil {
+		return nil, err
+	}
+	result.Task = task
+	return result, nil
+}
+
 // GetTasks mendapatkan semua tasks user
 func (s *TaskService) GetTasks(userID string, categoryID *string) ([]models.Task, error) {
 	if categoryID != nil && *categoryID != "" {
@@ -76,6 +176,13 @@ func (s *TaskService) GetTasks(userID string, categoryID *string) ([]models.Task
 	return s.taskRepo.FindByUserID(userID)
 }
 
+// GetTasksByDate mendapatkan tasks user dengan deadline pada tanggal tertentu
+func (s *TaskService) GetTasksByDate(userID string, date time.Time) ([]models.Task, error) {
+	start := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+	end := start.Add(24*time.Hour - time.Second)
+	return s.taskRepo.FindByUserIDAndDateRange(userID, start, end)
+}
+
 // GetTaskByID mendapatkan task by ID
 func (s *TaskService) GetTaskByID(userID, taskID string) (*models.Task, error) {
 	task, err := s.taskRepo.FindByID(taskID)
@@ -129,9 +236,20 @@ func (s *TaskService) UpdateTask(userID, taskID string, data UpdateTaskDTO) (*mo
 	}
 
 	if data.ReminderMinutes != nil {
+		if err := validateReminderOffset(*data.ReminderMinutes); err != nil {
+			return nil, err
+		}
 		task.ReminderMinutes = data.ReminderMinutes
 	}
 
+	var reminders []models.TaskReminder
+	if data.Reminders != nil {
+		reminders, err = buildReminders(*data.Reminders, task.Deadline)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// ✅ FIX: Handle duration and difficulty updates
 	if data.DurationMinutes != nil {
 		task.DurationMinutes = data.DurationMinutes
@@ -141,6 +259,14 @@ func (s *TaskService) UpdateTask(userID, taskID string, data UpdateTaskDTO) (*mo
 		task.Difficulty = data.Difficulty
 	}
 
+	if data.Priority != nil {
+		priority, err := normalizePriority(data.Priority)
+		if err != nil {
+			return nil, err
+		}
+		task.Priority = priority
+	}
+
 	if data.RepeatType != nil {
 		task.RepeatType = *data.RepeatType
 	}
@@ -153,6 +279,11 @@ func (s *TaskService) UpdateTask(userID, taskID string, data UpdateTaskDTO) (*mo
 		task.RepeatEndDate = data.RepeatEndDate
 	}
 
+	if data.ScheduledStart != nil {
+		task.ScheduledStart = data.ScheduledStart
+		task.AutoScheduled = false
+	}
+
 	if data.IsCompleted != nil {
 		task.IsCompleted = *data.IsCompleted
 		if *data.IsCompleted {
@@ -167,11 +298,35 @@ func (s *TaskService) UpdateTask(userID, taskID string, data UpdateTaskDTO) (*mo
 		return nil, err
 	}
 
+	if data.Reminders != nil {
+		if err := s.reminderRepo.ReplaceForTask(task.ID, reminders); err != nil {
+			return nil, err
+		}
+	}
+
 	// Reload with category
 	task, _ = s.taskRepo.FindByID(task.ID)
+	s.events.Publish(userID, EventTaskUpdated, task)
 	return task, nil
 }
 
+// GetMatrix mengelompokkan pending tasks ke Eisenhower matrix
+func (s *TaskService) GetMatrix(userID string) (*EisenhowerMatrix, error) {
+	tasks, err := s.taskRepo.FindByUserIDAndComplete(userID, false)
+	if err != nil {
+		return nil, err
+	}
+	return BuildEisenhowerMatrix(tasks, time.Now()), nil
+}
+
+// GetReminderHistory mendapatkan riwayat reminder yang sudah dikirim untuk task
+func (s *TaskService) GetReminderHistory(userID, taskID string) ([]models.ReminderDelivery, error) {
+	if _, err := s.GetTaskByID(userID, taskID); err != nil {
+		return nil, err
+	}
+	return s.reminderRepo.FindDeliveriesByTaskID(taskID)
+}
+
 // DeleteTask menghapus task
 func (s *TaskService) DeleteTask(userID, taskID string) error {
 	// Verify ownership
@@ -180,7 +335,15 @@ func (s *TaskService) DeleteTask(userID, taskID string) error {
 		return err
 	}
 
-	return s.taskRepo.Delete(taskID)
+	if err := s.reminderRepo.DeleteByTaskID(taskID); err != nil {
+		return err
+	}
+	if err := s.taskRepo.Delete(taskID); err != nil {
+		return err
+	}
+
+	s.events.Publish(userID, EventTaskDeleted, map[string]string{"id": taskID})
+	return nil
 }
 
 // ToggleTaskComplete toggle status completed task
@@ -218,6 +381,8 @@ func (s *TaskService) ToggleTaskComplete(userID, taskID string) (*models.Task, e
 					ReminderMinutes: task.ReminderMinutes,
 					DurationMinutes: task.DurationMinutes,
 					Difficulty:      task.Difficulty, // ✅ FIX: Copy difficulty to next occurrence
+					Priority:        task.Priority,
+					Reminders:       shiftReminders(task.Reminders, nextDeadline.Sub(*task.Deadline)),
 					RepeatType:      task.RepeatType,
 					RepeatInterval:  task.RepeatInterval,
 					RepeatEndDate:   task.RepeatEndDate,
@@ -228,6 +393,8 @@ func (s *TaskService) ToggleTaskComplete(userID, taskID string) (*models.Task, e
 				if err := s.taskRepo.Create(newTask); err != nil {
 					// Log error but don't fail the completion
 					log.Printf("⚠️ Failed to create next repeat task: %v", err)
+				} else {
+					s.events.Publish(userID, EventTaskCreated, newTask)
 				}
 			}
 		}
@@ -239,6 +406,7 @@ func (s *TaskService) ToggleTaskComplete(userID, taskID string) (*models.Task, e
 		return nil, err
 	}
 
+	s.events.Publish(userID, EventTaskUpdated, task)
 	return task, nil
 }
 
@@ -268,9 +436,11 @@ type CreateTaskDTO struct {
 	ReminderMinutes *int              `json:"reminder_minutes"`
 	DurationMinutes *int              `json:"duration_minutes"` // ✅ FIX: Added
 	Difficulty      *string           `json:"difficulty"`       // ✅ FIX: Added
+	Priority        *string           `json:"priority"`
 	RepeatType      models.RepeatType `json:"repeat_type"`
 	RepeatInterval  int               `json:"repeat_interval"`
 	RepeatEndDate   *time.Time        `json:"repeat_end_date"`
+	Reminders       []ReminderDTO     `json:"reminders"` // In addition to reminder_minutes
 }
 
 type UpdateTaskDTO struct {
@@ -281,8 +451,11 @@ type UpdateTaskDTO struct {
 	ReminderMinutes *int               `json:"reminder_minutes"`
 	DurationMinutes *int               `json:"duration_minutes"` // ✅ FIX: Added
 	Difficulty      *string            `json:"difficulty"`       // ✅ FIX: Added
+	Priority        *string            `json:"priority"`
 	RepeatType      *models.RepeatType `json:"repeat_type"`
 	RepeatInterval  *int               `json:"repeat_interval"`
 	RepeatEndDate   *time.Time         `json:"repeat_end_date"`
 	IsCompleted     *bool              `json:"is_completed"`
+	ScheduledStart  *time.Time         `json:"scheduled_start"`
+	Reminders       *[]ReminderDTO     `json:"reminders"` // Replaces all reminders when set
 }
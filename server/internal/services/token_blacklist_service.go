@@ -1,72 +1,55 @@
 package services
 
 import (
-	"sync"
+	"log"
 	"time"
 )
 
-// TokenBlacklistService manages blacklisted tokens
+const blacklistKeyPrefix = "blacklist:"
+
+// TokenBlacklistService manages blacklisted tokens. Entries live in the state store
+// until the token expires, so a logout survives restarts when the store is shared.
 type TokenBlacklistService struct {
-	blacklist map[string]time.Time
-	mu        sync.RWMutex
-	cleanup   *time.Ticker
+	store StateStore
 }
 
 var blacklistInstance *TokenBlacklistService
 
-// GetTokenBlacklistService returns singleton instance
+// InitTokenBlacklistService sets the store of the singleton; call it once at startup
+func InitTokenBlacklistService(store StateStore) *TokenBlacklistService {
+	blacklistInstance = &TokenBlacklistService{store: store}
+	return blacklistInstance
+}
+
+// GetTokenBlacklistService returns singleton instance, in memory until InitTokenBlacklistService is called
 func GetTokenBlacklistService() *TokenBlacklistService {
 	if blacklistInstance == nil {
-		blacklistInstance = &TokenBlacklistService{
-			blacklist: make(map[string]time.Time),
-			cleanup:   time.NewTicker(1 * time.Hour),
-		}
-
-		// Start cleanup goroutine
-		go blacklistInstance.cleanupExpiredTokens()
+		InitTokenBlacklistService(NewMemoryStateStore())
 	}
 
 	return blacklistInstance
 }
 
 // AddToken menambahkan token ke blacklist
-func (s *TokenBlacklistService) AddToken(jti string, expiresAt time.Time) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	s.blacklist[jti] = expiresAt
+func (s *TokenBlacklistService) AddToken(jti string, expiresAt time.Time) error {
+	ttl := time.Until(expiresAt)
+	if jti == "" || ttl <= 0 {
+		return nil // Already expired, nothing to revoke
+	}
+	return s.store.Set(blacklistKeyPrefix+jti, "1", ttl)
 }
 
-// IsBlacklisted checks if token is blacklisted
+// IsBlacklisted checks if token is blacklisted. Store errors are logged and treated as
+// blacklisted: single-use tokens such as MFA tokens rely on this check alone.
 func (s *TokenBlacklistService) IsBlacklisted(jti string) bool {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-
-	expiresAt, exists := s.blacklist[jti]
-	if !exists {
+	if jti == "" {
 		return false
 	}
 
-	// Check if token sudah expired (otomatis tidak perlu di blacklist lagi)
-	if time.Now().After(expiresAt) {
-		return false
-	}
-
-	return true
-}
-
-// cleanupExpiredTokens removes expired tokens dari blacklist
-func (s *TokenBlacklistService) cleanupExpiredTokens() {
-	for range s.cleanup.C {
-		s.mu.Lock()
-		now := time.Now()
-
-		for jti, expiresAt := range s.blacklist {
-			if now.After(expiresAt) {
-				delete(s.blacklist, jti)
-			}
-		}
-
-		s.mu.Unlock()
+	_, found, err := s.store.Get(blacklistKeyPrefix + jti)
+	if err != nil {
+		log.Printf("⚠️ Failed to check token blacklist: %v", err)
+		return true
 	}
+	return found
 }
@@ -0,0 +1,110 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/workradar/server/internal/models"
+	"github.com/workradar/server/internal/repository"
+	"gorm.io/gorm"
+)
+
+const userStateKeyPrefix = "userstate:"
+
+var (
+	ErrUserNotFound    = errors.New("user not found")
+	ErrPasswordChanged = errors.New("password has been changed, please login again")
+	ErrAccountLocked   = errors.New("account is temporarily locked. Please try again later")
+)
+
+// UserState is the part of a user that authorization depends on. It is read on every
+// authenticated request instead of trusting the claims copied into the JWT at login.
+type UserState struct {
+	UserType          models.UserType `json:"user_type"`
+	VIPExpiresAt      *time.Time      `json:"vip_expires_at,omitempty"`
+	LockedUntil       *time.Time      `json:"locked_until,omitempty"`
+	PasswordChangedAt *time.Time      `json:"password_changed_at,omitempty"`
+}
+
+// EffectiveUserType is regular once the VIP period has ended, even before the
+// expiry job downgrades the user
+func (u *UserState) EffectiveUserType(now time.Time) models.UserType {
+	if u.UserType == models.UserTypeVIP && (u.VIPExpiresAt == nil || now.Before(*u.VIPExpiresAt)) {
+		return models.UserTypeVIP
+	}
+	return models.UserTypeRegular
+}
+
+// IsLocked checks if the account is locked
+func (u *UserState) IsLocked(now time.Time) bool {
+	return u.LockedUntil != nil && now.Before(*u.LockedUntil)
+}
+
+// IssuedBeforePasswordChange checks if a token was issued before the last password change.
+// JWT iat has second precision, so the change time is truncated to the second.
+func (u *UserState) IssuedBeforePasswordChange(issuedAt time.Time) bool {
+	return u.PasswordChangedAt != nil && issuedAt.Before(u.PasswordChangedAt.Truncate(time.Second))
+}
+
+// UserStateService caches user state in the state store for a short TTL. Services that
+// change the state call Invalidate, so with a shared store every replica sees the change
+// on the next request; with the memory store other replicas see it within the TTL.
+type UserStateService struct {
+	userRepo *repository.UserRepository
+	store    StateStore
+	ttl      time.Duration
+}
+
+func NewUserStateService(userRepo *repository.UserRepository, store StateStore, ttl time.Duration) *UserStateService {
+	return &UserStateService{userRepo: userRepo, store: store, ttl: ttl}
+}
+
+// Get returns the user state, from the cache when possible
+func (s *UserStateService) Get(userID string) (*UserState, error) {
+	key := userStateKeyPrefix + userID
+
+	value, found, err := s.store.Get(key)
+	if err != nil {
+		log.Printf("⚠️ Failed to read cached user state: %v", err)
+	}
+	if found {
+		var state UserState
+		if err := json.Unmarshal([]byte(value), &state); err == nil {
+			return &state, nil
+		}
+	}
+
+	user, err := s.userRepo.FindByID(userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	state := &UserState{
+		UserType:          user.UserType,
+		VIPExpiresAt:      user.VIPExpiresAt,
+		LockedUntil:       user.LockedUntil,
+		PasswordChangedAt: user.PasswordChangedAt,
+	}
+	if body, err := json.Marshal(state); err == nil {
+		if err := s.store.Set(key, string(body), s.ttl); err != nil {
+			log.Printf("⚠️ Failed to cache user state: %v", err)
+		}
+	}
+	return state, nil
+}
+
+// Invalidate drops the cached state after the user changed; failures are logged and
+// the stale state expires with the TTL. A nil service is a no-op.
+func (s *UserStateService) Invalidate(userID string) {
+	if s == nil {
+		return
+	}
+	if err := s.store.Delete(userStateKeyPrefix + userID); err != nil {
+		log.Printf("⚠️ Failed to invalidate user state for %s: %v", userID, err)
+	}
+}
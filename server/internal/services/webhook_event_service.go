@@ -0,0 +1,209 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/workradar/server/internal/models"
+	"github.com/workradar/server/internal/repository"
+)
+
+var (
+	ErrWebhookInvalidPayload   = errors.New("invalid notification payload")
+	ErrWebhookInvalidSignature = errors.New("invalid signature")
+)
+
+// maxWebhookBodySize caps stored notification bodies; gateway notifications are a few KB
+const maxWebhookBodySize = 64 << 10
+
+// sensitiveWebhookHeaders are never persisted with a webhook event
+var sensitiveWebhookHeaders = map[string]bool{
+	"authorization": true,
+	"cookie":        true,
+	"x-api-key":     true,
+}
+
+// WebhookEventService persists inbound payment notifications and processes or replays them
+type WebhookEventService struct {
+	repo           *repository.WebhookEventRepository
+	paymentService *PaymentService
+}
+
+func NewWebhookEventService(repo *repository.WebhookEventRepository, paymentService *PaymentService) *WebhookEventService {
+	return &WebhookEventService{
+		repo:           repo,
+		paymentService: paymentService,
+	}
+}
+
+// Record verifies an inbound notification and stores it. Bodies that are too large, not
+// JSON or not signed by the gateway are rejected without being stored. When the same
+// notification was already processed successfully it returns the existing event with
+// duplicate=true; a gateway retry of a notification that failed earlier returns the stored
+// event, whose body is never overwritten, so it can be processed again.
+func (s *WebhookEventService) Record(provider string, rawBody []byte, headers map[string][]string) (*models.WebhookEvent, bool, error) {
+	if len(rawBody) > maxWebhookBodySize {
+		return nil, false, ErrWebhookInvalidPayload
+	}
+
+	payload, err := s.verify(rawBody)
+	if err != nil {
+		return nil, false, err
+	}
+
+	notificationID := webhookNotificationID(payload)
+	orderID, _ := payload["order_id"].(string)
+
+	existing, err := s.repo.FindByNotificationID(provider, notificationID)
+	if err == nil {
+		if existing.Status == models.WebhookEventStatusProcessed {
+			log.Printf("⏭️  Duplicate webhook %s for order %s, already processed", existing.ID, orderID)
+			return existing, true, nil
+		}
+		return existing, false, nil
+	}
+
+	event := &models.WebhookEvent{
+		Provider:       provider,
+		NotificationID: notificationID,
+		OrderID:        orderID,
+		RawBody:        string(rawBody),
+		Headers:        filterWebhookHeaders(headers),
+		SignatureValid: true,
+		Status:         models.WebhookEventStatusReceived,
+	}
+
+	if err := s.repo.Create(event); err != nil {
+		// Lost a race with a concurrent delivery of the same notification
+		if existing, findErr := s.repo.FindByNotificationID(provider, notificationID); findErr == nil {
+			return existing, existing.Status == models.WebhookEventStatusProcessed, nil
+		}
+		return nil, false, err
+	}
+
+	return event, false, nil
+}
+
+// Process verifies the stored payload and runs it through PaymentService.HandleNotification,
+// recording the outcome on the event
+func (s *WebhookEventService) Process(event *models.WebhookEvent) error {
+	event.Attempts++
+
+	processErr := s.process(event)
+
+	now := time.Now()
+	event.ProcessedAt = &now
+	if processErr == nil {
+		event.Status = models.WebhookEventStatusProcessed
+		event.Error = nil
+	} else {
+		if errors.Is(processErr, ErrWebhookInvalidPayload) || errors.Is(processErr, ErrWebhookInvalidSignature) {
+			event.Status = models.WebhookEventStatusRejected
+		} else {
+			event.Status = models.WebhookEventStatusFailed
+		}
+		errMsg := processErr.Error()
+		event.Error = &errMsg
+	}
+
+	if err := s.repo.Update(event); err != nil {
+		log.Printf("⚠️ Failed to save webhook event %s: %v", event.ID, err)
+	}
+
+	return processErr
+}
+
+// process verifies and handles a stored notification. Stored events were verified when
+// they were recorded; verifying again keeps replays safe after a server key rotation.
+func (s *WebhookEventService) process(event *models.WebhookEvent) error {
+	payload, err := s.verify([]byte(event.RawBody))
+	event.SignatureValid = !errors.Is(err, ErrWebhookInvalidSignature)
+	if err != nil {
+		return err
+	}
+
+	return s.paymentService.HandleNotification(payload)
+}
+
+// verify parses a notification and checks its signature from the gateway
+func (s *WebhookEventService) verify(rawBody []byte) (map[string]interface{}, error) {
+	var payload map[string]interface{}
+	if err := json.Unmarshal(rawBody, &payload); err != nil {
+		return nil, ErrWebhookInvalidPayload
+	}
+
+	// Extract required fields for signature verification
+	orderID, orderIDOK := payload["order_id"].(string)
+	statusCode, statusCodeOK := payload["status_code"].(string)
+	grossAmount, grossAmountOK := payload["gross_amount"].(string)
+	signatureKey, signatureKeyOK := payload["signature_key"].(string)
+
+	if !orderIDOK || !statusCodeOK || !grossAmountOK || !signatureKeyOK || orderID == "" {
+		return nil, ErrWebhookInvalidPayload
+	}
+
+	// SECURITY: Verify signature from the gateway
+	if !s.paymentService.VerifyNotificationSignature(orderID, statusCode, grossAmount, signatureKey) {
+		return nil, ErrWebhookInvalidSignature
+	}
+
+	return payload, nil
+}
+
+// Replay re-processes a stored event (admin only)
+func (s *WebhookEventService) Replay(id, adminID string) (*models.WebhookEvent, error) {
+	event, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, errors.New("webhook event not found")
+	}
+
+	event.LastReplayedBy = &adminID
+	log.Printf("🔁 Replaying webhook event %s for order %s (by %s)", event.ID, event.OrderID, adminID)
+
+	// The outcome is recorded on the event itself
+	_ = s.Process(event)
+
+	return event, nil
+}
+
+// GetEvent retrieves a single webhook event including its raw body
+func (s *WebhookEventService) GetEvent(id string) (*models.WebhookEvent, error) {
+	return s.repo.FindByID(id)
+}
+
+// ListEvents retrieves webhook events, newest first
+func (s *WebhookEventService) ListEvents(status, orderID string, limit, offset int) ([]models.WebhookEvent, int64, error) {
+	return s.repo.List(status, orderID, limit, offset)
+}
+
+// webhookNotificationID derives the dedupe key for a verified notification. The signature
+// only covers order_id, status_code and gross_amount, so unsigned fields such as a
+// notification_id cannot be trusted on their own; retries of the same notification are
+// identified by the signed fields plus the gateway transaction and status.
+func webhookNotificationID(payload map[string]interface{}) string {
+	fields := []string{"order_id", "status_code", "gross_amount", "transaction_id", "transaction_status", "fraud_status"}
+	parts := make([]string, len(fields))
+	for i, field := range fields {
+		parts[i], _ = payload[field].(string)
+	}
+
+	hash := sha256.Sum256([]byte(strings.Join(parts, ":")))
+	return hex.EncodeToString(hash[:])
+}
+
+// filterWebhookHeaders flattens request headers and drops sensitive ones
+func filterWebhookHeaders(headers map[string][]string) map[string]string {
+	result := make(map[string]string, len(headers))
+	for key, values := range headers {
+		if sensitiveWebhookHeaders[strings.ToLower(key)] {
+			continue
+		}
+		result[key] = strings.Join(values, ", ")
+	}
+	return result
+}
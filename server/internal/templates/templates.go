@@ -0,0 +1,9 @@
+// Package templates embeds the email templates so the server binary runs without the source tree
+package templates
+
+import "embed"
+
+// Email holds email/<kind>.<lang>.html and .txt plus the shared layout and footers
+//
+//go:embed email
+var Email embed.FS
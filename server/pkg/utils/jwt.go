@@ -1,6 +1,8 @@
 package utils
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"time"
 
@@ -9,23 +11,28 @@ import (
 	"github.com/workradar/server/internal/config"
 )
 
+// RefreshTokenTTL is the lifetime of a refresh token and of the session it belongs to
+const RefreshTokenTTL = 7 * 24 * time.Hour
+
 type Claims struct {
-	UserID   string `json:"user_id"`
-	Email    string `json:"email"`
-	UserType string `json:"user_type"`
-	Type     string `json:"type"` // "access" or "refresh"
+	UserID    string `json:"user_id"`
+	Email     string `json:"email"`
+	UserType  string `json:"user_type"`
+	Type      string `json:"type"`          // "access" or "refresh"
+	SessionID string `json:"sid,omitempty"` // Server-side session, see ActiveSession
 	jwt.RegisteredClaims
 }
 
 // GenerateAccessToken membuat access token (24 hours for development)
-func GenerateAccessToken(userID, email, userType string) (string, error) {
+func GenerateAccessToken(userID, email, userType, sessionID string) (string, error) {
 	expirationTime := time.Now().Add(24 * time.Hour) // Extended for better UX
 
 	claims := &Claims{
-		UserID:   userID,
-		Email:    email,
-		UserType: userType,
-		Type:     "access",
+		UserID:    userID,
+		Email:     email,
+		UserType:  userType,
+		Type:      "access",
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ID:        uuid.New().String(), // JTI untuk blacklisting
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
@@ -43,14 +50,15 @@ func GenerateAccessToken(userID, email, userType string) (string, error) {
 }
 
 // GenerateRefreshToken membuat long-lived refresh token (7 days)
-func GenerateRefreshToken(userID, email, userType string) (string, error) {
-	expirationTime := time.Now().Add(7 * 24 * time.Hour)
+func GenerateRefreshToken(userID, email, userType, sessionID string) (string, error) {
+	expirationTime := time.Now().Add(RefreshTokenTTL)
 
 	claims := &Claims{
-		UserID:   userID,
-		Email:    email,
-		UserType: userType,
-		Type:     "refresh",
+		UserID:    userID,
+		Email:     email,
+		UserType:  userType,
+		Type:      "refresh",
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ID:        uuid.New().String(), // JTI untuk blacklisting
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
@@ -67,10 +75,10 @@ func GenerateRefreshToken(userID, email, userType string) (string, error) {
 	return tokenString, nil
 }
 
-// GenerateToken (legacy) - untuk backward compatibility
-// Deprecated: Use GenerateAccessToken and GenerateRefreshToken instead
-func GenerateToken(userID, email, userType string) (string, error) {
-	return GenerateAccessToken(userID, email, userType)
+// HashToken returns the hex SHA-256 of a token for storage and lookup
+func HashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
 }
 
 // ValidateToken memvalidasi JWT token dan return claims
@@ -0,0 +1,25 @@
+package utils
+
+import (
+	"net"
+	"strings"
+)
+
+// IsTrustedProxy reports whether ip is one of the trusted proxies, given as IPs or CIDRs
+func IsTrustedProxy(ip string, trusted []string) bool {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false
+	}
+
+	for _, entry := range trusted {
+		if strings.Contains(entry, "/") {
+			if _, network, err := net.ParseCIDR(entry); err == nil && network.Contains(addr) {
+				return true
+			}
+		} else if proxy := net.ParseIP(entry); proxy != nil && proxy.Equal(addr) {
+			return true
+		}
+	}
+	return false
+}
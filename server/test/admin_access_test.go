@@ -0,0 +1,128 @@
+package test
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/workradar/server/internal/database"
+	"github.com/workradar/server/internal/middleware"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+)
+
+// ============================================
+// ADMIN ACCESS TESTS
+// ============================================
+
+// newTestDB opens an in-memory SQLite database private to the test
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+		Logger:                                   logger.Default.LogMode(logger.Silent),
+		DisableForeignKeyConstraintWhenMigrating: true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	// One connection keeps every query on the same in-memory database
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	return db
+}
+
+// migrateTestDB creates tables for models. MySQL enum columns, which SQLite cannot parse,
+// become text and index names are prefixed with the table, as SQLite scopes them per database.
+func migrateTestDB(t *testing.T, db *gorm.DB, models ...interface{}) {
+	t.Helper()
+	for _, model := range models {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			t.Fatal(err)
+		}
+		adaptSchemaForSQLite(stmt.Schema, map[*schema.Schema]bool{})
+	}
+	if err := db.AutoMigrate(models...); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+}
+
+var namedIndexTag = regexp.MustCompile(`(?i)\b((?:unique)?index):(\w+)`)
+
+// adaptSchemaForSQLite rewrites s and the models it relates to, which AutoMigrate creates as well
+func adaptSchemaForSQLite(s *schema.Schema, seen map[*schema.Schema]bool) {
+	if seen[s] {
+		return
+	}
+	seen[s] = true
+	for _, field := range s.Fields {
+		if strings.HasPrefix(string(field.DataType), "enum") {
+			field.DataType = "text"
+		}
+		field.Tag = reflect.StructTag(namedIndexTag.ReplaceAllString(string(field.Tag), "${1}:"+s.Table+"_$2"))
+	}
+	for _, rel := range s.Relationships.Relations {
+		adaptSchemaForSQLite(rel.FieldSchema, seen)
+	}
+}
+
+// useTestDatabase points database.DB, which the access control service reads, at db
+func useTestDatabase(t *testing.T, db *gorm.DB) {
+	t.Helper()
+	previous := database.DB
+	database.DB = db
+	t.Cleanup(func() { database.DB = previous })
+}
+
+func newAdminTestApp(userID string) *fiber.App {
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		// Stand-in for AuthMiddleware
+		if userID != "" {
+			c.Locals("user_id", userID)
+		}
+		return c.Next()
+	})
+	app.Post("/api/admin/payments/:order_id/refund", middleware.AdminOnlyMiddleware(), func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+	return app
+}
+
+func TestAdminOnlyMiddleware(t *testing.T) {
+	db := newTestDB(t)
+	useTestDatabase(t, db)
+	if err := db.Exec("CREATE TABLE users (id varchar(36) PRIMARY KEY, user_type varchar(20))").Error; err != nil {
+		t.Fatal(err)
+	}
+	db.Exec("INSERT INTO users (id, user_type) VALUES ('regular-1', 'regular'), ('vip-1', 'vip'), ('superadmin-1', 'superadmin')")
+
+	tests := []struct {
+		name   string
+		userID string
+		want   int
+	}{
+		{"unauthenticated", "", fiber.StatusUnauthorized},
+		{"regular user", "regular-1", fiber.StatusForbidden},
+		{"vip user", "vip-1", fiber.StatusForbidden},
+		{"superadmin", "superadmin-1", fiber.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newAdminTestApp(tt.userID)
+			resp, err := app.Test(httptest.NewRequest("POST", "/api/admin/payments/ORDER-1/refund", nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.want {
+				t.Errorf("Expected %d, got %d", tt.want, resp.StatusCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/workradar/server/internal/models"
+	"github.com/workradar/server/internal/services"
+)
+
+// ============================================
+// AI CONTEXT TESTS
+// Token estimation and task ranking for the chat prompt
+// ============================================
+
+// TestEstimateTokens tests the rough four-characters-per-token estimate
+func TestEstimateTokens(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected int
+	}{
+		{"", 0},
+		{"abc", 1},
+		{"abcd", 1},
+		{"abcde", 2},
+		{"tugasku", 2},
+	}
+
+	for _, tt := range tests {
+		if got := services.EstimateTokens(tt.text); got != tt.expected {
+			t.Errorf("EstimateTokens(%q) = %d, want %d", tt.text, got, tt.expected)
+		}
+	}
+}
+
+// TestRankTasksForPrompt tests that mentioned and urgent tasks come first
+func TestRankTasksForPrompt(t *testing.T) {
+	now := time.Date(2025, 3, 10, 9, 0, 0, 0, time.Local)
+	at := func(d time.Duration) *time.Time {
+		deadline := now.Add(d)
+		return &deadline
+	}
+
+	tasks := []models.Task{
+		{ID: "later", Title: "Belanja bulanan", Deadline: at(20 * 24 * time.Hour)},
+		{ID: "done", Title: "Laporan keuangan", Deadline: at(time.Hour), IsCompleted: true},
+		{ID: "soon", Title: "Kirim invoice", Deadline: at(3 * time.Hour)},
+		{ID: "overdue", Title: "Bayar listrik", Deadline: at(-2 * time.Hour)},
+		{ID: "nodeadline", Title: "Baca buku"},
+		{ID: "mentioned", Title: "Presentasi proyek", Deadline: at(10 * 24 * time.Hour)},
+	}
+
+	ranked := services.RankTasksForPrompt(tasks, "bantu saya siapkan presentasi", now)
+
+	expected := []string{"mentioned", "overdue", "soon", "later", "nodeadline"}
+	if len(ranked) != len(expected) {
+		t.Fatalf("Expected %d pending tasks, got %d", len(expected), len(ranked))
+	}
+	for i, id := range expected {
+		if ranked[i].ID != id {
+			t.Errorf("Position %d: expected %s, got %s", i, id, ranked[i].ID)
+		}
+	}
+}
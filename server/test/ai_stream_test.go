@@ -0,0 +1,204 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/workradar/server/internal/models"
+	"github.com/workradar/server/internal/repository"
+	"github.com/workradar/server/internal/services"
+)
+
+// ============================================
+// AI STREAM TESTS
+// SSE parsing and persistence of streamed replies
+// ============================================
+
+func newAIStreamTestService(t *testing.T, providers ...services.LLMProvider) *services.AIService {
+	t.Helper()
+	db := newTestDB(t)
+	migrateTestDB(t, db, &models.User{}, &models.Category{}, &models.Task{}, &models.ChatConversation{}, &models.ChatMessage{}, &models.AIUsage{})
+	if err := db.Create(&models.User{ID: "user-1", Email: "user@example.com", Username: "user"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	chatRepo := repository.NewChatRepository(db)
+	taskRepo := repository.NewTaskRepository(db)
+	conversations := services.NewChatConversationService(repository.NewChatConversationRepository(db), chatRepo, taskRepo, repository.NewCategoryRepository(db))
+	usage := services.NewAIUsageService(repository.NewAIUsageRepository(db), services.AIUsageLimits{})
+	llm := services.NewLLMClient(providers, 0, 0)
+	return services.NewAIService(chatRepo, taskRepo, repository.NewUserRepository(db), conversations, nil, usage, llm, 4000)
+}
+
+// assertSavedExchange checks that the history holds the user message and reply, in order
+func assertSavedExchange(t *testing.T, ai *services.AIService, userMessage, reply string) {
+	t.Helper()
+	history, err := ai.GetChatHistory("user-1", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("Expected the exchange saved as 2 messages, got %d", len(history))
+	}
+	contents := map[models.ChatRole]string{}
+	for _, msg := range history {
+		contents[msg.Role] = msg.Content
+	}
+	if contents[models.ChatRoleUser] != userMessage || contents[models.ChatRoleModel] != reply {
+		t.Errorf("Expected %q / %q saved, got %v", userMessage, reply, contents)
+	}
+}
+
+// TestLLMStreamParsesSSE tests that comments, malformed chunks and anything after [DONE] are skipped
+func TestLLMStreamParsesSSE(t *testing.T) {
+	var received services.ChatRequest
+	server := fakeStreamServer(t, &received,
+		`{"model":"test-model","choices":[{"delta":{"role":"assistant"}}]}`,
+		`{"choices":[{"delta":{"content":"Halo"}}]}`,
+		`{not json`,
+		`{"choices":[{"delta":{"content":", apa kabar?"},"finish_reason":"stop"}]}`,
+		`[DONE]`,
+		`{"choices":[{"delta":{"content":"terlambat"}}]}`,
+	)
+	defer server.Close()
+
+	var deltas []string
+	result, err := newTestLLMProvider("primary", server.URL, "test-model").Stream(context.Background(), services.ChatRequest{}, func(delta string) error {
+		deltas = append(deltas, delta)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Stream failed: %v", err)
+	}
+	if len(deltas) != 2 || result.Content != "Halo, apa kabar?" {
+		t.Errorf("Expected 2 deltas forming the reply, got %q (%q)", deltas, result.Content)
+	}
+	if result.Model != "test-model" {
+		t.Errorf("Expected the model from the chunks, got %q", result.Model)
+	}
+}
+
+// TestLLMStreamProviderError tests that an error chunk ends the stream with the content so far
+func TestLLMStreamProviderError(t *testing.T) {
+	var received services.ChatRequest
+	server := fakeStreamServer(t, &received,
+		`{"choices":[{"delta":{"content":"Sebagian"}}]}`,
+		`{"error":{"message":"overloaded","type":"server_error"}}`,
+		`{"choices":[{"delta":{"content":" lagi"}}]}`,
+	)
+	defer server.Close()
+
+	result, err := newTestLLMProvider("primary", server.URL, "test-model").Stream(context.Background(), services.ChatRequest{}, func(string) error { return nil })
+	var llmErr *services.LLMError
+	if !errors.As(err, &llmErr) || llmErr.Message != "overloaded" {
+		t.Fatalf("Expected the provider error, got %v", err)
+	}
+	if result.Content != "Sebagian" {
+		t.Errorf("Expected content up to the error, got %q", result.Content)
+	}
+}
+
+// TestAIStreamSavesReply tests that a completed stream is saved to the history
+func TestAIStreamSavesReply(t *testing.T) {
+	var received services.ChatRequest
+	server := fakeStreamServer(t, &received,
+		`{"choices":[{"delta":{"content":"Mulai dari "}}]}`,
+		`{"choices":[{"delta":{"content":"tugas terpenting."}}]}`,
+		`[DONE]`,
+	)
+	defer server.Close()
+	ai := newAIStreamTestService(t, newTestLLMProvider("primary", server.URL, "test-model"))
+
+	response, err := ai.StreamResponse(context.Background(), "user-1", "Apa yang harus saya kerjakan?", services.ChatOptions{}, func(string) error { return nil })
+	if err != nil {
+		t.Fatalf("StreamResponse failed: %v", err)
+	}
+	assertSavedExchange(t, ai, "Apa yang harus saya kerjakan?", response)
+
+	// Streaming is text-only: no tools are offered and the model is told it cannot act
+	if len(received.Tools) != 0 || len(received.Messages) == 0 ||
+		!strings.Contains(received.Messages[0].Content, "tidak bisa membuat, mengubah, atau menyelesaikan tugas") {
+		t.Errorf("Expected a text-only request, got %d tools", len(received.Tools))
+	}
+}
+
+// TestAIStreamSavesAfterDisconnect tests that the part streamed before the client went away is saved
+func TestAIStreamSavesAfterDisconnect(t *testing.T) {
+	var received services.ChatRequest
+	server := fakeStreamServer(t, &received,
+		`{"choices":[{"delta":{"content":"Bagian pertama"}}]}`,
+		`{"choices":[{"delta":{"content":", bagian kedua"}}]}`,
+		`{"choices":[{"delta":{"content":", bagian ketiga"}}]}`,
+		`[DONE]`,
+	)
+	defer server.Close()
+	ai := newAIStreamTestService(t, newTestLLMProvider("primary", server.URL, "test-model"))
+
+	sent := 0
+	response, err := ai.StreamResponse(context.Background(), "user-1", "Ceritakan", services.ChatOptions{}, func(string) error {
+		sent++
+		if sent == 2 {
+			return errors.New("client disconnected")
+		}
+		return nil
+	})
+	if !errors.Is(err, services.ErrAIStreamAborted) {
+		t.Fatalf("Expected ErrAIStreamAborted, got %v", err)
+	}
+	if response != "Bagian pertama, bagian kedua" {
+		t.Errorf("Expected the stream cut at the failed delta, got %q", response)
+	}
+	assertSavedExchange(t, ai, "Ceritakan", response)
+}
+
+// TestAIStreamSavesSetupAndFallbackReplies tests that replies not generated by the model are saved too
+func TestAIStreamSavesSetupAndFallbackReplies(t *testing.T) {
+	t.Run("no provider configured", func(t *testing.T) {
+		ai := newAIStreamTestService(t)
+		var streamed string
+		response, err := ai.StreamResponse(context.Background(), "user-1", "Halo", services.ChatOptions{}, func(delta string) error {
+			streamed += delta
+			return nil
+		})
+		if err != nil || response == "" || streamed != response {
+			t.Fatalf("Expected the setup message streamed, got %q / %q (err: %v)", response, streamed, err)
+		}
+		assertSavedExchange(t, ai, "Halo", response)
+	})
+
+	t.Run("every provider failed", func(t *testing.T) {
+		var calls int32
+		server := fakeLLMServer(t, http.StatusServiceUnavailable, "", &calls, nil)
+		defer server.Close()
+		ai := newAIStreamTestService(t, newTestLLMProvider("primary", server.URL, "test-model"))
+
+		response, err := ai.StreamResponse(context.Background(), "user-1", "Tips fokus", services.ChatOptions{}, func(string) error { return nil })
+		if err != nil || response == "" {
+			t.Fatalf("Expected the fallback reply, got %q (err: %v)", response, err)
+		}
+		assertSavedExchange(t, ai, "Tips fokus", response)
+	})
+
+	t.Run("client gone before the reply", func(t *testing.T) {
+		ai := newAIStreamTestService(t)
+		response, err := ai.StreamResponse(context.Background(), "user-1", "Halo", services.ChatOptions{}, func(string) error {
+			return errors.New("client disconnected")
+		})
+		if !errors.Is(err, services.ErrAIStreamAborted) {
+			t.Fatalf("Expected ErrAIStreamAborted, got %v", err)
+		}
+		assertSavedExchange(t, ai, "Halo", response)
+	})
+
+	t.Run("non-streaming parity", func(t *testing.T) {
+		ai := newAIStreamTestService(t)
+		result, err := ai.GenerateResponse("user-1", "Halo", services.ChatOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertSavedExchange(t, ai, "Halo", result.Response)
+	})
+}
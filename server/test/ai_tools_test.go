@@ -0,0 +1,252 @@
+package test
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/workradar/server/internal/models"
+	"github.com/workradar/server/internal/repository"
+	"github.com/workradar/server/internal/services"
+)
+
+// ============================================
+// AI TOOL TESTS
+// ============================================
+
+type aiToolTestEnv struct {
+	executor   *services.AIToolExecutor
+	taskRepo   *repository.TaskRepository
+	actionRepo *repository.AIActionRepository
+}
+
+func newAIToolTestEnv(t *testing.T) *aiToolTestEnv {
+	t.Helper()
+	db := newTestDB(t)
+	migrateTestDB(t, db, &models.User{}, &models.Task{}, &models.TaskReminder{}, &models.AIAction{}, &models.Leave{})
+
+	for _, user := range []models.User{
+		{ID: "user-a", Email: "a@example.com", Username: "a", Timezone: "Asia/Tokyo"},
+		{ID: "user-b", Email: "b@example.com", Username: "b"},
+	} {
+		if err := db.Create(&user).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	taskRepo := repository.NewTaskRepository(db)
+	userRepo := repository.NewUserRepository(db)
+	taskService := services.NewTaskService(taskRepo, repository.NewCategoryRepository(db), repository.NewTaskReminderRepository(db), userRepo, nil)
+	actionRepo := repository.NewAIActionRepository(db)
+	return &aiToolTestEnv{
+		executor:   services.NewAIToolExecutor(actionRepo, userRepo, taskService, services.NewLeaveService(repository.NewLeaveRepository(db))),
+		taskRepo:   taskRepo,
+		actionRepo: actionRepo,
+	}
+}
+
+func aiToolCall(name string, args map[string]interface{}) services.ToolCall {
+	var call services.ToolCall
+	call.ID = "call-1"
+	call.Type = "function"
+	call.Function.Name = name
+	if args != nil {
+		body, _ := json.Marshal(args)
+		call.Function.Arguments = string(body)
+	}
+	return call
+}
+
+func TestAIToolArgumentValidation(t *testing.T) {
+	env := newAIToolTestEnv(t)
+
+	call := aiToolCall(services.AIToolCreateTask, nil)
+	call.Function.Arguments = "{not json"
+	if action, content := env.executor.Handle("user-a", call, false); action != nil || !json.Valid([]byte(content)) {
+		t.Errorf("Expected unparseable arguments rejected before logging, got %v %s", action, content)
+	}
+
+	tests := []struct {
+		name string
+		call services.ToolCall
+	}{
+		{"missing title", aiToolCall(services.AIToolCreateTask, map[string]interface{}{"title": " "})},
+		{"invalid deadline", aiToolCall(services.AIToolCreateTask, map[string]interface{}{"title": "Report", "deadline": "next friday"})},
+		{"invalid priority", aiToolCall(services.AIToolCreateTask, map[string]interface{}{"title": "Report", "priority": "urgent"})},
+		{"missing deadline", aiToolCall(services.AIToolRescheduleTask, map[string]interface{}{"task_id": "x"})},
+		{"unknown task", aiToolCall(services.AIToolCompleteTask, map[string]interface{}{"task_id": "missing"})},
+		{"missing reason", aiToolCall(services.AIToolRequestLeave, map[string]interface{}{"date": "2026-03-02"})},
+		{"unknown tool", aiToolCall("delete_account", map[string]interface{}{})},
+	}
+	for _, tt := range tests {
+		action, content := env.executor.Handle("user-a", tt.call, false)
+		if action == nil || action.Status != models.AIActionStatusFailed || action.Error == nil {
+			t.Errorf("%s: expected failed action, got %+v", tt.name, action)
+			continue
+		}
+		var result map[string]interface{}
+		if err := json.Unmarshal([]byte(content), &result); err != nil || result["error"] == nil {
+			t.Errorf("%s: expected error tool message, got %s", tt.name, content)
+		}
+	}
+
+	if tasks, _ := env.taskRepo.FindByUserID("user-a"); len(tasks) != 0 {
+		t.Errorf("Expected no tasks created by invalid calls, got %d", len(tasks))
+	}
+}
+
+func TestAIToolDatesUseUserTimezone(t *testing.T) {
+	env := newAIToolTestEnv(t)
+
+	action, _ := env.executor.Handle("user-a", aiToolCall(services.AIToolCreateTask, map[string]interface{}{
+		"title":    "Standup",
+		"deadline": "2026-03-02T09:00",
+	}), false)
+	if action == nil || action.Status != models.AIActionStatusExecuted {
+		t.Fatalf("Expected executed action, got %+v", action)
+	}
+
+	task, err := env.taskRepo.FindByID(action.Result["task_id"].(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokyo, _ := time.LoadLocation("Asia/Tokyo")
+	if want := time.Date(2026, 3, 2, 9, 0, 0, 0, tokyo); task.Deadline == nil || !task.Deadline.Equal(want) {
+		t.Errorf("Expected deadline %v in the user's timezone, got %v", want, task.Deadline)
+	}
+	// The tool result echoes the deadline back in the same timezone
+	if got := action.Result["deadline"]; got != "2026-03-02T09:00" {
+		t.Errorf("Expected result deadline in the user's timezone, got %v", got)
+	}
+}
+
+func TestAIToolConfirmAndReject(t *testing.T) {
+	env := newAIToolTestEnv(t)
+	create := aiToolCall(services.AIToolCreateTask, map[string]interface{}{"title": "Write report"})
+
+	pending, _ := env.executor.Handle("user-a", create, true)
+	if pending == nil || pending.Status != models.AIActionStatusPending {
+		t.Fatalf("Expected pending action in confirm mode, got %+v", pending)
+	}
+	if tasks, _ := env.taskRepo.FindByUserID("user-a"); len(tasks) != 0 {
+		t.Fatal("Expected nothing executed before confirmation")
+	}
+
+	confirmed, err := env.executor.Confirm("user-a", pending.ID)
+	if err != nil || confirmed.Status != models.AIActionStatusExecuted || confirmed.ExecutedAt == nil {
+		t.Fatalf("Expected executed action, got %+v (err: %v)", confirmed, err)
+	}
+	if _, err := env.executor.Confirm("user-a", pending.ID); !errors.Is(err, services.ErrAIActionNotPending) {
+		t.Errorf("Expected second confirmation refused, got %v", err)
+	}
+
+	other, _ := env.executor.Handle("user-a", create, true)
+	rejected, err := env.executor.Reject("user-a", other.ID)
+	if err != nil || rejected.Status != models.AIActionStatusRejected {
+		t.Fatalf("Expected rejected action, got %+v (err: %v)", rejected, err)
+	}
+	if _, err := env.executor.Confirm("user-a", other.ID); !errors.Is(err, services.ErrAIActionNotPending) {
+		t.Errorf("Expected rejected action not confirmable, got %v", err)
+	}
+
+	if tasks, _ := env.taskRepo.FindByUserID("user-a"); len(tasks) != 1 {
+		t.Errorf("Expected exactly one task, got %d", len(tasks))
+	}
+}
+
+func TestAIToolConfirmRunsOnce(t *testing.T) {
+	env := newAIToolTestEnv(t)
+	pending, _ := env.executor.Handle("user-a", aiToolCall(services.AIToolCreateTask, map[string]interface{}{"title": "Once"}), true)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	succeeded := 0
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := env.executor.Confirm("user-a", pending.ID); err == nil {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if succeeded != 1 {
+		t.Errorf("Expected one confirmation to win, %d succeeded", succeeded)
+	}
+	if tasks, _ := env.taskRepo.FindByUserID("user-a"); len(tasks) != 1 {
+		t.Errorf("Expected the action executed once, got %d tasks", len(tasks))
+	}
+}
+
+func TestAIToolInterruptedActionIsRecovered(t *testing.T) {
+	env := newAIToolTestEnv(t)
+
+	// A confirmation whose process died after claiming the action
+	claimedAt := time.Now().Add(-time.Hour)
+	stuck := &models.AIAction{UserID: "user-a", Tool: services.AIToolCreateTask, Status: models.AIActionStatusRunning, ClaimedAt: &claimedAt}
+	if err := env.actionRepo.Create(stuck); err != nil {
+		t.Fatal(err)
+	}
+	pending, _ := env.executor.Handle("user-a", aiToolCall(services.AIToolCreateTask, map[string]interface{}{"title": "Fresh"}), true)
+	if _, err := env.executor.Confirm("user-a", pending.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := env.executor.ListActions("user-a", "", 10); err != nil {
+		t.Fatal(err)
+	}
+	recovered, _ := env.actionRepo.FindByID(stuck.ID)
+	if recovered.Status != models.AIActionStatusFailed || recovered.Error == nil {
+		t.Errorf("Expected the interrupted action marked failed, got %+v", recovered)
+	}
+	if done, _ := env.actionRepo.FindByID(pending.ID); done.Status != models.AIActionStatusExecuted {
+		t.Errorf("Expected the completed action untouched, got %s", done.Status)
+	}
+}
+
+func TestAIToolCannotTouchOtherUsersTasks(t *testing.T) {
+	env := newAIToolTestEnv(t)
+
+	created, _ := env.executor.Handle("user-a", aiToolCall(services.AIToolCreateTask, map[string]interface{}{
+		"title":    "Private",
+		"deadline": "2026-03-02",
+	}), false)
+	taskID := created.Result["task_id"].(string)
+
+	for _, call := range []services.ToolCall{
+		aiToolCall(services.AIToolCompleteTask, map[string]interface{}{"task_id": taskID}),
+		aiToolCall(services.AIToolRescheduleTask, map[string]interface{}{"task_id": taskID, "deadline": "2026-04-01"}),
+	} {
+		if action, _ := env.executor.Handle("user-b", call, false); action.Status != models.AIActionStatusFailed {
+			t.Errorf("Expected %s on another user's task to fail, got %s", call.Function.Name, action.Status)
+		}
+	}
+
+	task, _ := env.taskRepo.FindByID(taskID)
+	if task.IsCompleted || task.Deadline.Month() != time.March {
+		t.Errorf("Expected task unchanged, got completed=%v deadline=%v", task.IsCompleted, task.Deadline)
+	}
+
+	listed, _ := env.executor.Handle("user-b", aiToolCall(services.AIToolListTasks, map[string]interface{}{"date": "2026-03-02"}), false)
+	if count := listed.Result["count"]; count != 0 {
+		t.Errorf("Expected no tasks listed for another user, got %v", count)
+	}
+
+	// Pending actions of another user can be neither confirmed nor rejected
+	pending, _ := env.executor.Handle("user-a", aiToolCall(services.AIToolCompleteTask, map[string]interface{}{"task_id": taskID}), true)
+	if _, err := env.executor.Confirm("user-b", pending.ID); !errors.Is(err, services.ErrAIActionNotFound) {
+		t.Errorf("Expected not found confirming another user's action, got %v", err)
+	}
+	if _, err := env.executor.Reject("user-b", pending.ID); !errors.Is(err, services.ErrAIActionNotFound) {
+		t.Errorf("Expected not found rejecting another user's action, got %v", err)
+	}
+	if actions, _ := env.executor.ListActions("user-a", string(models.AIActionStatusPending), 10); len(actions) != 1 {
+		t.Errorf("Expected the action still pending, got %d pending", len(actions))
+	}
+}
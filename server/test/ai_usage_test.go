@@ -0,0 +1,158 @@
+package test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/workradar/server/internal/models"
+	"github.com/workradar/server/internal/repository"
+	"github.com/workradar/server/internal/services"
+	"gorm.io/gorm"
+)
+
+// ============================================
+// AI USAGE QUOTA TESTS
+// ============================================
+
+func int64Ptr(v int64) *int64 { return &v }
+
+// TestAIQuotaExceeded tests which quotas block further AI requests
+func TestAIQuotaExceeded(t *testing.T) {
+	tests := []struct {
+		name     string
+		quota    services.AIQuota
+		exceeded bool
+	}{
+		{"unlimited", services.AIQuota{}, false},
+		{"daily remaining", services.AIQuota{DailyRemaining: int64Ptr(100), MonthlyRemaining: int64Ptr(5000)}, false},
+		{"daily used up", services.AIQuota{DailyRemaining: int64Ptr(0), MonthlyRemaining: int64Ptr(5000)}, true},
+		{"monthly used up", services.AIQuota{DailyRemaining: int64Ptr(100), MonthlyRemaining: int64Ptr(0)}, true},
+		{"global budget exhausted", services.AIQuota{GlobalExhausted: true}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.quota.Exceeded(); got != tt.exceeded {
+				t.Errorf("Exceeded() = %v, want %v", got, tt.exceeded)
+			}
+		})
+	}
+}
+
+func newAIUsageTestService(t *testing.T, limits services.AIUsageLimits) (*services.AIUsageService, *gorm.DB) {
+	t.Helper()
+	db := newTestDB(t)
+	migrateTestDB(t, db, &models.User{}, &models.AIUsage{})
+	return services.NewAIUsageService(repository.NewAIUsageRepository(db), limits), db
+}
+
+func TestAIUsageRecordAndQuota(t *testing.T) {
+	usage, _ := newAIUsageTestService(t, services.AIUsageLimits{
+		DailyTokensPerUser:   1000,
+		MonthlyTokensPerUser: 5000,
+		InputPricePerMTok:    1,
+		OutputPricePerMTok:   2,
+	})
+
+	usage.Record("user-1", models.AIUsageKindChat, "test-model", 300, 100, false)
+	usage.Record("user-2", models.AIUsageKindChat, "test-model", 900, 100, false)
+
+	quota, err := usage.GetQuota("user-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if quota.DailyUsed != 400 || *quota.DailyRemaining != 600 || quota.MonthlyUsed != 400 || *quota.MonthlyRemaining != 4600 {
+		t.Errorf("Unexpected quota: %+v", quota)
+	}
+	if _, err := usage.CheckQuota("user-1"); err != nil {
+		t.Errorf("Expected user-1 within budget, got %v", err)
+	}
+
+	// user-2 used the whole daily budget
+	quota, err = usage.CheckQuota("user-2")
+	if !errors.Is(err, services.ErrAIQuotaExceeded) || quota == nil || *quota.DailyRemaining != 0 {
+		t.Errorf("Expected user-2 over the daily budget, got %+v (err: %v)", quota, err)
+	}
+}
+
+func TestAIUsageGlobalBudget(t *testing.T) {
+	usage, _ := newAIUsageTestService(t, services.AIUsageLimits{GlobalMonthlyTokens: 1000})
+
+	usage.Record("user-1", models.AIUsageKindChat, "test-model", 800, 200, false)
+
+	// Another user with no usage of their own is blocked by the service-wide budget
+	quota, err := usage.CheckQuota("user-2")
+	if !errors.Is(err, services.ErrAIQuotaExceeded) || !quota.GlobalExhausted {
+		t.Errorf("Expected the global budget exhausted, got %+v (err: %v)", quota, err)
+	}
+}
+
+func TestAIUsageReservationsHoldBudget(t *testing.T) {
+	usage, db := newAIUsageTestService(t, services.AIUsageLimits{DailyTokensPerUser: 2000})
+	req := services.ChatRequest{
+		Model:     "test-model",
+		Messages:  []services.ChatMessage{{Role: "user", Content: "Halo"}},
+		MaxTokens: 1024,
+	}
+
+	// The first request in flight holds its worst case, so a concurrent one is refused
+	first, _, err := usage.Reserve("user-1", models.AIUsageKindChat, req)
+	if err != nil || first == nil {
+		t.Fatalf("Expected the first reservation, got %v", err)
+	}
+	if _, quota, err := usage.Reserve("user-1", models.AIUsageKindChat, req); !errors.Is(err, services.ErrAIQuotaExceeded) || quota == nil {
+		t.Fatalf("Expected the second reservation refused, got %v", err)
+	}
+
+	// Settling replaces the estimate with the real usage and frees the rest
+	usage.SettleCompletion(first, "user-1", models.AIUsageKindChat, req, &services.ChatResponse{
+		Model: "test-model",
+		Usage: &services.ChatUsage{PromptTokens: 50, CompletionTokens: 30},
+	})
+	var rows []models.AIUsage
+	db.Find(&rows)
+	if len(rows) != 1 || rows[0].Reserved || rows[0].TotalTokens != 80 {
+		t.Fatalf("Expected one settled row of 80 tokens, got %+v", rows)
+	}
+
+	second, _, err := usage.Reserve("user-1", models.AIUsageKindChat, req)
+	if err != nil {
+		t.Fatalf("Expected a reservation after settling, got %v", err)
+	}
+	usage.Release(second)
+	if quota, _ := usage.GetQuota("user-1"); quota.DailyUsed != 80 {
+		t.Errorf("Expected the released reservation dropped, got %d used", quota.DailyUsed)
+	}
+}
+
+func TestAIUsageStats(t *testing.T) {
+	usage, db := newAIUsageTestService(t, services.AIUsageLimits{GlobalMonthlyTokens: 10000})
+	if err := db.Create(&models.User{ID: "user-1", Email: "user@example.com", Username: "budi"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	usage.Record("user-1", models.AIUsageKindChat, "model-a", 300, 100, false)
+	usage.Record("user-1", models.AIUsageKindStream, "model-b", 100, 100, true)
+	usage.Record("user-2", models.AIUsageKindChat, "model-a", 50, 50, false)
+
+	stats, err := usage.GetStats(7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Totals.Requests != 3 || stats.Totals.TotalTokens != 700 {
+		t.Errorf("Unexpected totals: %+v", stats.Totals)
+	}
+	if len(stats.ByModel) != 2 || stats.ByModel[0].Key != "model-a" || stats.ByModel[0].TotalTokens != 500 {
+		t.Errorf("Unexpected usage by model: %+v", stats.ByModel)
+	}
+	if len(stats.ByDay) != 1 || stats.ByDay[0].Key != time.Now().Format("2006-01-02") {
+		t.Errorf("Expected today's usage in one day, got %+v", stats.ByDay)
+	}
+	if len(stats.TopUsers) != 2 || stats.TopUsers[0].UserID != "user-1" || stats.TopUsers[0].Username != "budi" {
+		t.Errorf("Unexpected top users: %+v", stats.TopUsers)
+	}
+	if stats.Global["monthly_used"] != int64(700) {
+		t.Errorf("Expected the global budget usage, got %+v", stats.Global)
+	}
+}
@@ -0,0 +1,128 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/workradar/server/internal/models"
+	"github.com/workradar/server/internal/repository"
+	"github.com/workradar/server/internal/services"
+)
+
+// ============================================
+// AUTO-SCHEDULER TESTS
+// ============================================
+
+func scheduleInput(now time.Time, tasks, blocked []models.Task) services.ScheduleInput {
+	return services.ScheduleInput{
+		Now:      now,
+		Until:    now.AddDate(0, 0, 7),
+		WorkDays: services.ParseWorkDays(nil), // Mon-Fri 09:00-17:00
+		DaysOff:  map[string]string{},
+		Blocked:  blocked,
+		Tasks:    tasks,
+	}
+}
+
+func assignmentFor(t *testing.T, preview *services.SchedulePreview, taskID string) services.ScheduleAssignment {
+	t.Helper()
+	for _, a := range preview.Assignments {
+		if a.TaskID == taskID {
+			return a
+		}
+	}
+	t.Fatalf("Task %s was not scheduled (unscheduled: %+v)", taskID, preview.Unscheduled)
+	return services.ScheduleAssignment{}
+}
+
+// TestAutoScheduleEarliestDeadlineFirst tests EDF order around an existing time block
+func TestAutoScheduleEarliestDeadlineFirst(t *testing.T) {
+	monday := time.Date(2026, 3, 2, 9, 0, 0, 0, time.Local)
+	dueTue := monday.AddDate(0, 0, 1).Add(8 * time.Hour)
+	dueMon := monday.Add(8 * time.Hour)
+
+	meetingStart := monday.Add(time.Hour) // 10:00-11:00
+	meeting := planTask("meeting", 60, "", nil)
+	meeting.ScheduledStart = &meetingStart
+
+	preview := services.AutoSchedule(scheduleInput(monday, []models.Task{
+		planTask("later", 60, "", &dueTue),
+		planTask("sooner", 60, "", &dueMon),
+		planTask("anytime", 30, "", nil),
+	}, []models.Task{meeting}))
+
+	if got := assignmentFor(t, preview, "sooner").Start; !got.Equal(monday) {
+		t.Errorf("Expected earliest deadline at 09:00, got %v", got)
+	}
+	if got := assignmentFor(t, preview, "later").Start; !got.Equal(monday.Add(2 * time.Hour)) {
+		t.Errorf("Expected second task after the meeting at 11:00, got %v", got)
+	}
+	if got := assignmentFor(t, preview, "anytime").Start; !got.Equal(monday.Add(3 * time.Hour)) {
+		t.Errorf("Expected task without deadline last at 12:00, got %v", got)
+	}
+}
+
+// TestAutoScheduleSeparatesFocusTasks tests that focus blocks are not stacked
+func TestAutoScheduleSeparatesFocusTasks(t *testing.T) {
+	monday := time.Date(2026, 3, 2, 9, 0, 0, 0, time.Local)
+	due := monday.AddDate(0, 0, 2)
+
+	preview := services.AutoSchedule(scheduleInput(monday, []models.Task{
+		planTask("deep1", 60, "focus", &due),
+		planTask("deep2", 60, "focus", &due),
+	}, nil))
+
+	first := assignmentFor(t, preview, "deep1")
+	second := assignmentFor(t, preview, "deep2")
+	if second.Start.Sub(first.End) < 15*time.Minute {
+		t.Errorf("Expected a break between focus tasks, got %v-%v and %v-%v",
+			first.Start.Format("15:04"), first.End.Format("15:04"), second.Start.Format("15:04"), second.End.Format("15:04"))
+	}
+}
+
+// TestAutoScheduleSkipsDaysOff tests holidays, weekends and impossible deadlines
+func TestAutoScheduleSkipsDaysOff(t *testing.T) {
+	friday := time.Date(2026, 3, 6, 16, 30, 0, 0, time.Local)
+	dueMonday := time.Date(2026, 3, 9, 17, 0, 0, 0, time.Local)
+	dueFriday := time.Date(2026, 3, 6, 17, 0, 0, 0, time.Local)
+
+	input := scheduleInput(friday, []models.Task{
+		planTask("report", 120, "", &dueMonday),
+		planTask("tooLate", 120, "", &dueFriday),
+	}, nil)
+	input.DaysOff["2026-03-09"] = "Hari libur"
+	input.Until = friday.AddDate(0, 0, 7)
+
+	preview := services.AutoSchedule(input)
+
+	if len(preview.Unscheduled) != 2 {
+		t.Fatalf("Expected both tasks unscheduled (weekend + holiday before deadline), got %+v", preview.Assignments)
+	}
+}
+
+func TestPreviewScheduleUsesUserWorkHours(t *testing.T) {
+	db := newTestDB(t)
+	migrateTestDB(t, db, &models.User{}, &models.Task{}, &models.TaskReminder{}, &models.Holiday{}, &models.Leave{})
+	if err := db.Create(&models.User{ID: "user-1", Email: "user@example.com", Username: "user", Timezone: "Pacific/Kiritimati"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	taskRepo := repository.NewTaskRepository(db)
+	task := planTask("task-1", 60, "", nil)
+	task.UserID = "user-1"
+	if err := taskRepo.Create(&task); err != nil {
+		t.Fatal(err)
+	}
+
+	scheduling := services.NewSchedulingService(repository.NewUserRepository(db), taskRepo,
+		repository.NewHolidayRepository(db), repository.NewLeaveRepository(db), nil)
+	preview, err := scheduling.PreviewSchedule("user-1", 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loc, _ := time.LoadLocation("Pacific/Kiritimati")
+	start := assignmentFor(t, preview, "task-1").Start.In(loc)
+	if start.Hour() < 9 || start.Hour() >= 17 || start.Weekday() == time.Saturday || start.Weekday() == time.Sunday {
+		t.Errorf("Expected a block within the user's work hours, got %s", start.Format(time.RFC1123Z))
+	}
+}
@@ -0,0 +1,220 @@
+package test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/workradar/server/internal/models"
+	"github.com/workradar/server/internal/repository"
+	"github.com/workradar/server/internal/services"
+	"gorm.io/gorm"
+)
+
+// ============================================
+// CHAT CONVERSATION TESTS
+// Auto-generated thread titles, thread management and pins
+// ============================================
+
+func newConversationTestService(t *testing.T) (*services.ChatConversationService, *gorm.DB) {
+	t.Helper()
+	db := newTestDB(t)
+	migrateTestDB(t, db, &models.User{}, &models.Category{}, &models.Task{}, &models.TaskReminder{}, &models.ChatConversation{}, &models.ChatMessage{})
+	for _, user := range []models.User{
+		{ID: "user-a", Email: "a@example.com", Username: "a"},
+		{ID: "user-b", Email: "b@example.com", Username: "b"},
+	} {
+		if err := db.Create(&user).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	service := services.NewChatConversationService(
+		repository.NewChatConversationRepository(db),
+		repository.NewChatRepository(db),
+		repository.NewTaskRepository(db),
+		repository.NewCategoryRepository(db),
+	)
+	return service, db
+}
+
+func saveTestMessage(t *testing.T, db *gorm.DB, userID string, conversationID *string, content string) {
+	t.Helper()
+	message := &models.ChatMessage{UserID: userID, ConversationID: conversationID, Role: models.ChatRoleUser, Content: content}
+	if err := db.Create(message).Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestGenerateConversationTitle tests titles derived from the first message of a thread
+func TestGenerateConversationTitle(t *testing.T) {
+	tests := []struct {
+		message  string
+		expected string
+	}{
+		{"bagaimana mengatur jadwal kerja?", "Bagaimana mengatur jadwal kerja"},
+		{"  tips   fokus \n kerja!!  ", "Tips fokus kerja"},
+		{"???", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := services.GenerateConversationTitle(tt.message); got != tt.expected {
+			t.Errorf("GenerateConversationTitle(%q) = %q, want %q", tt.message, got, tt.expected)
+		}
+	}
+
+	long := strings.Repeat("rencana proyek besar ", 10)
+	title := services.GenerateConversationTitle(long)
+	if utf8.RuneCountInString(title) > 60 {
+		t.Errorf("Expected title of at most 60 characters, got %d: %q", utf8.RuneCountInString(title), title)
+	}
+	if !strings.HasSuffix(title, "…") || strings.HasSuffix(strings.TrimSuffix(title, "…"), " ") {
+		t.Errorf("Expected title cut at a word boundary with an ellipsis, got %q", title)
+	}
+}
+
+// TestConversationLifecycle tests listing, renaming, archiving and deleting threads
+func TestConversationLifecycle(t *testing.T) {
+	service, db := newConversationTestService(t)
+
+	older, _ := service.CreateConversation("user-a", services.CreateConversationDTO{Title: "Lama"})
+	newer, _ := service.CreateConversation("user-a", services.CreateConversationDTO{})
+	service.CreateConversation("user-b", services.CreateConversationDTO{Title: "Milik b"})
+	service.RecordMessage(newer, "rencana minggu depan?")
+
+	list, err := service.ListConversations("user-a", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 || list[0].ID != newer.ID || list[0].Title != "Rencana minggu depan" {
+		t.Fatalf("Expected the user's threads, most recently active first, got %+v", list)
+	}
+
+	title := "  Proyek kantor  "
+	renamed, err := service.UpdateConversation("user-a", older.ID, services.UpdateConversationDTO{Title: &title})
+	if err != nil || renamed.Title != "Proyek kantor" {
+		t.Fatalf("Expected the thread renamed, got %+v (err: %v)", renamed, err)
+	}
+	blank := " "
+	if _, err := service.UpdateConversation("user-a", older.ID, services.UpdateConversationDTO{Title: &blank}); err == nil {
+		t.Error("Expected an empty title rejected")
+	}
+
+	archived := true
+	if _, err := service.UpdateConversation("user-a", older.ID, services.UpdateConversationDTO{IsArchived: &archived}); err != nil {
+		t.Fatal(err)
+	}
+	if list, _ := service.ListConversations("user-a", false); len(list) != 1 || list[0].ID != newer.ID {
+		t.Errorf("Expected the archived thread hidden from the active list, got %+v", list)
+	}
+	if list, _ := service.ListConversations("user-a", true); len(list) != 1 || list[0].Title != "Proyek kantor" {
+		t.Errorf("Expected the archived thread listed with its new title, got %+v", list)
+	}
+
+	// Another user can neither change nor delete the thread
+	if _, err := service.UpdateConversation("user-b", newer.ID, services.UpdateConversationDTO{Title: &title}); !errors.Is(err, services.ErrConversationNotFound) {
+		t.Errorf("Expected not found renaming another user's thread, got %v", err)
+	}
+	if err := service.DeleteConversation("user-b", newer.ID); !errors.Is(err, services.ErrConversationNotFound) {
+		t.Errorf("Expected not found deleting another user's thread, got %v", err)
+	}
+
+	saveTestMessage(t, db, "user-a", &newer.ID, "halo")
+	saveTestMessage(t, db, "user-a", &older.ID, "tetap ada")
+	if err := service.DeleteConversation("user-a", newer.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.GetConversation("user-a", newer.ID); !errors.Is(err, services.ErrConversationNotFound) {
+		t.Errorf("Expected the deleted thread gone, got %v", err)
+	}
+	var remaining []models.ChatMessage
+	db.Find(&remaining)
+	if len(remaining) != 1 || remaining[0].Content != "tetap ada" {
+		t.Errorf("Expected only the deleted thread's messages removed, got %+v", remaining)
+	}
+}
+
+// TestConversationPinsRequireOwnership tests that only the user's own task or category can be pinned
+func TestConversationPinsRequireOwnership(t *testing.T) {
+	service, db := newConversationTestService(t)
+
+	ownTask := &models.Task{UserID: "user-a", Title: "Laporan"}
+	otherTask := &models.Task{UserID: "user-b", Title: "Rahasia"}
+	otherCategory := &models.Category{UserID: "user-b", Name: "Pribadi"}
+	for _, record := range []interface{}{ownTask, otherTask, otherCategory} {
+		if err := db.Create(record).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := service.CreateConversation("user-a", services.CreateConversationDTO{PinnedTaskID: &otherTask.ID}); err == nil {
+		t.Error("Expected pinning another user's task rejected")
+	}
+	if _, err := service.CreateConversation("user-a", services.CreateConversationDTO{PinnedCategoryID: &otherCategory.ID}); err == nil {
+		t.Error("Expected pinning another user's category rejected")
+	}
+
+	conversation, err := service.CreateConversation("user-a", services.CreateConversationDTO{PinnedTaskID: &ownTask.ID})
+	if err != nil || conversation.PinnedTaskID == nil {
+		t.Fatalf("Expected the user's own task pinned, got %+v (err: %v)", conversation, err)
+	}
+	if _, err := service.UpdateConversation("user-a", conversation.ID, services.UpdateConversationDTO{PinnedTaskID: &otherTask.ID}); err == nil {
+		t.Error("Expected re-pinning to another user's task rejected")
+	}
+	if stored, _ := service.GetConversation("user-a", conversation.ID); stored.PinnedTaskID == nil || *stored.PinnedTaskID != ownTask.ID {
+		t.Errorf("Expected the original pin kept, got %v", stored.PinnedTaskID)
+	}
+	if context := service.PinnedContext(conversation); !strings.Contains(context, "Laporan") {
+		t.Errorf("Expected the pinned task in the context, got %q", context)
+	}
+
+	unpin := ""
+	unpinned, err := service.UpdateConversation("user-a", conversation.ID, services.UpdateConversationDTO{PinnedTaskID: &unpin})
+	if err != nil || unpinned.PinnedTaskID != nil {
+		t.Errorf("Expected an empty ID to unpin, got %v (err: %v)", unpinned.PinnedTaskID, err)
+	}
+}
+
+// TestResolveForChatFallback tests which thread a message without a conversation ID joins
+func TestResolveForChatFallback(t *testing.T) {
+	service, db := newConversationTestService(t)
+
+	// Messages sent before threads existed are adopted by the user's first thread
+	saveTestMessage(t, db, "user-a", nil, "pesan lama")
+	legacy, err := service.ResolveForChat("user-a", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if legacy.Title != "Percakapan sebelumnya" {
+		t.Errorf("Expected a thread for the earlier messages, got %q", legacy.Title)
+	}
+	if messages, _ := service.GetMessages("user-a", legacy.ID, 10); len(messages) != 1 {
+		t.Errorf("Expected the earlier message moved into the thread, got %d", len(messages))
+	}
+
+	// Without an ID the most recently active thread is continued
+	recent, _ := service.CreateConversation("user-a", services.CreateConversationDTO{})
+	service.RecordMessage(recent, "halo")
+	if resolved, _ := service.ResolveForChat("user-a", ""); resolved.ID != recent.ID {
+		t.Errorf("Expected the most recent thread continued, got %s", resolved.ID)
+	}
+
+	// Archived threads are not continued
+	archived := true
+	service.UpdateConversation("user-a", recent.ID, services.UpdateConversationDTO{IsArchived: &archived})
+	service.UpdateConversation("user-a", legacy.ID, services.UpdateConversationDTO{IsArchived: &archived})
+	fresh, err := service.ResolveForChat("user-a", "")
+	if err != nil || fresh.ID == recent.ID || fresh.ID == legacy.ID || fresh.Title != "Percakapan baru" {
+		t.Errorf("Expected a new thread once all are archived, got %+v (err: %v)", fresh, err)
+	}
+
+	// An explicit ID must belong to the user
+	if _, err := service.ResolveForChat("user-b", recent.ID); !errors.Is(err, services.ErrConversationNotFound) {
+		t.Errorf("Expected not found for another user's thread, got %v", err)
+	}
+	if resolved, err := service.ResolveForChat("user-a", recent.ID); err != nil || resolved.ID != recent.ID {
+		t.Errorf("Expected the requested thread, got %+v (err: %v)", resolved, err)
+	}
+}
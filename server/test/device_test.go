@@ -0,0 +1,299 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	firebase "firebase.google.com/go/v4"
+	"github.com/workradar/server/internal/models"
+	"github.com/workradar/server/internal/repository"
+	"github.com/workradar/server/internal/services"
+	"google.golang.org/api/option"
+)
+
+// ============================================
+// DEVICE TESTS
+// Device registry and FCM fan-out against a fake FCM endpoint
+// ============================================
+
+// fcmFailure is how the fake FCM endpoint rejects a token
+type fcmFailure struct {
+	status    int
+	errorCode string // FcmError code, e.g. UNREGISTERED
+	message   string
+}
+
+// fakeFCM answers messages:send, failing the tokens in failures and recording every token
+type fakeFCM struct {
+	mu       sync.Mutex
+	received []string
+	failures map[string]fcmFailure
+}
+
+func (f *fakeFCM) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Message struct {
+			Token string `json:"token"`
+		} `json:"message"`
+	}
+	_ = json.NewDecoder(r.Body).Decode(&body)
+	token := body.Message.Token
+
+	f.mu.Lock()
+	f.received = append(f.received, token)
+	failure, failed := f.failures[token]
+	f.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	if !failed {
+		fmt.Fprintf(w, `{"name":"projects/test/messages/%s"}`, token)
+		return
+	}
+	w.WriteHeader(failure.status)
+	fmt.Fprintf(w, `{"error":{"code":%d,"message":%q,"status":"ERROR","details":[{"@type":"type.googleapis.com/google.firebase.fcm.v1.FcmError","errorCode":%q}]}}`,
+		failure.status, failure.message, failure.errorCode)
+}
+
+func (f *fakeFCM) Received() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	tokens := append([]string(nil), f.received...)
+	sort.Strings(tokens)
+	return tokens
+}
+
+func newFCMTestNotifier(t *testing.T, deviceRepo *repository.DeviceRepository, fcm *fakeFCM) *services.FCMNotifier {
+	t.Helper()
+	server := httptest.NewServer(fcm)
+	t.Cleanup(server.Close)
+
+	ctx := context.Background()
+	app, err := firebase.NewApp(ctx, &firebase.Config{ProjectID: "test"},
+		option.WithEndpoint(server.URL), option.WithHTTPClient(server.Client()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := app.Messaging(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return services.NewFCMNotifierWithClient(deviceRepo, client)
+}
+
+func registerTestDevices(t *testing.T, env *notificationTestEnv, userID string, tokens ...string) {
+	t.Helper()
+	for _, token := range tokens {
+		if _, err := env.service.RegisterDevice(userID, services.DeviceRegistration{FCMToken: token, Platform: models.DevicePlatformAndroid}); err != nil {
+			t.Fatalf("RegisterDevice failed: %v", err)
+		}
+	}
+}
+
+func deviceTokens(t *testing.T, repo *repository.DeviceRepository, userID string) []string {
+	t.Helper()
+	devices, err := repo.FindByUserID(userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokens := make([]string, len(devices))
+	for i, device := range devices {
+		tokens[i] = device.Token
+	}
+	sort.Strings(tokens)
+	return tokens
+}
+
+func TestFCMNotifierFansOutToEveryDevice(t *testing.T) {
+	env := newNotificationTestEnv(t, nil)
+	fcm := &fakeFCM{}
+	notifier := newFCMTestNotifier(t, env.devices, fcm)
+
+	registerTestDevices(t, env, "user-1", "phone", "tablet", "browser")
+	registerTestDevices(t, env, "user-2", "other-phone")
+
+	if err := notifier.Send("user-1", services.Notification{Type: models.NotificationTypeTaskReminder, Title: "Pengingat"}); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if got := fcm.Received(); fmt.Sprint(got) != "[browser phone tablet]" {
+		t.Errorf("Expected every device of the user to receive the push, got %v", got)
+	}
+}
+
+func TestFCMNotifierPrunesOnlyUnregisteredTokens(t *testing.T) {
+	env := newNotificationTestEnv(t, nil)
+	fcm := &fakeFCM{failures: map[string]fcmFailure{
+		"uninstalled": {http.StatusNotFound, "UNREGISTERED", "Requested entity was not found."},
+		"malformed":   {http.StatusBadRequest, "INVALID_ARGUMENT", "The registration token is not a valid FCM registration token"},
+		"bad-payload": {http.StatusBadRequest, "INVALID_ARGUMENT", "Invalid value at 'message.data'"},
+		"throttled":   {http.StatusTooManyRequests, "QUOTA_EXCEEDED", "Quota exceeded"},
+		"fcm-outage":  {http.StatusInternalServerError, "INTERNAL", "Internal error"},
+	}}
+	notifier := newFCMTestNotifier(t, env.devices, fcm)
+	registerTestDevices(t, env, "user-1", "phone", "uninstalled", "malformed", "bad-payload", "throttled", "fcm-outage")
+
+	if err := notifier.Send("user-1", services.Notification{Type: models.NotificationTypeWeather, Title: "Cuaca"}); err != nil {
+		t.Fatalf("Expected success while one device received it, got %v", err)
+	}
+	if got := deviceTokens(t, env.devices, "user-1"); fmt.Sprint(got) != "[bad-payload fcm-outage phone throttled]" {
+		t.Errorf("Expected only unregistered and invalid tokens pruned, got %v", got)
+	}
+}
+
+func TestFCMNotifierFailures(t *testing.T) {
+	env := newNotificationTestEnv(t, nil)
+	fcm := &fakeFCM{failures: map[string]fcmFailure{
+		"uninstalled": {http.StatusNotFound, "UNREGISTERED", "Requested entity was not found."},
+		"throttled":   {http.StatusTooManyRequests, "QUOTA_EXCEEDED", "Quota exceeded"},
+	}}
+	notifier := newFCMTestNotifier(t, env.devices, fcm)
+	n := services.Notification{Type: models.NotificationTypeWeather, Title: "Cuaca"}
+
+	if err := notifier.Send("user-1", n); !errors.Is(err, services.ErrNoFCMToken) {
+		t.Errorf("Expected ErrNoFCMToken without devices, got %v", err)
+	}
+
+	// A transient failure on every device is an error worth retrying; the token stays
+	registerTestDevices(t, env, "user-1", "throttled")
+	if err := notifier.Send("user-1", n); err == nil || errors.Is(err, services.ErrNoFCMToken) {
+		t.Errorf("Expected the send error, got %v", err)
+	}
+
+	// Only dead tokens: pruned, and the user has no device left
+	registerTestDevices(t, env, "user-2", "uninstalled")
+	if err := notifier.Send("user-2", n); !errors.Is(err, services.ErrNoFCMToken) {
+		t.Errorf("Expected ErrNoFCMToken once every token was pruned, got %v", err)
+	}
+	if got := deviceTokens(t, env.devices, "user-2"); len(got) != 0 {
+		t.Errorf("Expected the dead token pruned, got %v", got)
+	}
+	if got := deviceTokens(t, env.devices, "user-1"); len(got) != 1 {
+		t.Errorf("Expected the throttled token kept, got %v", got)
+	}
+}
+
+func TestFCMNotifierSkipsInactiveDevices(t *testing.T) {
+	env := newNotificationTestEnv(t, nil)
+	fcm := &fakeFCM{}
+	notifier := newFCMTestNotifier(t, env.devices, fcm)
+
+	registerTestDevices(t, env, "user-1", "current")
+	if err := env.devices.Upsert(&models.Device{UserID: "user-1", Token: "abandoned", LastSeenAt: time.Now().AddDate(0, -6, 0)}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := notifier.Send("user-1", services.Notification{Type: models.NotificationTypeWeather, Title: "Cuaca"}); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if got := fcm.Received(); fmt.Sprint(got) != "[current]" {
+		t.Errorf("Expected only the recently seen device pushed, got %v", got)
+	}
+}
+
+func TestDeviceRepositoryUpsertMovesToken(t *testing.T) {
+	env := newNotificationTestEnv(t, nil)
+
+	registerTestDevices(t, env, "user-1", "shared-tablet")
+	first, _ := env.devices.FindByUserID("user-1")
+	registerTestDevices(t, env, "user-2", "shared-tablet")
+
+	if got := deviceTokens(t, env.devices, "user-1"); len(got) != 0 {
+		t.Errorf("Expected the token moved away from the previous user, got %v", got)
+	}
+	moved, _ := env.devices.FindByUserID("user-2")
+	if len(moved) != 1 || moved[0].ID != first[0].ID {
+		t.Errorf("Expected the same device row moved to the new user, got %+v", moved)
+	}
+}
+
+func TestDeviceRepositoryDeletes(t *testing.T) {
+	env := newNotificationTestEnv(t, nil)
+	registerTestDevices(t, env, "user-1", "a", "b", "c")
+	registerTestDevices(t, env, "user-2", "d")
+
+	// Another user's token cannot be removed through this user
+	if removed, err := env.devices.DeleteByToken("user-1", "d"); err != nil || removed != 0 {
+		t.Errorf("Expected nothing removed, got %d (err: %v)", removed, err)
+	}
+	if err := env.devices.DeleteTokens([]string{"a", "d"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := deviceTokens(t, env.devices, "user-1"); fmt.Sprint(got) != "[b c]" {
+		t.Errorf("Expected only listed tokens deleted, got %v", got)
+	}
+	if err := env.devices.DeleteTokens(nil); err != nil {
+		t.Errorf("Expected no-op for no tokens, got %v", err)
+	}
+	if err := env.service.UnregisterDevice("user-1", ""); err != nil {
+		t.Fatal(err)
+	}
+	if got := deviceTokens(t, env.devices, "user-1"); len(got) != 0 {
+		t.Errorf("Expected every device removed, got %v", got)
+	}
+}
+
+func TestDeviceRepositoryKeepsNewestDevices(t *testing.T) {
+	env := newNotificationTestEnv(t, nil)
+	base := time.Now().Add(-time.Hour)
+	for i := 0; i < 5; i++ {
+		if err := env.devices.Upsert(&models.Device{UserID: "user-1", Token: fmt.Sprintf("token-%d", i), LastSeenAt: base.Add(time.Duration(i) * time.Minute)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := env.devices.DeleteOldest("user-1", 2); err != nil {
+		t.Fatal(err)
+	}
+	if got := deviceTokens(t, env.devices, "user-1"); fmt.Sprint(got) != "[token-3 token-4]" {
+		t.Errorf("Expected the 2 most recently seen devices kept, got %v", got)
+	}
+}
+
+func TestDeviceRepositoryImportLegacyTokens(t *testing.T) {
+	env := newNotificationTestEnv(t, nil)
+	legacy := "legacy-token"
+	if err := env.db.Create(&models.User{ID: "user-1", Email: "a@example.com", Username: "a", FCMToken: &legacy}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	imported, err := env.devices.ImportLegacyTokens()
+	if err != nil || imported != 1 {
+		t.Fatalf("Expected 1 token imported, got %d (err: %v)", imported, err)
+	}
+	if got := deviceTokens(t, env.devices, "user-1"); fmt.Sprint(got) != "[legacy-token]" {
+		t.Errorf("Expected the legacy token as a device, got %v", got)
+	}
+	var user models.User
+	env.db.First(&user, "id = ?", "user-1")
+	if user.FCMToken != nil {
+		t.Errorf("Expected users.fcm_token cleared, got %q", *user.FCMToken)
+	}
+	if imported, _ := env.devices.ImportLegacyTokens(); imported != 0 {
+		t.Errorf("Expected a second import to do nothing, got %d", imported)
+	}
+}
+
+func TestRegisterDeviceAgainRefreshesIt(t *testing.T) {
+	env := newNotificationTestEnv(t, nil)
+	registerTestDevices(t, env, "user-1", "phone")
+	first, _ := env.devices.FindByUserID("user-1")
+
+	// Apps register on every start
+	device, err := env.service.RegisterDevice("user-1", services.DeviceRegistration{FCMToken: "phone", Platform: models.DevicePlatformAndroid, AppVersion: "2.0.0"})
+	if err != nil {
+		t.Fatalf("Expected re-registration to succeed, got %v", err)
+	}
+	if device.ID != first[0].ID || device.AppVersion != "2.0.0" {
+		t.Errorf("Expected the existing device refreshed, got %+v", device)
+	}
+	if got := deviceTokens(t, env.devices, "user-1"); len(got) != 1 {
+		t.Errorf("Expected one device, got %v", got)
+	}
+}
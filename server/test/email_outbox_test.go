@@ -0,0 +1,103 @@
+package test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/workradar/server/internal/models"
+	"github.com/workradar/server/internal/repository"
+	"github.com/workradar/server/internal/services"
+	"gorm.io/gorm"
+)
+
+// ============================================
+// EMAIL OUTBOX TESTS
+// ============================================
+
+type emailOutboxTestEnv struct {
+	db      *gorm.DB
+	mail    *recordingMailTransport
+	jobs    *repository.JobRepository
+	service *services.EmailService
+}
+
+func newEmailOutboxTestEnv(t *testing.T) *emailOutboxTestEnv {
+	t.Helper()
+	useTestJWTSecret(t)
+	db := newTestDB(t)
+	migrateTestDB(t, db, &models.EmailOutbox{}, &models.Job{})
+
+	env := &emailOutboxTestEnv{db: db, mail: &recordingMailTransport{}, jobs: repository.NewJobRepository(db)}
+	queue := services.NewJobQueue(env.jobs, 1, time.Second, time.Minute, 3)
+	env.service = services.NewEmailService(env.mail, services.NewEmailRenderer(""), repository.NewEmailOutboxRepository(db), queue)
+	return env
+}
+
+// runEmailJobs runs every queued email job once
+func (env *emailOutboxTestEnv) runEmailJobs(t *testing.T) {
+	t.Helper()
+	jobs, _, err := env.jobs.List("", services.JobTypeEmail, 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, job := range jobs {
+		if err := env.service.HandleJob(context.Background(), []byte(job.Payload)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestEmailHelpersGoThroughOutbox(t *testing.T) {
+	env := newEmailOutboxTestEnv(t)
+
+	if err := env.service.SendPasswordResetCode("user@example.com", "PWD-123456"); err != nil {
+		t.Fatal(err)
+	}
+	if len(env.mail.Sent()) != 0 {
+		t.Fatal("Expected the email queued, not sent right away")
+	}
+
+	var email models.EmailOutbox
+	if err := env.db.First(&email).Error; err != nil {
+		t.Fatal(err)
+	}
+	if email.Kind != services.EmailKindPasswordReset || email.ExpiresAt == nil {
+		t.Errorf("Expected a code email with an expiry, got %+v", email)
+	}
+
+	env.runEmailJobs(t)
+
+	sent := env.mail.Sent()
+	if len(sent) != 1 || !strings.Contains(sent[0].Text, "PWD-123456") {
+		t.Fatalf("Expected the code delivered once, got %+v", sent)
+	}
+	env.db.First(&email, "id = ?", email.ID)
+	if email.Status != models.EmailOutboxSent || email.HTML != "" || email.Text != "" {
+		t.Errorf("Expected the sent email stored without its body, got status %s", email.Status)
+	}
+}
+
+func TestEmailOutboxDropsExpiredCodes(t *testing.T) {
+	env := newEmailOutboxTestEnv(t)
+
+	if err := env.service.SendAccountVerificationCode("user@example.com", "REG-654321"); err != nil {
+		t.Fatal(err)
+	}
+	// The provider was down until after the code expired
+	if err := env.db.Model(&models.EmailOutbox{}).Where("1 = 1").Update("expires_at", time.Now().Add(-time.Minute)).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	env.runEmailJobs(t)
+
+	if len(env.mail.Sent()) != 0 {
+		t.Error("Expected an expired code not delivered")
+	}
+	var email models.EmailOutbox
+	env.db.First(&email)
+	if email.Status != models.EmailOutboxFailed || email.Text != "" {
+		t.Errorf("Expected the expired email failed without its body, got status %s", email.Status)
+	}
+}
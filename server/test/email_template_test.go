@@ -0,0 +1,203 @@
+package test
+
+import (
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/workradar/server/internal/services"
+)
+
+// ============================================
+// EMAIL TEMPLATE TESTS
+// ============================================
+
+func TestEmailRendererLanguages(t *testing.T) {
+	renderer := services.NewEmailRenderer("")
+	data := services.EmailData{Code: "123456", ExpiresInMinutes: 10}
+
+	id, err := renderer.Render(services.EmailKindPasswordReset, "id", data)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if id.Subject != "Workradar - Kode Reset Password" {
+		t.Errorf("Unexpected subject %q", id.Subject)
+	}
+	for _, body := range []string{id.HTML, id.Text} {
+		if !strings.Contains(body, "123456") || !strings.Contains(body, "10 menit") {
+			t.Errorf("Expected code and expiry in body:\n%s", body)
+		}
+	}
+	if !strings.Contains(id.HTML, "Mohon jangan membalas") || !strings.Contains(id.HTML, "<!DOCTYPE html>") {
+		t.Error("Expected the Indonesian footer inside the shared layout")
+	}
+
+	en, err := renderer.Render(services.EmailKindPasswordReset, "en-US", data)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if en.Subject != "Workradar - Password Reset Code" || !strings.Contains(en.HTML, "10 minutes") {
+		t.Errorf("Expected English email, got %q", en.Subject)
+	}
+	if !strings.Contains(en.HTML, "Please do not reply") {
+		t.Error("Expected the English footer")
+	}
+}
+
+func TestEmailRendererAllKinds(t *testing.T) {
+	renderer := services.NewEmailRenderer("")
+	kinds := []string{
+		services.EmailKindPasswordReset,
+		services.EmailKindAccountVerification,
+		services.EmailKindWelcome,
+		services.EmailKindVIPUpgrade,
+		services.EmailKindNotification,
+		services.EmailKindRefreshTokenReuse,
+		services.EmailKindBackupCodeUsed,
+	}
+
+	for _, kind := range kinds {
+		for _, lang := range []string{services.EmailLanguageID, services.EmailLanguageEN} {
+			email, err := renderer.Render(kind, lang, services.EmailData{UserName: "Budi", Title: "Pengingat"})
+			if err != nil {
+				t.Errorf("%s.%s: %v", kind, lang, err)
+				continue
+			}
+			if email.Subject == "" || email.HTML == "" || email.Text == "" {
+				t.Errorf("%s.%s: expected subject, html and text", kind, lang)
+			}
+		}
+	}
+}
+
+func TestEmailRendererEscapesHTML(t *testing.T) {
+	renderer := services.NewEmailRenderer("")
+	email, err := renderer.Render(services.EmailKindNotification, "id", services.EmailData{
+		UserName: "<script>alert(1)</script>",
+		Title:    "Tugas & Jadwal",
+		Message:  "Rapat jam 10",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if strings.Contains(email.HTML, "<script>") {
+		t.Error("Expected user input escaped in HTML")
+	}
+	if email.Subject != "Workradar - Tugas & Jadwal" {
+		t.Errorf("Expected plain subject, got %q", email.Subject)
+	}
+	if !strings.Contains(email.Text, "<script>alert(1)</script>") {
+		t.Error("Expected text body left unescaped")
+	}
+}
+
+func TestEmailRendererFallsBackToDefaultLanguage(t *testing.T) {
+	fsys := fstest.MapFS{
+		"email/layout.html":    {Data: []byte(`{{define "layout"}}{{template "header" .}}{{template "content" .}}{{template "footer" .}}{{end}}`)},
+		"email/footer.id.html": {Data: []byte(`{{define "footer"}}-- id{{end}}`)},
+		"email/hello.id.html":  {Data: []byte(`{{template "layout" .}}{{define "header"}}H{{end}}{{define "content"}}Halo {{.UserName}}{{end}}`)},
+		"email/hello.id.txt":   {Data: []byte(`{{define "subject"}}Salam{{end}}Halo {{.UserName}}`)},
+	}
+	renderer := services.NewEmailRendererFS(fsys)
+
+	email, err := renderer.Render("hello", "en", services.EmailData{UserName: "Ana"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if email.Subject != "Salam" || email.HTML != "HHalo Ana-- id" {
+		t.Errorf("Expected Indonesian fallback, got %q / %q", email.Subject, email.HTML)
+	}
+
+	if _, err := renderer.Render("missing", "id", services.EmailData{}); err == nil {
+		t.Error("Expected error for unknown kind")
+	}
+}
+
+func TestNormalizeEmailLanguage(t *testing.T) {
+	cases := map[string]string{"en": "en", "EN_gb": "en", "id-ID": "id", "": "id", "fr": "id"}
+	for in, want := range cases {
+		if got := services.NormalizeEmailLanguage(in); got != want {
+			t.Errorf("NormalizeEmailLanguage(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestFileTransportWritesMultipartEmail(t *testing.T) {
+	dir := t.TempDir()
+	transport := services.NewFileTransport(dir)
+
+	err := transport.Send(services.MailMessage{
+		From:    "Workradar <noreply@workradar.app>",
+		To:      "user@example.com",
+		Subject: "Selamat Datang di Workradar! 🎉",
+		HTML:    "<p>Halo, Budi!</p>",
+		Text:    "Halo, Budi!",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if len(files) != 1 {
+		t.Fatalf("Expected one .eml file, got %d", len(files))
+	}
+	f, err := os.Open(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	msg, err := mail.ReadMessage(f)
+	if err != nil {
+		t.Fatalf("Invalid message: %v", err)
+	}
+	subject, _ := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if subject != "Selamat Datang di Workradar! 🎉" || msg.Header.Get("To") != "user@example.com" {
+		t.Errorf("Unexpected headers: %v", msg.Header)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("Unexpected content type %q: %v", mediaType, err)
+	}
+	reader := multipart.NewReader(msg.Body, params["boundary"])
+	var bodies []string
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(part) // Decodes quoted-printable
+		bodies = append(bodies, string(body))
+	}
+	if len(bodies) != 2 || bodies[0] != "Halo, Budi!" || bodies[1] != "<p>Halo, Budi!</p>" {
+		t.Errorf("Unexpected parts: %q", bodies)
+	}
+}
+
+func TestBuildMIMEMessageWrapsLongLines(t *testing.T) {
+	msg, err := services.BuildMIMEMessage(services.MailMessage{
+		From: "a@example.com",
+		To:   "b@example.com",
+		HTML: strings.Repeat("x", 2000),
+	}, time.Now())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, line := range strings.Split(string(msg), "\r\n") {
+		if len(line) > 998 {
+			t.Fatalf("Line exceeds RFC 5322 limit: %d", len(line))
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package test
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/workradar/server/internal/models"
+	"github.com/workradar/server/internal/repository"
+	"github.com/workradar/server/internal/services"
+)
+
+// ============================================
+// EVENT HUB TESTS
+// ============================================
+
+func receiveEvent(t *testing.T, sub *services.EventSubscription) services.Event {
+	t.Helper()
+	select {
+	case event := <-sub.C:
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("Expected an event")
+		return services.Event{}
+	}
+}
+
+func TestEventHubDeliversToUserStreams(t *testing.T) {
+	hub := services.NewEventHub(services.NewMemoryEventBroker())
+	defer hub.Close()
+
+	phone, _ := hub.Subscribe("user-1")
+	laptop, _ := hub.Subscribe("user-1")
+	other, _ := hub.Subscribe("user-2")
+
+	hub.Publish("user-1", services.EventTaskDeleted, map[string]string{"id": "task-1"})
+
+	for _, sub := range []*services.EventSubscription{phone, laptop} {
+		event := receiveEvent(t, sub)
+		var data map[string]string
+		if err := json.Unmarshal(event.Data, &data); err != nil || data["id"] != "task-1" {
+			t.Errorf("Unexpected data %s: %v", event.Data, err)
+		}
+		if event.Type != services.EventTaskDeleted || event.ID == "" {
+			t.Errorf("Unexpected event: %+v", event)
+		}
+	}
+
+	select {
+	case event := <-other.C:
+		t.Errorf("Other user received %+v", event)
+	default:
+	}
+}
+
+func TestEventHubLimitsStreamsPerUser(t *testing.T) {
+	hub := services.NewEventHub(services.NewMemoryEventBroker())
+	defer hub.Close()
+
+	var subs []*services.EventSubscription
+	for i := 0; i < 5; i++ {
+		sub, err := hub.Subscribe("user-1")
+		if err != nil {
+			t.Fatalf("Unexpected error on stream %d: %v", i, err)
+		}
+		subs = append(subs, sub)
+	}
+
+	if _, err := hub.Subscribe("user-1"); !errors.Is(err, services.ErrTooManyStreams) {
+		t.Errorf("Expected ErrTooManyStreams, got %v", err)
+	}
+
+	hub.Unsubscribe(subs[0])
+	hub.Unsubscribe(subs[0]) // Second call is a no-op
+	if _, ok := <-subs[0].C; ok {
+		t.Error("Expected the unsubscribed stream to be closed")
+	}
+	if _, err := hub.Subscribe("user-1"); err != nil {
+		t.Errorf("Expected a free slot after unsubscribe, got %v", err)
+	}
+}
+
+func TestEventHubDropsForLaggingStream(t *testing.T) {
+	hub := services.NewEventHub(services.NewMemoryEventBroker())
+	defer hub.Close()
+
+	sub, _ := hub.Subscribe("user-1")
+
+	// Publishing never blocks, even when nobody reads
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 200; i++ {
+			hub.Publish("user-1", services.EventMessageCreated, i)
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Publish blocked on a lagging stream")
+	}
+	if len(sub.C) == 0 || len(sub.C) == 200 {
+		t.Errorf("Expected a full buffer with dropped events, got %d", len(sub.C))
+	}
+}
+
+func TestEventHubCloseEndsStreams(t *testing.T) {
+	hub := services.NewEventHub(services.NewMemoryEventBroker())
+	sub, _ := hub.Subscribe("user-1")
+
+	if err := hub.Close(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, ok := <-sub.C; ok {
+		t.Error("Expected stream closed on shutdown")
+	}
+	hub.Unsubscribe(sub) // Handler cleanup after shutdown must not panic
+	if hub.StreamCount() != 0 {
+		t.Errorf("Expected no streams, got %d", hub.StreamCount())
+	}
+}
+
+func TestEventHubCloseStopsDelivery(t *testing.T) {
+	hub := services.NewEventHub(services.NewMemoryEventBroker())
+	sub, _ := hub.Subscribe("user-1")
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		// Publishing while and after the hub shuts down must not send on a closed stream
+		for i := 0; i < 1000; i++ {
+			hub.Publish("user-1", services.EventTaskUpdated, nil)
+		}
+	}()
+	hub.Close()
+	wg.Wait()
+
+	for range sub.C {
+	}
+	if _, err := hub.Subscribe("user-1"); !errors.Is(err, services.ErrEventHubClosed) {
+		t.Errorf("Expected ErrEventHubClosed after Close, got %v", err)
+	}
+}
+
+func newDatabaseEventHub(t *testing.T) (*services.EventHub, *repository.RealtimeEventRepository) {
+	t.Helper()
+	db := newTestDB(t)
+	migrateTestDB(t, db, &models.RealtimeEvent{})
+	repo := repository.NewRealtimeEventRepository(db)
+
+	// Published before the hub started: never delivered
+	if err := repo.Create(&models.RealtimeEvent{EventID: "before-start", UserID: "user-1", Type: services.EventTaskCreated, Data: "{}", CreatedAt: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	hub := services.NewEventHub(services.NewDatabaseEventBroker(repo, 10*time.Millisecond))
+	t.Cleanup(func() { hub.Close() })
+	return hub, repo
+}
+
+func TestDatabaseEventBrokerDeliversLateCommits(t *testing.T) {
+	hub, repo := newDatabaseEventHub(t)
+	sub, _ := hub.Subscribe("user-1")
+
+	// ID 3 commits first and is read, then ID 2 commits below the cursor
+	for _, e := range []models.RealtimeEvent{
+		{ID: 3, EventID: "event-3", UserID: "user-1", Type: services.EventTaskCreated, Data: "{}"},
+		{ID: 2, EventID: "event-2", UserID: "user-1", Type: services.EventTaskUpdated, Data: "{}"},
+	} {
+		e.CreatedAt = time.Now()
+		if err := repo.Create(&e); err != nil {
+			t.Fatal(err)
+		}
+		if event := receiveEvent(t, sub); event.ID != e.EventID {
+			t.Fatalf("Expected %s, got %s", e.EventID, event.ID)
+		}
+	}
+
+	// Later polls re-read both rows within the lookback window without delivering them again
+	time.Sleep(100 * time.Millisecond)
+	select {
+	case event := <-sub.C:
+		t.Errorf("Expected each event delivered once, got %s again", event.ID)
+	default:
+	}
+}
+
+func TestDatabaseEventBrokerCloseEndsStreams(t *testing.T) {
+	hub, _ := newDatabaseEventHub(t)
+	sub, _ := hub.Subscribe("user-1")
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			hub.Publish("user-1", services.EventTaskUpdated, nil)
+		}
+	}()
+	time.Sleep(20 * time.Millisecond)
+	if err := hub.Close(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	wg.Wait()
+
+	for event := range sub.C {
+		if event.ID == "before-start" {
+			t.Error("Expected events from before the hub started skipped")
+		}
+	}
+}
+
+func TestNilEventHubPublish(t *testing.T) {
+	var hub *services.EventHub
+	hub.Publish("user-1", services.EventTaskCreated, nil) // Services built without a hub
+}
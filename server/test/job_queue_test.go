@@ -0,0 +1,275 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/workradar/server/internal/models"
+	"github.com/workradar/server/internal/repository"
+	"github.com/workradar/server/internal/services"
+)
+
+// ============================================
+// JOB QUEUE TESTS
+// ============================================
+
+func TestJobBackoff(t *testing.T) {
+	cases := map[int]time.Duration{
+		1:  30 * time.Second,
+		2:  time.Minute,
+		3:  2 * time.Minute,
+		8:  time.Hour,
+		20: time.Hour,
+	}
+	for attempt, want := range cases {
+		if got := services.JobBackoff(attempt); got != want {
+			t.Errorf("Attempt %d: expected %v, got %v", attempt, want, got)
+		}
+	}
+}
+
+func TestJobRetryDecision(t *testing.T) {
+	failure := errors.New("fcm unavailable")
+
+	delay, dead := services.JobRetryDecision(1, 5, failure)
+	if dead || delay != 30*time.Second {
+		t.Errorf("Expected retry in 30s, got delay=%v dead=%v", delay, dead)
+	}
+
+	if _, dead := services.JobRetryDecision(5, 5, failure); !dead {
+		t.Error("Expected job to be dead-lettered after its last attempt")
+	}
+
+	if _, dead := services.JobRetryDecision(1, 5, services.PermanentJobError(services.ErrNoFCMToken)); !dead {
+		t.Error("Expected permanent error to dead-letter immediately")
+	}
+}
+
+func TestEmailJobUniqueKey(t *testing.T) {
+	a := services.EmailJob{Kind: services.EmailKindPasswordReset, To: "user@example.com", Code: "123456"}
+	b := services.EmailJob{Kind: services.EmailKindPasswordReset, To: "user@example.com", Code: "654321"}
+
+	if a.UniqueKey() == b.UniqueKey() {
+		t.Error("Expected different codes to produce different keys")
+	}
+}
+
+// newJobTestRepos returns one job repository per replica, all on the same database
+func newJobTestRepos(t *testing.T, replicas int) []*repository.JobRepository {
+	t.Helper()
+	db := newTestDB(t)
+	migrateTestDB(t, db, &models.Job{})
+	repos := make([]*repository.JobRepository, replicas)
+	for i := range repos {
+		repos[i] = repository.NewJobRepository(db)
+	}
+	return repos
+}
+
+func enqueueTestJob(t *testing.T, repo *repository.JobRepository, runAt time.Time) *models.Job {
+	t.Helper()
+	job := &models.Job{Type: "test", Payload: "{}", RunAt: runAt, MaxAttempts: 3}
+	if created, err := repo.Enqueue(job); err != nil || !created {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+	return job
+}
+
+func TestJobRepositoryLease(t *testing.T) {
+	repo := newJobTestRepos(t, 1)[0]
+	now := time.Now()
+	later := enqueueTestJob(t, repo, now.Add(time.Hour))
+	due := enqueueTestJob(t, repo, now.Add(-time.Minute))
+
+	job, err := repo.Lease("worker-a", []string{"test"}, time.Minute, now)
+	if err != nil || job == nil || job.ID != due.ID {
+		t.Fatalf("Expected the due job leased, got %+v (err: %v)", job, err)
+	}
+	if job.Status != models.JobStatusRunning || job.Attempts != 1 || *job.LockedBy != "worker-a" {
+		t.Errorf("Unexpected leased job: %+v", job)
+	}
+
+	// The leased job is not handed out again and the other one is not due yet
+	if job, _ := repo.Lease("worker-b", []string{"test"}, time.Minute, now); job != nil {
+		t.Errorf("Expected nothing runnable, got %s", job.ID)
+	}
+	if job, _ := repo.Lease("worker-b", []string{"other"}, time.Minute, now.Add(2*time.Hour)); job != nil {
+		t.Errorf("Expected jobs of other types skipped, got %s", job.ID)
+	}
+
+	if err := repo.Complete(due.ID, "worker-a", now); err != nil {
+		t.Fatal(err)
+	}
+	stored, _ := repo.FindByID(due.ID)
+	if stored.Status != models.JobStatusSucceeded || stored.LockedBy != nil || stored.CompletedAt == nil {
+		t.Errorf("Expected the job completed and unlocked, got %+v", stored)
+	}
+	if job, _ := repo.Lease("worker-a", nil, time.Minute, now.Add(2*time.Hour)); job == nil || job.ID != later.ID {
+		t.Errorf("Expected the later job once due, got %+v", job)
+	}
+}
+
+func TestJobRepositoryExpiredLease(t *testing.T) {
+	repos := newJobTestRepos(t, 2)
+	now := time.Now()
+	enqueued := enqueueTestJob(t, repos[0], now)
+
+	if job, _ := repos[0].Lease("worker-a", nil, time.Minute, now); job == nil {
+		t.Fatal("Expected the job leased")
+	}
+	if job, _ := repos[1].Lease("worker-b", nil, time.Minute, now.Add(30*time.Second)); job != nil {
+		t.Fatal("Expected a live lease to be respected")
+	}
+
+	// Worker a died: once its lease expires another replica picks the job up
+	job, err := repos[1].Lease("worker-b", nil, time.Minute, now.Add(2*time.Minute))
+	if err != nil || job == nil || job.ID != enqueued.ID {
+		t.Fatalf("Expected the expired job leased again, got %+v (err: %v)", job, err)
+	}
+	if job.Attempts != 2 || *job.LockedBy != "worker-b" {
+		t.Errorf("Expected a second attempt by worker b, got %+v", job)
+	}
+
+	// The stale worker can no longer complete or fail it
+	repos[0].Complete(enqueued.ID, "worker-a", now)
+	repos[0].Fail(enqueued.ID, "worker-a", "late", now, true)
+	if stored, _ := repos[0].FindByID(enqueued.ID); stored.Status != models.JobStatusRunning || *stored.LockedBy != "worker-b" {
+		t.Errorf("Expected the job still leased by worker b, got %+v", stored)
+	}
+}
+
+func TestJobRepositoryUniqueKeyAcrossReplicas(t *testing.T) {
+	repos := newJobTestRepos(t, 3)
+	runAt := time.Now()
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	created := 0
+	for i := 0; i < 9; i++ {
+		wg.Add(1)
+		go func(repo *repository.JobRepository) {
+			defer wg.Done()
+			key := "digest:user-1:2026-03-05"
+			ok, err := repo.Enqueue(&models.Job{Type: "test", Payload: "{}", UniqueKey: &key, RunAt: runAt})
+			if err != nil {
+				t.Errorf("Enqueue failed: %v", err)
+				return
+			}
+			if ok {
+				mu.Lock()
+				created++
+				mu.Unlock()
+			}
+		}(repos[i%len(repos)])
+	}
+	wg.Wait()
+
+	if created != 1 {
+		t.Errorf("Expected exactly one replica to create the job, got %d", created)
+	}
+	if _, total, _ := repos[0].List("", "", 10, 0); total != 1 {
+		t.Errorf("Expected one stored job, got %d", total)
+	}
+}
+
+func TestJobRepositoryDeadLetterAndRetry(t *testing.T) {
+	repo := newJobTestRepos(t, 1)[0]
+	now := time.Now()
+	enqueued := enqueueTestJob(t, repo, now)
+
+	if job, _ := repo.Lease("worker-a", nil, time.Minute, now); job == nil {
+		t.Fatal("Expected the job leased")
+	}
+	if err := repo.Fail(enqueued.ID, "worker-a", "permanent failure", now, true); err != nil {
+		t.Fatal(err)
+	}
+	if job, _ := repo.Lease("worker-a", nil, time.Minute, now.Add(time.Hour)); job != nil {
+		t.Fatal("Expected a dead job never leased")
+	}
+
+	stored, _ := repo.FindByID(enqueued.ID)
+	if stored.Status != models.JobStatusDead || stored.LastError == nil || *stored.LastError != "permanent failure" {
+		t.Errorf("Expected the job dead-lettered with its error, got %+v", stored)
+	}
+
+	if updated, err := repo.Retry(enqueued.ID, now); err != nil || updated != 1 {
+		t.Fatalf("Expected the dead job retried, got %d (err: %v)", updated, err)
+	}
+	if updated, _ := repo.Retry(enqueued.ID, now); updated != 0 {
+		t.Error("Expected only dead jobs to be retried")
+	}
+
+	job, _ := repo.Lease("worker-a", nil, time.Minute, now)
+	if job == nil || job.ID != enqueued.ID || job.Attempts != 1 {
+		t.Fatalf("Expected the retried job leased with fresh attempts, got %+v", job)
+	}
+}
+
+func TestJobRepositoryDeadJobReleasesUniqueKey(t *testing.T) {
+	repo := newJobTestRepos(t, 1)[0]
+	now := time.Now()
+	key := "reminder:task-1:1440"
+
+	job := &models.Job{Type: "test", Payload: "{}", UniqueKey: &key, RunAt: now}
+	if created, err := repo.Enqueue(job); err != nil || !created {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+	if leased, _ := repo.Lease("worker-a", nil, time.Minute, now); leased == nil {
+		t.Fatal("Expected the job leased")
+	}
+	if err := repo.Fail(job.ID, "worker-a", "permanent failure", now, true); err != nil {
+		t.Fatal(err)
+	}
+
+	if stored, _ := repo.FindByID(job.ID); stored.UniqueKey != nil {
+		t.Errorf("Expected the unique key released, got %s", *stored.UniqueKey)
+	}
+	if created, err := repo.Enqueue(&models.Job{Type: "test", Payload: "{}", UniqueKey: &key, RunAt: now}); err != nil || !created {
+		t.Errorf("Expected the same work enqueued again, got created=%v err=%v", created, err)
+	}
+}
+
+func TestJobQueueDeadLetterAndRetry(t *testing.T) {
+	repo := newJobTestRepos(t, 1)[0]
+	queue := services.NewJobQueue(repo, 1, 10*time.Millisecond, time.Minute, 3)
+
+	var runs int32
+	queue.Register("test", func(ctx context.Context, payload []byte) error {
+		if atomic.AddInt32(&runs, 1) == 1 {
+			return services.PermanentJobError(errors.New("bad payload"))
+		}
+		return nil
+	})
+	queue.Start()
+	defer queue.Stop()
+
+	job, err := queue.Enqueue("test", map[string]string{"id": "1"}, services.JobOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitForJobStatus(t, repo, job.ID, models.JobStatusDead)
+
+	if _, err := queue.Retry(job.ID); err != nil {
+		t.Fatalf("Retry failed: %v", err)
+	}
+	waitForJobStatus(t, repo, job.ID, models.JobStatusSucceeded)
+	if atomic.LoadInt32(&runs) != 2 {
+		t.Errorf("Expected the job run twice, got %d", runs)
+	}
+}
+
+func waitForJobStatus(t *testing.T, repo *repository.JobRepository, id string, status models.JobStatus) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if job, err := repo.FindByID(id); err == nil && job.Status == status {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("Expected job %s to become %s", id, status)
+}
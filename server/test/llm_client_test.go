@@ -0,0 +1,211 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/workradar/server/internal/config"
+	"github.com/workradar/server/internal/models"
+	"github.com/workradar/server/internal/repository"
+	"github.com/workradar/server/internal/services"
+)
+
+// ============================================
+// LLM CLIENT TESTS
+// Provider fallback chain against local OpenAI-compatible servers
+// ============================================
+
+// fakeLLMServer answers /chat/completions with status (and reply on 200), counting calls
+func fakeLLMServer(t *testing.T, status int, reply string, calls *int32, models *[]string) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(calls, 1)
+
+		var req services.ChatRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if models != nil {
+			*models = append(*models, req.Model)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		if status != http.StatusOK {
+			fmt.Fprintf(w, `{"error":{"message":"upstream failure","type":"server_error"}}`)
+			return
+		}
+		fmt.Fprintf(w, `{"choices":[{"message":{"content":%q}}]}`, reply)
+	}))
+}
+
+func newTestLLMProvider(name, url, model string, models ...string) services.LLMProvider {
+	return services.NewOpenAICompatibleProvider(config.LLMProviderConfig{
+		Name:         name,
+		BaseURL:      url,
+		DefaultModel: model,
+		Models:       models,
+	}, 5*time.Second)
+}
+
+// TestLLMClientFallsBackAfterRetries tests that a failing provider is retried, then skipped
+func TestLLMClientFallsBackAfterRetries(t *testing.T) {
+	var primaryCalls, secondaryCalls int32
+	primary := fakeLLMServer(t, http.StatusServiceUnavailable, "", &primaryCalls, nil)
+	defer primary.Close()
+	secondary := fakeLLMServer(t, http.StatusOK, "halo dari cadangan", &secondaryCalls, nil)
+	defer secondary.Close()
+
+	client := services.NewLLMClient([]services.LLMProvider{
+		newTestLLMProvider("primary", primary.URL, "big-model"),
+		newTestLLMProvider("secondary", secondary.URL, "small-model"),
+	}, 2, time.Millisecond)
+
+	resp, err := client.Complete(context.Background(), services.ChatRequest{})
+	if err != nil {
+		t.Fatalf("Expected fallback provider to answer, got %v", err)
+	}
+	if got := resp.Choices[0].Message.Content; got != "halo dari cadangan" {
+		t.Errorf("Expected reply from secondary provider, got %q", got)
+	}
+	if primaryCalls != 3 {
+		t.Errorf("Expected primary to be tried 3 times (1 + 2 retries), got %d", primaryCalls)
+	}
+	if secondaryCalls != 1 {
+		t.Errorf("Expected secondary to be called once, got %d", secondaryCalls)
+	}
+}
+
+// TestLLMClientDoesNotRetryClientErrors tests that 4xx errors move straight to the next provider
+func TestLLMClientDoesNotRetryClientErrors(t *testing.T) {
+	var primaryCalls, secondaryCalls int32
+	primary := fakeLLMServer(t, http.StatusBadRequest, "", &primaryCalls, nil)
+	defer primary.Close()
+	secondary := fakeLLMServer(t, http.StatusBadRequest, "", &secondaryCalls, nil)
+	defer secondary.Close()
+
+	client := services.NewLLMClient([]services.LLMProvider{
+		newTestLLMProvider("primary", primary.URL, "big-model"),
+		newTestLLMProvider("secondary", secondary.URL, "small-model"),
+	}, 3, time.Millisecond)
+
+	_, err := client.Complete(context.Background(), services.ChatRequest{})
+	if !errors.Is(err, services.ErrLLMUnavailable) {
+		t.Fatalf("Expected ErrLLMUnavailable when every provider fails, got %v", err)
+	}
+	if primaryCalls != 1 || secondaryCalls != 1 {
+		t.Errorf("Expected one call per provider, got %d and %d", primaryCalls, secondaryCalls)
+	}
+}
+
+// TestLLMClientModelSelection tests that a selected model is only sent to providers offering it
+func TestLLMClientModelSelection(t *testing.T) {
+	var primaryCalls, secondaryCalls int32
+	var primaryModels, secondaryModels []string
+	primary := fakeLLMServer(t, http.StatusServiceUnavailable, "", &primaryCalls, &primaryModels)
+	defer primary.Close()
+	secondary := fakeLLMServer(t, http.StatusOK, "ok", &secondaryCalls, &secondaryModels)
+	defer secondary.Close()
+
+	client := services.NewLLMClient([]services.LLMProvider{
+		newTestLLMProvider("primary", primary.URL, "big-model", "fast-model"),
+		newTestLLMProvider("secondary", secondary.URL, "small-model"),
+	}, 0, time.Millisecond)
+
+	if !client.SupportsModel("fast-model") || client.SupportsModel("unknown-model") {
+		t.Fatal("Expected SupportsModel to reflect the configured model lists")
+	}
+
+	if _, err := client.Complete(context.Background(), services.ChatRequest{Model: "fast-model"}); err != nil {
+		t.Fatalf("Complete failed: %v", err)
+	}
+	if len(primaryModels) != 1 || primaryModels[0] != "fast-model" {
+		t.Errorf("Expected primary to receive the selected model, got %v", primaryModels)
+	}
+	if len(secondaryModels) != 1 || secondaryModels[0] != "small-model" {
+		t.Errorf("Expected secondary to fall back to its default model, got %v", secondaryModels)
+	}
+}
+
+// fakeStreamServer answers /chat/completions with the given SSE data lines, recording the request
+func fakeStreamServer(t *testing.T, received *services.ChatRequest, lines ...string) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(received)
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, line := range lines {
+			fmt.Fprintf(w, "data: %s\n\n", line)
+		}
+	}))
+}
+
+// TestLLMStreamRequestsUsage tests that streams ask for usage and read it from the final chunk
+func TestLLMStreamRequestsUsage(t *testing.T) {
+	var received services.ChatRequest
+	server := fakeStreamServer(t, &received,
+		`{"model":"test-model","choices":[{"delta":{"content":"Halo"}}]}`,
+		`{"choices":[{"delta":{"content":" juga"}}]}`,
+		`{"choices":[],"usage":{"prompt_tokens":10,"completion_tokens":2,"total_tokens":12}}`,
+		`[DONE]`,
+	)
+	defer server.Close()
+
+	provider := newTestLLMProvider("primary", server.URL, "test-model")
+	result, err := provider.Stream(context.Background(), services.ChatRequest{Model: "test-model"}, func(string) error { return nil })
+	if err != nil {
+		t.Fatalf("Stream failed: %v", err)
+	}
+
+	if !received.Stream || received.StreamOptions == nil || !received.StreamOptions.IncludeUsage {
+		t.Errorf("Expected stream_options.include_usage in the request, got %+v", received.StreamOptions)
+	}
+	if result.Content != "Halo juga" {
+		t.Errorf("Expected streamed content, got %q", result.Content)
+	}
+	if result.Usage == nil || result.Usage.TotalTokens != 12 {
+		t.Errorf("Expected usage from the final chunk, got %+v", result.Usage)
+	}
+}
+
+// TestAIUsageEstimatesStreamsWithoutUsage tests that streams without reported usage still count towards the quota
+func TestAIUsageEstimatesStreamsWithoutUsage(t *testing.T) {
+	var received services.ChatRequest
+	server := fakeStreamServer(t, &received,
+		`{"choices":[{"delta":{"content":"Ini jawaban yang cukup panjang untuk dihitung"}}]}`,
+		`[DONE]`,
+	)
+	defer server.Close()
+
+	req := services.ChatRequest{
+		Model:    "test-model",
+		Messages: []services.ChatMessage{{Role: "user", Content: "Tolong jelaskan jadwal saya hari ini"}},
+	}
+	result, err := newTestLLMProvider("primary", server.URL, "test-model").Stream(context.Background(), req, func(string) error { return nil })
+	if err != nil {
+		t.Fatalf("Stream failed: %v", err)
+	}
+	if result.Usage != nil {
+		t.Fatalf("Expected no usage from the provider, got %+v", result.Usage)
+	}
+
+	db := newTestDB(t)
+	migrateTestDB(t, db, &models.AIUsage{})
+	usage := services.NewAIUsageService(repository.NewAIUsageRepository(db), services.AIUsageLimits{DailyTokensPerUser: 5})
+	usage.RecordStream("user-1", req, result)
+
+	var stored models.AIUsage
+	if err := db.First(&stored).Error; err != nil {
+		t.Fatalf("Expected the stream recorded: %v", err)
+	}
+	if !stored.Estimated || stored.PromptTokens == 0 || stored.CompletionTokens == 0 {
+		t.Errorf("Expected estimated prompt and completion tokens, got %+v", stored)
+	}
+	if _, err := usage.CheckQuota("user-1"); !errors.Is(err, services.ErrAIQuotaExceeded) {
+		t.Errorf("Expected the estimated stream to use up the quota, got %v", err)
+	}
+}
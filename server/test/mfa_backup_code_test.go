@@ -0,0 +1,153 @@
+package test
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/workradar/server/internal/models"
+	"github.com/workradar/server/internal/repository"
+	"github.com/workradar/server/internal/services"
+	"github.com/workradar/server/pkg/utils"
+)
+
+// ============================================
+// MFA BACKUP CODE TESTS
+// ============================================
+
+func TestGenerateBackupCodes(t *testing.T) {
+	codes, err := services.GenerateBackupCodes(services.BackupCodeCount)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(codes) != services.BackupCodeCount {
+		t.Fatalf("Expected %d codes, got %d", services.BackupCodeCount, len(codes))
+	}
+
+	format := regexp.MustCompile(`^[0-9a-f]{5}-[0-9a-f]{5}$`)
+	seen := make(map[string]bool)
+	for _, code := range codes {
+		if !format.MatchString(code) {
+			t.Errorf("Unexpected code format %q", code)
+		}
+		if seen[code] {
+			t.Errorf("Duplicate code %q", code)
+		}
+		seen[code] = true
+		if services.IsTOTPCode(code) {
+			t.Errorf("Backup code %q must not look like a TOTP code", code)
+		}
+	}
+}
+
+func TestHashBackupCode(t *testing.T) {
+	hash, err := services.HashBackupCode("ab12c-34def")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Typed loosely: uppercase, without dash, with spaces
+	for _, typed := range []string{"ab12c-34def", "AB12C-34DEF", "ab12c34def", " ab12c 34def "} {
+		if !services.CheckBackupCode(typed, hash) {
+			t.Errorf("Expected %q to match the stored code", typed)
+		}
+	}
+	if services.CheckBackupCode("ab12c-34dee", hash) {
+		t.Error("Expected a different code not to match")
+	}
+
+	// Salted: the same code never hashes the same twice
+	if again, _ := services.HashBackupCode("ab12c-34def"); again == hash {
+		t.Error("Expected salted hashes")
+	}
+}
+
+func TestVerifyLoginUsesBackupCodeOnce(t *testing.T) {
+	useTestJWTSecret(t)
+	db := newTestDB(t)
+	migrateTestDB(t, db, &models.User{}, &models.MFABackupCode{}, &models.SecurityEvent{})
+
+	secret := "JBSWY3DPEHPK3PXP"
+	if err := db.Create(&models.User{ID: "user-1", Email: "user@example.com", Username: "user", MFAEnabled: true, MFASecret: &secret}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	backupRepo := repository.NewMFABackupCodeRepository(db)
+	hashes := make([]string, 0, 2)
+	for _, code := range []string{"ab12c-34def", "99999-00000"} {
+		hash, err := services.HashBackupCode(code)
+		if err != nil {
+			t.Fatal(err)
+		}
+		hashes = append(hashes, hash)
+	}
+	if err := backupRepo.ReplaceForUser("user-1", hashes); err != nil {
+		t.Fatal(err)
+	}
+
+	mfaService := services.NewMFAService(
+		repository.NewUserRepository(db),
+		backupRepo,
+		services.NewEmailService(nil, nil, nil, nil),
+		services.NewAuditService(repository.NewAuditRepository(db)),
+		services.NewMemoryStateStore(),
+	)
+
+	result, err := mfaService.VerifyLogin("user-1", "AB12C34DEF", services.ClientInfo{IPAddress: "203.0.113.7"})
+	if err != nil {
+		t.Fatalf("Expected backup code accepted, got %v", err)
+	}
+	if !result.UsedBackupCode || result.BackupCodesRemaining != 1 {
+		t.Errorf("Expected one code left, got %+v", result)
+	}
+
+	if _, err := mfaService.VerifyLogin("user-1", "ab12c-34def", services.ClientInfo{}); err == nil {
+		t.Error("Expected a used backup code to be rejected")
+	}
+	if _, err := mfaService.VerifyLogin("user-1", "fffff-fffff", services.ClientInfo{}); err == nil {
+		t.Error("Expected an unknown backup code to be rejected")
+	}
+
+	var alerts int64
+	db.Model(&models.SecurityEvent{}).Where("event_type = ?", models.EventMFABackupCodeUsed).Count(&alerts)
+	if alerts != 1 {
+		t.Errorf("Expected one backup code alert, got %d", alerts)
+	}
+}
+
+func TestIsTOTPCode(t *testing.T) {
+	cases := map[string]bool{"123456": true, "000000": true, "12345": false, "1234567": false, "12a456": false, "ab12c-34def": false}
+	for code, want := range cases {
+		if got := services.IsTOTPCode(code); got != want {
+			t.Errorf("IsTOTPCode(%q) = %v, want %v", code, got, want)
+		}
+	}
+}
+
+func TestVerifyPendingLoginChecksMFAToken(t *testing.T) {
+	useTestJWTSecret(t)
+	store := services.NewMemoryStateStore()
+	mfaService := services.NewMFAService(nil, nil, nil, nil, store)
+
+	if _, _, err := mfaService.VerifyPendingLogin("not-a-jwt", "123456", services.ClientInfo{}); !errors.Is(err, services.ErrInvalidMFAToken) {
+		t.Errorf("Expected ErrInvalidMFAToken, got %v", err)
+	}
+
+	// An access token is not an MFA token
+	access, _ := utils.GenerateAccessToken("user-1", "user@example.com", "regular", services.NewSessionID())
+	if _, _, err := mfaService.VerifyPendingLogin(access, "123456", services.ClientInfo{}); !errors.Is(err, services.ErrInvalidMFAToken) {
+		t.Errorf("Expected access token rejected, got %v", err)
+	}
+
+	// A token that used up its attempts is rejected before any code is checked
+	mfaToken, _ := utils.GenerateMFAToken("user-1")
+	claims, err := utils.ValidateMFAToken(mfaToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Set("mfa:attempts:"+claims.ID, "5", time.Minute)
+	if _, _, err := mfaService.VerifyPendingLogin(mfaToken, "123456", services.ClientInfo{}); !errors.Is(err, services.ErrTooManyMFAAttempts) {
+		t.Errorf("Expected ErrTooManyMFAAttempts, got %v", err)
+	}
+}
@@ -0,0 +1,287 @@
+package test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/workradar/server/internal/models"
+	"github.com/workradar/server/internal/repository"
+	"github.com/workradar/server/internal/services"
+	"gorm.io/gorm"
+)
+
+// ============================================
+// NOTIFICATION PREFERENCE TESTS
+// ============================================
+
+func quietPreference(start, end string) *models.NotificationPreference {
+	pref := services.DefaultNotificationPreference("user-1")
+	pref.QuietHoursEnabled = true
+	pref.QuietHoursStart = start
+	pref.QuietHoursEnd = end
+	return pref
+}
+
+func TestDefaultPreferenceDeliversPush(t *testing.T) {
+	pref := services.DefaultNotificationPreference("user-1")
+	now := time.Date(2026, 3, 5, 23, 0, 0, 0, time.Local)
+
+	for _, nt := range models.NotificationTypes {
+		route := services.RouteNotification(pref, services.Notification{Type: nt, Priority: services.NotificationPriorityLow}, now)
+		if route.Action != services.RouteDeliver || route.Channel != models.NotificationChannelPush {
+			t.Errorf("Expected push delivery for %s, got %+v", nt, route)
+		}
+	}
+}
+
+func TestRouteDropsTurnedOffType(t *testing.T) {
+	pref := services.DefaultNotificationPreference("user-1")
+	pref.Channels[models.NotificationTypeWeather] = models.NotificationChannelOff
+
+	route := services.RouteNotification(pref, services.Notification{Type: models.NotificationTypeWeather}, time.Now())
+	if route.Action != services.RouteDrop {
+		t.Errorf("Expected drop, got %+v", route)
+	}
+}
+
+func TestQuietHoursAcrossMidnight(t *testing.T) {
+	pref := quietPreference("22:00", "07:00")
+
+	tests := []struct {
+		now   time.Time
+		quiet bool
+		end   time.Time
+	}{
+		{time.Date(2026, 3, 5, 23, 30, 0, 0, time.Local), true, time.Date(2026, 3, 6, 7, 0, 0, 0, time.Local)},
+		{time.Date(2026, 3, 6, 6, 59, 0, 0, time.Local), true, time.Date(2026, 3, 6, 7, 0, 0, 0, time.Local)},
+		{time.Date(2026, 3, 6, 7, 0, 0, 0, time.Local), false, time.Time{}},
+		{time.Date(2026, 3, 6, 12, 0, 0, 0, time.Local), false, time.Time{}},
+	}
+
+	for _, tt := range tests {
+		end, quiet := services.QuietHoursEnd(pref, tt.now)
+		if quiet != tt.quiet || !end.Equal(tt.end) {
+			t.Errorf("At %s: expected (%v, %s), got (%v, %s)", tt.now.Format("15:04"), tt.quiet, tt.end, quiet, end)
+		}
+	}
+}
+
+func TestQuietHoursSameDay(t *testing.T) {
+	pref := quietPreference("12:00", "13:00")
+
+	if _, quiet := services.QuietHoursEnd(pref, time.Date(2026, 3, 5, 11, 59, 0, 0, time.Local)); quiet {
+		t.Error("Expected 11:59 to be outside quiet hours")
+	}
+	end, quiet := services.QuietHoursEnd(pref, time.Date(2026, 3, 5, 12, 30, 0, 0, time.Local))
+	if !quiet || end.Hour() != 13 {
+		t.Errorf("Expected quiet hours until 13:00, got (%v, %s)", quiet, end)
+	}
+}
+
+func TestQuietHoursDeferOnlyPush(t *testing.T) {
+	pref := quietPreference("22:00", "07:00")
+	now := time.Date(2026, 3, 5, 23, 0, 0, 0, time.Local)
+	reminder := services.Notification{Type: models.NotificationTypeTaskReminder, Priority: services.NotificationPriorityNormal}
+
+	route := services.RouteNotification(pref, reminder, now)
+	if route.Action != services.RouteDefer || route.At.Hour() != 7 || route.At.Day() != 6 {
+		t.Errorf("Expected push deferred to 07:00 next day, got %+v", route)
+	}
+
+	pref.Channels[models.NotificationTypeTaskReminder] = models.NotificationChannelInApp
+	if route := services.RouteNotification(pref, reminder, now); route.Action != services.RouteDeliver {
+		t.Errorf("Expected in-app delivery during quiet hours, got %+v", route)
+	}
+}
+
+func TestQuietHoursAllowUrgent(t *testing.T) {
+	pref := quietPreference("22:00", "07:00")
+	now := time.Date(2026, 3, 5, 23, 0, 0, 0, time.Local)
+	urgent := services.Notification{Type: models.NotificationTypeTaskReminder, Priority: services.NotificationPriorityHigh}
+
+	if route := services.RouteNotification(pref, urgent, now); route.Action != services.RouteDefer {
+		t.Errorf("Expected high priority deferred when urgent is not allowed, got %+v", route)
+	}
+
+	pref.QuietHoursAllowUrgent = true
+	if route := services.RouteNotification(pref, urgent, now); route.Action != services.RouteDeliver {
+		t.Errorf("Expected high priority delivered, got %+v", route)
+	}
+}
+
+func TestDigestHoldsLowPriority(t *testing.T) {
+	pref := services.DefaultNotificationPreference("user-1")
+	pref.DigestEnabled = true
+	pref.DigestTime = "18:00"
+	now := time.Date(2026, 3, 5, 19, 0, 0, 0, time.Local)
+
+	route := services.RouteNotification(pref, services.Notification{Type: models.NotificationTypeWeather, Priority: services.NotificationPriorityLow}, now)
+	if route.Action != services.RouteDigest || !route.At.Equal(time.Date(2026, 3, 6, 18, 0, 0, 0, time.Local)) {
+		t.Errorf("Expected digest at 18:00 next day, got %+v", route)
+	}
+
+	route = services.RouteNotification(pref, services.Notification{Type: models.NotificationTypeTaskOverdue, Priority: services.NotificationPriorityNormal}, now)
+	if route.Action != services.RouteDeliver {
+		t.Errorf("Expected normal priority delivered immediately, got %+v", route)
+	}
+
+	// The digest itself is never held for the next digest
+	route = services.RouteNotification(pref, services.Notification{Type: models.NotificationTypeDigest, Priority: services.NotificationPriorityLow}, now)
+	if route.Action != services.RouteDeliver {
+		t.Errorf("Expected digest delivered, got %+v", route)
+	}
+}
+
+func TestApplyNotificationPreferences(t *testing.T) {
+	pref := services.DefaultNotificationPreference("user-1")
+	enabled := true
+	start := "21:30"
+	end := "6:00"
+
+	err := services.ApplyNotificationPreferences(pref, services.UpdateNotificationPreferencesDTO{
+		Channels:          map[models.NotificationType]models.NotificationChannel{models.NotificationTypeWeather: models.NotificationChannelEmail},
+		QuietHoursEnabled: &enabled,
+		QuietHoursStart:   &start,
+		QuietHoursEnd:     &end,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if pref.Channels[models.NotificationTypeWeather] != models.NotificationChannelEmail ||
+		pref.Channels[models.NotificationTypeTaskReminder] != models.NotificationChannelPush {
+		t.Errorf("Unexpected channels: %v", pref.Channels)
+	}
+	if pref.QuietHoursStart != "21:30" || pref.QuietHoursEnd != "06:00" {
+		t.Errorf("Expected normalized quiet hours, got %s-%s", pref.QuietHoursStart, pref.QuietHoursEnd)
+	}
+}
+
+func TestApplyNotificationPreferencesRejectsInvalid(t *testing.T) {
+	bad := "25:00"
+	same := "22:00"
+	enabled := true
+
+	tests := []services.UpdateNotificationPreferencesDTO{
+		{Channels: map[models.NotificationType]models.NotificationChannel{"unknown": models.NotificationChannelPush}},
+		{Channels: map[models.NotificationType]models.NotificationChannel{models.NotificationTypeWeather: "sms"}},
+		{Channels: map[models.NotificationType]models.NotificationChannel{models.NotificationTypeDigest: models.NotificationChannelOff}},
+		{DigestTime: &bad},
+		{QuietHoursEnabled: &enabled, QuietHoursStart: &same, QuietHoursEnd: &same},
+	}
+
+	for i, dto := range tests {
+		if err := services.ApplyNotificationPreferences(services.DefaultNotificationPreference("user-1"), dto); err == nil {
+			t.Errorf("Case %d: expected an error", i)
+		}
+	}
+}
+
+func TestBuildDigestNotification(t *testing.T) {
+	var items []models.NotificationDigestItem
+	for i := 0; i < 7; i++ {
+		items = append(items, models.NotificationDigestItem{Type: models.NotificationTypeWeather, Title: "Cuaca", Body: "Cerah"})
+	}
+
+	n := services.BuildDigestNotification(items)
+	if n.Type != models.NotificationTypeDigest || n.Data["count"] != "7" {
+		t.Errorf("Unexpected digest: %+v", n)
+	}
+	if strings.Count(n.Body, "•") != 5 || !strings.Contains(n.Body, "dan 2 lainnya") {
+		t.Errorf("Expected 5 lines and a summary, got %q", n.Body)
+	}
+}
+
+type notificationTestEnv struct {
+	db      *gorm.DB
+	service *services.NotificationService
+	prefs   *repository.NotificationPreferenceRepository
+	jobs    *repository.JobRepository
+	devices *repository.DeviceRepository
+}
+
+// newNotificationTestEnv wires the notification service to a test database; the job queue
+// is never started, so scheduled jobs stay in the jobs table
+func newNotificationTestEnv(t *testing.T, push services.Notifier) *notificationTestEnv {
+	t.Helper()
+	db := newTestDB(t)
+	migrateTestDB(t, db, &models.User{}, &models.Device{}, &models.NotificationPreference{}, &models.NotificationDigestItem{}, &models.NotificationHistory{}, &models.Job{})
+
+	env := &notificationTestEnv{
+		db:      db,
+		prefs:   repository.NewNotificationPreferenceRepository(db),
+		jobs:    repository.NewJobRepository(db),
+		devices: repository.NewDeviceRepository(db),
+	}
+	jobQueue := services.NewJobQueue(env.jobs, 1, time.Second, time.Minute, 3)
+	env.service = services.NewNotificationService(env.devices, repository.NewUserRepository(db), env.prefs, jobQueue, nil, map[models.NotificationChannel]services.Notifier{
+		models.NotificationChannelPush: push,
+	})
+	return env
+}
+
+func TestNotifyUsesUserTimezone(t *testing.T) {
+	sink := services.NewSinkNotifier(models.NotificationChannelPush, "")
+	env := newNotificationTestEnv(t, sink)
+
+	// Quiet hours around the current time in Tokyo, which is mid-day or night in UTC
+	tokyo, _ := time.LoadLocation("Asia/Tokyo")
+	tokyoNow := time.Now().In(tokyo)
+	start := tokyoNow.Add(-time.Hour).Format("15:04")
+	end := tokyoNow.Add(2 * time.Hour).Truncate(time.Minute)
+
+	for _, user := range []models.User{
+		{ID: "user-tokyo", Email: "tokyo@example.com", Username: "tokyo", Timezone: "Asia/Tokyo"},
+		{ID: "user-utc", Email: "utc@example.com", Username: "utc", Timezone: "UTC"},
+	} {
+		if err := env.db.Create(&user).Error; err != nil {
+			t.Fatal(err)
+		}
+		pref := quietPreference(start, end.Format("15:04"))
+		pref.UserID = user.ID
+		if err := env.prefs.Upsert(pref); err != nil {
+			t.Fatal(err)
+		}
+		if err := env.service.Notify(user.ID, services.Notification{Type: models.NotificationTypeDailyPlan, Priority: services.NotificationPriorityNormal, Title: "Rencana"}); err != nil {
+			t.Fatalf("Notify failed: %v", err)
+		}
+	}
+
+	sent := sink.Sent()
+	if len(sent) != 1 || sent[0].UserID != "user-utc" {
+		t.Fatalf("Expected only the UTC user outside quiet hours, got %+v", sent)
+	}
+	jobs, _, err := env.jobs.List("", services.JobTypeNotification, 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 1 || !jobs[0].RunAt.Equal(end) {
+		t.Fatalf("Expected the Tokyo notification deferred to %v, got %+v", end, jobs)
+	}
+}
+
+func TestDigestScheduledInUserTimezone(t *testing.T) {
+	env := newNotificationTestEnv(t, services.NewSinkNotifier(models.NotificationChannelPush, ""))
+	if err := env.db.Create(&models.User{ID: "user-1", Email: "a@example.com", Username: "a", Timezone: "America/New_York"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	pref := services.DefaultNotificationPreference("user-1")
+	pref.DigestEnabled = true
+	pref.DigestTime = "18:00"
+	if err := env.prefs.Upsert(pref); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := env.service.Notify("user-1", services.Notification{Type: models.NotificationTypeWeather, Priority: services.NotificationPriorityLow, Title: "Cuaca"}); err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+
+	jobs, _, _ := env.jobs.List("", services.JobTypeNotificationDigest, 10, 0)
+	if len(jobs) != 1 {
+		t.Fatalf("Expected a scheduled digest, got %d", len(jobs))
+	}
+	newYork, _ := time.LoadLocation("America/New_York")
+	if at := jobs[0].RunAt.In(newYork); at.Hour() != 18 || at.Minute() != 0 {
+		t.Errorf("Expected the digest at 18:00 New York time, got %v", at)
+	}
+}
@@ -0,0 +1,101 @@
+package test
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/workradar/server/internal/models"
+	"github.com/workradar/server/internal/services"
+)
+
+// ============================================
+// NOTIFIER TESTS
+// ============================================
+
+func sinkNotificationService(sink *services.SinkNotifier) *services.NotificationService {
+	return services.NewNotificationService(nil, nil, nil, nil, nil, map[models.NotificationChannel]services.Notifier{
+		models.NotificationChannelPush: sink,
+	})
+}
+
+func TestNotificationServiceSendsToSink(t *testing.T) {
+	sink := services.NewSinkNotifier(models.NotificationChannelPush, "")
+	service := sinkNotificationService(sink)
+
+	if err := service.SendTaskReminder("user-1", "task-1", "Laporan", time.Now().Add(2*time.Hour), models.PriorityHigh); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := service.SendOverdueNudge("user-1", "task-1", "Laporan", time.Now().Add(-2*time.Hour), 1); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	sent := sink.Sent()
+	if len(sent) != 2 {
+		t.Fatalf("Expected 2 notifications, got %d", len(sent))
+	}
+	reminder := sent[0]
+	if reminder.UserID != "user-1" || reminder.Channel != models.NotificationChannelPush ||
+		reminder.Notification.Type != models.NotificationTypeTaskReminder ||
+		reminder.Notification.Priority != services.NotificationPriorityHigh {
+		t.Errorf("Unexpected reminder: %+v", reminder)
+	}
+	if reminder.Notification.Title != "⏰ Pengingat Tugas Penting" || reminder.Notification.Data["priority"] != "high" ||
+		reminder.Notification.Data["task_id"] != "task-1" {
+		t.Errorf("Expected an important task reminder, got %+v", reminder.Notification)
+	}
+	if sent[1].Notification.Type != models.NotificationTypeTaskOverdue || sent[1].Notification.Data["task_id"] != "task-1" {
+		t.Errorf("Unexpected overdue nudge: %+v", sent[1].Notification)
+	}
+
+	sink.Reset()
+	if len(sink.Sent()) != 0 {
+		t.Error("Expected Reset to clear the sink")
+	}
+}
+
+func TestNotificationServiceWithoutPushNotifier(t *testing.T) {
+	service := services.NewNotificationService(nil, nil, nil, nil, nil, map[models.NotificationChannel]services.Notifier{})
+
+	err := service.SendWeatherAlert("user-1", "Jakarta", "Hujan", 24)
+	if !errors.Is(err, services.ErrFCMNotConfigured) {
+		t.Errorf("Expected ErrFCMNotConfigured, got %v", err)
+	}
+}
+
+func TestSinkNotifierWritesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notifications.jsonl")
+	sink := services.NewSinkNotifier(models.NotificationChannelEmail, path)
+
+	for _, title := range []string{"Satu", "Dua"} {
+		if err := sink.Send("user-1", services.Notification{Type: models.NotificationTypeWeather, Title: title}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Expected sink file: %v", err)
+	}
+	defer file.Close()
+
+	var titles []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry services.SentNotification
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("Invalid JSON line %q: %v", scanner.Text(), err)
+		}
+		if entry.Channel != models.NotificationChannelEmail {
+			t.Errorf("Expected email channel, got %s", entry.Channel)
+		}
+		titles = append(titles, entry.Notification.Title)
+	}
+	if len(titles) != 2 || titles[0] != "Satu" || titles[1] != "Dua" {
+		t.Errorf("Unexpected lines: %v", titles)
+	}
+}
@@ -0,0 +1,85 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/workradar/server/internal/models"
+	"github.com/workradar/server/internal/services"
+)
+
+// ============================================
+// PAYMENT GATEWAY TESTS
+// Full upgrade flow against the in-process fake gateway
+// ============================================
+
+// TestFakeGatewayUpgradeFlow tests checkout → payment → signed webhook → refund
+func TestFakeGatewayUpgradeFlow(t *testing.T) {
+	var gateway services.PaymentGateway = services.NewFakePaymentGateway()
+	fake := gateway.(*services.FakePaymentGateway)
+	orderID := "ORDER-TEST-1"
+
+	session, err := gateway.CreateCheckout(services.CheckoutRequest{
+		OrderID:       orderID,
+		Amount:        models.PriceMonthly,
+		ItemID:        string(models.PlanTypeMonthly),
+		ItemName:      "Workradar VIP (Monthly)",
+		CustomerName:  "tester",
+		CustomerEmail: "tester@example.com",
+	})
+	if err != nil {
+		t.Fatalf("CreateCheckout failed: %v", err)
+	}
+	if session.Token == "" || session.RedirectURL == "" {
+		t.Fatalf("Expected token and redirect URL, got %+v", session)
+	}
+
+	status, err := gateway.CheckTransaction(orderID)
+	if err != nil || status.TransactionStatus != "pending" {
+		t.Fatalf("Expected pending transaction, got %+v (err: %v)", status, err)
+	}
+
+	payload, err := fake.Simulate(orderID, "settlement", "", "bank_transfer")
+	if err != nil {
+		t.Fatalf("Simulate failed: %v", err)
+	}
+
+	statusCode := payload["status_code"].(string)
+	grossAmount := payload["gross_amount"].(string)
+	signature := payload["signature_key"].(string)
+
+	if !gateway.VerifySignature(orderID, statusCode, grossAmount, signature) {
+		t.Error("Expected simulated notification signature to be valid")
+	}
+	if gateway.VerifySignature(orderID, statusCode, "1.00", signature) {
+		t.Error("Expected tampered gross_amount to be rejected")
+	}
+
+	status, _ = gateway.CheckTransaction(orderID)
+	if services.MapGatewayStatus(status.TransactionStatus, status.FraudStatus) != models.TransactionStatusSettlement {
+		t.Errorf("Expected settlement after simulation, got %s", status.TransactionStatus)
+	}
+
+	refund, err := gateway.Refund(orderID, models.PriceMonthly, "test refund")
+	if err != nil {
+		t.Fatalf("Refund failed: %v", err)
+	}
+	if services.MapGatewayStatus(refund.TransactionStatus, "") != models.TransactionStatusRefund {
+		t.Errorf("Expected refund status, got %s", refund.TransactionStatus)
+	}
+
+	if _, err := gateway.Refund(orderID, models.PriceMonthly, "again"); err == nil {
+		t.Error("Expected refunding a refunded transaction to fail")
+	}
+}
+
+// TestFakeGatewayUnknownOrder tests that unknown orders are reported as not found
+func TestFakeGatewayUnknownOrder(t *testing.T) {
+	gateway := services.NewFakePaymentGateway()
+
+	if _, err := gateway.CheckTransaction("ORDER-UNKNOWN"); err != services.ErrGatewayTransactionNotFound {
+		t.Errorf("Expected ErrGatewayTransactionNotFound, got %v", err)
+	}
+	if _, err := gateway.Simulate("ORDER-UNKNOWN", "settlement", "", ""); err == nil {
+		t.Error("Expected simulating an unknown order to fail")
+	}
+}
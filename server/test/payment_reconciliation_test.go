@@ -0,0 +1,178 @@
+package test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/workradar/server/internal/models"
+	"github.com/workradar/server/internal/repository"
+	"github.com/workradar/server/internal/services"
+)
+
+// ============================================
+// PAYMENT RECONCILIATION TESTS
+// Runs against the in-process fake gateway (no network)
+// ============================================
+
+// TestMapGatewayStatus tests the gateway → local status state machine
+func TestMapGatewayStatus(t *testing.T) {
+	testCases := []struct {
+		transactionStatus string
+		fraudStatus       string
+		expected          models.TransactionStatus
+	}{
+		{"capture", "accept", models.TransactionStatusSettlement},
+		{"capture", "challenge", models.TransactionStatusPending},
+		{"capture", "deny", models.TransactionStatusDeny},
+		{"settlement", "", models.TransactionStatusSettlement},
+		{"deny", "", models.TransactionStatusDeny},
+		{"cancel", "", models.TransactionStatusCancel},
+		{"expire", "", models.TransactionStatusExpire},
+		{"refund", "", models.TransactionStatusRefund},
+		{"partial_refund", "", models.TransactionStatusPartialRefund},
+		{"pending", "", models.TransactionStatusPending},
+		{"unknown", "", models.TransactionStatusPending},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.transactionStatus+"/"+tc.fraudStatus, func(t *testing.T) {
+			got := services.MapGatewayStatus(tc.transactionStatus, tc.fraudStatus)
+			if got != tc.expected {
+				t.Errorf("Expected %s, got %s", tc.expected, got)
+			}
+		})
+	}
+}
+
+// TestEvaluateReconciliation tests reconciliation decisions against the fake gateway
+func TestEvaluateReconciliation(t *testing.T) {
+	now := time.Now()
+	expireAfter := 24 * time.Hour
+
+	gateway := services.NewFakePaymentGateway()
+	gateway.SetTransactionStatus("ORDER-SETTLED", "settlement", "", "bank_transfer")
+	gateway.SetTransactionStatus("ORDER-PENDING", "pending", "", "gopay")
+	gateway.SetTransactionStatus("ORDER-EXPIRED", "expire", "", "")
+
+	testCases := []struct {
+		name           string
+		orderID        string
+		createdAt      time.Time
+		expectedStatus models.TransactionStatus
+		expectedAction models.ReconciliationAction
+	}{
+		{"Lost settlement webhook", "ORDER-SETTLED", now.Add(-time.Hour), models.TransactionStatusSettlement, models.ReconciliationActionUpdated},
+		{"Still pending at gateway", "ORDER-PENDING", now.Add(-time.Hour), models.TransactionStatusPending, models.ReconciliationActionNone},
+		{"Expired at gateway", "ORDER-EXPIRED", now.Add(-time.Hour), models.TransactionStatusExpire, models.ReconciliationActionExpired},
+		{"Abandoned checkout", "ORDER-MISSING", now.Add(-48 * time.Hour), models.TransactionStatusExpire, models.ReconciliationActionExpired},
+		{"Recent unknown order", "ORDER-MISSING", now.Add(-time.Hour), models.TransactionStatusPending, models.ReconciliationActionNone},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			trx := &models.Transaction{
+				OrderID:   tc.orderID,
+				Status:    models.TransactionStatusPending,
+				CreatedAt: tc.createdAt,
+			}
+
+			gatewayStatus, err := gateway.CheckTransaction(tc.orderID)
+			status, action := services.EvaluateReconciliation(trx, gatewayStatus, err, now, expireAfter)
+			if status != tc.expectedStatus || action != tc.expectedAction {
+				t.Errorf("Expected (%s, %s), got (%s, %s)", tc.expectedStatus, tc.expectedAction, status, action)
+			}
+		})
+	}
+
+	t.Run("Gateway outage is reported, not applied", func(t *testing.T) {
+		gateway.SetCheckError(errors.New("connection refused"))
+		defer gateway.SetCheckError(nil)
+
+		trx := &models.Transaction{OrderID: "ORDER-SETTLED", Status: models.TransactionStatusPending, CreatedAt: now.Add(-48 * time.Hour)}
+		gatewayStatus, err := gateway.CheckTransaction(trx.OrderID)
+		status, action := services.EvaluateReconciliation(trx, gatewayStatus, err, now, expireAfter)
+		if status != models.TransactionStatusPending || action != models.ReconciliationActionError {
+			t.Errorf("Expected (pending, error), got (%s, %s)", status, action)
+		}
+	})
+}
+
+func TestTransactionUpdateStatusIsCompareAndSet(t *testing.T) {
+	db := newTestDB(t)
+	migrateTestDB(t, db, &models.Transaction{})
+	repo := repository.NewTransactionRepository(db)
+
+	if err := repo.Create(&models.Transaction{OrderID: "ORDER-1", UserID: "user-1", PlanType: models.PlanTypeMonthly, Amount: models.PriceMonthly}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The first writer (e.g. the webhook) wins...
+	applied, err := repo.UpdateStatus("ORDER-1", models.TransactionStatusPending, models.TransactionStatusSettlement)
+	if err != nil || !applied {
+		t.Fatalf("Expected first update to apply, got applied=%v err=%v", applied, err)
+	}
+
+	// ...and a reconciler that read the same pending row loses
+	applied, err = repo.UpdateStatus("ORDER-1", models.TransactionStatusPending, models.TransactionStatusExpire)
+	if err != nil || applied {
+		t.Fatalf("Expected stale update to be rejected, got applied=%v err=%v", applied, err)
+	}
+
+	trx, _ := repo.FindByOrderID("ORDER-1")
+	if trx.Status != models.TransactionStatusSettlement {
+		t.Errorf("Expected settlement to be kept, got %s", trx.Status)
+	}
+}
+
+func TestReconcileStoresReportsInDatabase(t *testing.T) {
+	db := newTestDB(t)
+	migrateTestDB(t, db, &models.Transaction{}, &models.PaymentReconciliationReport{})
+	trxRepo := repository.NewTransactionRepository(db)
+	reportRepo := repository.NewPaymentReconciliationRepository(db)
+
+	gateway := services.NewFakePaymentGateway()
+	gateway.SetTransactionStatus("ORDER-EXPIRED", "expire", "", "")
+	gateway.SetTransactionStatus("ORDER-PENDING", "pending", "", "gopay")
+
+	for _, orderID := range []string{"ORDER-EXPIRED", "ORDER-PENDING"} {
+		if err := trxRepo.Create(&models.Transaction{
+			OrderID:   orderID,
+			UserID:    "user-1",
+			PlanType:  models.PlanTypeMonthly,
+			Amount:    models.PriceMonthly,
+			CreatedAt: time.Now().Add(-2 * time.Hour),
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	paymentService := services.NewPaymentService(trxRepo, nil, nil, nil, gateway, nil)
+	newReconciler := func() *services.PaymentReconciliationService {
+		return services.NewPaymentReconciliationService(trxRepo, reportRepo, paymentService, gateway, time.Minute, time.Hour, 24*time.Hour)
+	}
+
+	report, err := newReconciler().Reconcile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Checked != 2 || report.Expired != 1 || report.StillPending != 1 {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+
+	// Another replica (or a restarted server) sees the same history
+	reports, err := newReconciler().GetReports(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) != 1 || reports[0].ID != report.ID {
+		t.Fatalf("Expected the stored report, got %+v", reports)
+	}
+	if len(reports[0].Mismatches) != 1 || reports[0].Mismatches[0].OrderID != "ORDER-EXPIRED" {
+		t.Errorf("Expected the expired order in the stored mismatches, got %+v", reports[0].Mismatches)
+	}
+
+	if trx, _ := trxRepo.FindByOrderID("ORDER-EXPIRED"); trx.Status != models.TransactionStatusExpire {
+		t.Errorf("Expected expired transaction, got %s", trx.Status)
+	}
+}
@@ -0,0 +1,128 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/workradar/server/internal/models"
+	"github.com/workradar/server/internal/repository"
+	"github.com/workradar/server/internal/services"
+)
+
+// ============================================
+// DAILY PLANNER TESTS
+// Rule-based planner used when the AI provider is unavailable
+// ============================================
+
+func planTask(id string, minutes int, difficulty string, deadline *time.Time) models.Task {
+	task := models.Task{ID: id, Title: "Task " + id, DurationMinutes: &minutes}
+	if difficulty != "" {
+		task.Difficulty = &difficulty
+	}
+	task.Deadline = deadline
+	return task
+}
+
+// TestParseWorkDays tests the Monday-based keys and the default schedule
+func TestParseWorkDays(t *testing.T) {
+	defaults := services.ParseWorkDays(nil)
+	if !defaults[time.Monday].IsWorkDay || defaults[time.Saturday].IsWorkDay {
+		t.Error("Expected Monday-Friday as default work days")
+	}
+
+	raw := `{"0":{"is_work_day":false,"start":"","end":""},"5":{"is_work_day":true,"start":"10:00","end":"14:00"}}`
+	days := services.ParseWorkDays(&raw)
+	if days[time.Monday].IsWorkDay {
+		t.Error("Expected key 0 to configure Monday")
+	}
+	if !days[time.Saturday].IsWorkDay || days[time.Saturday].Start != "10:00" {
+		t.Errorf("Expected key 5 to configure Saturday, got %+v", days[time.Saturday])
+	}
+}
+
+// TestBuildDailyPlanFitsWorkHours tests ordering, breaks and overflow
+func TestBuildDailyPlanFitsWorkHours(t *testing.T) {
+	day := time.Date(2026, 3, 2, 0, 0, 0, 0, time.Local)
+	start := day.Add(9 * time.Hour)
+	end := day.Add(12 * time.Hour)
+	dueNoon := day.Add(12 * time.Hour)
+
+	tasks := []models.Task{
+		planTask("light", 30, "relaxed", nil),
+		planTask("report", 60, "focus", &dueNoon),
+		planTask("slides", 60, "focus", &dueNoon),
+		planTask("huge", 240, "normal", nil),
+	}
+
+	ordered := services.PlanCandidates(tasks, day)
+	plan := services.BuildDailyPlan(ordered, start, end, day)
+
+	var kinds, ids []string
+	for _, item := range plan.Items {
+		kinds = append(kinds, item.Kind)
+		ids = append(ids, item.TaskID)
+	}
+
+	// Focus tasks due today first, separated by the lighter task and a break
+	want := []string{"report", "", "light", "slides"}
+	if len(ids) != len(want) {
+		t.Fatalf("Expected items %v, got %v (kinds %v)", want, ids, kinds)
+	}
+	for i := range want {
+		if ids[i] != want[i] {
+			t.Fatalf("Expected items %v, got %v", want, ids)
+		}
+	}
+	if plan.Items[1].Kind != services.PlanItemBreak {
+		t.Errorf("Expected a break after focus work, got %s", plan.Items[1].Kind)
+	}
+	if last := plan.Items[len(plan.Items)-1]; last.End.After(end) {
+		t.Errorf("Plan runs past work hours: %v", last.End)
+	}
+	if len(plan.Unscheduled) != 1 || plan.Unscheduled[0].TaskID != "huge" {
+		t.Errorf("Expected the 4-hour task to be unscheduled, got %+v", plan.Unscheduled)
+	}
+	if plan.PlannedMinutes != 150 {
+		t.Errorf("Expected 150 planned minutes, got %d", plan.PlannedMinutes)
+	}
+}
+
+// TestReorderTasksIgnoresUnknownIDs tests that an AI-proposed order cannot drop or invent tasks
+func TestReorderTasksIgnoresUnknownIDs(t *testing.T) {
+	tasks := []models.Task{{ID: "a"}, {ID: "b"}, {ID: "c"}}
+
+	ordered := services.ReorderTasks(tasks, []string{"c", "x", "c", "a"})
+
+	got := ""
+	for _, task := range ordered {
+		got += task.ID
+	}
+	if got != "cab" {
+		t.Errorf("Expected order cab, got %s", got)
+	}
+}
+
+func TestGenerateDailyPlanUsesUserTimezone(t *testing.T) {
+	db := newTestDB(t)
+	migrateTestDB(t, db, &models.User{}, &models.Task{}, &models.Holiday{}, &models.Leave{})
+	if err := db.Create(&models.User{ID: "user-1", Email: "user@example.com", Username: "user", Timezone: "Pacific/Kiritimati"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	planner := services.NewPlannerService(
+		repository.NewUserRepository(db),
+		repository.NewTaskRepository(db),
+		repository.NewHolidayRepository(db),
+		repository.NewLeaveRepository(db),
+		nil, nil, nil, nil, nil,
+	)
+
+	// Sunday noon in UTC is already Monday (a work day) at UTC+14
+	plan, err := planner.GenerateDailyPlan("user-1", time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.Date != "2026-03-02" || plan.DayOffReason != "" {
+		t.Errorf("Expected the user's Monday planned, got date %s (%q)", plan.Date, plan.DayOffReason)
+	}
+}
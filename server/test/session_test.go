@@ -0,0 +1,337 @@
+package test
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/workradar/server/internal/config"
+	"github.com/workradar/server/internal/middleware"
+	"github.com/workradar/server/internal/models"
+	"github.com/workradar/server/internal/repository"
+	"github.com/workradar/server/internal/services"
+	"github.com/workradar/server/pkg/utils"
+	"gorm.io/gorm"
+)
+
+// ============================================
+// SESSION TESTS
+// ============================================
+
+func useTestJWTSecret(t *testing.T) {
+	t.Helper()
+	previous := config.AppConfig
+	config.AppConfig = &config.Config{JWTSecret: "test-secret"}
+	t.Cleanup(func() { config.AppConfig = previous })
+}
+
+func TestTokensCarrySessionID(t *testing.T) {
+	useTestJWTSecret(t)
+	sessionID := services.NewSessionID()
+
+	access, err := utils.GenerateAccessToken("user-1", "user@example.com", "regular", sessionID)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	refresh, err := utils.GenerateRefreshToken("user-1", "user@example.com", "regular", sessionID)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for _, token := range []string{access, refresh} {
+		claims, err := utils.ValidateToken(token)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if claims.SessionID != sessionID {
+			t.Errorf("Expected sid %q, got %q", sessionID, claims.SessionID)
+		}
+	}
+}
+
+func TestHashToken(t *testing.T) {
+	a := utils.HashToken("refresh-token")
+	if len(a) != 64 || a != utils.HashToken("refresh-token") {
+		t.Errorf("Expected stable hex SHA-256, got %q", a)
+	}
+	if a == utils.HashToken("other-token") {
+		t.Error("Expected different tokens to hash differently")
+	}
+}
+
+func TestNewSessionIDUnique(t *testing.T) {
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		id := services.NewSessionID()
+		if seen[id] || len(id) > 36 {
+			t.Fatalf("Unexpected session ID %q", id)
+		}
+		seen[id] = true
+	}
+}
+
+func TestAuthMiddlewareRejectsTokenWithoutSession(t *testing.T) {
+	useTestJWTSecret(t)
+
+	// Tokens issued before server-side sessions have no sid claim
+	token, _ := utils.GenerateAccessToken("user-1", "user@example.com", "regular", "")
+
+	app := fiber.New()
+	app.Get("/protected", middleware.AuthMiddleware(nil, nil), func(c *fiber.Ctx) error {
+		return c.SendString("ok")
+	})
+
+	req := httptest.NewRequest("GET", "/protected", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusUnauthorized {
+		t.Errorf("Expected 401, got %d", resp.StatusCode)
+	}
+}
+
+func TestRefreshTokensRejectsNonRefreshTokens(t *testing.T) {
+	useTestJWTSecret(t)
+	authService := services.NewAuthService(nil, nil, nil, nil, nil, nil, nil)
+
+	if _, _, err := authService.RefreshTokens("not-a-jwt", services.ClientInfo{}); !errors.Is(err, services.ErrInvalidRefreshToken) {
+		t.Errorf("Expected ErrInvalidRefreshToken, got %v", err)
+	}
+
+	access, _ := utils.GenerateAccessToken("user-1", "user@example.com", "regular", services.NewSessionID())
+	if _, _, err := authService.RefreshTokens(access, services.ClientInfo{}); err == nil {
+		t.Error("Expected an access token to be rejected")
+	}
+
+	// Refresh tokens without a session cannot be rotated
+	legacy, _ := utils.GenerateRefreshToken("user-1", "user@example.com", "regular", "")
+	if _, _, err := authService.RefreshTokens(legacy, services.ClientInfo{}); err == nil {
+		t.Error("Expected a refresh token without session to be rejected")
+	}
+}
+
+// recordingMailTransport keeps sent emails in memory
+type recordingMailTransport struct {
+	mu   sync.Mutex
+	sent []services.MailMessage
+}
+
+func (r *recordingMailTransport) Send(msg services.MailMessage) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sent = append(r.sent, msg)
+	return nil
+}
+
+func (r *recordingMailTransport) Sent() []services.MailMessage {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]services.MailMessage(nil), r.sent...)
+}
+
+type sessionTestEnv struct {
+	db       *gorm.DB
+	sessions *services.SessionManagementService
+	auth     *services.AuthService
+	mail     *recordingMailTransport
+}
+
+func newSessionTestEnv(t *testing.T) *sessionTestEnv {
+	t.Helper()
+	useTestJWTSecret(t)
+	db := newTestDB(t)
+	migrateTestDB(t, db, &models.User{}, &models.ActiveSession{}, &models.RefreshToken{}, &models.SecurityEvent{})
+	if err := db.Create(&models.User{ID: "user-1", Email: "user@example.com", Username: "user"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	userRepo := repository.NewUserRepository(db)
+	env := &sessionTestEnv{db: db, mail: &recordingMailTransport{}}
+	env.sessions = services.NewSessionManagementService(db, services.NewAuditService(repository.NewAuditRepository(db)), 5)
+	env.auth = services.NewAuthService(
+		userRepo,
+		nil,
+		nil,
+		nil,
+		services.NewEmailService(env.mail, services.NewEmailRenderer(""), nil, nil),
+		env.sessions,
+		services.NewUserStateService(userRepo, services.NewMemoryStateStore(), time.Minute),
+	)
+	return env
+}
+
+// login opens a session for user-1 and returns its ID and refresh token
+func (env *sessionTestEnv) login(t *testing.T) (string, string) {
+	t.Helper()
+	sessionID := services.NewSessionID()
+	refresh, err := utils.GenerateRefreshToken("user-1", "user@example.com", "regular", sessionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.sessions.CreateSession(sessionID, "user-1", utils.HashToken(refresh), "Chrome on Linux", "203.0.113.7", "Mozilla/5.0", "", utils.RefreshTokenTTL); err != nil {
+		t.Fatal(err)
+	}
+	return sessionID, refresh
+}
+
+func (env *sessionTestEnv) reuseEvents(t *testing.T) int64 {
+	t.Helper()
+	var count int64
+	env.db.Model(&models.SecurityEvent{}).Where("event_type = ?", models.EventRefreshTokenReuse).Count(&count)
+	return count
+}
+
+func TestRotateRefreshToken(t *testing.T) {
+	env := newSessionTestEnv(t)
+	sessionID, _ := env.login(t)
+
+	var first models.RefreshToken
+	env.db.Where("family_id = ?", sessionID).First(&first)
+
+	session, sealed, err := env.sessions.RotateRefreshToken(sessionID, first.TokenHash, "hash-2", "sealed-2", time.Hour, "203.0.113.7", "Mozilla/5.0")
+	if err != nil {
+		t.Fatalf("Rotate failed: %v", err)
+	}
+	if session.TokenHash != "hash-2" || sealed != "" {
+		t.Errorf("Expected the session moved to the new token, got hash=%s sealed=%q", session.TokenHash, sealed)
+	}
+
+	if _, _, err := env.sessions.RotateRefreshToken(sessionID, "hash-2", "hash-3", "sealed-3", time.Hour, "", ""); err != nil {
+		t.Fatalf("Rotating the new token failed: %v", err)
+	}
+	if _, _, err := env.sessions.RotateRefreshToken(sessionID, "unknown", "hash-4", "", time.Hour, "", ""); !errors.Is(err, services.ErrInvalidRefreshToken) {
+		t.Errorf("Expected ErrInvalidRefreshToken for an unknown token, got %v", err)
+	}
+
+	var tokens int64
+	env.db.Model(&models.RefreshToken{}).Where("family_id = ? AND used_at IS NULL", sessionID).Count(&tokens)
+	if tokens != 1 {
+		t.Errorf("Expected exactly one unused token in the family, got %d", tokens)
+	}
+}
+
+func TestRefreshTokenReuseRevokesFamily(t *testing.T) {
+	env := newSessionTestEnv(t)
+	sessionID, t0 := env.login(t)
+	other, _ := env.login(t)
+
+	_, t1, err := env.auth.RefreshTokens(t0, services.ClientInfo{})
+	if err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+
+	// Past the grace window the rotated token can only be a stolen copy
+	env.db.Model(&models.RefreshToken{}).Where("token_hash = ?", utils.HashToken(t0)).
+		Update("used_at", time.Now().Add(-time.Hour))
+
+	client := services.ClientInfo{IPAddress: "198.51.100.9", UserAgent: "curl/8.0"}
+	if _, _, err := env.auth.RefreshTokens(t0, client); !errors.Is(err, services.ErrRefreshTokenReused) {
+		t.Fatalf("Expected ErrRefreshTokenReused, got %v", err)
+	}
+
+	// The whole family is gone, including the token the legitimate client holds
+	var tokens int64
+	env.db.Model(&models.RefreshToken{}).Where("family_id = ?", sessionID).Count(&tokens)
+	if tokens != 0 {
+		t.Errorf("Expected the token family revoked, %d tokens left", tokens)
+	}
+	if _, _, err := env.auth.RefreshTokens(t1, services.ClientInfo{}); err == nil {
+		t.Error("Expected the newest token of a revoked family to be rejected")
+	}
+	if _, err := env.sessions.ValidateSession(sessionID); !errors.Is(err, services.ErrSessionNotFound) {
+		t.Errorf("Expected the session revoked, got %v", err)
+	}
+	if _, err := env.sessions.ValidateSession(other); err != nil {
+		t.Errorf("Expected other sessions kept, got %v", err)
+	}
+
+	// The reuse is recorded and the user is told
+	if n := env.reuseEvents(t); n != 1 {
+		t.Errorf("Expected one reuse security event, got %d", n)
+	}
+	sent := env.mail.Sent()
+	if len(sent) != 1 || sent[0].To != "user@example.com" || !strings.Contains(sent[0].Text, "198.51.100.9") {
+		t.Errorf("Expected a reuse alert to the user, got %+v", sent)
+	}
+}
+
+func TestConcurrentRefreshWithinGraceWindow(t *testing.T) {
+	env := newSessionTestEnv(t)
+	_, t0 := env.login(t)
+
+	_, t1, err := env.auth.RefreshTokens(t0, services.ClientInfo{})
+	if err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+
+	// A second request that raced the first with the same token gets the same pair
+	access, again, err := env.auth.RefreshTokens(t0, services.ClientInfo{})
+	if err != nil {
+		t.Fatalf("Expected the concurrent refresh accepted, got %v", err)
+	}
+	if again != t1 || access == "" {
+		t.Errorf("Expected the current refresh token returned again")
+	}
+	if n := env.reuseEvents(t); n != 0 {
+		t.Errorf("Expected no reuse event, got %d", n)
+	}
+
+	// Once the token moved on, the old one is reuse even inside the window
+	if _, _, err := env.auth.RefreshTokens(t1, services.ClientInfo{}); err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+	if _, _, err := env.auth.RefreshTokens(t0, services.ClientInfo{}); !errors.Is(err, services.ErrRefreshTokenReused) {
+		t.Errorf("Expected ErrRefreshTokenReused, got %v", err)
+	}
+}
+
+func TestValidateSessionAfterRevoke(t *testing.T) {
+	env := newSessionTestEnv(t)
+	sessionID, refresh := env.login(t)
+
+	if _, err := env.sessions.ValidateSession(sessionID); err != nil {
+		t.Fatalf("Expected a new session valid, got %v", err)
+	}
+
+	if err := env.sessions.InvalidateSession("user-2", sessionID); !errors.Is(err, services.ErrSessionNotFound) {
+		t.Errorf("Expected another user unable to revoke the session, got %v", err)
+	}
+	if err := env.sessions.InvalidateSession("user-1", sessionID); err != nil {
+		t.Fatalf("Revoke failed: %v", err)
+	}
+
+	if _, err := env.sessions.ValidateSession(sessionID); !errors.Is(err, services.ErrSessionNotFound) {
+		t.Errorf("Expected ErrSessionNotFound after revoke, got %v", err)
+	}
+	if _, _, err := env.auth.RefreshTokens(refresh, services.ClientInfo{}); !errors.Is(err, services.ErrInvalidRefreshToken) {
+		t.Errorf("Expected the refresh token of a revoked session rejected, got %v", err)
+	}
+}
+
+func TestIsTrustedProxy(t *testing.T) {
+	trusted := []string{"173.245.48.0/20", "2400:cb00::/32", "10.0.0.5"}
+	cases := map[string]bool{
+		"173.245.48.1": true,
+		"2400:cb00::1": true,
+		"10.0.0.5":     true,
+		"10.0.0.6":     false,
+		"203.0.113.7":  false,
+		"not-an-ip":    false,
+		"173.245.64.1": false,
+		"2400:cb01::1": false,
+	}
+	for ip, want := range cases {
+		if got := utils.IsTrustedProxy(ip, trusted); got != want {
+			t.Errorf("IsTrustedProxy(%q) = %v, want %v", ip, got, want)
+		}
+	}
+	if utils.IsTrustedProxy("173.245.48.1", nil) {
+		t.Error("Expected no proxy trusted without configuration")
+	}
+}
@@ -0,0 +1,330 @@
+package test
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/workradar/server/internal/middleware"
+	"github.com/workradar/server/internal/models"
+	"github.com/workradar/server/internal/repository"
+	"github.com/workradar/server/internal/services"
+)
+
+// ============================================
+// STATE STORE TESTS
+// ============================================
+
+// testStateStore runs the behaviour every store must share; wait lets time pass for the store
+func testStateStore(t *testing.T, store services.StateStore, wait func(time.Duration)) {
+	t.Helper()
+
+	if _, found, err := store.Get("missing"); err != nil || found {
+		t.Fatalf("Expected missing key, got found=%v err=%v", found, err)
+	}
+
+	if err := store.Set("blacklist:abc", "1", time.Minute); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if value, found, err := store.Get("blacklist:abc"); err != nil || !found || value != "1" {
+		t.Fatalf("Expected stored value, got %q found=%v err=%v", value, found, err)
+	}
+	if err := store.Delete("blacklist:abc"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, found, _ := store.Get("blacklist:abc"); found {
+		t.Error("Expected key deleted")
+	}
+
+	if err := store.Set("short", "x", 20*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	wait(40 * time.Millisecond)
+	if _, found, _ := store.Get("short"); found {
+		t.Error("Expected key expired")
+	}
+
+	for want := int64(1); want <= 3; want++ {
+		n, err := store.Incr("counter", 50*time.Millisecond)
+		if err != nil || n != want {
+			t.Fatalf("Incr = %d, %v; want %d", n, err, want)
+		}
+	}
+	wait(80 * time.Millisecond)
+	if n, err := store.Incr("counter", time.Minute); err != nil || n != 1 {
+		t.Errorf("Expected counter to restart after its window, got %d, %v", n, err)
+	}
+}
+
+func TestMemoryStateStore(t *testing.T) {
+	testStateStore(t, services.NewMemoryStateStore(), time.Sleep)
+}
+
+func TestMemoryStateStoreIncrIsAtomic(t *testing.T) {
+	store := services.NewMemoryStateStore()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			store.Incr("counter", time.Minute)
+		}()
+	}
+	wg.Wait()
+
+	if value, _, _ := store.Get("counter"); value != "50" {
+		t.Errorf("Expected 50 increments, got %s", value)
+	}
+}
+
+func TestDatabaseStateStore(t *testing.T) {
+	db := newTestDB(t)
+	migrateTestDB(t, db, &models.StateEntry{})
+
+	testStateStore(t, services.NewDatabaseStateStore(repository.NewStateEntryRepository(db)), time.Sleep)
+}
+
+func TestDatabaseStateStoreLongKeys(t *testing.T) {
+	db := newTestDB(t)
+	migrateTestDB(t, db, &models.StateEntry{})
+	store := services.NewDatabaseStateStore(repository.NewStateEntryRepository(db))
+
+	// "delay:email:" and a 254 character address do not fit the key column
+	long := "delay:email:" + strings.Repeat("a", 242) + "@example.com"
+	other := long[:len(long)-1] + "m2"
+	if err := store.Set(long, "1", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := store.Incr(other, time.Minute); err != nil || n != 1 {
+		t.Fatalf("Expected a separate counter for another long key, got %d, %v", n, err)
+	}
+	if value, found, err := store.Get(long); err != nil || !found || value != "1" {
+		t.Errorf("Expected the long key stored, got %q found=%v err=%v", value, found, err)
+	}
+
+	var keys []string
+	db.Model(&models.StateEntry{}).Pluck("key", &keys)
+	for _, key := range keys {
+		if len(key) > 191 || !strings.HasPrefix(key, "delay:email:") {
+			t.Errorf("Expected a hashed key within the column size, got %q", key)
+		}
+	}
+
+	if err := store.Delete(long); err != nil {
+		t.Fatal(err)
+	}
+	if _, found, _ := store.Get(long); found {
+		t.Error("Expected the long key deleted")
+	}
+}
+
+func startMiniredis(t *testing.T) *miniredis.Miniredis {
+	t.Helper()
+	server, err := miniredis.Run()
+	if err != nil {
+		t.Skipf("Loopback networking unavailable: %v", err)
+	}
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestRedisStateStore(t *testing.T) {
+	server := startMiniredis(t)
+	server.RequireAuth("secret")
+
+	store, err := services.NewRedisStateStore("redis://:secret@" + server.Addr() + "/2")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer store.Close()
+
+	testStateStore(t, store, server.FastForward)
+
+	if !server.DB(2).Exists("counter") {
+		t.Error("Expected keys stored in the selected database")
+	}
+}
+
+func TestRedisStateStoreSubMillisecondTTL(t *testing.T) {
+	server := startMiniredis(t)
+	store, err := services.NewRedisStateStore("redis://" + server.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	if err := store.Set("tiny", "x", 500*time.Microsecond); err != nil {
+		t.Fatalf("Expected sub-millisecond TTL accepted, got %v", err)
+	}
+	if ttl := server.TTL("tiny"); ttl != time.Millisecond {
+		t.Errorf("Expected TTL rounded up to 1ms, got %v", ttl)
+	}
+	if _, err := store.Incr("tiny-counter", 500*time.Microsecond); err != nil {
+		t.Fatalf("Expected sub-millisecond window accepted, got %v", err)
+	}
+	if ttl := server.TTL("tiny-counter"); ttl != time.Millisecond {
+		t.Errorf("Expected counter TTL rounded up to 1ms, got %v", ttl)
+	}
+
+	server.FastForward(time.Millisecond)
+	if _, found, _ := store.Get("tiny"); found {
+		t.Error("Expected key expired")
+	}
+}
+
+func TestRedisStateStoreRejectsBadConfig(t *testing.T) {
+	server := startMiniredis(t)
+	server.RequireAuth("secret")
+
+	if _, err := services.NewRedisStateStore("redis://:wrong@" + server.Addr()); err == nil || !strings.Contains(err.Error(), "WRONGPASS") {
+		t.Errorf("Expected auth error, got %v", err)
+	}
+	if _, err := services.NewRedisStateStore("http://localhost:6379"); err == nil {
+		t.Error("Expected error for unknown scheme")
+	}
+	if _, err := services.NewRedisStateStore("redis://localhost:6379/abc"); err == nil {
+		t.Error("Expected error for invalid database")
+	}
+}
+
+func TestRedisStateStoreSharedBetweenReplicas(t *testing.T) {
+	server := startMiniredis(t)
+
+	replicaA, err := services.NewRedisStateStore("redis://" + server.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer replicaA.Close()
+	replicaB, err := services.NewRedisStateStore("redis://" + server.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer replicaB.Close()
+
+	limiterA := middleware.NewRateLimiter(replicaA)
+	limiterB := middleware.NewRateLimiter(replicaB)
+	allowed := 0
+	for i := 0; i < 5; i++ {
+		if limiterA.Allow("1.2.3.4", 3, time.Minute) {
+			allowed++
+		}
+		if limiterB.Allow("1.2.3.4", 3, time.Minute) {
+			allowed++
+		}
+	}
+	if allowed != 3 {
+		t.Errorf("Expected the limit shared by both replicas, %d requests allowed", allowed)
+	}
+}
+
+func TestRateLimiterSlidingWindow(t *testing.T) {
+	store := services.NewMemoryStateStore()
+	limiter := middleware.NewRateLimiter(store)
+	window := time.Hour
+	limit := 10
+
+	// The client used its whole limit at the end of the previous window
+	now := time.Now()
+	previousKey := fmt.Sprintf("ratelimit:1.2.3.4:%d", now.UnixNano()/int64(window)-1)
+	if err := store.Set(previousKey, strconv.Itoa(limit), 2*window); err != nil {
+		t.Fatal(err)
+	}
+
+	allowed := 0
+	for i := 0; i < 2*limit; i++ {
+		if limiter.Allow("1.2.3.4", limit, window) {
+			allowed++
+		}
+	}
+
+	// Only the part of the previous window that slid out frees up requests
+	elapsed := float64(time.Now().UnixNano()%int64(window)) / float64(window)
+	if max := int(float64(limit) * elapsed); allowed > max {
+		t.Errorf("Expected at most %d requests right after a full window, %d allowed", max, allowed)
+	}
+}
+
+type failingStateStore struct{}
+
+func (failingStateStore) Get(string) (string, bool, error) {
+	return "", false, errors.New("store unavailable")
+}
+func (failingStateStore) Set(string, string, time.Duration) error {
+	return errors.New("store unavailable")
+}
+func (failingStateStore) Delete(string) error { return errors.New("store unavailable") }
+func (failingStateStore) Incr(string, time.Duration) (int64, error) {
+	return 0, errors.New("store unavailable")
+}
+
+func TestTokenBlacklistFailsClosed(t *testing.T) {
+	blacklist := services.InitTokenBlacklistService(failingStateStore{})
+	t.Cleanup(func() { services.InitTokenBlacklistService(services.NewMemoryStateStore()) })
+
+	if !blacklist.IsBlacklisted("jti-1") {
+		t.Error("Expected tokens treated as blacklisted while the store is unavailable")
+	}
+}
+
+func TestTokenBlacklistUsesStateStore(t *testing.T) {
+	store := services.NewMemoryStateStore()
+	blacklist := services.InitTokenBlacklistService(store)
+	t.Cleanup(func() { services.InitTokenBlacklistService(services.NewMemoryStateStore()) })
+
+	if err := blacklist.AddToken("jti-1", time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if err := blacklist.AddToken("jti-expired", time.Now().Add(-time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+
+	if !services.GetTokenBlacklistService().IsBlacklisted("jti-1") {
+		t.Error("Expected token blacklisted")
+	}
+	if blacklist.IsBlacklisted("jti-expired") || blacklist.IsBlacklisted("jti-2") {
+		t.Error("Expected expired and unknown tokens not blacklisted")
+	}
+	if _, found, _ := store.Get("blacklist:jti-1"); !found {
+		t.Error("Expected blacklist entry in the state store")
+	}
+}
+
+func TestProgressiveDelayServiceSharesRecords(t *testing.T) {
+	store := services.NewMemoryStateStore()
+	cfg := services.DefaultProgressiveDelayConfig()
+	cfg.LockoutThreshold = 3
+
+	// Two replicas backed by the same store
+	replicaA := services.NewProgressiveDelayService(store, nil, cfg)
+	replicaB := services.NewProgressiveDelayService(store, nil, cfg)
+
+	delay, locked, _, err := replicaA.RecordFailedAttempt("user@example.com", "1.2.3.4")
+	if err != nil || locked || delay != time.Second {
+		t.Fatalf("Expected 1s delay, got %v locked=%v err=%v", delay, locked, err)
+	}
+	delay, _, _, _ = replicaB.RecordFailedAttempt("user@example.com", "5.6.7.8")
+	if delay != 2*time.Second {
+		t.Errorf("Expected the email counter shared between replicas, got delay %v", delay)
+	}
+	_, locked, lockUntil, _ := replicaA.RecordFailedAttempt("user@example.com", "9.9.9.9")
+	if !locked || lockUntil == nil {
+		t.Fatal("Expected lockout after the threshold")
+	}
+
+	if isLocked, _, _, _ := replicaB.CheckIfLocked("user@example.com", "0.0.0.0"); !isLocked {
+		t.Error("Expected lockout visible on the other replica")
+	}
+
+	if err := replicaB.RecordSuccessfulLogin("user@example.com", "9.9.9.9"); err != nil {
+		t.Fatal(err)
+	}
+	if isLocked, _, _, _ := replicaA.CheckIfLocked("user@example.com", "0.0.0.0"); isLocked {
+		t.Error("Expected records cleared after a successful login")
+	}
+}
@@ -0,0 +1,125 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/workradar/server/internal/models"
+	"github.com/workradar/server/internal/repository"
+	"github.com/workradar/server/internal/services"
+)
+
+// ============================================
+// QUICK-ADD PARSER TESTS
+// ============================================
+
+// Wednesday 10:00
+var quickAddNow = time.Date(2026, 3, 4, 10, 0, 0, 0, time.Local)
+
+func TestParseQuickAddIndonesian(t *testing.T) {
+	parsed := services.ParseQuickAdd("rapat klien jumat jam 3 sore 1 jam ingatkan 30 menit sebelumnya #Kerja", quickAddNow)
+
+	if parsed.Title != "Rapat klien" {
+		t.Errorf("Expected title 'Rapat klien', got %q", parsed.Title)
+	}
+	want := time.Date(2026, 3, 6, 15, 0, 0, 0, time.Local)
+	if parsed.Deadline == nil || !parsed.Deadline.Equal(want) {
+		t.Errorf("Expected deadline %v, got %v", want, parsed.Deadline)
+	}
+	if parsed.DurationMinutes == nil || *parsed.DurationMinutes != 60 {
+		t.Errorf("Expected 60 minute duration, got %v", parsed.DurationMinutes)
+	}
+	if parsed.ReminderMinutes == nil || *parsed.ReminderMinutes != 30 {
+		t.Errorf("Expected 30 minute reminder, got %v", parsed.ReminderMinutes)
+	}
+	if parsed.CategoryName != "Kerja" {
+		t.Errorf("Expected category Kerja, got %q", parsed.CategoryName)
+	}
+}
+
+func TestParseQuickAddEnglish(t *testing.T) {
+	parsed := services.ParseQuickAdd("submit report tomorrow at 9:30am for 45 min remind me 1 hour before", quickAddNow)
+
+	if parsed.Title != "Submit report" {
+		t.Errorf("Expected title 'Submit report', got %q", parsed.Title)
+	}
+	want := time.Date(2026, 3, 5, 9, 30, 0, 0, time.Local)
+	if parsed.Deadline == nil || !parsed.Deadline.Equal(want) {
+		t.Errorf("Expected deadline %v, got %v", want, parsed.Deadline)
+	}
+	if parsed.DurationMinutes == nil || *parsed.DurationMinutes != 45 {
+		t.Errorf("Expected 45 minute duration, got %v", parsed.DurationMinutes)
+	}
+	if parsed.ReminderMinutes == nil || *parsed.ReminderMinutes != 60 {
+		t.Errorf("Expected 60 minute reminder, got %v", parsed.ReminderMinutes)
+	}
+}
+
+func TestParseQuickAddDates(t *testing.T) {
+	tests := []struct {
+		input string
+		want  time.Time
+	}{
+		{"bayar listrik besok", time.Date(2026, 3, 5, 9, 0, 0, 0, time.Local)},
+		{"servis motor lusa jam 10", time.Date(2026, 3, 6, 10, 0, 0, 0, time.Local)},
+		{"presentasi rabu depan", time.Date(2026, 3, 11, 9, 0, 0, 0, time.Local)},
+		{"kirim invoice 25/12", time.Date(2026, 12, 25, 9, 0, 0, 0, time.Local)},
+		{"perpanjang STNK 2 januari", time.Date(2027, 1, 2, 9, 0, 0, 0, time.Local)},
+		{"telepon vendor 2 jam lagi", time.Date(2026, 3, 4, 12, 0, 0, 0, time.Local)},
+		{"call mom in 3 days", time.Date(2026, 3, 7, 9, 0, 0, 0, time.Local)},
+		{"standup jam 9 pagi", time.Date(2026, 3, 5, 9, 0, 0, 0, time.Local)}, // Already passed today
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			parsed := services.ParseQuickAdd(tt.input, quickAddNow)
+			if parsed.Deadline == nil || !parsed.Deadline.Equal(tt.want) {
+				t.Errorf("Expected deadline %v, got %v", tt.want, parsed.Deadline)
+			}
+		})
+	}
+}
+
+func TestParseQuickAddRepeat(t *testing.T) {
+	parsed := services.ParseQuickAdd("laporan mingguan setiap senin jam 8 pagi", quickAddNow)
+
+	if parsed.RepeatType != models.RepeatWeekly || parsed.RepeatInterval != 1 {
+		t.Errorf("Expected weekly repeat, got %s every %d", parsed.RepeatType, parsed.RepeatInterval)
+	}
+	want := time.Date(2026, 3, 9, 8, 0, 0, 0, time.Local)
+	if parsed.Deadline == nil || !parsed.Deadline.Equal(want) {
+		t.Errorf("Expected first occurrence %v, got %v", want, parsed.Deadline)
+	}
+	if parsed.Title != "Laporan mingguan" {
+		t.Errorf("Expected title 'Laporan mingguan', got %q", parsed.Title)
+	}
+
+	parsed = services.ParseQuickAdd("siram tanaman tiap 2 hari", quickAddNow)
+	if parsed.RepeatType != models.RepeatDaily || parsed.RepeatInterval != 2 {
+		t.Errorf("Expected daily repeat every 2, got %s every %d", parsed.RepeatType, parsed.RepeatInterval)
+	}
+	if parsed.Title != "Siram tanaman" {
+		t.Errorf("Expected title 'Siram tanaman', got %q", parsed.Title)
+	}
+}
+
+func TestParseTaskUsesUserTimezone(t *testing.T) {
+	db := newTestDB(t)
+	migrateTestDB(t, db, &models.User{}, &models.Category{}, &models.Task{}, &models.TaskReminder{})
+	if err := db.Create(&models.User{ID: "user-1", Email: "user@example.com", Username: "user", Timezone: "Asia/Tokyo"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	taskService := services.NewTaskService(repository.NewTaskRepository(db), repository.NewCategoryRepository(db),
+		repository.NewTaskReminderRepository(db), repository.NewUserRepository(db), nil)
+
+	result, err := taskService.ParseTask("user-1", "rapat besok jam 9 pagi", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Preview.Deadline == nil {
+		t.Fatal("Expected a deadline")
+	}
+	if local := result.Preview.Deadline.In(time.FixedZone("JST", 9*60*60)); local.Hour() != 9 {
+		t.Errorf("Expected 09:00 in the user's timezone, got %s", local.Format(time.RFC3339))
+	}
+}
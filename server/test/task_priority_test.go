@@ -0,0 +1,112 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/workradar/server/internal/models"
+	"github.com/workradar/server/internal/services"
+)
+
+// ============================================
+// TASK PRIORITY & EISENHOWER MATRIX TESTS
+// ============================================
+
+func priorityTask(id string, priority models.TaskPriority, deadline *time.Time) models.Task {
+	return models.Task{ID: id, Title: id, Priority: priority, Deadline: deadline}
+}
+
+func TestComputeUrgency(t *testing.T) {
+	now := time.Date(2026, 3, 4, 10, 0, 0, 0, time.Local)
+	at := func(d time.Duration) *time.Time {
+		v := now.Add(d)
+		return &v
+	}
+
+	cases := []struct {
+		deadline *time.Time
+		want     services.TaskUrgency
+	}{
+		{nil, services.UrgencyNone},
+		{at(-time.Hour), services.UrgencyOverdue},
+		{at(24 * time.Hour), services.UrgencyUrgent},
+		{at(4 * 24 * time.Hour), services.UrgencySoon},
+		{at(30 * 24 * time.Hour), services.UrgencyLater},
+	}
+	for _, tc := range cases {
+		got := services.ComputeUrgency(priorityTask("t", models.PriorityMedium, tc.deadline), now)
+		if got != tc.want {
+			t.Errorf("Deadline %v: expected %s, got %s", tc.deadline, tc.want, got)
+		}
+	}
+}
+
+func TestEisenhowerMatrixQuadrants(t *testing.T) {
+	now := time.Date(2026, 3, 4, 10, 0, 0, 0, time.Local)
+	tomorrow := now.Add(24 * time.Hour)
+	nextMonth := now.AddDate(0, 1, 0)
+
+	done := priorityTask("done", models.PriorityHigh, &tomorrow)
+	done.IsCompleted = true
+
+	matrix := services.BuildEisenhowerMatrix([]models.Task{
+		priorityTask("later-low", models.PriorityLow, &nextMonth),
+		priorityTask("urgent-medium", models.PriorityMedium, &tomorrow),
+		priorityTask("important-later", models.PriorityHigh, &nextMonth),
+		priorityTask("important-urgent", models.PriorityHigh, &tomorrow),
+		priorityTask("no-deadline", models.PriorityHigh, nil),
+		done,
+	}, now)
+
+	ids := func(items []services.MatrixTask) []string {
+		var out []string
+		for _, item := range items {
+			out = append(out, item.ID)
+		}
+		return out
+	}
+
+	if got := ids(matrix.DoFirst); len(got) != 1 || got[0] != "important-urgent" {
+		t.Errorf("Expected do_first [important-urgent], got %v", got)
+	}
+	if got := ids(matrix.Schedule); len(got) != 2 || got[0] != "important-later" || got[1] != "no-deadline" {
+		t.Errorf("Expected schedule [important-later no-deadline], got %v", got)
+	}
+	if got := ids(matrix.Delegate); len(got) != 1 || got[0] != "urgent-medium" {
+		t.Errorf("Expected delegate [urgent-medium], got %v", got)
+	}
+	if got := ids(matrix.Eliminate); len(got) != 1 || got[0] != "later-low" {
+		t.Errorf("Expected eliminate [later-low], got %v", got)
+	}
+}
+
+func TestSortByPriority(t *testing.T) {
+	now := time.Date(2026, 3, 4, 10, 0, 0, 0, time.Local)
+	soon, later := now.Add(time.Hour), now.Add(2*time.Hour)
+
+	tasks := []models.Task{
+		priorityTask("low", models.PriorityLow, &soon),
+		priorityTask("medium-later", "", &later),
+		priorityTask("high", models.PriorityHigh, &later),
+		priorityTask("medium-soon", models.PriorityMedium, &soon),
+	}
+	services.SortByPriority(tasks)
+
+	want := []string{"high", "medium-soon", "medium-later", "low"}
+	for i, id := range want {
+		if tasks[i].ID != id {
+			t.Fatalf("Expected order %v, got %s at %d", want, tasks[i].ID, i)
+		}
+	}
+}
+
+func TestParseQuickAddPriority(t *testing.T) {
+	parsed := services.ParseQuickAdd("kirim proposal besok !penting", quickAddNow)
+
+	if parsed.Priority == nil || *parsed.Priority != string(models.PriorityHigh) {
+		t.Errorf("Expected high priority, got %v", parsed.Priority)
+	}
+	if parsed.Title != "Kirim proposal" {
+		t.Errorf("Expected title 'Kirim proposal', got %q", parsed.Title)
+	}
+}
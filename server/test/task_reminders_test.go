@@ -0,0 +1,148 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/workradar/server/internal/models"
+	"github.com/workradar/server/internal/repository"
+	"github.com/workradar/server/internal/services"
+)
+
+// ============================================
+// TASK REMINDER TESTS
+// ============================================
+
+func reminderTask(deadline time.Time, reminderMinutes *int, reminders ...models.TaskReminder) models.Task {
+	return models.Task{
+		ID:              "task-1",
+		Title:           "Laporan",
+		Deadline:        &deadline,
+		ReminderMinutes: reminderMinutes,
+		Reminders:       reminders,
+	}
+}
+
+func TestDueRemindersDayBefore(t *testing.T) {
+	deadline := time.Date(2026, 3, 5, 10, 0, 0, 0, time.Local)
+	task := reminderTask(deadline, minutesPtr(30), models.TaskReminder{OffsetMinutes: minutesPtr(24 * 60)})
+
+	// One day before: only the 1-day reminder is due
+	due := services.DueReminders(task, deadline.Add(-24*time.Hour+time.Minute))
+	if len(due) != 1 || due[0].OffsetMinutes == nil || *due[0].OffsetMinutes != 24*60 {
+		t.Fatalf("Expected the 1-day reminder, got %+v", due)
+	}
+
+	// 30 minutes before: only the 30-minute reminder, the 1-day one is past its catch-up window
+	later := services.DueReminders(task, deadline.Add(-30*time.Minute))
+	if len(later) != 1 || later[0].Key == due[0].Key || *later[0].OffsetMinutes != 30 {
+		t.Fatalf("Expected the 30-minute reminder, got %+v", later)
+	}
+}
+
+func TestDueRemindersCatchUpWindow(t *testing.T) {
+	deadline := time.Date(2026, 3, 5, 10, 0, 0, 0, time.Local)
+	task := reminderTask(deadline, nil, models.TaskReminder{OffsetMinutes: minutesPtr(3 * 24 * 60)})
+
+	// Missed by two hours (e.g. server restart): still sent
+	if due := services.DueReminders(task, deadline.Add(-3*24*time.Hour+2*time.Hour)); len(due) != 1 {
+		t.Errorf("Expected missed reminder to be caught up, got %+v", due)
+	}
+	// Missed by a day: dropped
+	if due := services.DueReminders(task, deadline.Add(-2*24*time.Hour)); len(due) != 0 {
+		t.Errorf("Expected stale reminder to be dropped, got %+v", due)
+	}
+}
+
+func TestDueRemindersOverdueNudges(t *testing.T) {
+	deadline := time.Date(2026, 3, 5, 10, 0, 0, 0, time.Local)
+	task := reminderTask(deadline, minutesPtr(15))
+
+	first := services.DueReminders(task, deadline.Add(time.Hour))
+	if len(first) != 1 || first[0].Kind != services.ReminderKindOverdue || first[0].Nudge != 1 {
+		t.Fatalf("Expected first overdue nudge, got %+v", first)
+	}
+
+	second := services.DueReminders(task, deadline.Add(24*time.Hour))
+	if len(second) != 1 || second[0].Nudge != 2 {
+		t.Fatalf("Expected second overdue nudge, got %+v", second)
+	}
+
+	task.IsCompleted = true
+	if due := services.DueReminders(task, deadline.Add(24*time.Hour)); len(due) != 0 {
+		t.Errorf("Expected no nudges for completed task, got %+v", due)
+	}
+}
+
+func TestDueRemindersRearmOnNewDeadline(t *testing.T) {
+	deadline := time.Date(2026, 3, 5, 10, 0, 0, 0, time.Local)
+	task := reminderTask(deadline, minutesPtr(60))
+	before := services.DueReminders(task, deadline.Add(-time.Hour))
+
+	moved := deadline.Add(24 * time.Hour)
+	task.Deadline = &moved
+	after := services.DueReminders(task, moved.Add(-time.Hour))
+
+	if len(before) != 1 || len(after) != 1 || before[0].Key == after[0].Key {
+		t.Errorf("Expected a new reminder key after moving the deadline, got %+v and %+v", before, after)
+	}
+}
+
+func TestDueRemindersAbsolute(t *testing.T) {
+	at := time.Date(2026, 3, 4, 8, 0, 0, 0, time.Local)
+	task := models.Task{ID: "task-2", Title: "Telepon dokter", Reminders: []models.TaskReminder{{RemindAt: &at}}}
+
+	if due := services.DueReminders(task, at.Add(-time.Minute)); len(due) != 0 {
+		t.Errorf("Expected nothing before remind_at, got %+v", due)
+	}
+	if due := services.DueReminders(task, at.Add(time.Minute)); len(due) != 1 || due[0].Kind != services.ReminderKindReminder {
+		t.Errorf("Expected absolute reminder, got %+v", due)
+	}
+}
+
+func minutesPtr(v int) *int {
+	return &v
+}
+
+func TestReminderCandidatesLimitedToLargestOffset(t *testing.T) {
+	db := newTestDB(t)
+	migrateTestDB(t, db, &models.Task{}, &models.TaskReminder{})
+	repo := repository.NewTaskReminderRepository(db)
+	now := time.Now()
+
+	dayBefore := 24 * 60
+	soon, later := now.Add(23*time.Hour), now.AddDate(0, 0, 14)
+	tasks := []models.Task{
+		// A day-before reminder, due now
+		{ID: "task-1", UserID: "user-1", Title: "Laporan", Deadline: &soon, ReminderMinutes: &dayBefore},
+		// Due in two weeks, nothing fires yet
+		{ID: "task-2", UserID: "user-1", Title: "Presentasi", Deadline: &later},
+	}
+	for i := range tasks {
+		if err := db.Create(&tasks[i]).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	maxOffset, err := repo.MaxReminderOffset(now)
+	if err != nil || maxOffset != dayBefore {
+		t.Fatalf("Expected the largest offset %d, got %d (err: %v)", dayBefore, maxOffset, err)
+	}
+
+	// A two-hour extra reminder on the later task does not widen the window
+	twoHours := 120
+	if err := db.Create(&models.TaskReminder{TaskID: "task-2", OffsetMinutes: &twoHours}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if maxOffset, _ = repo.MaxReminderOffset(now); maxOffset != dayBefore {
+		t.Errorf("Expected the largest offset kept at %d, got %d", dayBefore, maxOffset)
+	}
+
+	candidates, err := repo.FindReminderCandidates(now, time.Duration(maxOffset)*time.Minute, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(candidates) != 1 || candidates[0].ID != "task-1" {
+		t.Errorf("Expected only the task with a due reminder, got %d candidates", len(candidates))
+	}
+}
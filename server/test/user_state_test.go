@@ -0,0 +1,90 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/workradar/server/internal/models"
+	"github.com/workradar/server/internal/services"
+)
+
+// ============================================
+// USER STATE TESTS
+// ============================================
+
+func TestUserStateEffectiveUserType(t *testing.T) {
+	now := time.Now()
+	past := now.Add(-time.Hour)
+	future := now.Add(time.Hour)
+
+	cases := []struct {
+		name  string
+		state services.UserState
+		want  models.UserType
+	}{
+		{"regular", services.UserState{UserType: models.UserTypeRegular}, models.UserTypeRegular},
+		{"active vip", services.UserState{UserType: models.UserTypeVIP, VIPExpiresAt: &future}, models.UserTypeVIP},
+		{"vip without expiry", services.UserState{UserType: models.UserTypeVIP}, models.UserTypeVIP},
+		{"expired vip", services.UserState{UserType: models.UserTypeVIP, VIPExpiresAt: &past}, models.UserTypeRegular},
+	}
+	for _, tc := range cases {
+		if got := tc.state.EffectiveUserType(now); got != tc.want {
+			t.Errorf("%s: got %s, want %s", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestUserStateIsLocked(t *testing.T) {
+	now := time.Now()
+	past := now.Add(-time.Minute)
+	future := now.Add(time.Minute)
+
+	if (&services.UserState{}).IsLocked(now) {
+		t.Error("Expected unlocked without LockedUntil")
+	}
+	if (&services.UserState{LockedUntil: &past}).IsLocked(now) {
+		t.Error("Expected unlocked after the lockout ended")
+	}
+	if !(&services.UserState{LockedUntil: &future}).IsLocked(now) {
+		t.Error("Expected locked")
+	}
+}
+
+func TestUserStateIssuedBeforePasswordChange(t *testing.T) {
+	changedAt := time.Date(2026, 5, 1, 12, 0, 0, 700*int(time.Millisecond), time.UTC)
+	state := services.UserState{PasswordChangedAt: &changedAt}
+
+	if !state.IssuedBeforePasswordChange(changedAt.Add(-2 * time.Second).Truncate(time.Second)) {
+		t.Error("Expected a token issued before the change to be rejected")
+	}
+	// iat has second precision: a token issued right after the change in the same second is valid
+	if state.IssuedBeforePasswordChange(changedAt.Truncate(time.Second)) {
+		t.Error("Expected a token issued in the same second to be accepted")
+	}
+	if (&services.UserState{}).IssuedBeforePasswordChange(changedAt) {
+		t.Error("Expected every token valid when the password never changed")
+	}
+}
+
+func TestUserStateServiceUsesCache(t *testing.T) {
+	store := services.NewMemoryStateStore()
+	store.Set("userstate:user-1", `{"user_type":"vip"}`, time.Minute)
+
+	// Without a repository the state can only come from the cache
+	userStates := services.NewUserStateService(nil, store, time.Minute)
+	state, err := userStates.Get("user-1")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if state.UserType != models.UserTypeVIP {
+		t.Errorf("Expected cached vip state, got %s", state.UserType)
+	}
+
+	userStates.Invalidate("user-1")
+	if _, found, _ := store.Get("userstate:user-1"); found {
+		t.Error("Expected cached state removed")
+	}
+
+	var disabled *services.UserStateService
+	disabled.Invalidate("user-1") // Must not panic
+}
@@ -0,0 +1,305 @@
+package test
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/workradar/server/internal/models"
+	"github.com/workradar/server/internal/repository"
+	"github.com/workradar/server/internal/services"
+	"gorm.io/gorm"
+)
+
+// ============================================
+// WEBHOOK EVENT TESTS
+// ============================================
+
+type webhookTestEnv struct {
+	db        *gorm.DB
+	gateway   *services.FakePaymentGateway
+	trxRepo   *repository.TransactionRepository
+	eventRepo *repository.WebhookEventRepository
+	service   *services.WebhookEventService
+}
+
+func newWebhookTestEnv(t *testing.T) *webhookTestEnv {
+	t.Helper()
+	db := newTestDB(t)
+	migrateTestDB(t, db, &models.User{}, &models.Subscription{}, &models.Transaction{}, &models.WebhookEvent{})
+
+	env := &webhookTestEnv{
+		db:        db,
+		gateway:   services.NewFakePaymentGateway(),
+		trxRepo:   repository.NewTransactionRepository(db),
+		eventRepo: repository.NewWebhookEventRepository(db),
+	}
+	userRepo := repository.NewUserRepository(db)
+	userStates := services.NewUserStateService(userRepo, services.NewMemoryStateStore(), time.Minute)
+	subService := services.NewSubscriptionService(userRepo, repository.NewSubscriptionRepository(db), db, userStates)
+	paymentService := services.NewPaymentService(env.trxRepo, userRepo, subService, nil, env.gateway, nil)
+	env.service = services.NewWebhookEventService(env.eventRepo, paymentService)
+	return env
+}
+
+// notify simulates a gateway notification for orderID and returns its body
+func (env *webhookTestEnv) notify(t *testing.T, orderID, transactionStatus string) map[string]interface{} {
+	t.Helper()
+	payload, err := env.gateway.Simulate(orderID, transactionStatus, "", "bank_transfer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return payload
+}
+
+func (env *webhookTestEnv) checkout(t *testing.T, orderID string) {
+	t.Helper()
+	if _, err := env.gateway.CreateCheckout(services.CheckoutRequest{OrderID: orderID, Amount: models.PriceMonthly}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func webhookBody(t *testing.T, payload map[string]interface{}) []byte {
+	t.Helper()
+	body, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return body
+}
+
+func TestWebhookRecordRejectsUnverifiedNotifications(t *testing.T) {
+	env := newWebhookTestEnv(t)
+	env.checkout(t, "ORDER-1")
+	payload := env.notify(t, "ORDER-1", "cancel")
+
+	forged := map[string]interface{}{}
+	for k, v := range payload {
+		forged[k] = v
+	}
+	forged["gross_amount"] = "1.00"
+
+	tests := []struct {
+		name string
+		body []byte
+		want error
+	}{
+		{"not json", []byte("not json"), services.ErrWebhookInvalidPayload},
+		{"missing signature", []byte(`{"order_id":"ORDER-1","status_code":"202","gross_amount":"49000.00"}`), services.ErrWebhookInvalidPayload},
+		{"forged signature", webhookBody(t, forged), services.ErrWebhookInvalidSignature},
+		{"oversized body", []byte(`{"padding":"` + strings.Repeat("x", 70<<10) + `"}`), services.ErrWebhookInvalidPayload},
+	}
+	for _, tt := range tests {
+		event, _, err := env.service.Record(services.PaymentGatewayFake, tt.body, nil)
+		if !errors.Is(err, tt.want) || event != nil {
+			t.Errorf("%s: expected %v, got event=%v err=%v", tt.name, tt.want, event, err)
+		}
+	}
+
+	if _, total, _ := env.eventRepo.List("", "", 10, 0); total != 0 {
+		t.Errorf("Expected unverified notifications not stored, got %d events", total)
+	}
+}
+
+func TestWebhookRecordDedupe(t *testing.T) {
+	env := newWebhookTestEnv(t)
+	env.checkout(t, "ORDER-1")
+	payload := env.notify(t, "ORDER-1", "cancel")
+	body := webhookBody(t, payload)
+
+	first, duplicate, err := env.service.Record(services.PaymentGatewayFake, body, map[string][]string{
+		"Content-Type":  {"application/json"},
+		"Authorization": {"Basic secret"},
+	})
+	if err != nil || duplicate {
+		t.Fatalf("Expected new event, got duplicate=%v err=%v", duplicate, err)
+	}
+	if !first.SignatureValid || first.Status != models.WebhookEventStatusReceived {
+		t.Errorf("Expected verified received event, got %+v", first)
+	}
+	if _, ok := first.Headers["Authorization"]; ok {
+		t.Error("Expected Authorization header dropped")
+	}
+
+	// A retry carrying different unsigned fields maps to the same event and
+	// does not replace the stored body
+	payload["notification_id"] = "attacker-chosen"
+	payload["payment_type"] = "credit_card"
+	retry, duplicate, err := env.service.Record(services.PaymentGatewayFake, webhookBody(t, payload), nil)
+	if err != nil || duplicate {
+		t.Fatalf("Expected unprocessed retry, got duplicate=%v err=%v", duplicate, err)
+	}
+	if retry.ID != first.ID {
+		t.Errorf("Expected retry deduped to %s, got %s", first.ID, retry.ID)
+	}
+	stored, _ := env.eventRepo.FindByID(first.ID)
+	if stored.RawBody != string(body) {
+		t.Errorf("Expected stored body kept, got %s", stored.RawBody)
+	}
+
+	// A new status for the same order is a different notification
+	other, _, err := env.service.Record(services.PaymentGatewayFake, webhookBody(t, env.notify(t, "ORDER-1", "expire")), nil)
+	if err != nil || other.ID == first.ID {
+		t.Errorf("Expected a separate event for a new status, got %v err=%v", other, err)
+	}
+}
+
+func TestWebhookProcessAndReplay(t *testing.T) {
+	env := newWebhookTestEnv(t)
+	env.checkout(t, "ORDER-1")
+	body := webhookBody(t, env.notify(t, "ORDER-1", "cancel"))
+
+	// The transaction is unknown yet, so processing fails
+	event, _, err := env.service.Record(services.PaymentGatewayFake, body, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.service.Process(event); err == nil {
+		t.Fatal("Expected processing to fail for an unknown transaction")
+	}
+	stored, _ := env.eventRepo.FindByID(event.ID)
+	if stored.Status != models.WebhookEventStatusFailed || stored.Error == nil || stored.Attempts != 1 {
+		t.Errorf("Expected failed event after one attempt, got %+v", stored)
+	}
+
+	if err := env.trxRepo.Create(&models.Transaction{
+		OrderID:  "ORDER-1",
+		UserID:   "user-1",
+		PlanType: models.PlanTypeMonthly,
+		Amount:   models.PriceMonthly,
+		Status:   models.TransactionStatusPending,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	replayed, err := env.service.Replay(event.ID, "admin-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replayed.Status != models.WebhookEventStatusProcessed || replayed.Error != nil || replayed.Attempts != 2 {
+		t.Errorf("Expected processed event after replay, got %+v", replayed)
+	}
+	if replayed.LastReplayedBy == nil || *replayed.LastReplayedBy != "admin-1" {
+		t.Errorf("Expected replay recorded, got %v", replayed.LastReplayedBy)
+	}
+	if trx, _ := env.trxRepo.FindByOrderID("ORDER-1"); trx.Status != models.TransactionStatusCancel {
+		t.Errorf("Expected transaction cancelled, got %s", trx.Status)
+	}
+
+	// Further deliveries are acknowledged as duplicates
+	if _, duplicate, err := env.service.Record(services.PaymentGatewayFake, body, nil); err != nil || !duplicate {
+		t.Errorf("Expected duplicate after processing, got duplicate=%v err=%v", duplicate, err)
+	}
+
+	if _, err := env.service.Replay("missing", "admin-1"); err == nil {
+		t.Error("Expected error replaying an unknown event")
+	}
+}
+
+func TestWebhookExpiredPaymentKeepsExpireStatus(t *testing.T) {
+	env := newWebhookTestEnv(t)
+	env.checkout(t, "ORDER-1")
+	if err := env.trxRepo.Create(&models.Transaction{
+		OrderID:  "ORDER-1",
+		UserID:   "user-1",
+		PlanType: models.PlanTypeMonthly,
+		Amount:   models.PriceMonthly,
+		Status:   models.TransactionStatusPending,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	event, _, err := env.service.Record(services.PaymentGatewayFake, webhookBody(t, env.notify(t, "ORDER-1", "expire")), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.service.Process(event); err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+	if trx, _ := env.trxRepo.FindByOrderID("ORDER-1"); trx.Status != models.TransactionStatusExpire {
+		t.Errorf("Expected transaction expired, not cancelled, got %s", trx.Status)
+	}
+}
+
+// settledOrder creates a VIP user whose subscription was paid by ORDER-1
+func (env *webhookTestEnv) settledOrder(t *testing.T) {
+	t.Helper()
+	env.checkout(t, "ORDER-1")
+	if err := env.db.Create(&models.User{ID: "user-1", Email: "user@example.com", Username: "user"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := env.trxRepo.Create(&models.Transaction{
+		OrderID:  "ORDER-1",
+		UserID:   "user-1",
+		PlanType: models.PlanTypeMonthly,
+		Amount:   models.PriceMonthly,
+		Status:   models.TransactionStatusPending,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	env.process(t, "ORDER-1", "settlement")
+
+	var user models.User
+	env.db.First(&user, "id = ?", "user-1")
+	if user.UserType != models.UserTypeVIP {
+		t.Fatalf("Expected VIP after settlement, got %s", user.UserType)
+	}
+}
+
+// process records and processes a gateway notification for orderID
+func (env *webhookTestEnv) process(t *testing.T, orderID, transactionStatus string) {
+	t.Helper()
+	event, _, err := env.service.Record(services.PaymentGatewayFake, webhookBody(t, env.notify(t, orderID, transactionStatus)), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.service.Process(event); err != nil {
+		t.Fatalf("Process %s failed: %v", transactionStatus, err)
+	}
+}
+
+func TestWebhookRefundOfSettledTransactionRevokesVIP(t *testing.T) {
+	env := newWebhookTestEnv(t)
+	env.settledOrder(t)
+
+	// A refund made from the gateway dashboard reaches us only as a notification
+	env.process(t, "ORDER-1", "refund")
+
+	if trx, _ := env.trxRepo.FindByOrderID("ORDER-1"); trx.Status != models.TransactionStatusRefund {
+		t.Errorf("Expected refunded transaction, got %s", trx.Status)
+	}
+	var user models.User
+	env.db.First(&user, "id = ?", "user-1")
+	if user.UserType != models.UserTypeRegular {
+		t.Errorf("Expected VIP revoked after refund, got %s", user.UserType)
+	}
+}
+
+func TestWebhookPartialRefundKeepsVIP(t *testing.T) {
+	env := newWebhookTestEnv(t)
+	env.settledOrder(t)
+
+	env.process(t, "ORDER-1", "partial_refund")
+
+	if trx, _ := env.trxRepo.FindByOrderID("ORDER-1"); trx.Status != models.TransactionStatusPartialRefund {
+		t.Errorf("Expected partially refunded transaction, got %s", trx.Status)
+	}
+	var user models.User
+	env.db.First(&user, "id = ?", "user-1")
+	if user.UserType != models.UserTypeVIP {
+		t.Errorf("Expected VIP kept after a partial refund, got %s", user.UserType)
+	}
+}
+
+func TestWebhookSettledTransactionIgnoresOtherStatuses(t *testing.T) {
+	env := newWebhookTestEnv(t)
+	env.settledOrder(t)
+
+	env.process(t, "ORDER-1", "expire")
+
+	if trx, _ := env.trxRepo.FindByOrderID("ORDER-1"); trx.Status != models.TransactionStatusSettlement {
+		t.Errorf("Expected settled transaction kept, got %s", trx.Status)
+	}
+}